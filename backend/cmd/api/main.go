@@ -1,15 +1,22 @@
 package main
 
 import (
+	"context"
 	"cupcake-delivery/internal/config"
 	"cupcake-delivery/internal/database"
 	"cupcake-delivery/internal/handlers"
 	"cupcake-delivery/internal/middleware"
 	"cupcake-delivery/internal/models"
 	"cupcake-delivery/internal/services"
+	"errors"
+	"expvar"
+	"fmt"
 	"log"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func main() {
@@ -23,21 +30,161 @@ func main() {
 	}
 
 	// Criar services e handlers
-	notificationService := services.NewNotificationService(db)
+	courierService := services.NewCourierService(db)
+	orderClaimService := services.NewOrderClaimService(services.NewDBOrderClaimStore(db))
+	notificationService := services.NewNotificationService(db, courierService)
+	// Uma réplica: o broker em memória basta para o stream de notificações
+	notificationBroker := services.NewMemoryNotificationBroker(16)
+	notificationService.SetBroker(notificationBroker)
+	// Quadro de pedidos ao vivo (WebSocket); cada conexão acumula até 64 eventos antes de ser encerrada
+	orderBoardStore := services.NewDBOrderBoardStore(db)
+	orderBoard := services.NewOrderBoard(64)
+	orderBoard.SetStore(orderBoardStore)
+	permissionService := services.NewPermissionService(db)
+	if err := permissionService.Reload(); err != nil {
+		log.Fatalf("Erro ao carregar permissões: %v", err)
+	}
+
+	var loginAttempts services.LoginAttemptStore = services.NewDBLoginAttemptStore(db)
+	if cfg.LoginAttemptStore == "memory" {
+		loginAttempts = services.NewMemoryLoginAttemptStore()
+	}
+	var lockoutNotifier services.LockoutNotifier = services.LogLockoutNotifier{}
+	if cfg.SMTPHost != "" {
+		lockoutNotifier = &services.SMTPLockoutNotifier{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+		}
+	}
+	loginThrottle := services.NewLoginThrottle(loginAttempts, lockoutNotifier)
+
+	twoFactorService := services.NewTwoFactorService(services.NewDBTwoFactorStore(db), cfg.TOTPIssuer)
+
+	tokenService, err := newTokenService(cfg)
+	if err != nil {
+		log.Fatalf("Erro ao carregar chaves JWT: %v", err)
+	}
+
+	cepResolver, err := newCEPResolver(cfg)
+	if err != nil {
+		log.Fatalf("Erro ao carregar base de CEPs: %v", err)
+	}
+
+	graceDays, err := strconv.Atoi(cfg.AccountDeletionGraceDays)
+	if err != nil || graceDays < 0 {
+		log.Fatalf("ACCOUNT_DELETION_GRACE_DAYS inválido: %q", cfg.AccountDeletionGraceDays)
+	}
+	auditService := services.NewAuditService(db)
+	fileStorage := services.NewLocalFileStorage(cfg.ProofStorageDir)
+	privacyService := services.NewPrivacyService(db, auditService, fileStorage, time.Duration(graceDays)*24*time.Hour)
+	go privacyService.RunPurger(context.Background(), time.Hour)
+
+	deliveryPricer, err := newDeliveryPricer(cfg, db)
+	if err != nil {
+		log.Fatalf("Erro na configuração da taxa de entrega: %v", err)
+	}
+
+	breadcrumbSeconds, err := strconv.Atoi(cfg.LocationBreadcrumbSeconds)
+	if err != nil || breadcrumbSeconds < 0 {
+		log.Fatalf("LOCATION_BREADCRUMB_SECONDS inválido: %q", cfg.LocationBreadcrumbSeconds)
+	}
+	retentionHours, err := strconv.Atoi(cfg.LocationRetentionHours)
+	if err != nil || retentionHours < 0 {
+		log.Fatalf("LOCATION_RETENTION_HOURS inválido: %q", cfg.LocationRetentionHours)
+	}
+	trackingService := services.NewTrackingService(db, time.Duration(breadcrumbSeconds)*time.Second, time.Duration(retentionHours)*time.Hour)
+	go trackingService.RunPurger(context.Background(), time.Hour)
+
+	deliveryProofService := services.NewDeliveryProofService(db, fileStorage)
+
+	requireVerifiedVehicle, err := strconv.ParseBool(cfg.RequireVerifiedVehicle)
+	if err != nil {
+		log.Fatalf("REQUIRE_VERIFIED_VEHICLE inválido: %q", cfg.RequireVerifiedVehicle)
+	}
+	vehicleService := services.NewVehicleService(db, fileStorage, requireVerifiedVehicle)
+	orderClaimService.SetLoadChecker(vehicleService)
+
+	dispatcher, err := newDispatcher(cfg, db, courierService, orderClaimService, vehicleService, notificationService)
+	if err != nil {
+		log.Fatalf("Erro na configuração do despacho automático: %v", err)
+	}
+	if dispatcher != nil {
+		go dispatcher.Run(context.Background(), 5*time.Second)
+	}
+
+	deliveryFailureService := services.NewDeliveryFailureService(db)
+
+	batchService, err := newBatchService(cfg, db)
+	if err != nil {
+		log.Fatalf("Erro na configuração dos lotes de entrega: %v", err)
+	}
+	batchService.SetLoadChecker(vehicleService)
+
+	earningsService, err := newEarningsService(cfg, db)
+	if err != nil {
+		log.Fatalf("Erro na configuração do repasse aos entregadores: %v", err)
+	}
+	deliveryProofService.SetEarnings(earningsService)
+
+	// Sem gateway configurado, as cobranças (pedido e gorjeta) são apenas registradas no log
+	var payments services.PaymentProcessor = services.LogPaymentProcessor{}
+	orderPlacement := services.NewOrderPlacementService(services.NewDBOrderPlacementStore(db), payments)
+	tipWindowHours, err := strconv.Atoi(cfg.TipWindowHours)
+	if err != nil || tipWindowHours < 0 {
+		log.Fatalf("TIP_WINDOW_HOURS inválido: %q", cfg.TipWindowHours)
+	}
+	tipService := services.NewTipService(services.NewDBTipStore(db), payments, time.Duration(tipWindowHours)*time.Hour)
+	// Cobranças sem resposta conclusiva do gateway são repetidas com a mesma chave de idempotência
+	go orderPlacement.RunPaymentRetrier(context.Background(), time.Minute)
+	go tipService.RunPaymentRetrier(context.Background(), time.Minute)
+
+	etaService, err := newETAService(cfg, db)
+	if err != nil {
+		log.Fatalf("Erro na configuração da previsão de entrega: %v", err)
+	}
 
-	authHandler := handlers.NewAuthHandler(db, cfg.JWTSecret)
+	slaMonitor, slaInterval, err := newSLAMonitor(cfg, db, notificationService)
+	if err != nil {
+		log.Fatalf("Erro na configuração dos prazos dos pedidos: %v", err)
+	}
+	if slaInterval > 0 {
+		go slaMonitor.Run(context.Background(), slaInterval)
+	}
+
+	authHandler := handlers.NewAuthHandler(db, tokenService, loginThrottle, twoFactorService)
+	twoFactorHandler := handlers.NewTwoFactorHandler(db, twoFactorService, loginThrottle)
 	productHandler := handlers.NewProductHandler(db)
-	orderHandler := handlers.NewOrderHandler(db, notificationService)
-	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	orderHandler := handlers.NewOrderHandler(db, notificationService, permissionService, cepResolver, deliveryPricer, courierService, orderClaimService, dispatcher, deliveryProofService, batchService, earningsService, orderPlacement, etaService, orderBoard)
+	notificationHandler := handlers.NewNotificationHandler(notificationService, notificationBroker)
+	permissionHandler := handlers.NewPermissionHandler(permissionService)
+	kitchenHandler := handlers.NewKitchenHandler(db)
+	profileHandler := handlers.NewProfileHandler(db, cepResolver, loginThrottle)
+	cepHandler := handlers.NewCEPHandler(cepResolver)
+	privacyHandler := handlers.NewPrivacyHandler(privacyService, auditService)
+	deliveryZoneHandler := handlers.NewDeliveryZoneHandler(db)
+	courierHandler := handlers.NewCourierHandler(db, courierService, permissionService)
+	trackingHandler := handlers.NewTrackingHandler(db, trackingService, permissionService)
+	tipHandler := handlers.NewTipHandler(tipService, notificationService)
+	earningsHandler := handlers.NewEarningsHandler(db, earningsService, permissionService)
+	batchHandler := handlers.NewBatchHandler(db, batchService, courierService, notificationService, etaService, orderBoard)
+	deliveryFailureHandler := handlers.NewDeliveryFailureHandler(db, deliveryFailureService, notificationService, permissionService, dispatcher, etaService, orderBoard)
+	slaHandler := handlers.NewSLAHandler(slaMonitor)
+	etaHandler := handlers.NewETAHandler(etaService)
+	vehicleHandler := handlers.NewVehicleHandler(vehicleService)
+	orderBoardHandler := handlers.NewOrderBoardHandler(orderBoardStore, orderBoard, permissionService, courierService)
 
-	// Configurar rotas
-	r := gin.Default()
+	// Configurar rotas. O access_token das rotas de streaming sai da URL antes do log de acesso
+	r := gin.New()
+	r.Use(middleware.StripQueryToken(), gin.Logger(), gin.Recovery())
 
 	// Middleware de CORS
 	r.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, Last-Event-ID")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(200)
@@ -52,9 +199,58 @@ func main() {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 
+	// Chaves públicas para outros serviços validarem os tokens
+	r.GET("/.well-known/jwks.json", authHandler.JWKS)
+
+	// Consulta de CEP para preencher formulários de endereço; só para usuários logados, para que
+	// a rota não sirva de proxy aberto para o provedor de CEP
+	r.GET("/cep/:cep", middleware.AuthMiddleware(tokenService), cepHandler.Lookup)
+
 	// Rotas de autenticação
 	r.POST("/register", authHandler.Register)
 	r.POST("/login", authHandler.Login)
+	r.POST("/login/2fa", authHandler.LoginTwoFactor)
+	r.POST("/login/2fa/enroll", authHandler.LoginTwoFactorEnroll)
+
+	// Rotas de autenticação em dois fatores do usuário logado
+	twoFactor := r.Group("/2fa")
+	twoFactor.Use(middleware.AuthMiddleware(tokenService))
+	{
+		twoFactor.GET("", twoFactorHandler.Status)
+		twoFactor.POST("/enroll", twoFactorHandler.Enroll)
+		twoFactor.POST("/confirm", twoFactorHandler.Confirm)
+		twoFactor.POST("/disable", twoFactorHandler.Disable)
+		twoFactor.POST("/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
+
+		// Políticas de obrigatoriedade por tipo de usuário (admin)
+		twoFactor.GET("/policies", middleware.RequirePermission(permissionService, models.PermRolesManage), twoFactorHandler.ListPolicies)
+		twoFactor.PUT("/policies/:role", middleware.RequirePermission(permissionService, models.PermRolesManage), twoFactorHandler.UpdatePolicy)
+	}
+
+	// Rotas do perfil do usuário logado
+	me := r.Group("/me")
+	me.Use(middleware.AuthMiddleware(tokenService))
+	{
+		me.GET("", profileHandler.GetMe)
+		me.PUT("", profileHandler.UpdateMe)
+		me.GET("/addresses", profileHandler.ListAddresses)
+		me.POST("/addresses", profileHandler.CreateAddress)
+		me.PUT("/addresses/:id", profileHandler.UpdateAddress)
+		me.DELETE("/addresses/:id", profileHandler.DeleteAddress)
+
+		// Direitos do titular (LGPD): exportação e exclusão dos dados
+		me.GET("/export", privacyHandler.Export)
+		me.DELETE("", privacyHandler.RequestDeletion)
+		me.GET("/deletion", privacyHandler.DeletionStatus)
+		me.DELETE("/deletion", privacyHandler.CancelDeletion)
+	}
+
+	// Rotas de administração de usuários
+	users := r.Group("/users")
+	users.Use(middleware.AuthMiddleware(tokenService), middleware.RequirePermission(permissionService, models.PermUsersUnlock))
+	{
+		users.POST("/:id/unlock", authHandler.Unlock)
+	}
 
 	// Rotas de produtos
 	products := r.Group("/products")
@@ -62,9 +258,9 @@ func main() {
 		products.GET("", productHandler.List)
 		products.GET("/:id", productHandler.Get)
 
-		// Rotas protegidas para admin
+		// Rotas protegidas para quem pode gerenciar produtos
 		adminProducts := products.Group("")
-		adminProducts.Use(middleware.AuthMiddleware(cfg.JWTSecret), middleware.TypeMiddleware(models.AdminType))
+		adminProducts.Use(middleware.AuthMiddleware(tokenService), middleware.RequirePermission(permissionService, models.PermProductsWrite))
 		{
 			adminProducts.POST("", productHandler.Create)
 			adminProducts.PUT("/:id", productHandler.Update)
@@ -74,19 +270,128 @@ func main() {
 
 	// Rotas de pedidos (todas precisam de autenticação)
 	orders := r.Group("/orders")
-	orders.Use(middleware.AuthMiddleware(cfg.JWTSecret))
+	orders.Use(middleware.AuthMiddleware(tokenService))
 	{
 		// Rotas para clientes
-		orders.POST("", orderHandler.Create)
-		orders.GET("", orderHandler.List) // Lista filtrada por tipo de usuário
+		orders.POST("", middleware.RequirePermission(permissionService, models.PermOrdersCreate), orderHandler.Create)
+		orders.POST("/quote", middleware.RequirePermission(permissionService, models.PermOrdersCreate), orderHandler.Quote)
+		orders.GET("", orderHandler.List) // Lista filtrada pelas permissões do papel
 
-		// Rota de atualização de status (admin e entregadores)
+		// Rota de atualização de status (permissões orders:status:<status>)
 		orders.PUT("/:id/status", orderHandler.UpdateStatus)
+
+		// Conclusão da entrega com comprovante (PIN, foto ou nome de quem recebeu) e consulta pelo admin
+		orders.POST("/:id/deliver", middleware.RequirePermission(permissionService, models.OrderStatusPermission(models.StatusDelivered)), orderHandler.Deliver)
+		orders.GET("/:id/proof", middleware.RequirePermission(permissionService, models.PermOrdersReadAny), orderHandler.DeliveryProof)
+		orders.GET("/:id/proof/photo", middleware.RequirePermission(permissionService, models.PermOrdersReadAny), orderHandler.DeliveryProofPhoto)
+
+		// Entrega malsucedida e devolução à loja pelo entregador; reagendamento pelo cliente do pedido ou admin
+		orders.POST("/:id/fail", middleware.RequirePermission(permissionService, models.OrderStatusPermission(models.StatusDeliveryFailed)), deliveryFailureHandler.Fail)
+		orders.POST("/:id/return", middleware.RequirePermission(permissionService, models.OrderStatusPermission(models.StatusReturned)), deliveryFailureHandler.Return)
+		orders.POST("/:id/reschedule", deliveryFailureHandler.Reschedule)
+		orders.GET("/:id/attempts", middleware.RequirePermission(permissionService, models.PermOrdersReadAny), deliveryFailureHandler.ListAttempts)
+
+		// Gorjeta do cliente após a entrega (até TIP_WINDOW_HOURS)
+		orders.POST("/:id/tip", middleware.RequirePermission(permissionService, models.PermOrdersCreate), tipHandler.AddTip)
+
+		// Posição do entregador e previsão de chegada para o cliente do pedido
+		orders.GET("/:id/tracking", trackingHandler.OrderTracking)
+
+		// Retirada atômica de pedido pronto pelo entregador (409 se outro chegou antes)
+		orders.POST("/:id/claim", middleware.RequirePermission(permissionService, models.PermOrdersAssignSelf), orderHandler.Claim)
+	}
+
+	// Quadro de pedidos ao vivo (WebSocket), filtrado pelo papel; o navegador envia o token em access_token
+	r.GET("/orders/board", middleware.TokenFromQuery(), middleware.AuthMiddleware(tokenService), orderBoardHandler.Connect)
+
+	// Zonas de entrega: consulta pública, gerenciamento com delivery_zones:manage
+	deliveryZones := r.Group("/delivery-zones")
+	{
+		deliveryZones.GET("", deliveryZoneHandler.List)
+
+		adminZones := deliveryZones.Group("")
+		adminZones.Use(middleware.AuthMiddleware(tokenService), middleware.RequirePermission(permissionService, models.PermDeliveryZonesManage))
+		{
+			adminZones.POST("", deliveryZoneHandler.Create)
+			adminZones.PUT("/:id", deliveryZoneHandler.Update)
+			adminZones.DELETE("/:id", deliveryZoneHandler.Delete)
+		}
+	}
+
+	// Rotas de disponibilidade e turnos dos entregadores
+	courier := r.Group("/courier")
+	courier.Use(middleware.AuthMiddleware(tokenService))
+	{
+		courier.GET("/status", middleware.RequirePermission(permissionService, models.PermCourierStatus), courierHandler.GetStatus)
+		courier.POST("/status", middleware.RequirePermission(permissionService, models.PermCourierStatus), courierHandler.SetStatus)
+		courier.POST("/location", middleware.RequirePermission(permissionService, models.PermCourierStatus), trackingHandler.RecordLocation)
+		courier.GET("/shifts", courierHandler.ListShifts) // Entregador vê os próprios; admin vê todos
+
+		// Escala e painel em tempo real (admin)
+		courier.POST("/shifts", middleware.RequirePermission(permissionService, models.PermCouriersManage), courierHandler.CreateShift)
+		courier.DELETE("/shifts/:id", middleware.RequirePermission(permissionService, models.PermCouriersManage), courierHandler.DeleteShift)
+		courier.GET("/roster", middleware.RequirePermission(permissionService, models.PermCouriersManage), courierHandler.Roster)
+
+		// Extrato de ganhos: entregador vê o próprio, admin informa courier_id e faz lançamentos e pagamentos
+		courier.GET("/earnings", earningsHandler.Statement)
+		courier.POST("/earnings", middleware.RequirePermission(permissionService, models.PermEarningsManage), earningsHandler.AddEntry)
+		courier.POST("/payouts", middleware.RequirePermission(permissionService, models.PermEarningsManage), earningsHandler.Payout)
+
+		// Lote de entregas: vários pedidos prontos retirados de uma vez, com rota sugerida
+		courier.POST("/batches", middleware.RequirePermission(permissionService, models.PermOrdersAssignSelf), batchHandler.Claim)
+		courier.GET("/batch", middleware.RequirePermission(permissionService, models.PermOrdersAssignSelf), batchHandler.Current)
+
+		// Veículo do entregador: capacidade e porte conferidos na retirada; documento verificado pelo admin
+		courier.GET("/vehicle", middleware.RequirePermission(permissionService, models.PermCourierStatus), vehicleHandler.GetMine)
+		courier.PUT("/vehicle", middleware.RequirePermission(permissionService, models.PermCourierStatus), vehicleHandler.SaveMine)
+		courier.POST("/vehicle/document", middleware.RequirePermission(permissionService, models.PermCourierStatus), vehicleHandler.UploadDocument)
+
+		// Ofertas do despacho automático (DISPATCH_STRATEGY diferente de "off")
+		if dispatcher != nil {
+			dispatchHandler := handlers.NewDispatchHandler(db, dispatcher, notificationService, etaService, orderBoard)
+			courier.GET("/offers", middleware.RequirePermission(permissionService, models.PermOrdersAssignSelf), dispatchHandler.ListOffers)
+			courier.POST("/offers/:id/accept", middleware.RequirePermission(permissionService, models.PermOrdersAssignSelf), dispatchHandler.Accept)
+			courier.POST("/offers/:id/decline", middleware.RequirePermission(permissionService, models.PermOrdersAssignSelf), dispatchHandler.Decline)
+		}
+	}
+
+	// Montagem e rebalanceamento manual de lotes de entrega (admin)
+	batches := r.Group("/batches")
+	batches.Use(middleware.AuthMiddleware(tokenService), middleware.RequirePermission(permissionService, models.PermCouriersManage))
+	{
+		batches.GET("", batchHandler.List)
+		batches.POST("", batchHandler.Create)
+		batches.GET("/:id", batchHandler.Get)
+		batches.PUT("/:id/stops", batchHandler.Reorder)
+		batches.POST("/:id/stops/:orderId/move", batchHandler.MoveStop)
+	}
+
+	// Verificação dos documentos dos veículos dos entregadores (admin)
+	vehicles := r.Group("/vehicles")
+	vehicles.Use(middleware.AuthMiddleware(tokenService), middleware.RequirePermission(permissionService, models.PermCouriersManage))
+	{
+		vehicles.GET("", vehicleHandler.List)
+		vehicles.GET("/:id", vehicleHandler.Get)
+		vehicles.GET("/:id/document", vehicleHandler.Document)
+		vehicles.POST("/:id/verify", vehicleHandler.Verify)
+	}
+
+	// Relatórios administrativos
+	reports := r.Group("/reports")
+	reports.Use(middleware.AuthMiddleware(tokenService))
+	{
+		reports.GET("/delivery-failures", middleware.RequirePermission(permissionService, models.PermOrdersReadAny), deliveryFailureHandler.Report)
+		reports.GET("/eta-accuracy", middleware.RequirePermission(permissionService, models.PermOrdersReadAny), etaHandler.Accuracy)
+		reports.GET("/payouts", middleware.RequirePermission(permissionService, models.PermEarningsManage), earningsHandler.PayoutReport)
+
+		// Painel de prazos: pedidos em risco ou atrasados e métricas (expvar) da verificação periódica
+		reports.GET("/sla/at-risk", middleware.RequirePermission(permissionService, models.PermOrdersReadAny), slaHandler.AtRisk)
+		reports.GET("/metrics", middleware.RequirePermission(permissionService, models.PermOrdersReadAny), gin.WrapH(expvar.Handler()))
 	}
 
 	// Rotas de notificações (todas precisam de autenticação)
 	notifications := r.Group("/notifications")
-	notifications.Use(middleware.AuthMiddleware(cfg.JWTSecret))
+	notifications.Use(middleware.AuthMiddleware(tokenService))
 	{
 		notifications.GET("", notificationHandler.GetNotifications)
 		notifications.GET("/unread-count", notificationHandler.GetUnreadCount)
@@ -97,9 +402,223 @@ func main() {
 		notifications.POST("/test", notificationHandler.CreateTestNotification)
 	}
 
+	// Stream das notificações (SSE); o EventSource do navegador envia o token em access_token
+	r.GET("/notifications/stream", middleware.TokenFromQuery(), middleware.AuthMiddleware(tokenService), notificationHandler.Stream)
+
+	// Rotas da cozinha
+	kitchen := r.Group("/kitchen")
+	kitchen.Use(middleware.AuthMiddleware(tokenService), middleware.RequirePermission(permissionService, models.PermKitchenQueue))
+	{
+		kitchen.GET("/queue", kitchenHandler.Queue)
+	}
+
+	// Rotas de gerenciamento de permissões por papel
+	roles := r.Group("/roles")
+	roles.Use(middleware.AuthMiddleware(tokenService), middleware.RequirePermission(permissionService, models.PermRolesManage))
+	{
+		roles.GET("/permissions", permissionHandler.List)
+		roles.PUT("/:role/permissions", permissionHandler.UpdateRole)
+		roles.DELETE("/:role/permissions", permissionHandler.ResetRole)
+	}
+
 	// Iniciar servidor
 	log.Printf("Servidor rodando na porta %s", cfg.Port)
 	if err := r.Run(":" + cfg.Port); err != nil {
 		log.Fatalf("Erro ao iniciar servidor: %v", err)
 	}
 }
+
+// newTokenService carrega as chaves de JWT_KEYS_DIR ou, sem configuração e fora de produção, gera uma
+// chave efêmera. Em produção (GIN_MODE=release) a chave é obrigatória: cada réplica geraria a sua e os
+// tokens deixariam de valer entre elas e a cada reinício.
+func newTokenService(cfg *config.Config) (*services.TokenService, error) {
+	if cfg.JWTKeysDir == "" {
+		if gin.Mode() == gin.ReleaseMode {
+			return nil, errors.New("JWT_KEYS_DIR e JWT_ACTIVE_KID são obrigatórios com GIN_MODE=release")
+		}
+		log.Println("JWT_KEYS_DIR não configurado: usando chave Ed25519 efêmera (tokens expiram ao reiniciar)")
+		key, err := services.GenerateEd25519SigningKey("dev")
+		if err != nil {
+			return nil, err
+		}
+		return services.NewTokenService(key, nil, cfg.JWTIssuer, cfg.JWTAudience)
+	}
+
+	active, verification, err := services.LoadSigningKeys(cfg.JWTKeysDir, cfg.JWTActiveKID)
+	if err != nil {
+		return nil, err
+	}
+	return services.NewTokenService(active, verification, cfg.JWTIssuer, cfg.JWTAudience)
+}
+
+// newCEPResolver escolhe o provedor de CEP; o modo offline exige CEP_DATASET_PATH
+func newCEPResolver(cfg *config.Config) (services.CEPResolver, error) {
+	if cfg.CEPProvider == "offline" {
+		if cfg.CEPDatasetPath == "" {
+			return nil, errors.New("CEP_DATASET_PATH é obrigatório com CEP_PROVIDER=offline")
+		}
+		return services.LoadOfflineCEPResolver(cfg.CEPDatasetPath)
+	}
+	return services.NewHTTPCEPResolver(cfg.CEPAPIURL), nil
+}
+
+// newDeliveryPricer escolhe entre zonas de entrega e taxa por distância (DELIVERY_PRICING)
+func newDeliveryPricer(cfg *config.Config, db *gorm.DB) (services.DeliveryPricer, error) {
+	if cfg.DeliveryPricing != "distance" {
+		return services.NewDeliveryZoneService(db), nil
+	}
+
+	values := map[string]string{
+		"STORE_LATITUDE":           cfg.StoreLatitude,
+		"STORE_LONGITUDE":          cfg.StoreLongitude,
+		"DELIVERY_BASE_FEE":        cfg.DeliveryBaseFee,
+		"DELIVERY_FEE_PER_KM":      cfg.DeliveryFeePerKm,
+		"DELIVERY_FREE_THRESHOLD":  cfg.DeliveryFreeThreshold,
+		"DELIVERY_MAX_DISTANCE_KM": cfg.DeliveryMaxDistanceKm,
+	}
+	parsed := make(map[string]float64, len(values))
+	for name, value := range values {
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s inválido: %q", name, value)
+		}
+		parsed[name] = number
+	}
+
+	return &services.DistancePricing{
+		StoreLatitude:         parsed["STORE_LATITUDE"],
+		StoreLongitude:        parsed["STORE_LONGITUDE"],
+		BaseFee:               parsed["DELIVERY_BASE_FEE"],
+		PerKm:                 parsed["DELIVERY_FEE_PER_KM"],
+		FreeDeliveryThreshold: parsed["DELIVERY_FREE_THRESHOLD"],
+		MaxDistanceKm:         parsed["DELIVERY_MAX_DISTANCE_KM"],
+		Distance:              services.HaversineDistance{},
+	}, nil
+}
+
+// newDispatcher monta o despacho automático conforme DISPATCH_STRATEGY; "off" retorna nil
+func newDispatcher(cfg *config.Config, db *gorm.DB, couriers *services.CourierService, claims *services.OrderClaimService, vehicles *services.VehicleService, notifier services.DispatchNotifier) (*services.Dispatcher, error) {
+	var strategy services.DispatchStrategy
+	switch cfg.DispatchStrategy {
+	case "off":
+		return nil, nil
+	case "round_robin":
+		strategy = services.RoundRobinStrategy{}
+	case "nearest":
+		strategy = services.NearestFirstStrategy{}
+	default:
+		return nil, fmt.Errorf("DISPATCH_STRATEGY inválido: %q", cfg.DispatchStrategy)
+	}
+
+	seconds, err := strconv.Atoi(cfg.DispatchOfferSeconds)
+	if err != nil || seconds <= 0 {
+		return nil, fmt.Errorf("DISPATCH_OFFER_SECONDS inválido: %q", cfg.DispatchOfferSeconds)
+	}
+	maxLoad, err := strconv.Atoi(cfg.DispatchMaxActiveDeliveries)
+	if err != nil || maxLoad < 0 {
+		return nil, fmt.Errorf("DISPATCH_MAX_ACTIVE_DELIVERIES inválido: %q", cfg.DispatchMaxActiveDeliveries)
+	}
+
+	store := services.NewDBDispatchStore(db, couriers)
+	store.SetVehicles(vehicles)
+	// Com a localização da loja configurada, os candidatos recebem a distância da última posição até ela
+	latitude, longitude, ok, err := storeLocation(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		store.SetStoreLocation(latitude, longitude)
+	}
+	return services.NewDispatcher(store, strategy, claims, notifier, time.Duration(seconds)*time.Second, maxLoad), nil
+}
+
+func newBatchService(cfg *config.Config, db *gorm.DB) (*services.BatchService, error) {
+	maxStops, err := strconv.Atoi(cfg.BatchMaxStops)
+	if err != nil || maxStops < 1 {
+		return nil, fmt.Errorf("BATCH_MAX_STOPS inválido: %q", cfg.BatchMaxStops)
+	}
+
+	batches := services.NewBatchService(db, maxStops)
+	// Entregadores sem posição recente começam a rota na loja
+	latitude, longitude, ok, err := storeLocation(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		batches.SetStoreLocation(latitude, longitude)
+	}
+	return batches, nil
+}
+
+func newEarningsService(cfg *config.Config, db *gorm.DB) (*services.EarningsService, error) {
+	perDelivery, err := strconv.ParseFloat(cfg.CourierFeePerDelivery, 64)
+	if err != nil || perDelivery < 0 {
+		return nil, fmt.Errorf("COURIER_FEE_PER_DELIVERY inválido: %q", cfg.CourierFeePerDelivery)
+	}
+	perKm, err := strconv.ParseFloat(cfg.CourierFeePerKm, 64)
+	if err != nil || perKm < 0 {
+		return nil, fmt.Errorf("COURIER_FEE_PER_KM inválido: %q", cfg.CourierFeePerKm)
+	}
+	return services.NewEarningsService(db, services.CourierPayRate{PerDelivery: perDelivery, PerKm: perKm}), nil
+}
+
+func newETAService(cfg *config.Config, db *gorm.DB) (*services.ETAService, error) {
+	slots, err := strconv.Atoi(cfg.ETAKitchenSlots)
+	if err != nil || slots < 1 {
+		return nil, fmt.Errorf("ETA_KITCHEN_SLOTS inválido: %q", cfg.ETAKitchenSlots)
+	}
+	prepMinutes, err := strconv.Atoi(cfg.ETADefaultPrepMinutes)
+	if err != nil || prepMinutes < 1 {
+		return nil, fmt.Errorf("ETA_DEFAULT_PREP_MINUTES inválido: %q", cfg.ETADefaultPrepMinutes)
+	}
+
+	eta := services.NewETAService(db, slots, time.Duration(prepMinutes)*time.Minute)
+	// Pedidos sem distância calculada no checkout usam a distância da loja até as coordenadas do endereço
+	latitude, longitude, ok, err := storeLocation(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		eta.SetStoreLocation(latitude, longitude)
+	}
+	return eta, nil
+}
+
+// newSLAMonitor lê os prazos por status; com ORDER_SLA_MINUTES=off o intervalo retornado é zero
+// e a verificação periódica não é iniciada
+func newSLAMonitor(cfg *config.Config, db *gorm.DB, notifier services.SLANotifier) (*services.SLAMonitor, time.Duration, error) {
+	policy := services.SLAPolicy{}
+	if cfg.OrderSLAMinutes != "off" {
+		var err error
+		policy, err = services.ParseSLAPolicy(cfg.OrderSLAMinutes)
+		if err != nil {
+			return nil, 0, fmt.Errorf("ORDER_SLA_MINUTES inválido: %w", err)
+		}
+	}
+	seconds, err := strconv.Atoi(cfg.SLACheckSeconds)
+	if err != nil || seconds <= 0 {
+		return nil, 0, fmt.Errorf("SLA_CHECK_SECONDS inválido: %q", cfg.SLACheckSeconds)
+	}
+
+	monitor := services.NewSLAMonitor(db, policy, notifier, services.NewExpvarSLAMetrics())
+	if len(policy) == 0 {
+		return monitor, 0, nil
+	}
+	return monitor, time.Duration(seconds) * time.Second, nil
+}
+
+// storeLocation lê STORE_LATITUDE e STORE_LONGITUDE; ok é false quando não configuradas
+func storeLocation(cfg *config.Config) (latitude, longitude float64, ok bool, err error) {
+	if cfg.StoreLatitude == "" || cfg.StoreLongitude == "" {
+		return 0, 0, false, nil
+	}
+	latitude, err = strconv.ParseFloat(cfg.StoreLatitude, 64)
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("STORE_LATITUDE inválido: %q", cfg.StoreLatitude)
+	}
+	longitude, err = strconv.ParseFloat(cfg.StoreLongitude, 64)
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("STORE_LONGITUDE inválido: %q", cfg.StoreLongitude)
+	}
+	return latitude, longitude, true, nil
+}
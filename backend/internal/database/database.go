@@ -1,13 +1,18 @@
 package database
 
 import (
+    "log"
+
     "gorm.io/driver/postgres"
     "gorm.io/gorm"
     "cupcake-delivery/internal/models"
 )
 
 func Connect(url string) (*gorm.DB, error) {
-    db, err := gorm.Open(postgres.Open(url), &gorm.Config{})
+    db, err := gorm.Open(postgres.Open(url), &gorm.Config{
+        // Violações de unicidade chegam como gorm.ErrDuplicatedKey
+        TranslateError: true,
+    })
     if err != nil {
         return nil, err
     }
@@ -18,10 +23,80 @@ func Connect(url string) (*gorm.DB, error) {
         &models.Product{},
         &models.Order{},
         &models.OrderItem{},
+        &models.RolePermission{},
+        &models.RolePermissionOverride{},
+        &models.LoginAttempt{},
+        &models.TwoFactor{},
+        &models.RecoveryCode{},
+        &models.TwoFactorPolicy{},
+        &models.UsedChallengeToken{},
+        &models.UserAddress{},
+        &models.AuditLog{},
+        &models.AccountDeletion{},
+        &models.DeliveryZone{},
+        &models.CourierAvailability{},
+        &models.CourierShift{},
+        &models.DispatchOffer{},
+        &models.CourierPosition{},
+        &models.CourierLocation{},
+        &models.DeliveryProof{},
+        &models.DeliveryAttempt{},
+        &models.DeliveryBatch{},
+        &models.DeliveryBatchStop{},
+        &models.CourierEarning{},
+        &models.OrderSLABreach{},
+        &models.OrderStatusChange{},
+        &models.ETAPrediction{},
+        &models.Vehicle{},
     )
     if err != nil {
         return nil, err
     }
 
+    if err := backfillVehicles(db); err != nil {
+        return nil, err
+    }
+
     return db, nil
 }
+
+// backfillVehicles cria o Vehicle dos entregadores cadastrados quando o veículo era texto livre em
+// users.vehicle. A coluna antiga continua no banco (AutoMigrate não remove colunas); só entregadores
+// sem Vehicle são migrados, então rodar de novo não altera nada. Os documentos ficam pendentes de envio.
+func backfillVehicles(db *gorm.DB) error {
+    if !db.Migrator().HasColumn("users", "vehicle") {
+        return nil
+    }
+
+    var legacy []struct {
+        ID      uint
+        Vehicle string
+    }
+    err := db.Table("users").
+        Select("users.id, users.vehicle").
+        Where("users.type = ? AND users.deleted_at IS NULL", models.DeliveryType).
+        Where("COALESCE(users.vehicle, '') <> ''").
+        Where("NOT EXISTS (SELECT 1 FROM vehicles WHERE vehicles.courier_id = users.id)").
+        Scan(&legacy).Error
+    if err != nil {
+        return err
+    }
+
+    for _, row := range legacy {
+        vehicleType, ok := models.LegacyVehicleType(row.Vehicle)
+        if !ok {
+            log.Printf("Veículo %q do entregador %d não reconhecido; cadastro fica a cargo do entregador", row.Vehicle, row.ID)
+            continue
+        }
+        vehicle := models.Vehicle{
+            CourierID:      row.ID,
+            Type:           vehicleType,
+            CapacityBoxes:  vehicleType.DefaultCapacity(),
+            DocumentStatus: models.VehicleDocumentMissing,
+        }
+        if err := db.Create(&vehicle).Error; err != nil {
+            return err
+        }
+    }
+    return nil
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"cupcake-delivery/internal/models"
+	"cupcake-delivery/internal/services"
+	"cupcake-delivery/internal/utils"
+	"cupcake-delivery/internal/validators"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PostalAddressRequest campos do endereço estruturado; logradouro, bairro, cidade e UF
+// podem ser omitidos quando o CEP é encontrado pelo resolvedor
+type PostalAddressRequest struct {
+	CEP          string `json:"cep"`
+	Street       string `json:"street"`
+	Number       string `json:"number"`
+	Complement   string `json:"complement"`
+	Neighborhood string `json:"neighborhood"`
+	City         string `json:"city"`
+	State        string `json:"state"`
+}
+
+func (r PostalAddressRequest) isEmpty() bool {
+	return r == PostalAddressRequest{}
+}
+
+func (r PostalAddressRequest) toPostalAddress() models.PostalAddress {
+	return models.PostalAddress{
+		CEP:          r.CEP,
+		Street:       r.Street,
+		Number:       r.Number,
+		Complement:   r.Complement,
+		Neighborhood: r.Neighborhood,
+		City:         r.City,
+		State:        r.State,
+	}
+}
+
+// preparePostalAddress completa o endereço pelo CEP e o valida; responde com erro e retorna false se inválido
+func preparePostalAddress(c *gin.Context, resolver services.CEPResolver, req PostalAddressRequest) (models.PostalAddress, bool) {
+	address := req.toPostalAddress()
+
+	if err := services.CompletePostalAddress(c.Request.Context(), resolver, &address); err != nil {
+		if errors.Is(err, services.ErrCEPNotFound) {
+			utils.RespondWithValidationError(c, []utils.ValidationError{{Field: "cep", Message: "CEP não encontrado"}})
+			return address, false
+		}
+		// Falha do provedor não impede o cadastro se o usuário informou todos os campos
+		log.Printf("Erro ao consultar CEP %s: %v", address.CEP, err)
+	}
+
+	if validationErrors := validators.ValidatePostalAddress(address); len(validationErrors) > 0 {
+		utils.RespondWithValidationError(c, validationErrors)
+		return address, false
+	}
+
+	return address, true
+}
+
+type CEPHandler struct {
+	resolver services.CEPResolver
+}
+
+func NewCEPHandler(resolver services.CEPResolver) *CEPHandler {
+	return &CEPHandler{resolver: resolver}
+}
+
+// Lookup retorna logradouro, bairro, cidade e UF de um CEP para preencher formulários
+func (h *CEPHandler) Lookup(c *gin.Context) {
+	cep := c.Param("cep")
+	if err := validators.ValidateCEP(cep); err != nil {
+		utils.RespondWithValidationError(c, []utils.ValidationError{*err})
+		return
+	}
+
+	address, err := h.resolver.Resolve(c.Request.Context(), cep)
+	if err != nil {
+		if errors.Is(err, services.ErrCEPNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, utils.ErrorTypeNotFound, "CEP não encontrado")
+			return
+		}
+		utils.RespondWithError(c, http.StatusBadGateway, utils.ErrorTypeInternal, "Serviço de CEP indisponível")
+		return
+	}
+
+	c.JSON(http.StatusOK, address)
+}
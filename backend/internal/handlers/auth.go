@@ -1,10 +1,18 @@
 package handlers
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"cupcake-delivery/internal/models"
+	"cupcake-delivery/internal/services"
+	"cupcake-delivery/internal/utils"
+	"cupcake-delivery/internal/validators"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
@@ -13,11 +21,13 @@ import (
 )
 
 type RegisterRequest struct {
-	Name     string `json:"name" binding:"required"`
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
-	Type     string `json:"type" binding:"required,oneof=customer delivery admin"`
-	Vehicle  string `json:"vehicle,omitempty"` // Opcional, apenas para entregador
+	Name     string          `json:"name" binding:"required"`
+	Email    string          `json:"email" binding:"required,email"`
+	Password string          `json:"password" binding:"required,min=6"`
+	Type     string          `json:"type" binding:"required,oneof=customer delivery admin kitchen"`
+	Vehicle  *VehicleRequest `json:"vehicle,omitempty"` // Opcional, apenas para entregador
+	Phone    string          `json:"phone,omitempty"`
+	Document string          `json:"document,omitempty"` // CPF ou CNPJ
 }
 
 type LoginRequest struct {
@@ -25,15 +35,36 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+type TwoFactorEnrollLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+}
+
+// Propósito do token emitido entre a senha e o segundo fator
+const twoFactorChallengePurpose = "2fa_challenge"
+
+const twoFactorChallengeTTL = 5 * time.Minute
+
+const accessTokenTTL = 24 * time.Hour
+
 type AuthHandler struct {
-	db     *gorm.DB
-	secret string
+	db        *gorm.DB
+	tokens    *services.TokenService
+	throttle  *services.LoginThrottle
+	twoFactor *services.TwoFactorService
 }
 
-func NewAuthHandler(db *gorm.DB, secret string) *AuthHandler {
+func NewAuthHandler(db *gorm.DB, tokens *services.TokenService, throttle *services.LoginThrottle, twoFactor *services.TwoFactorService) *AuthHandler {
 	return &AuthHandler{
-		db:     db,
-		secret: secret,
+		db:        db,
+		tokens:    tokens,
+		throttle:  throttle,
+		twoFactor: twoFactor,
 	}
 }
 
@@ -44,6 +75,38 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	// Telefone e CPF/CNPJ são opcionais no cadastro, mas exigidos para fazer pedidos
+	var validationErrors []utils.ValidationError
+	var phone, document string
+	if req.Phone != "" {
+		normalized, err := validators.NormalizePhone(req.Phone)
+		if err != nil {
+			validationErrors = append(validationErrors, *err)
+		}
+		phone = normalized
+	}
+	if req.Document != "" {
+		if err := validators.ValidateDocument(req.Document); err != nil {
+			validationErrors = append(validationErrors, *err)
+		}
+		document = validators.NormalizeDocument(req.Document)
+	}
+	var vehicle *models.Vehicle
+	if req.Type == "delivery" && req.Vehicle != nil {
+		validationErrors = append(validationErrors, validators.ValidateVehicle(req.Vehicle.Type, req.Vehicle.Plate, req.Vehicle.CapacityBoxes)...)
+		vehicle = &models.Vehicle{}
+		services.ApplyVehicleInput(vehicle, services.VehicleInput{
+			Type:          req.Vehicle.Type,
+			Plate:         validators.NormalizePlate(req.Vehicle.Plate),
+			CapacityBoxes: req.Vehicle.CapacityBoxes,
+			Refrigerated:  req.Vehicle.Refrigerated,
+		})
+	}
+	if len(validationErrors) > 0 {
+		utils.RespondWithValidationError(c, validationErrors)
+		return
+	}
+
 	// Hash da senha
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -51,15 +114,12 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	// Criar usuário
-	var vehicle *string
-	if req.Type == "delivery" && req.Vehicle != "" {
-		vehicle = &req.Vehicle
-	}
-
+	// Criar usuário; o veículo é criado junto, aguardando o envio do documento
 	user := models.User{
 		Name:     req.Name,
 		Email:    req.Email,
+		Phone:    phone,
+		Document: document,
 		Password: string(hashedPassword),
 		Type:     models.UserType(req.Type),
 		Vehicle:  vehicle,
@@ -78,6 +138,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 			"email":   user.Email,
 			"type":    user.Type,
 			"vehicle": user.Vehicle,
+			"phone":   user.Phone,
 		},
 	})
 }
@@ -89,31 +150,211 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	// O bloqueio é consultado antes de conferir a senha, sem reservar a tentativa: uma rajada
+	// simultânea pode passar um pouco do limite antes de as falhas dela serem registradas
+	ip := c.ClientIP()
+	if !checkThrottle(c, h.throttle, req.Email, ip) {
+		return
+	}
+
 	var user models.User
-	if err := h.db.Where("email = ?", req.Email).First(&user).Error; err != nil {
+	if err := h.db.Preload("Vehicle").Where("email = ?", req.Email).First(&user).Error; err != nil {
+		recordLoginFailure(h.throttle, req.Email, ip, nil)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Email ou senha inválidos"})
 		return
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		recordLoginFailure(h.throttle, req.Email, ip, &user)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Email ou senha inválidos"})
 		return
 	}
 
-	// Gerar JWT
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"type":    user.Type,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
+	// Segundo fator: ativo para o usuário ou obrigatório para o tipo dele
+	enabled, err := h.twoFactor.IsEnabled(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar 2FA"})
+		return
+	}
+	required, err := h.twoFactor.IsRequired(user.Type)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar 2FA"})
+		return
+	}
+	if enabled || required {
+		challenge, err := h.issueChallengeToken(&user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar token"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"two_factor_required": true,
+			"enrollment_required": !enabled,
+			"challenge_token":     challenge,
+			"expires_in":          int(twoFactorChallengeTTL.Seconds()),
+		})
+		return
+	}
+
+	if err := h.throttle.RecordSuccess(req.Email); err != nil {
+		log.Printf("Erro ao limpar tentativas de login de %s: %v", req.Email, err)
+	}
+
+	h.respondWithSession(c, &user, nil)
+}
+
+// LoginTwoFactor conclui o login com o código TOTP ou um código de recuperação.
+// Se o usuário ainda não tinha 2FA ativo (obrigatório pelo tipo), o código confirma o cadastro.
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	var req TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ch, ok := h.parseChallenge(c, req.ChallengeToken)
+	if !ok {
+		return
+	}
+	user := ch.user
+
+	ip := c.ClientIP()
+	if !checkThrottle(c, h.throttle, user.Email, ip) {
+		return
+	}
+
+	enabled, err := h.twoFactor.IsEnabled(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar 2FA"})
+		return
+	}
+
+	var recoveryCodes []string
+	if enabled {
+		code := req.Code
+		if code == "" {
+			code = req.RecoveryCode
+		}
+		err = h.twoFactor.Verify(user.ID, code)
+	} else {
+		recoveryCodes, err = h.twoFactor.ConfirmEnrollment(user.ID, req.Code)
+	}
+
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTwoFactorCode) {
+			recordLoginFailure(h.throttle, user.Email, ip, user)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Código de verificação inválido"})
+			return
+		}
+		if errors.Is(err, services.ErrTwoFactorNotEnrolled) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cadastro de 2FA não iniciado"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar código"})
+		return
+	}
+
+	// O desafio conclui um único login, mesmo que o código ainda seja aceito
+	used, err := h.useChallenge(ch)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar desafio de 2FA"})
+		return
+	}
+	if !used {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Desafio de 2FA inválido ou expirado"})
+		return
+	}
+
+	if err := h.throttle.RecordSuccess(user.Email); err != nil {
+		log.Printf("Erro ao limpar tentativas de login de %s: %v", user.Email, err)
+	}
+
+	h.respondWithSession(c, user, recoveryCodes)
+}
+
+// LoginTwoFactorEnroll inicia o cadastro do 2FA durante o login quando ele é obrigatório
+func (h *AuthHandler) LoginTwoFactorEnroll(c *gin.Context) {
+	var req TwoFactorEnrollLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ch, ok := h.parseChallenge(c, req.ChallengeToken)
+	if !ok {
+		return
+	}
+
+	secret, uri, err := h.twoFactor.BeginEnrollment(ch.user)
+	if err != nil {
+		if errors.Is(err, services.ErrTwoFactorAlreadyEnabled) {
+			c.JSON(http.StatusConflict, gin.H{"error": "2FA já está ativo"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao iniciar cadastro de 2FA"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_uri": uri,
 	})
+}
+
+// Unlock remove o bloqueio por tentativas de login de uma conta (admin)
+func (h *AuthHandler) Unlock(c *gin.Context) {
+	var user models.User
+	if err := h.db.First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+		return
+	}
+
+	if err := h.throttle.Unlock(user.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao desbloquear conta"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Conta desbloqueada com sucesso"})
+}
+
+// JWKS publica as chaves públicas de verificação dos tokens
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.tokens.JWKS())
+}
+
+// checkThrottle responde 429 e retorna false se a conta ou o IP estiverem bloqueados
+func checkThrottle(c *gin.Context, throttle *services.LoginThrottle, email, ip string) bool {
+	err := throttle.Check(email, ip)
+	if err == nil {
+		return true
+	}
+
+	var lockout *services.LockoutError
+	if errors.As(err, &lockout) {
+		c.Header("Retry-After", strconv.Itoa(int(time.Until(lockout.Until).Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":        "Muitas tentativas de login. Tente novamente mais tarde.",
+			"locked_until": lockout.Until,
+		})
+		return false
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar tentativas de login"})
+	return false
+}
 
-	tokenString, err := token.SignedString([]byte(h.secret))
+// respondWithSession gera o JWT de acesso e responde com os dados do usuário
+func (h *AuthHandler) respondWithSession(c *gin.Context, user *models.User, recoveryCodes []string) {
+	tokenString, err := h.tokens.Sign(jwt.MapClaims{
+		"user_id": user.ID,
+		"type":    user.Type,
+	}, accessTokenTTL)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"token": tokenString,
 		"user": gin.H{
 			"id":      user.ID,
@@ -122,5 +363,85 @@ func (h *AuthHandler) Login(c *gin.Context) {
 			"type":    user.Type,
 			"vehicle": user.Vehicle,
 		},
-	})
+	}
+	if recoveryCodes != nil {
+		response["recovery_codes"] = recoveryCodes
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// issueChallengeToken gera o token de curta duração que só serve para concluir o 2FA.
+// O jti identifica o desafio para que ele conclua um único login.
+func (h *AuthHandler) issueChallengeToken(user *models.User) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return h.tokens.Sign(jwt.MapClaims{
+		"user_id": user.ID,
+		"purpose": twoFactorChallengePurpose,
+		"jti":     hex.EncodeToString(b),
+	}, twoFactorChallengeTTL)
+}
+
+// challenge desafio de 2FA validado
+type challenge struct {
+	user      *models.User
+	jti       string
+	expiresAt time.Time
+}
+
+// parseChallenge valida o token de desafio e carrega o usuário; responde 401 se inválido ou já usado
+func (h *AuthHandler) parseChallenge(c *gin.Context, token string) (*challenge, bool) {
+	claims, err := h.tokens.Parse(token)
+	if err != nil || claims["purpose"] != twoFactorChallengePurpose {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Desafio de 2FA inválido ou expirado"})
+		return nil, false
+	}
+
+	userID, ok := claims["user_id"].(float64)
+	jti, _ := claims["jti"].(string)
+	exp, err := claims.GetExpirationTime()
+	if !ok || jti == "" || err != nil || exp == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Desafio de 2FA inválido ou expirado"})
+		return nil, false
+	}
+
+	var used int64
+	if err := h.db.Model(&models.UsedChallengeToken{}).Where("jti = ?", jti).Count(&used).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar desafio de 2FA"})
+		return nil, false
+	}
+	if used > 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Desafio de 2FA inválido ou expirado"})
+		return nil, false
+	}
+
+	var user models.User
+	if err := h.db.Preload("Vehicle").First(&user, uint(userID)).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Desafio de 2FA inválido ou expirado"})
+		return nil, false
+	}
+	return &challenge{user: &user, jti: jti, expiresAt: exp.Time}, true
+}
+
+// useChallenge marca o desafio como usado; false se outra requisição já concluiu o login com ele
+func (h *AuthHandler) useChallenge(ch *challenge) (bool, error) {
+	// Desafios expirados não podem mais ser apresentados, então os registros deles são descartados
+	if err := h.db.Where("expires_at < ?", time.Now()).Delete(&models.UsedChallengeToken{}).Error; err != nil {
+		log.Printf("Erro ao remover desafios de 2FA expirados: %v", err)
+	}
+
+	err := h.db.Create(&models.UsedChallengeToken{JTI: ch.jti, ExpiresAt: ch.expiresAt}).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// recordLoginFailure conta a falha para a conta e o IP; erros só são registrados no log
+func recordLoginFailure(throttle *services.LoginThrottle, email, ip string, user *models.User) {
+	if err := throttle.RecordFailure(email, ip, user); err != nil {
+		log.Printf("Erro ao registrar falha de login de %s: %v", email, err)
+	}
 }
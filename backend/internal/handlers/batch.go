@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"cupcake-delivery/internal/models"
+	"cupcake-delivery/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type BatchHandler struct {
+	db                  *gorm.DB
+	batches             *services.BatchService
+	couriers            *services.CourierService
+	notificationService *services.NotificationService
+	eta                 *services.ETAService
+	board               *services.OrderBoard
+}
+
+type CreateBatchRequest struct {
+	CourierID uint   `json:"courier_id"` // Apenas admin; o entregador monta o próprio lote
+	OrderIDs  []uint `json:"order_ids" binding:"required,min=1"`
+}
+
+type ReorderBatchRequest struct {
+	OrderIDs []uint `json:"order_ids" binding:"required,min=1"`
+}
+
+type MoveStopRequest struct {
+	BatchID uint `json:"batch_id" binding:"required"`
+}
+
+func NewBatchHandler(db *gorm.DB, batches *services.BatchService, couriers *services.CourierService, notificationService *services.NotificationService, eta *services.ETAService, board *services.OrderBoard) *BatchHandler {
+	return &BatchHandler{
+		db:                  db,
+		batches:             batches,
+		couriers:            couriers,
+		notificationService: notificationService,
+		eta:                 eta,
+		board:               board,
+	}
+}
+
+// Claim retira vários pedidos prontos de uma vez como um lote do entregador logado
+func (h *BatchHandler) Claim(c *gin.Context) {
+	var req CreateBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	courierID := c.GetUint("user_id")
+	online, err := h.couriers.IsOnline(courierID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar disponibilidade do entregador"})
+		return
+	}
+	if !online {
+		c.JSON(http.StatusConflict, gin.H{"error": "Fique online durante um turno para retirar pedidos"})
+		return
+	}
+
+	h.create(c, courierID, req.OrderIDs)
+}
+
+// Current mostra o lote em andamento do entregador logado
+func (h *BatchHandler) Current(c *gin.Context) {
+	batch, err := h.batches.ActiveBatch(c.GetUint("user_id"))
+	if err != nil {
+		if errors.Is(err, services.ErrBatchNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Nenhum lote em andamento"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar lote"})
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
+}
+
+// Create monta um lote para o entregador informado (admin)
+func (h *BatchHandler) Create(c *gin.Context) {
+	var req CreateBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var courier models.User
+	if err := h.db.Where("id = ? AND type = ?", req.CourierID, models.DeliveryType).First(&courier).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Entregador não encontrado"})
+		return
+	}
+
+	h.create(c, courier.ID, req.OrderIDs)
+}
+
+// List lista os lotes em andamento (admin)
+func (h *BatchHandler) List(c *gin.Context) {
+	batches, err := h.batches.ActiveBatches()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar lotes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, batches)
+}
+
+// Get mostra um lote com as paradas e os pedidos (admin)
+func (h *BatchHandler) Get(c *gin.Context) {
+	id, ok := batchID(c)
+	if !ok {
+		return
+	}
+
+	batch, err := h.batches.Batch(id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
+}
+
+// Reorder define à mão a ordem das paradas pendentes do lote (admin)
+func (h *BatchHandler) Reorder(c *gin.Context) {
+	id, ok := batchID(c)
+	if !ok {
+		return
+	}
+
+	var req ReorderBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.batches.Reorder(id, req.OrderIDs); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	h.respondUpdated(c, id)
+}
+
+// MoveStop passa um pedido pendente do lote para o lote de outro entregador (admin)
+func (h *BatchHandler) MoveStop(c *gin.Context) {
+	id, ok := batchID(c)
+	if !ok {
+		return
+	}
+	orderID, err := strconv.ParseUint(c.Param("orderId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID do pedido inválido"})
+		return
+	}
+
+	var req MoveStopRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.batches.Move(id, uint(orderID), req.BatchID); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	h.notifyETAs(id)
+	h.respondUpdated(c, req.BatchID)
+}
+
+func (h *BatchHandler) create(c *gin.Context, courierID uint, orderIDs []uint) {
+	batch, err := h.batches.Create(courierID, c.GetUint("user_id"), orderIDs)
+	if err != nil {
+		var notClaimable *services.OrderNotClaimableError
+		if errors.As(err, &notClaimable) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Pedido não está disponível para retirada", "order_id": notClaimable.OrderID})
+			return
+		}
+		h.respondError(c, err)
+		return
+	}
+
+	for _, stop := range batch.Stops {
+		if stop.Order == nil {
+			continue
+		}
+		refreshETA(h.eta, stop.Order)
+		publishOrderEvent(h.board, services.OrderEventStatusChanged, stop.Order)
+		if h.notificationService == nil {
+			continue
+		}
+		if err := h.notificationService.NotifyOrderStatusChange(stop.Order, string(models.StatusDelivering)); err != nil {
+			log.Printf("Erro ao notificar retirada do pedido %d: %v", stop.OrderID, err)
+		}
+	}
+	h.notifyBatchETAs(batch)
+
+	c.JSON(http.StatusCreated, batch)
+}
+
+// respondUpdated responde com o lote replanejado e avisa os clientes das novas previsões
+func (h *BatchHandler) respondUpdated(c *gin.Context, id uint) {
+	batch, err := h.batches.Batch(id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	h.notifyBatchETAs(batch)
+	c.JSON(http.StatusOK, batch)
+}
+
+// notifyETAs avisa os clientes das paradas pendentes do lote sobre a previsão atual
+func (h *BatchHandler) notifyETAs(id uint) {
+	batch, err := h.batches.Batch(id)
+	if err != nil {
+		log.Printf("Erro ao buscar lote %d para notificar previsões: %v", id, err)
+		return
+	}
+	h.notifyBatchETAs(batch)
+}
+
+func (h *BatchHandler) notifyBatchETAs(batch *models.DeliveryBatch) {
+	if h.notificationService == nil {
+		return
+	}
+
+	ahead := 0
+	for _, stop := range batch.Stops {
+		if stop.Status != models.StopPending {
+			continue
+		}
+		if stop.Order != nil && stop.ETA != nil {
+			if err := h.notificationService.NotifyDeliveryETA(stop.Order, ahead, *stop.ETA); err != nil {
+				log.Printf("Erro ao notificar previsão do pedido %d: %v", stop.OrderID, err)
+			}
+		}
+		ahead++
+	}
+}
+
+func (h *BatchHandler) respondError(c *gin.Context, err error) {
+	if respondVehicleLoadError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, services.ErrBatchNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Lote não encontrado ou já concluído"})
+	case errors.Is(err, services.ErrBatchTooLarge):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Lote excede o número máximo de paradas"})
+	case errors.Is(err, services.ErrCourierHasBatch):
+		c.JSON(http.StatusConflict, gin.H{"error": "Entregador já tem um lote em andamento"})
+	case errors.Is(err, services.ErrStopNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": "Pedido não é uma parada pendente deste lote"})
+	case errors.Is(err, services.ErrInvalidStopOrder):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Informe todas as paradas pendentes do lote, cada uma uma vez"})
+	case errors.Is(err, services.ErrSameBatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Pedido já pertence a este lote"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar lote"})
+	}
+}
+
+func batchID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cupcake-delivery/internal/models"
+	"cupcake-delivery/internal/services"
+	"cupcake-delivery/internal/utils"
+	"cupcake-delivery/internal/validators"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CourierHandler struct {
+	db          *gorm.DB
+	couriers    *services.CourierService
+	permissions *services.PermissionService
+}
+
+type CourierStatusRequest struct {
+	Status models.CourierStatus `json:"status" binding:"required"`
+}
+
+type CourierShiftRequest struct {
+	CourierID uint      `json:"courier_id" binding:"required"`
+	StartsAt  time.Time `json:"starts_at" binding:"required"`
+	EndsAt    time.Time `json:"ends_at" binding:"required"`
+	Notes     string    `json:"notes"`
+}
+
+func NewCourierHandler(db *gorm.DB, couriers *services.CourierService, permissions *services.PermissionService) *CourierHandler {
+	return &CourierHandler{
+		db:          db,
+		couriers:    couriers,
+		permissions: permissions,
+	}
+}
+
+// GetStatus retorna a disponibilidade e o turno atual do entregador logado
+func (h *CourierHandler) GetStatus(c *gin.Context) {
+	courierID := c.GetUint("user_id")
+
+	availability, err := h.couriers.Status(courierID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar disponibilidade"})
+		return
+	}
+	shift, err := h.couriers.CurrentShift(courierID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar turno"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":        availability.Status,
+		"since":         availability.Since,
+		"online":        availability.Status == models.CourierOnline && shift != nil,
+		"current_shift": shift,
+	})
+}
+
+// SetStatus alterna o entregador logado entre online, offline e pausa
+func (h *CourierHandler) SetStatus(c *gin.Context) {
+	var req CourierStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !req.Status.Valid() {
+		utils.RespondWithValidationError(c, []utils.ValidationError{{
+			Field:   "status",
+			Message: "Status deve ser 'online', 'offline' ou 'break'",
+		}})
+		return
+	}
+
+	availability, err := h.couriers.SetStatus(c.GetUint("user_id"), req.Status)
+	if err != nil {
+		if errors.Is(err, services.ErrNoActiveShift) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Não há turno em andamento para ficar online"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar disponibilidade"})
+		return
+	}
+
+	c.JSON(http.StatusOK, availability)
+}
+
+// ListShifts lista turnos entre from e to (RFC 3339; padrão: próximos 7 dias).
+// Entregadores veem apenas os próprios; administradores podem filtrar por courier_id.
+func (h *CourierHandler) ListShifts(c *gin.Context) {
+	role := models.UserType(c.GetString("type"))
+
+	var courierID uint
+	switch {
+	case h.permissions.HasPermission(role, models.PermCouriersManage):
+		if raw := c.Query("courier_id"); raw != "" {
+			id, err := strconv.ParseUint(raw, 10, 32)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "courier_id inválido"})
+				return
+			}
+			courierID = uint(id)
+		}
+	case h.permissions.HasPermission(role, models.PermCourierStatus):
+		courierID = c.GetUint("user_id")
+	default:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Acesso não autorizado"})
+		return
+	}
+
+	from, to := time.Now(), time.Now().Add(7*24*time.Hour)
+	for param, target := range map[string]*time.Time{"from": &from, "to": &to} {
+		if raw := c.Query(param); raw != "" {
+			parsed, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Data inválida em '" + param + "' (use RFC 3339)"})
+				return
+			}
+			*target = parsed
+		}
+	}
+
+	shifts, err := h.couriers.ListShifts(courierID, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar turnos"})
+		return
+	}
+
+	c.JSON(http.StatusOK, shifts)
+}
+
+// CreateShift agenda um turno para um entregador (admin)
+func (h *CourierHandler) CreateShift(c *gin.Context) {
+	var req CourierShiftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validators.ValidateShiftPeriod(req.StartsAt, req.EndsAt); err != nil {
+		utils.RespondWithValidationError(c, []utils.ValidationError{*err})
+		return
+	}
+
+	var courier models.User
+	if err := h.db.Where("id = ? AND type = ?", req.CourierID, models.DeliveryType).First(&courier).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Entregador não encontrado"})
+		return
+	}
+
+	shift := models.CourierShift{
+		CourierID: courier.ID,
+		StartsAt:  req.StartsAt,
+		EndsAt:    req.EndsAt,
+		Notes:     req.Notes,
+	}
+	if err := h.couriers.CreateShift(&shift); err != nil {
+		if errors.Is(err, services.ErrShiftOverlap) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Turno sobreposto a outro turno do entregador"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao criar turno"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, shift)
+}
+
+// DeleteShift remove um turno agendado (admin)
+func (h *CourierHandler) DeleteShift(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	if err := h.couriers.DeleteShift(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Turno não encontrado"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao remover turno"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Turno removido com sucesso"})
+}
+
+// Roster mostra a situação atual de todos os entregadores (admin)
+func (h *CourierHandler) Roster(c *gin.Context) {
+	roster, err := h.couriers.Roster()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao montar escala de entregadores"})
+		return
+	}
+
+	c.JSON(http.StatusOK, roster)
+}
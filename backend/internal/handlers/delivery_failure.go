@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"cupcake-delivery/internal/models"
+	"cupcake-delivery/internal/services"
+	"cupcake-delivery/internal/utils"
+	"cupcake-delivery/internal/validators"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type DeliveryFailureHandler struct {
+	db                  *gorm.DB
+	failures            *services.DeliveryFailureService
+	notificationService *services.NotificationService
+	permissions         *services.PermissionService
+	dispatcher          *services.Dispatcher
+	eta                 *services.ETAService
+	board               *services.OrderBoard
+}
+
+type DeliveryFailureRequest struct {
+	Reason models.DeliveryFailureReason `json:"reason" binding:"required"`
+	Notes  string                       `json:"notes"`
+}
+
+type RescheduleRequest struct {
+	ScheduledFor *time.Time `json:"scheduled_for"`
+}
+
+func NewDeliveryFailureHandler(db *gorm.DB, failures *services.DeliveryFailureService, notificationService *services.NotificationService, permissions *services.PermissionService, dispatcher *services.Dispatcher, eta *services.ETAService, board *services.OrderBoard) *DeliveryFailureHandler {
+	return &DeliveryFailureHandler{
+		db:                  db,
+		failures:            failures,
+		notificationService: notificationService,
+		permissions:         permissions,
+		dispatcher:          dispatcher,
+		eta:                 eta,
+		board:               board,
+	}
+}
+
+// Fail registra que o entregador logado não conseguiu entregar o pedido
+func (h *DeliveryFailureHandler) Fail(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var req DeliveryFailureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if validationErrors := validateDeliveryFailure(req); len(validationErrors) > 0 {
+		utils.RespondWithValidationError(c, validationErrors)
+		return
+	}
+
+	order, err := h.failures.Fail(uint(id), c.GetUint("user_id"), req.Reason, req.Notes)
+	switch {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pedido não encontrado"})
+		return
+	case errors.Is(err, services.ErrOrderNotDeliverable):
+		c.JSON(http.StatusConflict, gin.H{"error": "Pedido não está em entrega com você"})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao registrar entrega malsucedida"})
+		return
+	}
+
+	h.notify(order, string(models.StatusDeliveryFailed))
+	c.JSON(http.StatusOK, order)
+}
+
+// Return registra que o entregador logado trouxe o pedido de volta à loja
+func (h *DeliveryFailureHandler) Return(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	order, err := h.failures.Return(uint(id), c.GetUint("user_id"))
+	if err != nil {
+		if errors.Is(err, services.ErrNoFailedDelivery) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Pedido não tem entrega malsucedida com você"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao registrar devolução"})
+		return
+	}
+
+	h.notify(order, string(models.StatusReturned))
+	c.JSON(http.StatusOK, order)
+}
+
+// Reschedule devolve o pedido devolvido à fila de entrega, opcionalmente a partir de scheduled_for.
+// Permitido ao cliente do pedido e a quem gerencia qualquer pedido.
+func (h *DeliveryFailureHandler) Reschedule(c *gin.Context) {
+	var order models.Order
+	if err := h.db.First(&order, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pedido não encontrado"})
+		return
+	}
+
+	role := models.UserType(c.GetString("type"))
+	if order.CustomerID != c.GetUint("user_id") && !h.permissions.HasPermission(role, models.PermOrdersUpdateAny) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pedido não encontrado"})
+		return
+	}
+
+	var req RescheduleRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.ScheduledFor != nil {
+		if err := validators.ValidateRescheduleTime(*req.ScheduledFor, time.Now()); err != nil {
+			utils.RespondWithValidationError(c, []utils.ValidationError{*err})
+			return
+		}
+	}
+
+	rescheduled, err := h.failures.Reschedule(order.ID, req.ScheduledFor)
+	if err != nil {
+		if errors.Is(err, services.ErrOrderNotReturned) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Só pedidos devolvidos à loja podem ser reagendados"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao reagendar entrega"})
+		return
+	}
+
+	h.notify(rescheduled, "rescheduled")
+
+	// Sem data agendada o pedido volta imediatamente para o despacho automático
+	if rescheduled.ScheduledFor == nil && h.dispatcher != nil {
+		if _, err := h.dispatcher.Dispatch(rescheduled.ID); err != nil {
+			log.Printf("Erro no despacho automático do pedido %d: %v", rescheduled.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, rescheduled)
+}
+
+// ListAttempts lista as tentativas de entrega sem sucesso do pedido (admin)
+func (h *DeliveryFailureHandler) ListAttempts(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	attempts, err := h.failures.Attempts(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar tentativas de entrega"})
+		return
+	}
+
+	c.JSON(http.StatusOK, attempts)
+}
+
+// Report mostra as falhas de entrega por entregador e por cliente (admin).
+// from e to em RFC 3339; por padrão, os últimos 30 dias.
+func (h *DeliveryFailureHandler) Report(c *gin.Context) {
+	from, to, ok := reportPeriod(c, defaultReportPeriod)
+	if !ok {
+		return
+	}
+
+	report, err := h.failures.Report(from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar relatório de falhas de entrega"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *DeliveryFailureHandler) notify(order *models.Order, status string) {
+	refreshETA(h.eta, order)
+	publishOrderEvent(h.board, services.OrderEventStatusChanged, order)
+	if h.notificationService == nil {
+		return
+	}
+	if err := h.notificationService.NotifyOrderStatusChange(order, status); err != nil {
+		log.Printf("Erro ao notificar status %s do pedido %d: %v", status, order.ID, err)
+	}
+}
+
+// validateDeliveryFailure valida o motivo e o tamanho das observações, contado em caracteres
+func validateDeliveryFailure(req DeliveryFailureRequest) []utils.ValidationError {
+	var validationErrors []utils.ValidationError
+	if !req.Reason.Valid() {
+		validationErrors = append(validationErrors, utils.ValidationError{
+			Field:   "reason",
+			Message: "Motivo deve ser customer_absent, wrong_address, refused ou damaged",
+		})
+	}
+	if utf8.RuneCountInString(req.Notes) > 500 {
+		validationErrors = append(validationErrors, utils.ValidationError{
+			Field:   "notes",
+			Message: "Observações devem ter no máximo 500 caracteres",
+		})
+	}
+	return validationErrors
+}
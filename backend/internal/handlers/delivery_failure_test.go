@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"cupcake-delivery/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateDeliveryFailure(t *testing.T) {
+	testCases := []struct {
+		name    string
+		req     DeliveryFailureRequest
+		invalid []string
+	}{
+		{"Valid", DeliveryFailureRequest{Reason: models.FailureCustomerAbsent, Notes: "Portão fechado"}, []string{}},
+		{"Unknown reason", DeliveryFailureRequest{Reason: "lost"}, []string{"reason"}},
+		// 500 caracteres acentuados ocupam 1000 bytes e continuam dentro do limite
+		{"Accented notes at the limit", DeliveryFailureRequest{Reason: models.FailureRefused, Notes: strings.Repeat("ç", 500)}, []string{}},
+		{"Notes too long", DeliveryFailureRequest{Reason: models.FailureRefused, Notes: strings.Repeat("a", 501)}, []string{"notes"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.invalid, validationFields(validateDeliveryFailure(tc.req)))
+		})
+	}
+}
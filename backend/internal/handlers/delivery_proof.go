@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"cupcake-delivery/internal/models"
+	"cupcake-delivery/internal/services"
+	"cupcake-delivery/internal/utils"
+	"cupcake-delivery/internal/validators"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxProofPhotoSize tamanho máximo da foto do comprovante de entrega
+const maxProofPhotoSize = 5 << 20
+
+// CustomerOrder pedido como o cliente o vê, com o PIN que ele informa ao entregador
+type CustomerOrder struct {
+	models.Order
+	DeliveryPIN string `json:"deliveryPin,omitempty"`
+}
+
+func customerOrders(orders []models.Order) []CustomerOrder {
+	result := make([]CustomerOrder, 0, len(orders))
+	for _, order := range orders {
+		result = append(result, CustomerOrder{Order: order, DeliveryPIN: order.DeliveryPIN})
+	}
+	return result
+}
+
+// Deliver conclui a entrega com comprovante (multipart): pin, photo ou recipient_name
+func (h *OrderHandler) Deliver(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	h.deliverOrder(c, uint(id))
+}
+
+func (h *OrderHandler) deliverOrder(c *gin.Context, orderID uint) {
+	limitUploadBody(c, maxProofPhotoSize)
+	input := services.DeliveryProofInput{
+		PIN:           c.PostForm("pin"),
+		RecipientName: c.PostForm("recipient_name"),
+	}
+
+	if input.PIN == "" {
+		header, err := c.FormFile("photo")
+		switch {
+		case err == nil:
+			if header.Size > maxProofPhotoSize {
+				utils.RespondWithValidationError(c, []utils.ValidationError{{
+					Field:   "photo",
+					Message: "Foto deve ter no máximo 5 MB",
+				}})
+				return
+			}
+			photo, err := header.Open()
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Erro ao ler a foto enviada"})
+				return
+			}
+			defer photo.Close()
+			input.Photo = photo
+		case isBodyTooLarge(err):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Foto deve ter no máximo 5 MB"})
+			return
+		case !errors.Is(err, http.ErrMissingFile) && !errors.Is(err, http.ErrNotMultipart):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Formulário inválido"})
+			return
+		}
+	}
+
+	if input.PIN == "" && input.Photo == nil && input.RecipientName != "" {
+		if err := validators.ValidateName(input.RecipientName); err != nil {
+			err.Field = "recipient_name"
+			utils.RespondWithValidationError(c, []utils.ValidationError{*err})
+			return
+		}
+	}
+
+	order, err := h.proofs.Deliver(c.Request.Context(), orderID, c.GetUint("user_id"), input)
+	switch {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pedido não encontrado"})
+		return
+	case errors.Is(err, services.ErrOrderNotDeliverable):
+		c.JSON(http.StatusConflict, gin.H{"error": "Pedido não está em entrega com você"})
+		return
+	case errors.Is(err, services.ErrProofRequired):
+		utils.RespondWithValidationError(c, []utils.ValidationError{{
+			Field:   "pin",
+			Message: "Informe o PIN do cliente, uma foto da entrega ou o nome de quem recebeu",
+		}})
+		return
+	case errors.Is(err, services.ErrInvalidDeliveryPIN):
+		utils.RespondWithValidationError(c, []utils.ValidationError{{Field: "pin", Message: "PIN de entrega incorreto"}})
+		return
+	case errors.Is(err, services.ErrDeliveryPINLocked):
+		c.JSON(http.StatusConflict, gin.H{"error": "PIN bloqueado após tentativas erradas; envie uma foto ou o nome de quem recebeu"})
+		return
+	case errors.Is(err, services.ErrUnsupportedPhoto):
+		utils.RespondWithValidationError(c, []utils.ValidationError{{Field: "photo", Message: "Foto deve ser JPEG, PNG ou WebP"}})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao concluir entrega"})
+		return
+	}
+
+	refreshETA(h.eta, order)
+	publishOrderEvent(h.board, services.OrderEventStatusChanged, order)
+	if h.notificationService != nil {
+		if err := h.notificationService.NotifyOrderStatusChange(order, string(models.StatusDelivered)); err != nil {
+			log.Printf("Erro ao notificar entrega do pedido %d: %v", order.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// DeliveryProof mostra o comprovante de entrega do pedido (admin)
+func (h *OrderHandler) DeliveryProof(c *gin.Context) {
+	proof, ok := h.findProof(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, proof)
+}
+
+// DeliveryProofPhoto envia a foto do comprovante de entrega (admin)
+func (h *OrderHandler) DeliveryProofPhoto(c *gin.Context) {
+	proof, ok := h.findProof(c)
+	if !ok {
+		return
+	}
+
+	photo, err := h.proofs.OpenPhoto(c.Request.Context(), proof)
+	if err != nil {
+		if errors.Is(err, services.ErrFileNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Comprovante sem foto"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao abrir foto do comprovante"})
+		return
+	}
+	defer photo.Close()
+
+	c.Header("Content-Type", proof.PhotoContentType)
+	c.Status(http.StatusOK)
+	if _, err := io.Copy(c.Writer, photo); err != nil {
+		log.Printf("Erro ao enviar foto do comprovante %d: %v", proof.ID, err)
+	}
+}
+
+func (h *OrderHandler) findProof(c *gin.Context) (*models.DeliveryProof, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return nil, false
+	}
+
+	proof, err := h.proofs.Proof(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Comprovante de entrega não encontrado"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar comprovante de entrega"})
+		return nil, false
+	}
+	return proof, true
+}
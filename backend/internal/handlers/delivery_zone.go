@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"cupcake-delivery/internal/models"
+	"cupcake-delivery/internal/utils"
+	"cupcake-delivery/internal/validators"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type DeliveryZoneHandler struct {
+	db *gorm.DB
+}
+
+type DeliveryZoneRequest struct {
+	Name             string                `json:"name" binding:"required"`
+	Polygon          models.GeoJSONPolygon `json:"polygon"`
+	Fee              float64               `json:"fee" binding:"min=0"`
+	MinOrderValue    float64               `json:"minOrderValue" binding:"min=0"`
+	EstimatedMinutes int                   `json:"estimatedMinutes" binding:"min=0"`
+	Active           *bool                 `json:"active"`
+}
+
+func NewDeliveryZoneHandler(db *gorm.DB) *DeliveryZoneHandler {
+	return &DeliveryZoneHandler{db: db}
+}
+
+// List lista as zonas de entrega; ?active=true retorna apenas as zonas atendidas
+func (h *DeliveryZoneHandler) List(c *gin.Context) {
+	query := h.db.Order("id")
+	if c.Query("active") == "true" {
+		query = query.Where("active = ?", true)
+	}
+
+	var zones []models.DeliveryZone
+	if err := query.Find(&zones).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao listar zonas de entrega"})
+		return
+	}
+
+	c.JSON(http.StatusOK, zones)
+}
+
+func (h *DeliveryZoneHandler) Create(c *gin.Context) {
+	var req DeliveryZoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validators.ValidateGeoJSONPolygon(req.Polygon); err != nil {
+		utils.RespondWithValidationError(c, []utils.ValidationError{*err})
+		return
+	}
+
+	zone := models.DeliveryZone{Active: true}
+	applyDeliveryZoneRequest(&zone, &req)
+
+	if err := h.db.Create(&zone).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao criar zona de entrega"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, zone)
+}
+
+func (h *DeliveryZoneHandler) Update(c *gin.Context) {
+	var zone models.DeliveryZone
+	if err := h.db.First(&zone, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Zona de entrega não encontrada"})
+		return
+	}
+
+	var req DeliveryZoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validators.ValidateGeoJSONPolygon(req.Polygon); err != nil {
+		utils.RespondWithValidationError(c, []utils.ValidationError{*err})
+		return
+	}
+
+	applyDeliveryZoneRequest(&zone, &req)
+
+	if err := h.db.Save(&zone).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar zona de entrega"})
+		return
+	}
+
+	c.JSON(http.StatusOK, zone)
+}
+
+func (h *DeliveryZoneHandler) Delete(c *gin.Context) {
+	result := h.db.Delete(&models.DeliveryZone{}, c.Param("id"))
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao remover zona de entrega"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Zona de entrega não encontrada"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Zona de entrega removida com sucesso"})
+}
+
+func applyDeliveryZoneRequest(zone *models.DeliveryZone, req *DeliveryZoneRequest) {
+	zone.Name = strings.TrimSpace(req.Name)
+	zone.Polygon = req.Polygon
+	zone.Fee = req.Fee
+	zone.MinOrderValue = req.MinOrderValue
+	zone.EstimatedMinutes = req.EstimatedMinutes
+	if req.Active != nil {
+		zone.Active = *req.Active
+	}
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"cupcake-delivery/internal/models"
+	"cupcake-delivery/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type DispatchHandler struct {
+	db                  *gorm.DB
+	dispatcher          *services.Dispatcher
+	notificationService *services.NotificationService
+	eta                 *services.ETAService
+	board               *services.OrderBoard
+}
+
+func NewDispatchHandler(db *gorm.DB, dispatcher *services.Dispatcher, notificationService *services.NotificationService, eta *services.ETAService, board *services.OrderBoard) *DispatchHandler {
+	return &DispatchHandler{
+		db:                  db,
+		dispatcher:          dispatcher,
+		notificationService: notificationService,
+		eta:                 eta,
+		board:               board,
+	}
+}
+
+// ListOffers lista as ofertas que o entregador logado ainda pode aceitar ou recusar
+func (h *DispatchHandler) ListOffers(c *gin.Context) {
+	offers, err := h.dispatcher.PendingOffers(c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar ofertas"})
+		return
+	}
+
+	c.JSON(http.StatusOK, offers)
+}
+
+// Accept aceita a oferta e atribui o pedido ao entregador logado
+func (h *DispatchHandler) Accept(c *gin.Context) {
+	offer, ok := h.respond(c, h.dispatcher.Accept)
+	if !ok {
+		return
+	}
+
+	var order models.Order
+	if err := h.db.First(&order, offer.OrderID).Error; err != nil {
+		log.Printf("Erro ao buscar pedido %d aceito na oferta %d: %v", offer.OrderID, offer.ID, err)
+		return
+	}
+	refreshETA(h.eta, &order)
+	publishOrderEvent(h.board, services.OrderEventStatusChanged, &order)
+	if err := h.notificationService.NotifyOrderStatusChange(&order, string(models.StatusDelivering)); err != nil {
+		log.Printf("Erro ao notificar retirada do pedido %d: %v", order.ID, err)
+	}
+}
+
+// Decline recusa a oferta, que passa ao próximo entregador
+func (h *DispatchHandler) Decline(c *gin.Context) {
+	h.respond(c, h.dispatcher.Decline)
+}
+
+// respond executa a resposta à oferta e escreve o resultado; retorna a oferta se deu certo
+func (h *DispatchHandler) respond(c *gin.Context, action func(offerID, courierID uint) (*models.DispatchOffer, error)) (*models.DispatchOffer, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return nil, false
+	}
+
+	offer, err := action(uint(id), c.GetUint("user_id"))
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, offer)
+	case errors.Is(err, services.ErrOfferNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Oferta não encontrada"})
+	case errors.Is(err, services.ErrOfferNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": "Oferta já respondida ou expirada"})
+	case errors.Is(err, services.ErrOrderNotClaimable):
+		c.JSON(http.StatusConflict, gin.H{"error": "Pedido já foi retirado por outro entregador"})
+	case respondVehicleLoadError(c, err):
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao responder oferta"})
+	}
+	return offer, err == nil
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"cupcake-delivery/internal/models"
+	"cupcake-delivery/internal/services"
+	"cupcake-delivery/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type EarningsHandler struct {
+	db          *gorm.DB
+	earnings    *services.EarningsService
+	permissions *services.PermissionService
+}
+
+type EarningEntryRequest struct {
+	CourierID   uint               `json:"courier_id" binding:"required"`
+	Type        models.EarningType `json:"type" binding:"required"`
+	Amount      float64            `json:"amount"`
+	OrderID     *uint              `json:"order_id"`
+	Description string             `json:"description" binding:"required"`
+}
+
+type PayoutRequest struct {
+	CourierID   uint     `json:"courier_id" binding:"required"`
+	Amount      *float64 `json:"amount"` // Sem valor, paga o saldo inteiro
+	Description string   `json:"description"`
+}
+
+func NewEarningsHandler(db *gorm.DB, earnings *services.EarningsService, permissions *services.PermissionService) *EarningsHandler {
+	return &EarningsHandler{
+		db:          db,
+		earnings:    earnings,
+		permissions: permissions,
+	}
+}
+
+// Statement extrato de ganhos entre from e to (RFC 3339; padrão: últimos 30 dias) e saldo atual.
+// Entregadores veem o próprio; admin informa courier_id.
+func (h *EarningsHandler) Statement(c *gin.Context) {
+	role := models.UserType(c.GetString("type"))
+
+	var courierID uint
+	switch {
+	case h.permissions.HasPermission(role, models.PermEarningsManage):
+		id, err := strconv.ParseUint(c.Query("courier_id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "courier_id inválido"})
+			return
+		}
+		courierID = uint(id)
+	case h.permissions.HasPermission(role, models.PermEarningsReadOwn):
+		courierID = c.GetUint("user_id")
+	default:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Acesso não autorizado"})
+		return
+	}
+
+	from, to, ok := reportPeriod(c, defaultReportPeriod)
+	if !ok {
+		return
+	}
+
+	statement, err := h.earnings.Statement(courierID, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar ganhos"})
+		return
+	}
+
+	c.JSON(http.StatusOK, statement)
+}
+
+// AddEntry lança gorjeta ou ajuste no extrato de um entregador (admin)
+func (h *EarningsHandler) AddEntry(c *gin.Context) {
+	var req EarningEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.courierExists(c, req.CourierID) {
+		return
+	}
+
+	entry, err := h.earnings.AddEntry(req.CourierID, c.GetUint("user_id"), services.EarningsInput{
+		Type:        req.Type,
+		Amount:      req.Amount,
+		OrderID:     req.OrderID,
+		Description: req.Description,
+	})
+	switch {
+	case err == nil:
+		c.JSON(http.StatusCreated, entry)
+	case errors.Is(err, services.ErrInvalidEarningType):
+		utils.RespondWithValidationError(c, []utils.ValidationError{{Field: "type", Message: "Tipo deve ser tip ou adjustment"}})
+	case errors.Is(err, services.ErrInvalidEarningAmount):
+		utils.RespondWithValidationError(c, []utils.ValidationError{{Field: "amount", Message: "Gorjeta deve ser positiva e ajuste diferente de zero"}})
+	case errors.Is(err, services.ErrDuplicateEarning):
+		c.JSON(http.StatusConflict, gin.H{"error": "Este pedido já tem gorjeta lançada"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao registrar lançamento"})
+	}
+}
+
+// Payout registra um pagamento ao entregador, descontado do saldo (admin)
+func (h *EarningsHandler) Payout(c *gin.Context) {
+	var req PayoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.courierExists(c, req.CourierID) {
+		return
+	}
+
+	entry, err := h.earnings.Payout(req.CourierID, c.GetUint("user_id"), req.Amount, req.Description)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusCreated, entry)
+	case errors.Is(err, services.ErrInvalidEarningAmount):
+		utils.RespondWithValidationError(c, []utils.ValidationError{{Field: "amount", Message: "Valor do pagamento deve ser positivo"}})
+	case errors.Is(err, services.ErrNothingToPay):
+		c.JSON(http.StatusConflict, gin.H{"error": "Entregador sem saldo a receber"})
+	case errors.Is(err, services.ErrPayoutExceedsBalance):
+		c.JSON(http.StatusConflict, gin.H{"error": "Pagamento maior que o saldo do entregador"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao registrar pagamento"})
+	}
+}
+
+// PayoutReport relatório de pagamentos por entregador no período (admin); ?format=csv para planilha
+func (h *EarningsHandler) PayoutReport(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Formato deve ser 'json' ou 'csv'"})
+		return
+	}
+	from, to, ok := reportPeriod(c, defaultReportPeriod)
+	if !ok {
+		return
+	}
+
+	report, err := h.earnings.PayoutReport(from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar relatório de pagamentos"})
+		return
+	}
+
+	if format == "json" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	filename := fmt.Sprintf("pagamentos-%s-%s.csv", from.Format("20060102"), to.Format("20060102"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+	if err := report.WriteCSV(c.Writer); err != nil {
+		// Os cabeçalhos já foram enviados; resta interromper o download
+		c.Error(err)
+		c.Abort()
+	}
+}
+
+func (h *EarningsHandler) courierExists(c *gin.Context, courierID uint) bool {
+	var courier models.User
+	if err := h.db.Select("id").Where("id = ? AND type = ?", courierID, models.DeliveryType).First(&courier).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Entregador não encontrado"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"cupcake-delivery/internal/models"
+	"cupcake-delivery/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ETAHandler struct {
+	eta *services.ETAService
+}
+
+func NewETAHandler(eta *services.ETAService) *ETAHandler {
+	return &ETAHandler{eta: eta}
+}
+
+// Accuracy previsto contra realizado das entregas do período, para calibrar a previsão de entrega
+func (h *ETAHandler) Accuracy(c *gin.Context) {
+	from, to, ok := reportPeriod(c, defaultReportPeriod)
+	if !ok {
+		return
+	}
+
+	report, err := h.eta.Accuracy(from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar relatório de previsão de entrega"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// refreshETA recalcula a previsão de entrega após a mudança de status, antes da notificação e da resposta
+func refreshETA(eta *services.ETAService, order *models.Order) {
+	if err := eta.Refresh(order); err != nil {
+		log.Printf("Erro ao atualizar previsão de entrega do pedido %d: %v", order.ID, err)
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+
+	"cupcake-delivery/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type KitchenHandler struct {
+	db *gorm.DB
+}
+
+// KitchenQueueItem quantidade pendente de um produto somando todos os pedidos aguardando preparo
+type KitchenQueueItem struct {
+	ProductID   uint   `json:"product_id"`
+	ProductName string `json:"product_name"`
+	Quantity    int    `json:"quantity"`
+	Orders      int    `json:"orders"`
+}
+
+func NewKitchenHandler(db *gorm.DB) *KitchenHandler {
+	return &KitchenHandler{db: db}
+}
+
+// Queue agrupa os itens dos pedidos pendentes por produto para orientar a produção
+func (h *KitchenHandler) Queue(c *gin.Context) {
+	var items []KitchenQueueItem
+
+	err := h.db.Table("order_items").
+		Select("order_items.product_id, products.name AS product_name, SUM(order_items.quantity) AS quantity, COUNT(DISTINCT order_items.order_id) AS orders").
+		Joins("JOIN orders ON orders.id = order_items.order_id AND orders.deleted_at IS NULL AND orders.payment_status <> ?", models.PaymentPending).
+		Joins("JOIN products ON products.id = order_items.product_id").
+		Where("orders.status = ? AND order_items.deleted_at IS NULL", models.StatusPending).
+		Group("order_items.product_id, products.name").
+		Order("quantity DESC, products.name").
+		Scan(&items).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao montar fila da cozinha"})
+		return
+	}
+
+	total := 0
+	for _, item := range items {
+		total += item.Quantity
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":          items,
+		"total_quantity": total,
+	})
+}
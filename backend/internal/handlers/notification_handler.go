@@ -3,19 +3,35 @@ package handlers
 import (
 	"cupcake-delivery/internal/models"
 	"cupcake-delivery/internal/services"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Stream de notificações: comentário periódico para manter a conexão aberta em proxies,
+// notificações buscadas por página ao retomar pelo Last-Event-ID e quantos IDs enviados são
+// lembrados para descartar repetições
+const (
+	streamHeartbeatInterval = 25 * time.Second
+	streamBacklogLimit      = 100
+	streamSentWindow        = 1000
+)
+
 type NotificationHandler struct {
 	notificationService *services.NotificationService
+	broker              services.NotificationBroker
 }
 
-func NewNotificationHandler(notificationService *services.NotificationService) *NotificationHandler {
+func NewNotificationHandler(notificationService *services.NotificationService, broker services.NotificationBroker) *NotificationHandler {
 	return &NotificationHandler{
 		notificationService: notificationService,
+		broker:              broker,
 	}
 }
 
@@ -150,3 +166,138 @@ func (h *NotificationHandler) CreateTestNotification(c *gin.Context) {
 		"notification": notification,
 	})
 }
+
+// Stream envia as notificações do usuário logado por Server-Sent Events assim que são criadas.
+// O ID de cada evento é o da notificação; ao reconectar com Last-Event-ID (ou last_event_id na
+// query), as notificações perdidas são reenviadas antes das novas.
+func (h *NotificationHandler) Stream(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	var lastID uint
+	if lastEventID != "" {
+		parsed, err := strconv.ParseUint(lastEventID, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Last-Event-ID inválido"})
+			return
+		}
+		lastID = uint(parsed)
+	}
+
+	// Assina antes de buscar as perdidas para não deixar buraco entre as duas
+	events, unsubscribe := h.broker.Subscribe(userID)
+	defer unsubscribe()
+
+	var backlog []models.Notification
+	if lastID > 0 {
+		var err error
+		backlog, err = h.notificationService.NotificationsAfter(userID, lastID, streamBacklogLimit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar notificações"})
+			return
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	fmt.Fprint(c.Writer, "retry: 3000\n\n")
+
+	// Os IDs não chegam em ordem (uma transação pode gravar depois de outra com ID maior),
+	// então as repetições são descartadas pelos IDs já enviados, não pelo maior deles
+	sent := newSentWindow(streamSentWindow)
+
+	// Página a página até alcançar as notificações atuais; as que forem gravadas enquanto isso
+	// chegam pela assinatura
+	for len(backlog) > 0 {
+		for _, notification := range backlog {
+			if err := writeNotificationEvent(c.Writer, notification); err != nil {
+				return
+			}
+			sent.add(notification.ID)
+			lastID = notification.ID
+		}
+		c.Writer.Flush()
+		if len(backlog) < streamBacklogLimit {
+			break
+		}
+
+		var err error
+		backlog, err = h.notificationService.NotificationsAfter(userID, lastID, streamBacklogLimit)
+		if err != nil {
+			// O cliente reconecta e retoma a partir da última enviada
+			log.Printf("Erro ao buscar notificações do usuário %d: %v", userID, err)
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case notification, ok := <-events:
+			if !ok {
+				// Conexão não acompanhou o ritmo; o cliente reconecta e retoma pelo último ID
+				return
+			}
+			if sent.contains(notification.ID) {
+				continue
+			}
+			if err := writeNotificationEvent(c.Writer, notification); err != nil {
+				return
+			}
+			sent.add(notification.ID)
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// sentWindow IDs das últimas notificações enviadas pelo stream; os mais antigos são esquecidos
+type sentWindow struct {
+	ids   map[uint]bool
+	order []uint
+	size  int
+}
+
+func newSentWindow(size int) *sentWindow {
+	return &sentWindow{ids: make(map[uint]bool, size), size: size}
+}
+
+func (w *sentWindow) contains(id uint) bool {
+	return w.ids[id]
+}
+
+func (w *sentWindow) add(id uint) {
+	if w.ids[id] {
+		return
+	}
+	if len(w.order) == w.size {
+		delete(w.ids, w.order[0])
+		w.order = w.order[1:]
+	}
+	w.ids[id] = true
+	w.order = append(w.order, id)
+}
+
+func writeNotificationEvent(w io.Writer, notification models.Notification) error {
+	data, err := json.Marshal(notification)
+	if err != nil {
+		log.Printf("Erro ao serializar notificação %d: %v", notification.ID, err)
+		return nil
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: notification\ndata: %s\n\n", notification.ID, data)
+	return err
+}
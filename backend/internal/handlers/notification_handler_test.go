@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cupcake-delivery/internal/models"
+	"cupcake-delivery/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newStreamServer sobe o stream de notificações com o usuário 7 já autenticado
+func newStreamServer(t *testing.T, broker services.NotificationBroker) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	handler := NewNotificationHandler(nil, broker)
+	r := gin.New()
+	r.GET("/notifications/stream", func(c *gin.Context) {
+		c.Set("user_id", uint(7))
+		c.Next()
+	}, handler.Stream)
+
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// readEvent lê as linhas do stream até o fim do próximo evento, ignorando comentários e o retry
+func readEvent(t *testing.T, reader *bufio.Reader) []string {
+	t.Helper()
+
+	var lines []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if len(lines) > 0 {
+				return lines
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") || strings.HasPrefix(line, "retry:") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestNotificationStreamDeliversPublishedNotifications(t *testing.T) {
+	broker := services.NewMemoryNotificationBroker(8)
+	server := newStreamServer(t, broker)
+
+	resp, err := http.Get(server.URL + "/notifications/stream")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Expected text/event-stream, got %q", got)
+	}
+
+	// A resposta só chega depois da assinatura; o que for publicado a partir daqui é entregue
+	broker.Publish(models.Notification{ID: 3, UserID: 8, Title: "Outro usuário"})
+	broker.Publish(models.Notification{ID: 5, UserID: 7, Title: "Pedido pronto"})
+	broker.Publish(models.Notification{ID: 5, UserID: 7, Title: "Repetida"})
+	broker.Publish(models.Notification{ID: 6, UserID: 7, Title: "Saiu para entrega"})
+
+	reader := bufio.NewReader(resp.Body)
+	first := readEvent(t, reader)
+	if len(first) != 3 || first[0] != "id: 5" || first[1] != "event: notification" || !strings.Contains(first[2], `"Pedido pronto"`) {
+		t.Errorf("Unexpected first event: %q", first)
+	}
+	second := readEvent(t, reader)
+	if len(second) != 3 || second[0] != "id: 6" || !strings.Contains(second[2], `"Saiu para entrega"`) {
+		t.Errorf("Expected the repeated ID to be skipped, got %q", second)
+	}
+}
+
+func TestNotificationStreamDeliversLateCommittedNotifications(t *testing.T) {
+	broker := services.NewMemoryNotificationBroker(8)
+	server := newStreamServer(t, broker)
+
+	resp, err := http.Get(server.URL + "/notifications/stream")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// A notificação 8 foi gravada por uma transação que terminou depois da 9
+	broker.Publish(models.Notification{ID: 9, UserID: 7, Title: "Pedido pronto"})
+	broker.Publish(models.Notification{ID: 8, UserID: 7, Title: "Pagamento confirmado"})
+
+	reader := bufio.NewReader(resp.Body)
+	if first := readEvent(t, reader); first[0] != "id: 9" {
+		t.Errorf("Expected notification 9 first, got %q", first)
+	}
+	if second := readEvent(t, reader); second[0] != "id: 8" {
+		t.Errorf("Expected the late notification 8 to be delivered, got %q", second)
+	}
+}
+
+func TestSentWindowForgetsOldestIDs(t *testing.T) {
+	window := newSentWindow(2)
+	window.add(1)
+	window.add(2)
+	window.add(2)
+	window.add(3)
+
+	if window.contains(1) || !window.contains(2) || !window.contains(3) {
+		t.Errorf("Expected only the last 2 IDs to be kept, got %v", window.order)
+	}
+}
+
+// droppedBroker encerra toda assinatura de imediato, como o broker faz com quem fica para trás
+type droppedBroker struct{}
+
+func (droppedBroker) Publish(models.Notification) error { return nil }
+
+func (droppedBroker) Subscribe(uint) (<-chan models.Notification, func()) {
+	ch := make(chan models.Notification)
+	close(ch)
+	return ch, func() {}
+}
+
+func TestNotificationStreamEndsWhenSubscriptionIsDropped(t *testing.T) {
+	server := newStreamServer(t, droppedBroker{})
+
+	resp, err := http.Get(server.URL + "/notifications/stream")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.ReadAll(resp.Body)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the stream to end so the client reconnects")
+	}
+}
+
+func TestNotificationStreamRejectsInvalidLastEventID(t *testing.T) {
+	server := newStreamServer(t, services.NewMemoryNotificationBroker(8))
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/notifications/stream", nil)
+	req.Header.Set("Last-Event-ID", "abc")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", resp.StatusCode)
+	}
+}
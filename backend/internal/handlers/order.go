@@ -1,10 +1,19 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"cupcake-delivery/internal/models"
 	"cupcake-delivery/internal/services"
+	"cupcake-delivery/internal/utils"
+	"cupcake-delivery/internal/validators"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -13,10 +22,31 @@ import (
 type OrderHandler struct {
 	db                  *gorm.DB
 	notificationService *services.NotificationService
+	permissions         *services.PermissionService
+	cepResolver         services.CEPResolver
+	deliveryPricer      services.DeliveryPricer
+	couriers            *services.CourierService
+	claims              *services.OrderClaimService
+	dispatcher          *services.Dispatcher // nil com o despacho automático desativado
+	proofs              *services.DeliveryProofService
+	batches             *services.BatchService
+	earnings            *services.EarningsService
+	placement           *services.OrderPlacementService
+	eta                 *services.ETAService
+	board               *services.OrderBoard
 }
 
+// CreateOrderRequest aceita um endereço salvo (address_id) ou um endereço estruturado informado na hora.
+// Sem nenhum dos dois, usa o endereço padrão do cliente.
 type CreateOrderRequest struct {
-	Items []OrderItemRequest `json:"items" binding:"required,min=1"`
+	PostalAddressRequest
+	Items     []OrderItemRequest `json:"items" binding:"required,min=1"`
+	AddressID *uint              `json:"address_id"`
+	Latitude  *float64           `json:"latitude"`
+	Longitude *float64           `json:"longitude"`
+	Document  string             `json:"document"` // CPF/CNPJ opcional na nota; padrão é o do perfil
+	Phone     string             `json:"phone"`    // Telefone de contato; padrão é o do perfil
+	Tip       float64            `json:"tip"`      // Gorjeta opcional para o entregador
 }
 
 type OrderItemRequest struct {
@@ -24,10 +54,22 @@ type OrderItemRequest struct {
 	Quantity  int  `json:"quantity" binding:"required,min=1"`
 }
 
-func NewOrderHandler(db *gorm.DB, notificationService *services.NotificationService) *OrderHandler {
+func NewOrderHandler(db *gorm.DB, notificationService *services.NotificationService, permissions *services.PermissionService, cepResolver services.CEPResolver, deliveryPricer services.DeliveryPricer, couriers *services.CourierService, claims *services.OrderClaimService, dispatcher *services.Dispatcher, proofs *services.DeliveryProofService, batches *services.BatchService, earnings *services.EarningsService, placement *services.OrderPlacementService, eta *services.ETAService, board *services.OrderBoard) *OrderHandler {
 	return &OrderHandler{
 		db:                  db,
 		notificationService: notificationService,
+		permissions:         permissions,
+		cepResolver:         cepResolver,
+		deliveryPricer:      deliveryPricer,
+		couriers:            couriers,
+		claims:              claims,
+		dispatcher:          dispatcher,
+		proofs:              proofs,
+		batches:             batches,
+		earnings:            earnings,
+		placement:           placement,
+		eta:                 eta,
+		board:               board,
 	}
 }
 
@@ -45,59 +87,326 @@ func (h *OrderHandler) Create(c *gin.Context) {
 		return
 	}
 
-	// Iniciar transação
-	tx := h.db.Begin()
+	if err := validators.ValidateTip(req.Tip); err != nil {
+		utils.RespondWithValidationError(c, []utils.ValidationError{*err})
+		return
+	}
 
-	// Criar pedido
-	order := models.Order{
-		CustomerID: userID.(uint),
-		Status:     models.StatusPending,
+	document, phone, ok := h.customerContact(c, userID.(uint), &req)
+	if !ok {
+		return
+	}
+
+	// Endereço de entrega copiado para o pedido
+	address, ok := h.deliveryAddress(c, userID.(uint), &req)
+	if !ok {
+		return
 	}
 
-	if err := tx.Create(&order).Error; err != nil {
-		tx.Rollback()
+	pin, err := services.GenerateDeliveryPIN()
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao criar pedido"})
 		return
 	}
 
-	// Adicionar itens ao pedido
-	var totalPrice float64 = 0
-	for _, item := range req.Items {
-		var product models.Product
-		if err := tx.First(&product, item.ProductID).Error; err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Produto não encontrado"})
+	pricing, err := h.priceOrder(h.db, req.Items, address, req.Tip)
+	if err != nil {
+		respondPricingError(c, err)
+		return
+	}
+
+	boxes, requiredVehicle, refrigerated := services.OrderVehicleNeeds(pricing.Items)
+	order := models.Order{
+		CustomerID:    userID.(uint),
+		Status:        models.StatusPending,
+		Total:         pricing.Total,
+		Address:       address.Address,
+		PostalAddress: address.PostalAddress,
+		Latitude:      address.Latitude,
+		Longitude:     address.Longitude,
+
+		CustomerDocument: document,
+		ContactPhone:     phone,
+
+		Subtotal:           pricing.Subtotal,
+		DeliveryFee:        pricing.Delivery.Fee,
+		Tip:                pricing.Tip,
+		DeliveryZoneID:     pricing.Delivery.ZoneID,
+		DeliveryDistanceKm: pricing.Delivery.DistanceKm,
+		EstimatedMinutes:   pricing.Delivery.EstimatedMinutes,
+
+		DeliveryPIN: pin,
+
+		Boxes:              boxes,
+		RequiredVehicle:    requiredVehicle,
+		NeedsRefrigeration: refrigerated,
+	}
+
+	// Grava o pedido e depois cobra, com a gorjeta em linha própria; recusada, o pedido é descartado.
+	// Sem resposta conclusiva do meio de pagamento o pedido fica gravado com a cobrança pendente (202).
+	status := http.StatusCreated
+	if err := h.placement.Place(c.Request.Context(), &order, pricing.Items); err != nil {
+		switch {
+		case errors.Is(err, services.ErrPaymentDeclined):
+			c.JSON(http.StatusPaymentRequired, gin.H{"error": "Pagamento recusado"})
+			return
+		case errors.Is(err, services.ErrPaymentPending):
+			status = http.StatusAccepted
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao criar pedido"})
 			return
 		}
+	}
+
+	refreshETA(h.eta, &order)
+	publishOrderEvent(h.board, services.OrderEventCreated, &order)
+
+	c.JSON(status, CustomerOrder{Order: order, DeliveryPIN: order.DeliveryPIN})
+}
+
+// Quote calcula subtotal, taxa de entrega e total sem criar o pedido, com o mesmo cálculo do Create
+func (h *OrderHandler) Quote(c *gin.Context) {
+	var req CreateOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := validators.ValidateTip(req.Tip); err != nil {
+		utils.RespondWithValidationError(c, []utils.ValidationError{*err})
+		return
+	}
+
+	address, ok := h.deliveryAddress(c, c.GetUint("user_id"), &req)
+	if !ok {
+		return
+	}
+
+	pricing, err := h.priceOrder(h.db, req.Items, address, req.Tip)
+	if err != nil {
+		respondPricingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pricing)
+}
+
+// OrderPricing composição do valor de um pedido
+type OrderPricing struct {
+	Items    []models.OrderItem      `json:"items"`
+	Subtotal float64                 `json:"subtotal"`
+	Delivery *services.DeliveryQuote `json:"delivery"`
+	Tip      float64                 `json:"tip"`
+	Total    float64                 `json:"total"`
+}
+
+var errProductNotFound = errors.New("produto não encontrado")
+
+// priceOrder calcula os itens com o preço atual dos produtos e a taxa de entrega para o endereço;
+// a gorjeta entra no total em linha própria
+func (h *OrderHandler) priceOrder(db *gorm.DB, items []OrderItemRequest, address *models.UserAddress, tip float64) (*OrderPricing, error) {
+	pricing := &OrderPricing{Tip: math.Round(tip*100) / 100}
+	for _, item := range items {
+		var product models.Product
+		if err := db.First(&product, item.ProductID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errProductNotFound
+			}
+			return nil, err
+		}
 
-		orderItem := models.OrderItem{
-			OrderID:   order.ID,
+		pricing.Items = append(pricing.Items, models.OrderItem{
 			ProductID: product.ID,
+			Product:   product,
 			Quantity:  item.Quantity,
 			Price:     product.Price,
+		})
+		pricing.Subtotal += product.Price * float64(item.Quantity)
+	}
+
+	delivery, err := h.deliveryPricer.Quote(pricing.Subtotal, address.Latitude, address.Longitude)
+	if err != nil {
+		return nil, err
+	}
+	pricing.Delivery = delivery
+	pricing.Total = pricing.Subtotal + delivery.Fee + pricing.Tip
+
+	return pricing, nil
+}
+
+// deliveryAddress resolve o endereço de entrega: endereço estruturado informado, address_id ou endereço padrão
+func (h *OrderHandler) deliveryAddress(c *gin.Context, userID uint, req *CreateOrderRequest) (*models.UserAddress, bool) {
+	if !req.PostalAddressRequest.isEmpty() {
+		postal, ok := preparePostalAddress(c, h.cepResolver, req.PostalAddressRequest)
+		if !ok {
+			return nil, false
 		}
+		return &models.UserAddress{
+			PostalAddress: postal,
+			Address:       postal.String(),
+			Latitude:      req.Latitude,
+			Longitude:     req.Longitude,
+		}, true
+	}
 
-		if err := tx.Create(&orderItem).Error; err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao criar item do pedido"})
-			return
+	saved, err := h.savedAddress(userID, req.AddressID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Endereço não encontrado"})
+			return nil, false
+		}
+		if errors.Is(err, errAddressRequired) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Endereço de entrega é obrigatório"})
+			return nil, false
 		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar endereço"})
+		return nil, false
+	}
+	return saved, true
+}
 
-		totalPrice += product.Price * float64(item.Quantity)
+// Claim retira um pedido pronto para o entregador logado. A atribuição é um UPDATE condicional,
+// então apenas um entregador vence; os demais recebem 409.
+func (h *OrderHandler) Claim(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
 	}
 
-	// Atualizar preço total do pedido
-	order.Total = totalPrice
-	if err := tx.Save(&order).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar preço total"})
+	h.claimOrder(c, uint(id))
+}
+
+func (h *OrderHandler) claimOrder(c *gin.Context, orderID uint) {
+	courierID := c.GetUint("user_id")
+
+	online, err := h.couriers.IsOnline(courierID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar disponibilidade do entregador"})
+		return
+	}
+	if !online {
+		c.JSON(http.StatusConflict, gin.H{"error": "Fique online durante um turno para retirar pedidos"})
 		return
 	}
 
-	// Commit da transação
-	tx.Commit()
+	claimErr := h.claims.Claim(orderID, courierID)
+	if claimErr != nil && respondVehicleLoadError(c, claimErr) {
+		return
+	}
+	if claimErr != nil && !errors.Is(claimErr, services.ErrOrderNotClaimable) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao retirar pedido"})
+		return
+	}
 
-	c.JSON(http.StatusCreated, order)
+	var order models.Order
+	if err := h.db.First(&order, orderID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pedido não encontrado"})
+		return
+	}
+
+	if claimErr != nil {
+		if order.DeliveryID != nil && *order.DeliveryID != courierID {
+			c.JSON(http.StatusConflict, gin.H{"error": "Pedido já foi retirado por outro entregador"})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": "Pedido não está pronto para retirada", "status": order.Status})
+		return
+	}
+
+	refreshETA(h.eta, &order)
+	publishOrderEvent(h.board, services.OrderEventStatusChanged, &order)
+	if h.notificationService != nil {
+		if err := h.notificationService.NotifyOrderStatusChange(&order, string(models.StatusDelivering)); err != nil {
+			log.Printf("Erro ao notificar retirada do pedido %d: %v", order.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// respondPricingError traduz os erros do cálculo do pedido e da entrega em respostas HTTP
+func respondPricingError(c *gin.Context, err error) {
+	var minimumErr *services.MinimumOrderError
+	switch {
+	case errors.Is(err, errProductNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Produto não encontrado"})
+	case errors.Is(err, services.ErrDeliveryLocationNeeded):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Informe a localização (latitude e longitude) do endereço de entrega"})
+	case errors.Is(err, services.ErrOutsideDeliveryArea):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Endereço fora da área de entrega"})
+	case errors.Is(err, services.ErrBeyondMaxDistance):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Endereço além da distância máxima de entrega"})
+	case errors.As(err, &minimumErr):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":         fmt.Sprintf("Pedido mínimo para entrega em %s é R$ %.2f", minimumErr.Zone, minimumErr.Minimum),
+			"minimum_order": minimumErr.Minimum,
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao calcular taxa de entrega"})
+	}
+}
+
+// customerContact resolve o CPF/CNPJ e o telefone do pedido, usando os do perfil quando não informados.
+// Responde com erro de validação e retorna false se o telefone faltar ou algum dos dois for inválido.
+func (h *OrderHandler) customerContact(c *gin.Context, userID uint, req *CreateOrderRequest) (string, string, bool) {
+	var customer models.User
+	if err := h.db.First(&customer, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+		return "", "", false
+	}
+
+	document, phone, validationErrors := orderContact(&customer, req.Document, req.Phone)
+	if len(validationErrors) > 0 {
+		utils.RespondWithValidationError(c, validationErrors)
+		return "", "", false
+	}
+	return document, phone, true
+}
+
+// orderContact escolhe o documento e o telefone do pedido: os informados ou, sem eles, os do perfil.
+// O telefone é obrigatório; o CPF/CNPJ é opcional e só vai na nota quando existe.
+func orderContact(customer *models.User, document, phone string) (string, string, []utils.ValidationError) {
+	if strings.TrimSpace(document) == "" {
+		document = customer.Document
+	}
+	if strings.TrimSpace(phone) == "" {
+		phone = customer.Phone
+	}
+
+	var validationErrors []utils.ValidationError
+	if document != "" {
+		if err := validators.ValidateDocument(document); err != nil {
+			validationErrors = append(validationErrors, *err)
+		}
+	}
+	phone, err := validators.NormalizePhone(phone)
+	if err != nil {
+		validationErrors = append(validationErrors, *err)
+	}
+
+	return validators.NormalizeDocument(document), phone, validationErrors
+}
+
+var errAddressRequired = errors.New("endereço de entrega é obrigatório")
+
+// savedAddress busca o endereço salvo informado ou, sem address_id, o endereço padrão do cliente
+func (h *OrderHandler) savedAddress(userID uint, addressID *uint) (*models.UserAddress, error) {
+	var saved models.UserAddress
+	if addressID != nil {
+		if err := h.db.Where("id = ? AND user_id = ?", *addressID, userID).First(&saved).Error; err != nil {
+			return nil, err
+		}
+		return &saved, nil
+	}
+
+	if err := h.db.Where("user_id = ? AND is_default = ?", userID, true).First(&saved).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errAddressRequired
+		}
+		return nil, err
+	}
+	return &saved, nil
 }
 
 func (h *OrderHandler) List(c *gin.Context) {
@@ -107,27 +416,45 @@ func (h *OrderHandler) List(c *gin.Context) {
 		return
 	}
 
-	role, _ := c.Get("type")
+	role := models.UserType(c.GetString("type"))
 
 	var orders []models.Order
 
-	// Filtrar pedidos baseado no role
-	switch role {
-	case string(models.CustomerType):
-		if err := h.db.Where("customer_id = ?", userID.(uint)).Order("created_at DESC").Find(&orders).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar pedidos do cliente", "details": err.Error()})
+	// Filtrar pedidos baseado nas permissões do papel
+	switch {
+	case h.permissions.HasPermission(role, models.PermOrdersReadAny):
+		if err := h.db.Order("created_at DESC").Find(&orders).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar todos os pedidos", "details": err.Error()})
 			return
 		}
-	case string(models.DeliveryType):
-		if err := h.db.Where("delivery_id = ? OR (delivery_id IS NULL AND status IN ?)", userID.(uint), []models.OrderStatus{models.StatusReady}).Order("created_at DESC").Find(&orders).Error; err != nil {
+	case h.permissions.HasPermission(role, models.PermOrdersReadKitchen):
+		// Pedidos com a cobrança pendente só entram na produção depois de pagos
+		if err := h.db.Where("status IN ? AND payment_status <> ?", []models.OrderStatus{models.StatusPending, models.StatusPreparing}, models.PaymentPending).Order("created_at ASC").Find(&orders).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar pedidos da cozinha", "details": err.Error()})
+			return
+		}
+	case h.permissions.HasPermission(role, models.PermOrdersReadAssigned):
+		// A fila de retirada (pedidos prontos sem entregador) só aparece para quem está online e em turno
+		online, err := h.couriers.IsOnline(userID.(uint))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar disponibilidade do entregador"})
+			return
+		}
+		query := h.db.Where("delivery_id = ?", userID.(uint))
+		if online {
+			query = query.Or("delivery_id IS NULL AND status IN ? AND (scheduled_for IS NULL OR scheduled_for <= ?)", []models.OrderStatus{models.StatusReady}, time.Now())
+		}
+		if err := query.Order("created_at DESC").Find(&orders).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar pedidos do entregador", "details": err.Error()})
 			return
 		}
-	case string(models.AdminType):
-		if err := h.db.Order("created_at DESC").Find(&orders).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar todos os pedidos", "details": err.Error()})
+	case h.permissions.HasPermission(role, models.PermOrdersReadOwn):
+		if err := h.db.Where("customer_id = ?", userID.(uint)).Order("created_at DESC").Find(&orders).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar pedidos do cliente", "details": err.Error()})
 			return
 		}
+		c.JSON(http.StatusOK, customerOrders(orders))
+		return
 	default:
 		c.JSON(http.StatusForbidden, gin.H{"error": "Acesso não autorizado"})
 		return
@@ -136,12 +463,16 @@ func (h *OrderHandler) List(c *gin.Context) {
 	c.JSON(http.StatusOK, orders)
 }
 
+// errOrderChanged o pedido mudou entre a leitura e a atualização do status
+var errOrderChanged = errors.New("pedido alterado por outra operação")
+
 func (h *OrderHandler) UpdateStatus(c *gin.Context) {
 	orderID := c.Param("id")
 	status := c.PostForm("status")
 
 	userID, _ := c.Get("user_id")
-	role, _ := c.Get("type")
+	role := models.UserType(c.GetString("type"))
+	newStatus := models.OrderStatus(status)
 
 	var order models.Order
 	if err := h.db.First(&order, orderID).Error; err != nil {
@@ -150,34 +481,82 @@ func (h *OrderHandler) UpdateStatus(c *gin.Context) {
 	}
 
 	// Verificar permissões
-	switch role {
-	case string(models.DeliveryType):
+	if !h.permissions.HasPermission(role, models.OrderStatusPermission(newStatus)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Status não permitido para este usuário"})
+		return
+	}
+	// Falha e devolução guardam o motivo e a tentativa; têm endpoints próprios
+	if newStatus == models.StatusDeliveryFailed || newStatus == models.StatusReturned {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Use POST /orders/:id/fail ou /orders/:id/return para registrar falha ou devolução"})
+		return
+	}
+	// Sem permissão sobre qualquer pedido, só é possível avançar pedidos livres ou atribuídos a si
+	if !h.permissions.HasPermission(role, models.PermOrdersUpdateAny) {
 		if order.DeliveryID != nil && *order.DeliveryID != userID.(uint) {
 			c.JSON(http.StatusForbidden, gin.H{"error": "Acesso não autorizado"})
 			return
 		}
-		// Entregador só pode atualizar para "delivering" ou "delivered"
-		if status != string(models.StatusDelivering) && status != string(models.StatusDelivered) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Status inválido para entregador"})
+		if !newStatus.Advances(order.Status) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Pedido não pode voltar para um status anterior"})
 			return
 		}
-		if status == string(models.StatusDelivering) {
-			deliveryID := userID.(uint)
-			order.DeliveryID = &deliveryID
-		}
-	case string(models.AdminType):
-		// Admin pode atualizar para qualquer status
-	default:
-		c.JSON(http.StatusForbidden, gin.H{"error": "Acesso não autorizado"})
+	}
+	// Quem não gerencia qualquer pedido só conclui a entrega com comprovante (PIN, foto ou nome)
+	if newStatus == models.StatusDelivered && !h.permissions.HasPermission(role, models.PermOrdersUpdateAny) {
+		h.deliverOrder(c, order.ID)
 		return
 	}
+	// Retirada de pedido livre: mesma atribuição atômica do POST /orders/:id/claim
+	if newStatus == models.StatusDelivering && order.DeliveryID == nil && h.permissions.HasPermission(role, models.PermOrdersAssignSelf) {
+		h.claimOrder(c, order.ID)
+		return
+	}
+
+	now := time.Now()
+	changes := map[string]interface{}{
+		"status":            newStatus,
+		"status_changed_at": now,
+	}
+	if newStatus == models.StatusDelivered {
+		changes["delivered_at"] = now
+	}
+	// Voltando para antes da retirada o pedido fica sem entregador, para ser retirado ou despachado de novo
+	if order.DeliveryID != nil && models.StatusDelivering.Advances(newStatus) {
+		changes["delivery_id"] = nil
+	}
 
-	order.Status = models.OrderStatus(status)
-	if err := h.db.Save(&order).Error; err != nil {
+	// Só as colunas do status, e apenas se o pedido continua como foi lido: uma retirada, troca de lote,
+	// gorjeta ou tentativa de PIN concorrente não é sobrescrita, e quem chegou depois recebe 409
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Order{}).
+			Where("id = ? AND status = ? AND delivery_id IS NOT DISTINCT FROM ?", order.ID, order.Status, order.DeliveryID).
+			Updates(changes)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errOrderChanged
+		}
+		if err := tx.First(&order, order.ID).Error; err != nil {
+			return err
+		}
+		if err := services.RecordStatusChange(tx, order.ID, newStatus, now); err != nil {
+			return err
+		}
+		// Pedido entregue: o repasse entra junto ou a entrega não é gravada
+		return h.earnings.RecordDelivery(tx, &order)
+	}); err != nil {
+		if errors.Is(err, errOrderChanged) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Pedido foi alterado por outra operação; atualize e tente de novo"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar status"})
 		return
 	}
 
+	refreshETA(h.eta, &order)
+	publishOrderEvent(h.board, services.OrderEventStatusChanged, &order)
+
 	// Disparar notificações sobre mudança de status
 	if h.notificationService != nil {
 		if err := h.notificationService.NotifyOrderStatusChange(&order, status); err != nil {
@@ -186,5 +565,19 @@ func (h *OrderHandler) UpdateStatus(c *gin.Context) {
 		}
 	}
 
+	// Pedido que estava em um lote: a parada deixa a rota e as previsões seguintes são recalculadas
+	if newStatus != models.StatusDelivering {
+		if err := h.batches.CloseStop(&order); err != nil {
+			log.Printf("Erro ao atualizar lote do pedido %d: %v", order.ID, err)
+		}
+	}
+
+	// Pedido pronto: oferecer ao melhor entregador online
+	if newStatus == models.StatusReady && h.dispatcher != nil {
+		if _, err := h.dispatcher.Dispatch(order.ID); err != nil {
+			log.Printf("Erro no despacho automático do pedido %d: %v", order.ID, err)
+		}
+	}
+
 	c.JSON(http.StatusOK, order)
 }
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"cupcake-delivery/internal/models"
+	"cupcake-delivery/internal/services"
+	"cupcake-delivery/internal/validators"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// Conexão do quadro ao vivo: prazo de cada escrita, ping periódico para detectar clientes que
+// sumiram sem fechar e limite das mensagens de filtro enviadas pelo cliente
+const (
+	boardWriteWait     = 10 * time.Second
+	boardPongWait      = 60 * time.Second
+	boardPingPeriod    = 50 * time.Second
+	boardMaxMessage    = 4096
+	boardSnapshotLimit = 500
+	boardPresenceCheck = 30 * time.Second
+)
+
+// Códigos de fechamento: conexão que não acompanhou o ritmo dos eventos (o cliente deve reconectar)
+// e entregador que ficou offline ou saiu do turno
+const (
+	boardCloseLagged  = websocket.CloseTryAgainLater
+	boardCloseOffline = websocket.ClosePolicyViolation
+)
+
+var boardUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// A autenticação é pelo token, não por cookie: outra origem não consegue agir em nome do usuário
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+type OrderBoardHandler struct {
+	store       services.OrderBoardStore
+	board       *services.OrderBoard
+	permissions *services.PermissionService
+	couriers    services.CourierPresence
+
+	presenceCheck time.Duration // Intervalo para conferir se o entregador continua online
+}
+
+// boardAccess o que o papel acompanha no quadro
+type boardAccess struct {
+	statuses  []models.OrderStatus // nil para todos
+	area      bool                 // Se recebe a região da entrega
+	courierID uint                 // Entregador cuja presença é conferida durante a conexão; 0 para os demais papéis
+}
+
+// OrderBoardFilter mensagem do cliente que troca os status acompanhados; vazio volta a todos os permitidos
+type OrderBoardFilter struct {
+	Statuses []models.OrderStatus `json:"statuses"`
+}
+
+// OrderBoardSnapshot foto dos pedidos em andamento, enviada ao conectar e a cada troca de filtro
+type OrderBoardSnapshot struct {
+	Type     string                `json:"type"`
+	Statuses []models.OrderStatus  `json:"statuses"`
+	Orders   []services.BoardOrder `json:"orders"`
+}
+
+func NewOrderBoardHandler(store services.OrderBoardStore, board *services.OrderBoard, permissions *services.PermissionService, couriers services.CourierPresence) *OrderBoardHandler {
+	return &OrderBoardHandler{
+		store:         store,
+		board:         board,
+		permissions:   permissions,
+		couriers:      couriers,
+		presenceCheck: boardPresenceCheck,
+	}
+}
+
+// publishOrderEvent avisa o quadro ao vivo do pedido criado ou com status alterado, depois do commit
+func publishOrderEvent(board *services.OrderBoard, eventType services.OrderEventType, order *models.Order) {
+	if board == nil {
+		return
+	}
+	board.PublishOrder(eventType, order)
+}
+
+// Connect abre o quadro ao vivo por WebSocket. Cada papel vê os status da sua listagem de pedidos:
+// cozinha pendentes e em preparo, entregadores online os prontos, admin todos. O filtro inicial pode
+// vir em status (separados por vírgula) e ser trocado enviando {"statuses": [...]}. Os pedidos vão
+// sem dados do cliente; a região da entrega só para entregadores e admin. A conexão do entregador
+// é fechada quando ele fica offline ou o turno termina.
+func (h *OrderBoardHandler) Connect(c *gin.Context) {
+	access, ok := h.access(c)
+	if !ok {
+		return
+	}
+
+	var initial []models.OrderStatus
+	if raw := c.Query("status"); raw != "" {
+		for _, status := range strings.Split(raw, ",") {
+			initial = append(initial, models.OrderStatus(strings.TrimSpace(status)))
+		}
+		if err := validateBoardStatuses(initial); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	conn, err := boardUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// O upgrader já respondeu ao cliente
+		log.Printf("Erro ao abrir quadro de pedidos: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	sub := h.board.Subscribe(access.statuses)
+	defer h.board.Unsubscribe(sub)
+	h.board.SetFilter(sub, initial)
+
+	filters := make(chan OrderBoardFilter)
+	done := make(chan struct{})
+	stop := make(chan struct{})
+	defer close(stop)
+	go readBoardFilters(conn, filters, done, stop)
+
+	if err := h.sendSnapshot(conn, sub, access); err != nil {
+		return
+	}
+
+	ping := time.NewTicker(boardPingPeriod)
+	defer ping.Stop()
+
+	// Só entregadores têm a presença conferida; para os demais o canal nunca dispara
+	var presence <-chan time.Time
+	if access.courierID != 0 {
+		ticker := time.NewTicker(h.presenceCheck)
+		defer ticker.Stop()
+		presence = ticker.C
+	}
+
+	for {
+		select {
+		case <-done:
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				if sub.Lagged() {
+					closeBoard(conn, boardCloseLagged, "Conexão lenta; reconecte para receber o quadro atualizado")
+				}
+				return
+			}
+			if !access.area {
+				event.Order = event.Order.WithoutArea()
+			}
+			if err := writeBoardJSON(conn, event); err != nil {
+				return
+			}
+		case filter := <-filters:
+			if err := validateBoardStatuses(filter.Statuses); err != nil {
+				if err := writeBoardJSON(conn, gin.H{"type": "error", "error": err.Error()}); err != nil {
+					return
+				}
+				continue
+			}
+			h.board.SetFilter(sub, filter.Statuses)
+			if err := h.sendSnapshot(conn, sub, access); err != nil {
+				return
+			}
+		case <-presence:
+			online, err := h.couriers.IsOnline(access.courierID)
+			if err != nil {
+				// Falha pontual na consulta: confere de novo no próximo intervalo
+				log.Printf("Erro ao conferir presença do entregador %d no quadro: %v", access.courierID, err)
+				continue
+			}
+			if !online {
+				closeBoard(conn, boardCloseOffline, "Entregador offline ou fora do turno")
+				return
+			}
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(boardWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// access o que o papel acompanha no quadro; responde com erro e retorna false se o usuário não pode
+// abrir o quadro
+func (h *OrderBoardHandler) access(c *gin.Context) (boardAccess, bool) {
+	role := models.UserType(c.GetString("type"))
+
+	switch {
+	case h.permissions.HasPermission(role, models.PermOrdersReadAny):
+		return boardAccess{area: true}, true
+	case h.permissions.HasPermission(role, models.PermOrdersReadKitchen):
+		return boardAccess{statuses: []models.OrderStatus{models.StatusPending, models.StatusPreparing}}, true
+	case h.permissions.HasPermission(role, models.PermOrdersReadAssigned):
+		// Como na fila de retirada, só quem está online e em turno vê os pedidos prontos
+		courierID := c.GetUint("user_id")
+		online, err := h.couriers.IsOnline(courierID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar disponibilidade do entregador"})
+			return boardAccess{}, false
+		}
+		if !online {
+			c.JSON(http.StatusConflict, gin.H{"error": "Fique online durante um turno para acompanhar os pedidos prontos"})
+			return boardAccess{}, false
+		}
+		return boardAccess{statuses: []models.OrderStatus{models.StatusReady}, area: true, courierID: courierID}, true
+	default:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Acesso não autorizado"})
+		return boardAccess{}, false
+	}
+}
+
+// sendSnapshot envia os pedidos em andamento que passam pelos filtros da conexão
+func (h *OrderBoardHandler) sendSnapshot(conn *websocket.Conn, sub *services.OrderBoardSubscription, access boardAccess) error {
+	statuses := h.board.ActiveStatuses(sub)
+	orders := []services.BoardOrder{}
+	if len(statuses) > 0 {
+		found, err := h.store.ActiveOrders(statuses, boardSnapshotLimit)
+		if err != nil {
+			log.Printf("Erro ao montar quadro de pedidos: %v", err)
+			closeBoard(conn, websocket.CloseInternalServerErr, "Erro ao buscar pedidos")
+			return err
+		}
+		orders = append(orders, found...)
+	}
+
+	ids := make([]uint, len(orders))
+	for i, order := range orders {
+		ids[i] = order.ID
+		if !access.area {
+			orders[i] = order.WithoutArea()
+		}
+	}
+	h.board.Show(sub, ids)
+
+	return writeBoardJSON(conn, OrderBoardSnapshot{Type: "snapshot", Statuses: statuses, Orders: orders})
+}
+
+// readBoardFilters lê as trocas de filtro do cliente e mantém o prazo de leitura renovado pelos pongs;
+// fecha done quando a conexão termina e para quando stop é fechado
+func readBoardFilters(conn *websocket.Conn, filters chan<- OrderBoardFilter, done, stop chan struct{}) {
+	defer close(done)
+
+	conn.SetReadLimit(boardMaxMessage)
+	conn.SetReadDeadline(time.Now().Add(boardPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(boardPongWait))
+	})
+
+	for {
+		var filter OrderBoardFilter
+		if err := conn.ReadJSON(&filter); err != nil {
+			return
+		}
+		select {
+		case filters <- filter:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func validateBoardStatuses(statuses []models.OrderStatus) error {
+	for _, status := range statuses {
+		if err := validators.ValidateOrderStatus(string(status)); err != nil {
+			return errors.New(err.Message)
+		}
+	}
+	return nil
+}
+
+func writeBoardJSON(conn *websocket.Conn, message interface{}) error {
+	conn.SetWriteDeadline(time.Now().Add(boardWriteWait))
+	return conn.WriteJSON(message)
+}
+
+func closeBoard(conn *websocket.Conn, code int, reason string) {
+	message := websocket.FormatCloseMessage(code, reason)
+	conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(boardWriteWait))
+}
@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cupcake-delivery/internal/middleware"
+	"cupcake-delivery/internal/models"
+	"cupcake-delivery/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
+)
+
+// memoryOrderBoardStore pedidos do quadro em memória; com gate, a foto espera até o canal fechar
+type memoryOrderBoardStore struct {
+	mu      sync.Mutex
+	orders  []services.BoardOrder
+	gate    chan struct{}
+	entered chan struct{}
+}
+
+func (s *memoryOrderBoardStore) ActiveOrders(statuses []models.OrderStatus, limit int) ([]services.BoardOrder, error) {
+	if s.entered != nil {
+		s.entered <- struct{}{}
+	}
+	if s.gate != nil {
+		<-s.gate
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := []services.BoardOrder{}
+	for _, order := range s.orders {
+		for _, status := range statuses {
+			if order.Status == status && len(found) < limit {
+				found = append(found, order)
+			}
+		}
+	}
+	return found, nil
+}
+
+func (s *memoryOrderBoardStore) OrderItems(orderID uint) ([]services.BoardOrderItem, error) {
+	return nil, errors.New("not used")
+}
+
+// switchPresence entregador online até ser desligado pelo teste
+type switchPresence struct{ online atomic.Bool }
+
+func (p *switchPresence) IsOnline(courierID uint) (bool, error) {
+	return p.online.Load(), nil
+}
+
+// boardMessage campos da foto, dos eventos e das mensagens de erro do quadro
+type boardMessage struct {
+	Type     string                `json:"type"`
+	Statuses []models.OrderStatus  `json:"statuses"`
+	Orders   []services.BoardOrder `json:"orders"`
+	Order    services.BoardOrder   `json:"order"`
+	Error    string                `json:"error"`
+}
+
+type boardTestServer struct {
+	url     string
+	handler *OrderBoardHandler
+	tokens  *services.TokenService
+}
+
+func newBoardTestServer(t *testing.T, store services.OrderBoardStore, board *services.OrderBoard, presence services.CourierPresence) *boardTestServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	key, err := services.GenerateEd25519SigningKey("test")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tokens, err := services.NewTokenService(key, nil, "cupcake-test", "cupcake-test")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	handler := NewOrderBoardHandler(store, board, services.NewPermissionService(nil), presence)
+	r := gin.New()
+	r.Use(middleware.StripQueryToken())
+	r.GET("/orders/board", middleware.TokenFromQuery(), middleware.AuthMiddleware(tokens), handler.Connect)
+
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return &boardTestServer{url: "ws" + strings.TrimPrefix(server.URL, "http") + "/orders/board", handler: handler, tokens: tokens}
+}
+
+// dial abre o quadro com o token do papel; role vazio conecta sem token
+func (s *boardTestServer) dial(t *testing.T, userID uint, role models.UserType, query string) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+
+	header := http.Header{}
+	if role != "" {
+		token, err := s.tokens.Sign(jwt.MapClaims{"user_id": userID, "type": string(role)}, time.Hour)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		header.Set("Authorization", "Bearer "+token)
+	}
+	conn, resp, err := websocket.DefaultDialer.Dial(s.url+query, header)
+	if conn != nil {
+		t.Cleanup(func() { conn.Close() })
+	}
+	return conn, resp, err
+}
+
+func readBoardMessage(t *testing.T, conn *websocket.Conn) boardMessage {
+	t.Helper()
+
+	var message boardMessage
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := conn.ReadJSON(&message); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return message
+}
+
+// readBoardClose lê até a conexão ser fechada e retorna o código recebido
+func readBoardClose(t *testing.T, conn *websocket.Conn) int {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			var closeErr *websocket.CloseError
+			if !errors.As(err, &closeErr) {
+				t.Fatalf("Expected a close message, got %v", err)
+			}
+			return closeErr.Code
+		}
+	}
+}
+
+func boardOrderIDs(orders []services.BoardOrder) []uint {
+	ids := []uint{}
+	for _, order := range orders {
+		ids = append(ids, order.ID)
+	}
+	return ids
+}
+
+func testBoardOrders() []services.BoardOrder {
+	area := &services.BoardOrderArea{Neighborhood: "Centro", City: "Curitiba"}
+	return []services.BoardOrder{
+		{ID: 1, Status: models.StatusPending, Items: []services.BoardOrderItem{{ProductID: 3, Name: "Red Velvet", Quantity: 2}}, Area: area},
+		{ID: 2, Status: models.StatusPreparing, Area: area},
+		{ID: 3, Status: models.StatusReady, Area: area},
+	}
+}
+
+func TestOrderBoardConnectRejectsUnauthorized(t *testing.T) {
+	presence := &switchPresence{}
+	server := newBoardTestServer(t, &memoryOrderBoardStore{}, services.NewOrderBoard(8), presence)
+
+	testCases := []struct {
+		name     string
+		role     models.UserType
+		query    string
+		expected int
+	}{
+		{"Without token", "", "", http.StatusUnauthorized},
+		{"Customer", models.CustomerType, "", http.StatusForbidden},
+		{"Offline courier", models.DeliveryType, "", http.StatusConflict},
+		{"Invalid status filter", models.KitchenType, "?status=pending,unknown", http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, resp, err := server.dial(t, 10, tc.role, tc.query)
+			if !errors.Is(err, websocket.ErrBadHandshake) {
+				t.Fatalf("Expected the handshake to be refused, got %v", err)
+			}
+			if resp.StatusCode != tc.expected {
+				t.Errorf("Expected status %d, got %d", tc.expected, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestOrderBoardConnectSendsSnapshotAndFilters(t *testing.T) {
+	board := services.NewOrderBoard(8)
+	server := newBoardTestServer(t, &memoryOrderBoardStore{orders: testBoardOrders()}, board, &switchPresence{})
+
+	conn, _, err := server.dial(t, 10, models.KitchenType, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	snapshot := readBoardMessage(t, conn)
+	if snapshot.Type != "snapshot" || !reflect.DeepEqual(boardOrderIDs(snapshot.Orders), []uint{1, 2}) {
+		t.Fatalf("Expected the kitchen snapshot with orders 1 and 2, got %+v", snapshot)
+	}
+	if snapshot.Orders[0].Area != nil || len(snapshot.Orders[0].Items) != 1 || snapshot.Orders[0].Items[0].Name != "Red Velvet" {
+		t.Errorf("Expected items without the delivery area, got %+v", snapshot.Orders[0])
+	}
+
+	if err := conn.WriteJSON(OrderBoardFilter{Statuses: []models.OrderStatus{"unknown"}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if message := readBoardMessage(t, conn); message.Type != "error" || message.Error == "" {
+		t.Errorf("Expected an error message for an invalid filter, got %+v", message)
+	}
+
+	if err := conn.WriteJSON(OrderBoardFilter{Statuses: []models.OrderStatus{models.StatusPreparing}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	filtered := readBoardMessage(t, conn)
+	if filtered.Type != "snapshot" || !reflect.DeepEqual(boardOrderIDs(filtered.Orders), []uint{2}) {
+		t.Fatalf("Expected a new snapshot with order 2, got %+v", filtered)
+	}
+
+	// Pedido pendente fica fora do filtro; o que entra em preparo chega sem a região da entrega
+	board.Publish(services.OrderEvent{Type: services.OrderEventCreated, Order: services.BoardOrder{ID: 4, Status: models.StatusPending}})
+	board.Publish(services.OrderEvent{Type: services.OrderEventStatusChanged, Order: services.BoardOrder{
+		ID: 1, Status: models.StatusPreparing, Area: &services.BoardOrderArea{Neighborhood: "Centro", City: "Curitiba"},
+	}})
+	event := readBoardMessage(t, conn)
+	if event.Type != string(services.OrderEventStatusChanged) || event.Order.ID != 1 || event.Order.Area != nil {
+		t.Errorf("Expected order 1 moving to preparing without area, got %+v", event)
+	}
+}
+
+func TestOrderBoardConnectSendsAreaToCouriers(t *testing.T) {
+	presence := &switchPresence{}
+	presence.online.Store(true)
+	server := newBoardTestServer(t, &memoryOrderBoardStore{orders: testBoardOrders()}, services.NewOrderBoard(8), presence)
+
+	conn, _, err := server.dial(t, 10, models.DeliveryType, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	snapshot := readBoardMessage(t, conn)
+	if !reflect.DeepEqual(boardOrderIDs(snapshot.Orders), []uint{3}) {
+		t.Fatalf("Expected only the ready order, got %+v", snapshot)
+	}
+	if area := snapshot.Orders[0].Area; area == nil || area.Neighborhood != "Centro" {
+		t.Errorf("Expected the delivery area for couriers, got %+v", area)
+	}
+}
+
+func TestOrderBoardConnectClosesLaggingConnection(t *testing.T) {
+	board := services.NewOrderBoard(1)
+	store := &memoryOrderBoardStore{gate: make(chan struct{}), entered: make(chan struct{}, 1)}
+	server := newBoardTestServer(t, store, board, &switchPresence{})
+
+	conn, _, err := server.dial(t, 1, models.AdminType, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Enquanto a foto está sendo montada a conexão já está assinada e não consome os eventos
+	<-store.entered
+	for id := uint(1); id <= 3; id++ {
+		board.Publish(services.OrderEvent{Type: services.OrderEventCreated, Order: services.BoardOrder{ID: id, Status: models.StatusPending}})
+	}
+	close(store.gate)
+
+	if code := readBoardClose(t, conn); code != websocket.CloseTryAgainLater {
+		t.Errorf("Expected close code %d, got %d", websocket.CloseTryAgainLater, code)
+	}
+}
+
+func TestOrderBoardConnectClosesWhenCourierGoesOffline(t *testing.T) {
+	presence := &switchPresence{}
+	presence.online.Store(true)
+	server := newBoardTestServer(t, &memoryOrderBoardStore{}, services.NewOrderBoard(8), presence)
+	server.handler.presenceCheck = 10 * time.Millisecond
+
+	conn, _, err := server.dial(t, 10, models.DeliveryType, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	readBoardMessage(t, conn)
+
+	presence.online.Store(false)
+	if code := readBoardClose(t, conn); code != websocket.ClosePolicyViolation {
+		t.Errorf("Expected close code %d, got %d", websocket.ClosePolicyViolation, code)
+	}
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"testing"
+
+	"cupcake-delivery/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderContact(t *testing.T) {
+	profile := models.User{Phone: "+5511987654321", Document: "52998224725"}
+
+	testCases := []struct {
+		name     string
+		customer models.User
+		document string
+		phone    string
+		expected [2]string // Documento e telefone do pedido
+		invalid  []string
+	}{
+		{"Profile data", profile, "", "", [2]string{"52998224725", "+5511987654321"}, []string{}},
+		{"Informed data wins", profile, "11.222.333/0001-81", "(21) 3456-7890", [2]string{"11222333000181", "+552134567890"}, []string{}},
+		{"Document is optional", models.User{Phone: "+5511987654321"}, "", "", [2]string{"", "+5511987654321"}, []string{}},
+		{"Phone is required", models.User{}, "", " ", [2]string{"", ""}, []string{"phone"}},
+		{"Invalid informed document", profile, "111.111.111-11", "", [2]string{}, []string{"document"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			document, phone, errs := orderContact(&tc.customer, tc.document, tc.phone)
+			assert.Equal(t, tc.invalid, validationFields(errs))
+			if len(tc.invalid) == 0 {
+				assert.Equal(t, tc.expected, [2]string{document, phone})
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"time"
+
+	"cupcake-delivery/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultReportPeriod período dos relatórios e extratos quando from não é informado
+const defaultReportPeriod = 30 * 24 * time.Hour
+
+// reportPeriod lê from e to (RFC 3339) da query. Sem to, o período termina agora; sem from, começa
+// period antes de to. Responde com erro de validação e retorna false se as datas forem inválidas.
+func reportPeriod(c *gin.Context, period time.Duration) (time.Time, time.Time, bool) {
+	to := time.Now()
+	if value := c.Query("to"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			utils.RespondWithValidationError(c, []utils.ValidationError{{Field: "to", Message: "Data deve estar no formato RFC 3339"}})
+			return time.Time{}, time.Time{}, false
+		}
+		to = parsed
+	}
+	from := to.Add(-period)
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			utils.RespondWithValidationError(c, []utils.ValidationError{{Field: "from", Message: "Data deve estar no formato RFC 3339"}})
+			return time.Time{}, time.Time{}, false
+		}
+		from = parsed
+	}
+	if !from.Before(to) {
+		utils.RespondWithValidationError(c, []utils.ValidationError{{Field: "from", Message: "Data inicial deve ser anterior à final"}})
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"cupcake-delivery/internal/models"
+	"cupcake-delivery/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PermissionHandler struct {
+	permissions *services.PermissionService
+}
+
+type UpdateRolePermissionsRequest struct {
+	Permissions []models.Permission `json:"permissions" binding:"required"`
+}
+
+func NewPermissionHandler(permissions *services.PermissionService) *PermissionHandler {
+	return &PermissionHandler{permissions: permissions}
+}
+
+// List retorna o mapeamento atual papel → permissões
+func (h *PermissionHandler) List(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"roles": h.permissions.RolePermissions()})
+}
+
+// UpdateRole substitui as permissões de um papel; lista vazia deixa o papel sem permissões
+func (h *PermissionHandler) UpdateRole(c *gin.Context) {
+	role := models.UserType(c.Param("role"))
+
+	var req UpdateRolePermissionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.permissions.SetRolePermissions(models.UserType(c.GetString("type")), role, req.Permissions); err != nil {
+		respondRoleError(c, err)
+		return
+	}
+
+	h.respondRole(c, role)
+}
+
+// ResetRole devolve o papel ao mapeamento padrão de permissões
+func (h *PermissionHandler) ResetRole(c *gin.Context) {
+	role := models.UserType(c.Param("role"))
+
+	if err := h.permissions.ResetRolePermissions(models.UserType(c.GetString("type")), role); err != nil {
+		respondRoleError(c, err)
+		return
+	}
+
+	h.respondRole(c, role)
+}
+
+func (h *PermissionHandler) respondRole(c *gin.Context, role models.UserType) {
+	c.JSON(http.StatusOK, gin.H{
+		"role":        role,
+		"permissions": h.permissions.RolePermissions()[role],
+	})
+}
+
+func respondRoleError(c *gin.Context, err error) {
+	var unknown *services.UnknownPermissionError
+	switch {
+	case errors.Is(err, services.ErrUnknownRole):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Papel não encontrado"})
+	case errors.As(err, &unknown):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Permissão desconhecida: " + string(unknown.Permission)})
+	case errors.Is(err, services.ErrRolesManageLockout):
+		c.JSON(http.StatusConflict, gin.H{"error": "Não é possível remover a gestão de papéis do seu próprio papel"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar permissões"})
+	}
+}
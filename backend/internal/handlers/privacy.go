@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"cupcake-delivery/internal/models"
+	"cupcake-delivery/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// PrivacyHandler atende os direitos do titular previstos na LGPD (acesso e eliminação dos dados)
+type PrivacyHandler struct {
+	privacy *services.PrivacyService
+	audit   *services.AuditService
+}
+
+func NewPrivacyHandler(privacy *services.PrivacyService, audit *services.AuditService) *PrivacyHandler {
+	return &PrivacyHandler{
+		privacy: privacy,
+		audit:   audit,
+	}
+}
+
+// Export baixa os dados do usuário logado em JSON ou, com ?format=zip, em um arquivo ZIP
+func (h *PrivacyHandler) Export(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Formato deve ser 'json' ou 'zip'"})
+		return
+	}
+
+	export, err := h.privacy.Export(c.Request.Context(), userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao exportar dados"})
+		return
+	}
+
+	h.audit.Record(models.AuditLog{
+		ActorID:   &userID,
+		SubjectID: userID,
+		Action:    models.AuditActionDataExport,
+		IP:        c.ClientIP(),
+		Details:   "Formato " + format,
+	})
+
+	filename := fmt.Sprintf("meus-dados-%s", export.ExportedAt.Format("20060102"))
+	if format == "json" {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, filename))
+		c.JSON(http.StatusOK, export)
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, filename))
+	c.Status(http.StatusOK)
+	if err := export.WriteZip(c.Writer); err != nil {
+		// Os cabeçalhos já foram enviados; resta interromper o download
+		c.Error(err)
+		c.Abort()
+	}
+}
+
+// DeletionStatus informa se há exclusão da conta agendada
+func (h *PrivacyHandler) DeletionStatus(c *gin.Context) {
+	pending, err := h.privacy.PendingDeletion(c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao consultar exclusão"})
+		return
+	}
+	if pending == nil {
+		c.JSON(http.StatusOK, gin.H{"scheduled": false})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"scheduled":    true,
+		"requested_at": pending.RequestedAt,
+		"purge_after":  pending.PurgeAfter,
+	})
+}
+
+// RequestDeletion agenda a exclusão da conta; até o fim da carência a conta continua ativa
+// e a exclusão pode ser cancelada
+func (h *PrivacyHandler) RequestDeletion(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	deletion, err := h.privacy.RequestDeletion(userID)
+	if err != nil {
+		if errors.Is(err, services.ErrDeletionAlreadyRequested) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":       "Exclusão da conta já solicitada",
+				"purge_after": deletion.PurgeAfter,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao solicitar exclusão"})
+		return
+	}
+
+	h.audit.Record(models.AuditLog{
+		ActorID:   &userID,
+		SubjectID: userID,
+		Action:    models.AuditActionDeletionRequested,
+		IP:        c.ClientIP(),
+		Details:   "Expurgo após " + deletion.PurgeAfter.Format(time.RFC3339),
+	})
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":     "Exclusão da conta agendada. Você pode cancelar até a data do expurgo.",
+		"purge_after": deletion.PurgeAfter,
+	})
+}
+
+// CancelDeletion cancela a exclusão agendada da conta
+func (h *PrivacyHandler) CancelDeletion(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	if err := h.privacy.CancelDeletion(userID); err != nil {
+		if errors.Is(err, services.ErrDeletionNotRequested) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Não há exclusão de conta pendente"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao cancelar exclusão"})
+		return
+	}
+
+	h.audit.Record(models.AuditLog{
+		ActorID:   &userID,
+		SubjectID: userID,
+		Action:    models.AuditActionDeletionCancelled,
+		IP:        c.ClientIP(),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Exclusão da conta cancelada"})
+}
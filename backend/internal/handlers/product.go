@@ -4,6 +4,8 @@ import (
 	"net/http"
 
 	"cupcake-delivery/internal/models"
+	"cupcake-delivery/internal/utils"
+	"cupcake-delivery/internal/validators"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -23,6 +25,9 @@ func (h *ProductHandler) Create(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !validateProductDelivery(c, &product) {
+		return
+	}
 
 	if err := h.db.Create(&product).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao criar produto"})
@@ -67,6 +72,9 @@ func (h *ProductHandler) Update(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !validateProductDelivery(c, &product) {
+		return
+	}
 
 	if err := h.db.Save(&product).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar produto"})
@@ -86,3 +94,15 @@ func (h *ProductHandler) Delete(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Produto deletado com sucesso"})
 }
+
+// validateProductDelivery valida as exigências de entrega do produto; sem caixas informadas, ocupa uma
+func validateProductDelivery(c *gin.Context, product *models.Product) bool {
+	if product.Boxes == 0 {
+		product.Boxes = 1
+	}
+	if validationErrors := validators.ValidateProductDelivery(product.Boxes, product.RequiredVehicle); len(validationErrors) > 0 {
+		utils.RespondWithValidationError(c, validationErrors)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"cupcake-delivery/internal/models"
+	"cupcake-delivery/internal/services"
+	"cupcake-delivery/internal/utils"
+	"cupcake-delivery/internal/validators"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+type ProfileHandler struct {
+	db          *gorm.DB
+	cepResolver services.CEPResolver
+	throttle    *services.LoginThrottle
+}
+
+type UpdateProfileRequest struct {
+	Name            *string `json:"name"`
+	Phone           *string `json:"phone"`
+	Document        *string `json:"document"`
+	CurrentPassword string  `json:"current_password"`
+	NewPassword     string  `json:"new_password"`
+}
+
+type AddressRequest struct {
+	PostalAddressRequest
+	Label     string   `json:"label"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	IsDefault bool     `json:"is_default"`
+}
+
+func NewProfileHandler(db *gorm.DB, cepResolver services.CEPResolver, throttle *services.LoginThrottle) *ProfileHandler {
+	return &ProfileHandler{
+		db:          db,
+		cepResolver: cepResolver,
+		throttle:    throttle,
+	}
+}
+
+// GetMe retorna o perfil do usuário logado
+func (h *ProfileHandler) GetMe(c *gin.Context) {
+	var user models.User
+	if err := h.db.First(&user, c.GetUint("user_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateMe atualiza nome, telefone e, com a senha atual, a senha do usuário logado
+func (h *ProfileHandler) UpdateMe(c *gin.Context) {
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, utils.ErrorTypeValidation, "Dados JSON inválidos")
+		return
+	}
+
+	phone, validationErrors := validateProfileUpdate(&req)
+	if len(validationErrors) > 0 {
+		utils.RespondWithValidationError(c, validationErrors)
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, c.GetUint("user_id")).Error; err != nil {
+		utils.RespondWithError(c, http.StatusNotFound, utils.ErrorTypeNotFound, "Usuário não encontrado")
+		return
+	}
+
+	if req.NewPassword != "" {
+		// A senha atual conta para o mesmo bloqueio do login, para que um token roubado não sirva para adivinhá-la
+		ip := c.ClientIP()
+		if !checkThrottle(c, h.throttle, user.Email, ip) {
+			return
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+			recordLoginFailure(h.throttle, user.Email, ip, &user)
+			utils.RespondWithError(c, http.StatusUnauthorized, utils.ErrorTypeAuthentication, "Senha atual incorreta")
+			return
+		}
+		if err := h.throttle.RecordSuccess(user.Email); err != nil {
+			log.Printf("Erro ao limpar tentativas de login de %s: %v", user.Email, err)
+		}
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusInternalServerError, utils.ErrorTypeInternal, utils.MessageInternalError)
+			return
+		}
+		user.Password = string(hashedPassword)
+	}
+
+	applyProfileUpdate(&user, &req, phone)
+
+	if err := h.db.Save(&user).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, utils.ErrorTypeInternal, "Erro ao atualizar perfil")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Perfil atualizado com sucesso",
+		"user":    user,
+	})
+}
+
+// validateProfileUpdate valida os campos informados e retorna o telefone normalizado
+func validateProfileUpdate(req *UpdateProfileRequest) (string, []utils.ValidationError) {
+	var validationErrors []utils.ValidationError
+
+	if req.Name != nil {
+		if err := validators.ValidateName(*req.Name); err != nil {
+			validationErrors = append(validationErrors, *err)
+		}
+	}
+
+	// Telefone e documento vazios removem o dado do perfil
+	var phone string
+	if req.Phone != nil && strings.TrimSpace(*req.Phone) != "" {
+		normalized, err := validators.NormalizePhone(*req.Phone)
+		if err != nil {
+			validationErrors = append(validationErrors, *err)
+		}
+		phone = normalized
+	}
+
+	if req.Document != nil && strings.TrimSpace(*req.Document) != "" {
+		if err := validators.ValidateDocument(*req.Document); err != nil {
+			validationErrors = append(validationErrors, *err)
+		}
+	}
+
+	if req.NewPassword != "" {
+		if err := validators.ValidatePassword(req.NewPassword); err != nil {
+			err.Field = "new_password"
+			validationErrors = append(validationErrors, *err)
+		}
+		if req.CurrentPassword == "" {
+			validationErrors = append(validationErrors, utils.ValidationError{
+				Field:   "current_password",
+				Message: "Senha atual é obrigatória para alterar a senha",
+			})
+		}
+	}
+
+	return phone, validationErrors
+}
+
+// applyProfileUpdate copia para o usuário os campos informados; os omitidos ficam como estão
+func applyProfileUpdate(user *models.User, req *UpdateProfileRequest, phone string) {
+	if req.Name != nil {
+		user.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Phone != nil {
+		user.Phone = phone
+	}
+	if req.Document != nil {
+		user.Document = validators.NormalizeDocument(*req.Document)
+	}
+}
+
+// ListAddresses lista os endereços salvos, com o padrão primeiro
+func (h *ProfileHandler) ListAddresses(c *gin.Context) {
+	var addresses []models.UserAddress
+	if err := h.db.Where("user_id = ?", c.GetUint("user_id")).Order("is_default DESC, created_at").Find(&addresses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar endereços"})
+		return
+	}
+
+	c.JSON(http.StatusOK, addresses)
+}
+
+// CreateAddress salva um novo endereço; o primeiro endereço vira o padrão
+func (h *ProfileHandler) CreateAddress(c *gin.Context) {
+	var req AddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	postal, ok := preparePostalAddress(c, h.cepResolver, req.PostalAddressRequest)
+	if !ok {
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	address := models.UserAddress{UserID: userID}
+	applyAddressRequest(&address, &req, postal)
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.UserAddress{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			address.IsDefault = true
+		}
+		if address.IsDefault {
+			if err := clearDefaultAddress(tx, userID); err != nil {
+				return err
+			}
+		}
+		return tx.Create(&address).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao salvar endereço"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, address)
+}
+
+// UpdateAddress altera um endereço salvo do usuário logado
+func (h *ProfileHandler) UpdateAddress(c *gin.Context) {
+	var req AddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	var address models.UserAddress
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&address).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Endereço não encontrado"})
+		return
+	}
+
+	postal, ok := preparePostalAddress(c, h.cepResolver, req.PostalAddressRequest)
+	if !ok {
+		return
+	}
+
+	wasDefault := address.IsDefault
+	applyAddressRequest(&address, &req, postal)
+	// O endereço padrão só deixa de ser padrão quando outro é escolhido
+	address.IsDefault = address.IsDefault || wasDefault
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if address.IsDefault && !wasDefault {
+			if err := clearDefaultAddress(tx, userID); err != nil {
+				return err
+			}
+		}
+		return tx.Save(&address).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar endereço"})
+		return
+	}
+
+	c.JSON(http.StatusOK, address)
+}
+
+// DeleteAddress remove um endereço; se era o padrão, o mais antigo restante assume
+func (h *ProfileHandler) DeleteAddress(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	var address models.UserAddress
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&address).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Endereço não encontrado"})
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&address).Error; err != nil {
+			return err
+		}
+		if !address.IsDefault {
+			return nil
+		}
+		var next models.UserAddress
+		if err := tx.Where("user_id = ?", userID).Order("created_at").First(&next).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		return tx.Model(&next).Update("is_default", true).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao remover endereço"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Endereço removido com sucesso"})
+}
+
+func applyAddressRequest(address *models.UserAddress, req *AddressRequest, postal models.PostalAddress) {
+	address.Label = strings.TrimSpace(req.Label)
+	address.PostalAddress = postal
+	address.Address = postal.String()
+	address.Latitude = req.Latitude
+	address.Longitude = req.Longitude
+	address.IsDefault = req.IsDefault
+}
+
+func clearDefaultAddress(tx *gorm.DB, userID uint) error {
+	return tx.Model(&models.UserAddress{}).
+		Where("user_id = ? AND is_default = ?", userID, true).
+		Update("is_default", false).Error
+}
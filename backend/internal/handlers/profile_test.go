@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cupcake-delivery/internal/models"
+	"cupcake-delivery/internal/services"
+	"cupcake-delivery/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func stringRef(s string) *string { return &s }
+
+func validationFields(errs []utils.ValidationError) []string {
+	fields := []string{}
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+	return fields
+}
+
+func TestValidateProfileUpdate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		req      UpdateProfileRequest
+		phone    string
+		expected []string
+	}{
+		{"Nothing to change", UpdateProfileRequest{}, "", []string{}},
+		{"Valid name, phone and document", UpdateProfileRequest{Name: stringRef("João Silva"), Phone: stringRef("(11) 98765-4321"), Document: stringRef("529.982.247-25")}, "+5511987654321", []string{}},
+		{"Empty phone and document clear the profile", UpdateProfileRequest{Phone: stringRef(" "), Document: stringRef("")}, "", []string{}},
+		{"Short name", UpdateProfileRequest{Name: stringRef("J")}, "", []string{"name"}},
+		{"Invalid phone", UpdateProfileRequest{Phone: stringRef("1234")}, "", []string{"phone"}},
+		{"Invalid CPF", UpdateProfileRequest{Document: stringRef("111.111.111-11")}, "", []string{"document"}},
+		{"New password without current", UpdateProfileRequest{NewPassword: "NovaSenha1"}, "", []string{"current_password"}},
+		{"Short new password", UpdateProfileRequest{NewPassword: "123", CurrentPassword: "senha-atual"}, "", []string{"new_password"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			phone, errs := validateProfileUpdate(&tc.req)
+			assert.Equal(t, tc.expected, validationFields(errs))
+			assert.Equal(t, tc.phone, phone)
+		})
+	}
+}
+
+func TestApplyProfileUpdate(t *testing.T) {
+	user := models.User{Name: "João", Phone: "+5511987654321", Document: "52998224725"}
+
+	// Campos omitidos ficam como estão
+	applyProfileUpdate(&user, &UpdateProfileRequest{Name: stringRef("  João Silva ")}, "")
+	assert.Equal(t, "João Silva", user.Name)
+	assert.Equal(t, "+5511987654321", user.Phone)
+	assert.Equal(t, "52998224725", user.Document)
+
+	applyProfileUpdate(&user, &UpdateProfileRequest{Phone: stringRef("(21) 3456-7890"), Document: stringRef("11.222.333/0001-81")}, "+552134567890")
+	assert.Equal(t, "+552134567890", user.Phone)
+	assert.Equal(t, "11222333000181", user.Document)
+
+	applyProfileUpdate(&user, &UpdateProfileRequest{Phone: stringRef(""), Document: stringRef("")}, "")
+	assert.Empty(t, user.Phone)
+	assert.Empty(t, user.Document)
+}
+
+func TestUpdateMeRejectsInvalidProfile(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// Sem banco: a validação responde antes de buscar o usuário
+	handler := NewProfileHandler(nil, nil, nil)
+	router := gin.New()
+	router.PUT("/me", handler.UpdateMe)
+
+	body, _ := json.Marshal(gin.H{"phone": "1234", "new_password": "NovaSenha1"})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/me", bytes.NewReader(body)))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var response utils.ValidationErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, []string{"phone", "current_password"}, validationFields(response.Validations))
+}
+
+type fakeCEPResolver struct {
+	address *models.PostalAddress
+	err     error
+}
+
+func (r fakeCEPResolver) Resolve(_ context.Context, _ string) (*models.PostalAddress, error) {
+	return r.address, r.err
+}
+
+func TestPreparePostalAddress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	found := &models.PostalAddress{Street: "Avenida Paulista", Neighborhood: "Bela Vista", City: "São Paulo", State: "SP"}
+
+	testCases := []struct {
+		name     string
+		resolver services.CEPResolver
+		req      PostalAddressRequest
+		expected string // Logradouro resultante; vazio quando o endereço é recusado
+		status   int
+	}{
+		{"Completed from CEP", fakeCEPResolver{address: found}, PostalAddressRequest{CEP: "01310-100", Number: "1000"}, "Avenida Paulista", http.StatusOK},
+		{"User fields win over CEP", fakeCEPResolver{address: found}, PostalAddressRequest{CEP: "01310100", Number: "1000", Street: "Alameda Santos"}, "Alameda Santos", http.StatusOK},
+		{"Unknown CEP", fakeCEPResolver{err: services.ErrCEPNotFound}, PostalAddressRequest{CEP: "99999999", Number: "1"}, "", http.StatusBadRequest},
+		{"Provider down with full address", fakeCEPResolver{err: errors.New("timeout")}, PostalAddressRequest{CEP: "01310100", Number: "1000", Street: "Avenida Paulista", Neighborhood: "Bela Vista", City: "São Paulo", State: "sp"}, "Avenida Paulista", http.StatusOK},
+		{"Provider down with missing fields", fakeCEPResolver{err: errors.New("timeout")}, PostalAddressRequest{CEP: "01310100", Number: "1000"}, "", http.StatusBadRequest},
+		{"Missing number", fakeCEPResolver{address: found}, PostalAddressRequest{CEP: "01310100"}, "", http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+
+			address, ok := preparePostalAddress(c, tc.resolver, tc.req)
+			if tc.expected == "" {
+				assert.False(t, ok)
+				assert.Equal(t, tc.status, w.Code)
+				return
+			}
+			assert.True(t, ok)
+			assert.Equal(t, tc.expected, address.Street)
+			assert.Equal(t, "SP", address.State)
+			assert.Equal(t, "01310100", address.CEP)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+
+	"cupcake-delivery/internal/models"
+	"cupcake-delivery/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SLAHandler struct {
+	monitor *services.SLAMonitor
+}
+
+func NewSLAHandler(monitor *services.SLAMonitor) *SLAHandler {
+	return &SLAHandler{monitor: monitor}
+}
+
+// AtRisk painel dos pedidos perto de estourar ou já fora do prazo do status, do mais atrasado para o menos.
+// Com breached=true lista apenas os que já passaram do prazo.
+func (h *SLAHandler) AtRisk(c *gin.Context) {
+	orders, err := h.monitor.AtRisk()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar pedidos em risco"})
+		return
+	}
+
+	if c.Query("breached") == "true" {
+		breached := make([]services.AtRiskOrder, 0, len(orders))
+		for _, order := range orders {
+			if order.Breached {
+				breached = append(breached, order)
+			}
+		}
+		orders = breached
+	}
+
+	limits := make(map[models.OrderStatus]int, len(h.monitor.Policy()))
+	for status, limit := range h.monitor.Policy() {
+		limits[status] = int(limit.Minutes())
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"orders":        orders,
+		"limit_minutes": limits,
+	})
+}
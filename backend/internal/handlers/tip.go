@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"cupcake-delivery/internal/services"
+	"cupcake-delivery/internal/utils"
+	"cupcake-delivery/internal/validators"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TipHandler struct {
+	tips                *services.TipService
+	notificationService *services.NotificationService
+}
+
+type TipRequest struct {
+	Amount float64 `json:"amount" binding:"required"`
+}
+
+func NewTipHandler(tips *services.TipService, notificationService *services.NotificationService) *TipHandler {
+	return &TipHandler{
+		tips:                tips,
+		notificationService: notificationService,
+	}
+}
+
+// AddTip gorjeta do cliente para o entregador depois da entrega, dentro do prazo configurado
+func (h *TipHandler) AddTip(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var req TipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validators.ValidateTip(req.Amount); err != nil {
+		err.Field = "amount"
+		utils.RespondWithValidationError(c, []utils.ValidationError{*err})
+		return
+	}
+
+	order, err := h.tips.AddTip(c.Request.Context(), uint(id), c.GetUint("user_id"), req.Amount)
+	switch {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pedido não encontrado"})
+		return
+	case errors.Is(err, services.ErrAlreadyTipped):
+		c.JSON(http.StatusConflict, gin.H{"error": "Pedido já tem gorjeta"})
+		return
+	case errors.Is(err, services.ErrTipWindowClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": "Gorjeta só pode ser dada após a entrega, dentro do prazo"})
+		return
+	case errors.Is(err, services.ErrTipNoCourier):
+		c.JSON(http.StatusConflict, gin.H{"error": "Pedido sem entregador para receber a gorjeta"})
+		return
+	case errors.Is(err, services.ErrPaymentDeclined):
+		c.JSON(http.StatusPaymentRequired, gin.H{"error": "Pagamento recusado"})
+		return
+	case errors.Is(err, services.ErrPaymentPending):
+		// Gorjeta gravada; o entregador só é avisado depois que a cobrança for confirmada
+		c.JSON(http.StatusAccepted, CustomerOrder{Order: *order, DeliveryPIN: order.DeliveryPIN})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao registrar gorjeta"})
+		return
+	}
+
+	if h.notificationService != nil {
+		if err := h.notificationService.NotifyTipReceived(order); err != nil {
+			log.Printf("Erro ao notificar gorjeta do pedido %d: %v", order.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, CustomerOrder{Order: *order, DeliveryPIN: order.DeliveryPIN})
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"cupcake-delivery/internal/models"
+	"cupcake-delivery/internal/services"
+	"cupcake-delivery/internal/utils"
+	"cupcake-delivery/internal/validators"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TrackingHandler struct {
+	db          *gorm.DB
+	tracking    *services.TrackingService
+	permissions *services.PermissionService
+}
+
+type CourierLocationRequest struct {
+	Latitude  *float64 `json:"latitude" binding:"required"`
+	Longitude *float64 `json:"longitude" binding:"required"`
+	Accuracy  *float64 `json:"accuracy"`
+}
+
+func NewTrackingHandler(db *gorm.DB, tracking *services.TrackingService, permissions *services.PermissionService) *TrackingHandler {
+	return &TrackingHandler{
+		db:          db,
+		tracking:    tracking,
+		permissions: permissions,
+	}
+}
+
+// RecordLocation recebe a posição GPS do entregador logado durante uma entrega
+func (h *TrackingHandler) RecordLocation(c *gin.Context) {
+	var req CourierLocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validators.ValidateCoordinates(*req.Latitude, *req.Longitude); err != nil {
+		utils.RespondWithValidationError(c, []utils.ValidationError{*err})
+		return
+	}
+
+	recorded, err := h.tracking.RecordPing(c.GetUint("user_id"), *req.Latitude, *req.Longitude, req.Accuracy)
+	if err != nil {
+		if errors.Is(err, services.ErrNoActiveDelivery) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Localização só é registrada durante uma entrega"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao registrar localização"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"recorded": recorded > 0})
+}
+
+// OrderTracking mostra ao cliente do pedido a última posição do entregador e a previsão de chegada
+func (h *TrackingHandler) OrderTracking(c *gin.Context) {
+	var order models.Order
+	if err := h.db.First(&order, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pedido não encontrado"})
+		return
+	}
+
+	role := models.UserType(c.GetString("type"))
+	isCustomer := order.CustomerID == c.GetUint("user_id")
+	if !isCustomer && !h.permissions.HasPermission(role, models.PermOrdersReadAny) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pedido não encontrado"})
+		return
+	}
+
+	tracking, err := h.tracking.Tracking(&order)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar rastreamento"})
+		return
+	}
+	if isCustomer && order.Status != models.StatusDelivered {
+		tracking.DeliveryPIN = order.DeliveryPIN
+	}
+
+	c.JSON(http.StatusOK, tracking)
+}
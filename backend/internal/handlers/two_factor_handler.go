@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"cupcake-delivery/internal/models"
+	"cupcake-delivery/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TwoFactorHandler struct {
+	db        *gorm.DB
+	twoFactor *services.TwoFactorService
+	throttle  *services.LoginThrottle
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorPolicyRequest struct {
+	Required bool `json:"required"`
+}
+
+func NewTwoFactorHandler(db *gorm.DB, twoFactor *services.TwoFactorService, throttle *services.LoginThrottle) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		db:        db,
+		twoFactor: twoFactor,
+		throttle:  throttle,
+	}
+}
+
+// Status informa se o 2FA está ativo e se é obrigatório para o usuário logado
+func (h *TwoFactorHandler) Status(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	enabled, err := h.twoFactor.IsEnabled(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar 2FA"})
+		return
+	}
+	required, err := h.twoFactor.IsRequired(models.UserType(c.GetString("type")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar 2FA"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"enabled": enabled, "required": required})
+}
+
+// Enroll gera um novo segredo TOTP pendente de confirmação
+func (h *TwoFactorHandler) Enroll(c *gin.Context) {
+	var user models.User
+	if err := h.db.First(&user, c.GetUint("user_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+		return
+	}
+
+	secret, uri, err := h.twoFactor.BeginEnrollment(&user)
+	if err != nil {
+		if errors.Is(err, services.ErrTwoFactorAlreadyEnabled) {
+			c.JSON(http.StatusConflict, gin.H{"error": "2FA já está ativo"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao iniciar cadastro de 2FA"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_uri": uri,
+	})
+}
+
+// Confirm ativa o 2FA com o primeiro código e devolve os códigos de recuperação (exibidos uma única vez)
+func (h *TwoFactorHandler) Confirm(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.twoFactor.ConfirmEnrollment(c.GetUint("user_id"), req.Code)
+	if err != nil {
+		h.respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "2FA ativado com sucesso",
+		"recovery_codes": codes,
+	})
+}
+
+// Disable desativa o 2FA, exceto quando ele é obrigatório para o tipo do usuário
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	required, err := h.twoFactor.IsRequired(models.UserType(c.GetString("type")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar 2FA"})
+		return
+	}
+	if required {
+		c.JSON(http.StatusForbidden, gin.H{"error": "2FA é obrigatório para este tipo de usuário"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if !h.verifyCode(c, userID, req.Code) {
+		return
+	}
+	if err := h.twoFactor.Disable(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao desativar 2FA"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "2FA desativado com sucesso"})
+}
+
+// RegenerateRecoveryCodes invalida os códigos de recuperação antigos e gera novos
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if !h.verifyCode(c, userID, req.Code) {
+		return
+	}
+
+	codes, err := h.twoFactor.RegenerateRecoveryCodes(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar códigos de recuperação"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// ListPolicies lista os tipos de usuário com 2FA obrigatório (admin)
+func (h *TwoFactorHandler) ListPolicies(c *gin.Context) {
+	policies, err := h.twoFactor.Policies()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar políticas de 2FA"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"policies": policies})
+}
+
+// UpdatePolicy torna o 2FA obrigatório ou opcional para um tipo de usuário (admin)
+func (h *TwoFactorHandler) UpdatePolicy(c *gin.Context) {
+	var req TwoFactorPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role := models.UserType(c.Param("role"))
+	if !role.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tipo de usuário inválido"})
+		return
+	}
+	if err := h.twoFactor.SetRequired(role, req.Required); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar política de 2FA"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"role": role, "required": req.Required})
+}
+
+// verifyCode confere o código do usuário logado com o mesmo limite de tentativas do login;
+// responde e retorna false se a conta estiver bloqueada ou o código for inválido
+func (h *TwoFactorHandler) verifyCode(c *gin.Context, userID uint, code string) bool {
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+		return false
+	}
+
+	ip := c.ClientIP()
+	if !checkThrottle(c, h.throttle, user.Email, ip) {
+		return false
+	}
+
+	if err := h.twoFactor.Verify(userID, code); err != nil {
+		if errors.Is(err, services.ErrInvalidTwoFactorCode) {
+			recordLoginFailure(h.throttle, user.Email, ip, &user)
+		}
+		h.respondTwoFactorError(c, err)
+		return false
+	}
+
+	if err := h.throttle.RecordSuccess(user.Email); err != nil {
+		log.Printf("Erro ao limpar tentativas de login de %s: %v", user.Email, err)
+	}
+	return true
+}
+
+func (h *TwoFactorHandler) respondTwoFactorError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidTwoFactorCode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Código de verificação inválido"})
+	case errors.Is(err, services.ErrTwoFactorNotEnrolled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "2FA não configurado"})
+	case errors.Is(err, services.ErrTwoFactorAlreadyEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": "2FA já está ativo"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao processar 2FA"})
+	}
+}
@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead folga para os demais campos e cabeçalhos do formulário além do arquivo
+const multipartOverhead = 1 << 20
+
+// limitUploadBody limita o corpo da requisição antes de o formulário ser lido, para que um envio
+// grande demais seja interrompido em vez de gravado inteiro em disco
+func limitUploadBody(c *gin.Context, maxFileSize int64) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxFileSize+multipartOverhead)
+}
+
+// isBodyTooLarge indica se a leitura do formulário parou no limite de limitUploadBody
+func isBodyTooLarge(err error) bool {
+	var maxBytes *http.MaxBytesError
+	return errors.As(err, &maxBytes)
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oversizedUpload formulário com um arquivo maior que o limite mais a folga do formulário
+func oversizedUpload(t *testing.T, field string, size int) (*bytes.Buffer, string) {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile(field, "arquivo.jpg")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	part.Write(bytes.Repeat([]byte{0xff}, size))
+	writer.Close()
+	return body, writer.FormDataContentType()
+}
+
+func TestUploadsRejectOversizedBodies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.POST("/orders/:id/deliver", (&OrderHandler{}).Deliver)
+	r.POST("/vehicle/document", NewVehicleHandler(nil).UploadDocument)
+
+	testCases := []struct {
+		name  string
+		path  string
+		field string
+		size  int
+	}{
+		{"Delivery proof photo", "/orders/1/deliver", "photo", maxProofPhotoSize + multipartOverhead},
+		{"Vehicle document", "/vehicle/document", "document", maxVehicleDocumentSize + multipartOverhead},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body, contentType := oversizedUpload(t, tc.field, tc.size)
+			req := httptest.NewRequest(http.MethodPost, tc.path, body)
+			req.Header.Set("Content-Type", contentType)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusRequestEntityTooLarge {
+				t.Errorf("Expected status 413, got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+}
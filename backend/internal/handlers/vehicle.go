@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	"cupcake-delivery/internal/models"
+	"cupcake-delivery/internal/services"
+	"cupcake-delivery/internal/utils"
+	"cupcake-delivery/internal/validators"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxVehicleDocumentSize tamanho máximo do documento do veículo
+const maxVehicleDocumentSize = 5 << 20
+
+type VehicleHandler struct {
+	vehicles *services.VehicleService
+}
+
+type VehicleRequest struct {
+	Type          models.VehicleType `json:"type" binding:"required"`
+	Plate         string             `json:"plate"`
+	CapacityBoxes int                `json:"capacity_boxes"` // Zero usa a capacidade padrão do tipo
+	Refrigerated  bool               `json:"refrigerated"`
+}
+
+type VerifyVehicleRequest struct {
+	Approved *bool  `json:"approved" binding:"required"`
+	Reason   string `json:"reason"`
+}
+
+func NewVehicleHandler(vehicles *services.VehicleService) *VehicleHandler {
+	return &VehicleHandler{vehicles: vehicles}
+}
+
+// vehicleInput valida o veículo informado; responde com erro de validação e retorna false se inválido
+func vehicleInput(c *gin.Context, req VehicleRequest) (services.VehicleInput, bool) {
+	if validationErrors := validators.ValidateVehicle(req.Type, req.Plate, req.CapacityBoxes); len(validationErrors) > 0 {
+		utils.RespondWithValidationError(c, validationErrors)
+		return services.VehicleInput{}, false
+	}
+	return services.VehicleInput{
+		Type:          req.Type,
+		Plate:         validators.NormalizePlate(req.Plate),
+		CapacityBoxes: req.CapacityBoxes,
+		Refrigerated:  req.Refrigerated,
+	}, true
+}
+
+// GetMine veículo do entregador logado
+func (h *VehicleHandler) GetMine(c *gin.Context) {
+	vehicle, err := h.vehicles.Vehicle(c.GetUint("user_id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Veículo não cadastrado"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar veículo"})
+		return
+	}
+
+	c.JSON(http.StatusOK, vehicle)
+}
+
+// SaveMine cadastra ou atualiza o veículo do entregador logado; trocar tipo ou placa exige nova verificação
+func (h *VehicleHandler) SaveMine(c *gin.Context) {
+	var req VehicleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	input, ok := vehicleInput(c, req)
+	if !ok {
+		return
+	}
+
+	vehicle, err := h.vehicles.Save(c.GetUint("user_id"), input)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao salvar veículo"})
+		return
+	}
+
+	c.JSON(http.StatusOK, vehicle)
+}
+
+// UploadDocument recebe o documento do veículo (multipart, campo document), que passa a aguardar verificação
+func (h *VehicleHandler) UploadDocument(c *gin.Context) {
+	limitUploadBody(c, maxVehicleDocumentSize)
+	header, err := c.FormFile("document")
+	if isBodyTooLarge(err) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Documento deve ter no máximo 5 MB"})
+		return
+	}
+	if err != nil {
+		utils.RespondWithValidationError(c, []utils.ValidationError{{Field: "document", Message: "Envie o documento do veículo"}})
+		return
+	}
+	if header.Size > maxVehicleDocumentSize {
+		utils.RespondWithValidationError(c, []utils.ValidationError{{Field: "document", Message: "Documento deve ter no máximo 5 MB"}})
+		return
+	}
+	document, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Erro ao ler o documento enviado"})
+		return
+	}
+	defer document.Close()
+
+	vehicle, err := h.vehicles.SaveDocument(c.Request.Context(), c.GetUint("user_id"), document)
+	switch {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusConflict, gin.H{"error": "Cadastre o veículo antes de enviar o documento"})
+		return
+	case errors.Is(err, services.ErrUnsupportedDocument):
+		utils.RespondWithValidationError(c, []utils.ValidationError{{Field: "document", Message: "Documento deve ser JPEG, PNG ou PDF"}})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao salvar documento do veículo"})
+		return
+	}
+
+	c.JSON(http.StatusOK, vehicle)
+}
+
+// List veículos dos entregadores, com status=pending para a fila de verificação (admin)
+func (h *VehicleHandler) List(c *gin.Context) {
+	status := models.VehicleDocumentStatus(c.Query("status"))
+	switch status {
+	case "", models.VehicleDocumentMissing, models.VehicleDocumentPending, models.VehicleDocumentVerified, models.VehicleDocumentRejected:
+	default:
+		utils.RespondWithValidationError(c, []utils.ValidationError{{Field: "status", Message: "Status deve ser missing, pending, verified ou rejected"}})
+		return
+	}
+
+	vehicles, err := h.vehicles.List(status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao listar veículos"})
+		return
+	}
+
+	c.JSON(http.StatusOK, vehicles)
+}
+
+// Get veículo pelo ID (admin)
+func (h *VehicleHandler) Get(c *gin.Context) {
+	vehicle, ok := h.findVehicle(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, vehicle)
+}
+
+// Document envia o documento do veículo para a verificação (admin)
+func (h *VehicleHandler) Document(c *gin.Context) {
+	vehicle, ok := h.findVehicle(c)
+	if !ok {
+		return
+	}
+
+	document, err := h.vehicles.OpenDocument(c.Request.Context(), vehicle)
+	if err != nil {
+		if errors.Is(err, services.ErrFileNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Veículo sem documento"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao abrir documento do veículo"})
+		return
+	}
+	defer document.Close()
+
+	c.Header("Content-Type", vehicle.DocumentContentType)
+	c.Status(http.StatusOK)
+	if _, err := io.Copy(c.Writer, document); err != nil {
+		log.Printf("Erro ao enviar documento do veículo %d: %v", vehicle.ID, err)
+	}
+}
+
+// Verify aprova ou recusa o documento enviado pelo entregador (admin); a recusa exige o motivo
+func (h *VehicleHandler) Verify(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var req VerifyVehicleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !*req.Approved && req.Reason == "" {
+		utils.RespondWithValidationError(c, []utils.ValidationError{{Field: "reason", Message: "Informe o motivo da recusa"}})
+		return
+	}
+	if utf8.RuneCountInString(req.Reason) > 500 {
+		utils.RespondWithValidationError(c, []utils.ValidationError{{Field: "reason", Message: "Motivo deve ter no máximo 500 caracteres"}})
+		return
+	}
+
+	vehicle, err := h.vehicles.Verify(uint(id), c.GetUint("user_id"), *req.Approved, req.Reason)
+	switch {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Veículo não encontrado"})
+		return
+	case errors.Is(err, services.ErrDocumentNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": "Documento do veículo não aguarda verificação"})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar veículo"})
+		return
+	}
+
+	c.JSON(http.StatusOK, vehicle)
+}
+
+func (h *VehicleHandler) findVehicle(c *gin.Context) (*models.Vehicle, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return nil, false
+	}
+
+	vehicle, err := h.vehicles.Get(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Veículo não encontrado"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar veículo"})
+		return nil, false
+	}
+	return vehicle, true
+}
+
+// respondVehicleLoadError responde 409 quando o veículo do entregador não comporta os pedidos;
+// retorna false para os demais erros
+func respondVehicleLoadError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrNoVehicle):
+		c.JSON(http.StatusConflict, gin.H{"error": "Cadastre o veículo para retirar pedidos"})
+	case errors.Is(err, services.ErrVehicleNotVerified):
+		c.JSON(http.StatusConflict, gin.H{"error": "Documentos do veículo ainda não foram verificados"})
+	case errors.Is(err, services.ErrVehicleTooSmall):
+		c.JSON(http.StatusConflict, gin.H{"error": "Pedido exige um veículo de maior porte"})
+	case errors.Is(err, services.ErrRefrigerationRequired):
+		c.JSON(http.StatusConflict, gin.H{"error": "Pedido exige veículo refrigerado"})
+	case errors.Is(err, services.ErrVehicleFull):
+		c.JSON(http.StatusConflict, gin.H{"error": "Pedidos excedem a capacidade do veículo"})
+	default:
+		return false
+	}
+	return true
+}
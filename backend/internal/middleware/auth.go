@@ -2,15 +2,16 @@ package middleware
 
 import (
 	"cupcake-delivery/internal/models"
+	"cupcake-delivery/internal/services"
+	"errors"
 	"net/http"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
+func AuthMiddleware(tokens *services.TokenService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := c.GetHeader("Authorization")
 		if tokenString == "" {
@@ -24,37 +25,65 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 			tokenString = strings.TrimPrefix(tokenString, "Bearer ")
 		}
 
-		// Validar token JWT
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return []byte(jwtSecret), nil
-		})
+		// Validar token JWT (assinatura, algoritmo, kid, expiração, iss e aud)
+		claims, err := tokens.Parse(tokenString)
+		if err != nil {
+			if errors.Is(err, jwt.ErrTokenExpired) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token expirado"})
+			} else {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token inválido"})
+			}
+			c.Abort()
+			return
+		}
 
-		if err != nil || !token.Valid {
+		// Tokens de desafio (ex.: 2FA pendente) não dão acesso à API
+		if purpose, ok := claims["purpose"]; ok && purpose != "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token inválido"})
 			c.Abort()
 			return
 		}
 
-		// Extrair claims do token
-		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			// Verificar se o token não expirou
-			if exp, ok := claims["exp"]; ok {
-				if expTime := int64(exp.(float64)); expTime < time.Now().Unix() {
-					c.JSON(http.StatusUnauthorized, gin.H{"error": "Token expirado"})
-					c.Abort()
-					return
-				}
-			}
+		// Adicionar informações do usuário ao contexto
+		if userID, ok := claims["user_id"]; ok {
+			c.Set("user_id", uint(userID.(float64)))
+		}
+		if userType, ok := claims["type"]; ok {
+			c.Set("type", userType.(string))
+		}
 
-			// Adicionar informações do usuário ao contexto
-			if userID, ok := claims["user_id"]; ok {
-				c.Set("user_id", uint(userID.(float64)))
-			}
-			if userType, ok := claims["type"]; ok {
-				c.Set("type", userType.(string))
-			}
+		c.Next()
+	}
+}
+
+// queryTokenKey chave do contexto onde StripQueryToken guarda o access_token tirado da URL
+const queryTokenKey = "query_access_token"
+
+// StripQueryToken tira o parâmetro access_token da URL para que o JWT não vá parar no log de acesso
+// nem em outros registros da requisição. Precisa vir antes do gin.Logger, que lê a URL ao receber a
+// requisição. O token fica guardado no contexto e só é aceito nas rotas com TokenFromQuery.
+func StripQueryToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := c.Request.URL.Query()
+		if query.Has("access_token") {
+			c.Set(queryTokenKey, query.Get("access_token"))
+			query.Del("access_token")
+			c.Request.URL.RawQuery = query.Encode()
+			c.Request.RequestURI = c.Request.URL.RequestURI()
 		}
+		c.Next()
+	}
+}
 
+// TokenFromQuery aceita o JWT do parâmetro access_token, guardado por StripQueryToken, quando não há
+// cabeçalho Authorization. Apenas para rotas de streaming: o EventSource do navegador não envia cabeçalhos.
+func TokenFromQuery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			if token := c.GetString(queryTokenKey); token != "" {
+				c.Request.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
 		c.Next()
 	}
 }
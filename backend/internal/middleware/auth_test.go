@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestStripQueryTokenKeepsTokenOutOfAccessLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var logged bytes.Buffer
+	r := gin.New()
+	r.Use(StripQueryToken(), gin.LoggerWithWriter(&logged))
+	r.GET("/stream", TokenFromQuery(), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetHeader("Authorization"))
+	})
+	r.GET("/other", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetHeader("Authorization"))
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stream?access_token=segredo&last_event_id=4", nil))
+	if got := w.Body.String(); got != "Bearer segredo" {
+		t.Errorf("Expected the query token as Authorization, got %q", got)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/other?access_token=segredo", nil))
+	if got := w.Body.String(); got != "" {
+		t.Errorf("Expected routes without TokenFromQuery to ignore the query token, got %q", got)
+	}
+
+	if strings.Contains(logged.String(), "segredo") {
+		t.Errorf("Expected the token to be stripped from the access log, got %q", logged.String())
+	}
+	if !strings.Contains(logged.String(), "/stream?last_event_id=4") {
+		t.Errorf("Expected the other query parameters to be logged, got %q", logged.String())
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"cupcake-delivery/internal/models"
+	"cupcake-delivery/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission exige que o papel do usuário autenticado possua todas as permissões informadas.
+// Deve ser usado após AuthMiddleware.
+func RequirePermission(permissions *services.PermissionService, required ...models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userType, exists := c.Get("type")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Tipo de usuário não encontrado"})
+			c.Abort()
+			return
+		}
+
+		role := models.UserType(userType.(string))
+		for _, perm := range required {
+			if !permissions.HasPermission(role, perm) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Acesso não autorizado"})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// PostalAddress endereço brasileiro estruturado
+type PostalAddress struct {
+	CEP          string `json:"cep" gorm:"type:varchar(8)"` // Apenas dígitos
+	Street       string `json:"street"`                     // Logradouro
+	Number       string `json:"number"`
+	Complement   string `json:"complement,omitempty"`
+	Neighborhood string `json:"neighborhood"` // Bairro
+	City         string `json:"city"`
+	State        string `json:"state" gorm:"type:varchar(2)"` // UF
+}
+
+// FormattedCEP retorna o CEP no formato 00000-000
+func (a PostalAddress) FormattedCEP() string {
+	if len(a.CEP) != 8 {
+		return a.CEP
+	}
+	return a.CEP[:5] + "-" + a.CEP[5:]
+}
+
+// String formata o endereço em uma linha, ex.: "Av. Paulista, 1000 - Apto 12 - Bela Vista, São Paulo - SP, 01310-100"
+func (a PostalAddress) String() string {
+	line := a.Street
+	if a.Number != "" {
+		line += ", " + a.Number
+	}
+	if a.Complement != "" {
+		line += " - " + a.Complement
+	}
+	if a.Neighborhood != "" {
+		line += " - " + a.Neighborhood
+	}
+	if a.City != "" {
+		line += fmt.Sprintf(", %s - %s", a.City, a.State)
+	}
+	if a.CEP != "" {
+		line += ", " + a.FormattedCEP()
+	}
+	return strings.TrimPrefix(line, ", ")
+}
+
+// UserAddress endereço salvo no caderno de endereços do cliente
+type UserAddress struct {
+	gorm.Model
+	UserID    uint     `json:"userId" gorm:"index;not null"`
+	Label     string   `json:"label"`                   // Ex.: "Casa", "Trabalho"
+	Address   string   `json:"address" gorm:"not null"` // Endereço formatado em uma linha
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+	IsDefault bool     `json:"isDefault" gorm:"default:false"`
+
+	PostalAddress `gorm:"embedded"`
+}
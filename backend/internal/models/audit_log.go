@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+// Ações registradas no log de auditoria
+const (
+	AuditActionDataExport        = "data_export"
+	AuditActionDeletionRequested = "deletion_requested"
+	AuditActionDeletionCancelled = "deletion_cancelled"
+	AuditActionAccountPurged     = "account_purged"
+)
+
+// AuditLog registro imutável de ações sobre dados pessoais (LGPD).
+// ActorID nulo indica uma ação do próprio sistema, como o expurgo agendado.
+type AuditLog struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ActorID   *uint     `json:"actor_id,omitempty"`
+	SubjectID uint      `json:"subject_id" gorm:"index;not null"`
+	Action    string    `json:"action" gorm:"type:varchar(50);not null"`
+	IP        string    `json:"ip,omitempty" gorm:"type:varchar(45)"`
+	Details   string    `json:"details,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AccountDeletion pedido de exclusão de conta; os dados só são expurgados após PurgeAfter
+type AccountDeletion struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	UserID      uint       `json:"user_id" gorm:"uniqueIndex;not null"`
+	RequestedAt time.Time  `json:"requested_at"`
+	PurgeAfter  time.Time  `json:"purge_after" gorm:"index"`
+	PurgedAt    *time.Time `json:"purged_at,omitempty"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// DeliveryBatchStatus situação de um lote de entregas (rota com várias paradas)
+type DeliveryBatchStatus string
+
+const (
+	BatchActive    DeliveryBatchStatus = "active"
+	BatchCompleted DeliveryBatchStatus = "completed" // Todas as paradas entregues ou com falha
+)
+
+// BatchStopStatus situação de cada parada do lote, acompanhada independentemente das demais
+type BatchStopStatus string
+
+const (
+	StopPending   BatchStopStatus = "pending"
+	StopDelivered BatchStopStatus = "delivered"
+	StopFailed    BatchStopStatus = "failed"
+	StopRemoved   BatchStopStatus = "removed" // Pedido tirado da rota por mudança de status pelo admin
+)
+
+// DeliveryBatch pedidos levados juntos por um entregador, na ordem das paradas
+type DeliveryBatch struct {
+	ID          uint                `json:"id" gorm:"primaryKey"`
+	CourierID   uint                `json:"courier_id" gorm:"index;uniqueIndex:idx_delivery_batches_active_courier,where:status = 'active';not null"` // Um lote ativo por entregador
+	Status      DeliveryBatchStatus `json:"status" gorm:"type:varchar(10);index;not null"`
+	CreatedByID uint                `json:"created_by_id"` // Entregador que montou o lote ou admin
+	DistanceKm  float64             `json:"distance_km"`   // Extensão planejada da rota
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+	CompletedAt *time.Time          `json:"completed_at,omitempty"`
+	Stops       []DeliveryBatchStop `json:"stops" gorm:"foreignKey:BatchID"`
+}
+
+// DeliveryBatchStop parada do lote: um pedido, sua posição na rota e a previsão de chegada
+type DeliveryBatchStop struct {
+	ID          uint            `json:"id" gorm:"primaryKey"`
+	BatchID     uint            `json:"batch_id" gorm:"index;not null"`
+	OrderID     uint            `json:"order_id" gorm:"index;not null"`
+	Sequence    int             `json:"sequence" gorm:"not null"`
+	Status      BatchStopStatus `json:"status" gorm:"type:varchar(10);not null"`
+	LegKm       float64         `json:"leg_km"` // Distância desde a parada anterior (ou do ponto de partida)
+	ETA         *time.Time      `json:"eta,omitempty"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+	Order       *Order          `json:"order,omitempty" gorm:"foreignKey:OrderID"`
+}
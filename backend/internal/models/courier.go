@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CourierStatus disponibilidade informada pelo entregador
+type CourierStatus string
+
+const (
+	CourierOnline  CourierStatus = "online"
+	CourierOffline CourierStatus = "offline"
+	CourierBreak   CourierStatus = "break"
+)
+
+// Valid indica se o status é um dos estados conhecidos
+func (s CourierStatus) Valid() bool {
+	return s == CourierOnline || s == CourierOffline || s == CourierBreak
+}
+
+// CourierAvailability estado atual de um entregador; sem registro o entregador está offline
+type CourierAvailability struct {
+	UserID    uint          `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	Status    CourierStatus `json:"status" gorm:"type:varchar(10);not null"`
+	Since     time.Time     `json:"since"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// CourierShift turno agendado de um entregador; só é possível ficar online durante um turno
+type CourierShift struct {
+	gorm.Model
+	CourierID uint      `json:"courier_id" gorm:"index;not null"`
+	Courier   *User     `json:"courier,omitempty" gorm:"foreignKey:CourierID"`
+	StartsAt  time.Time `json:"starts_at" gorm:"index;not null"`
+	EndsAt    time.Time `json:"ends_at" gorm:"index;not null"`
+	Notes     string    `json:"notes,omitempty"`
+}
+
+// ActiveAt indica se o turno está em andamento no instante informado
+func (s CourierShift) ActiveAt(t time.Time) bool {
+	return !t.Before(s.StartsAt) && t.Before(s.EndsAt)
+}
+
+// CourierPosition última posição informada pelo entregador
+type CourierPosition struct {
+	CourierID  uint      `json:"courier_id" gorm:"primaryKey;autoIncrement:false"`
+	Latitude   float64   `json:"latitude" gorm:"not null"`
+	Longitude  float64   `json:"longitude" gorm:"not null"`
+	Accuracy   *float64  `json:"accuracy,omitempty"` // Raio de precisão em metros informado pelo GPS
+	RecordedAt time.Time `json:"recorded_at" gorm:"not null"`
+}
+
+// CourierLocation ponto do trajeto de um pedido em entrega; expurgado após a entrega e o prazo de retenção
+type CourierLocation struct {
+	ID         uint      `json:"-" gorm:"primaryKey"`
+	OrderID    uint      `json:"-" gorm:"index;not null"`
+	CourierID  uint      `json:"-" gorm:"index;not null"`
+	Latitude   float64   `json:"latitude" gorm:"not null"`
+	Longitude  float64   `json:"longitude" gorm:"not null"`
+	RecordedAt time.Time `json:"recorded_at" gorm:"index;not null"`
+}
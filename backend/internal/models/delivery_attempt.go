@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// DeliveryFailureReason motivo informado pelo entregador quando a entrega não acontece
+type DeliveryFailureReason string
+
+const (
+	FailureCustomerAbsent DeliveryFailureReason = "customer_absent"
+	FailureWrongAddress   DeliveryFailureReason = "wrong_address"
+	FailureRefused        DeliveryFailureReason = "refused"
+	FailureDamaged        DeliveryFailureReason = "damaged"
+)
+
+// Valid indica se o motivo é um dos códigos conhecidos
+func (r DeliveryFailureReason) Valid() bool {
+	switch r {
+	case FailureCustomerAbsent, FailureWrongAddress, FailureRefused, FailureDamaged:
+		return true
+	}
+	return false
+}
+
+// Label descrição do motivo para as notificações
+func (r DeliveryFailureReason) Label() string {
+	switch r {
+	case FailureCustomerAbsent:
+		return "ninguém no endereço"
+	case FailureWrongAddress:
+		return "endereço incorreto"
+	case FailureRefused:
+		return "pedido recusado"
+	case FailureDamaged:
+		return "pedido danificado"
+	}
+	return string(r)
+}
+
+// DeliveryAttempt tentativa de entrega sem sucesso; conta nas métricas do entregador e do cliente
+type DeliveryAttempt struct {
+	ID         uint                  `json:"id" gorm:"primaryKey"`
+	OrderID    uint                  `json:"order_id" gorm:"index;not null"`
+	CourierID  uint                  `json:"courier_id" gorm:"index;not null"`
+	CustomerID uint                  `json:"customer_id" gorm:"index;not null"`
+	Reason     DeliveryFailureReason `json:"reason" gorm:"type:varchar(20);not null"`
+	Notes      string                `json:"notes,omitempty"`
+	FailedAt   time.Time             `json:"failed_at" gorm:"index;not null"`
+	ReturnedAt *time.Time            `json:"returned_at,omitempty"` // Pedido de volta à loja
+}
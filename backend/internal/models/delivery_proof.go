@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// DeliveryProofMethod evidência apresentada pelo entregador ao concluir a entrega
+type DeliveryProofMethod string
+
+const (
+	ProofPIN       DeliveryProofMethod = "pin"
+	ProofPhoto     DeliveryProofMethod = "photo"
+	ProofRecipient DeliveryProofMethod = "recipient"
+)
+
+// MaxDeliveryPINAttempts tentativas erradas de PIN antes de exigir foto ou nome de quem recebeu
+const MaxDeliveryPINAttempts = 5
+
+// DeliveryProof comprovante de entrega guardado com o pedido
+type DeliveryProof struct {
+	ID               uint                `json:"id" gorm:"primaryKey"`
+	OrderID          uint                `json:"order_id" gorm:"uniqueIndex;not null"`
+	CourierID        uint                `json:"courier_id" gorm:"index;not null"`
+	Method           DeliveryProofMethod `json:"method" gorm:"type:varchar(10);not null"`
+	RecipientName    string              `json:"recipient_name,omitempty"`
+	PhotoKey         string              `json:"-"`
+	PhotoContentType string              `json:"photo_content_type,omitempty"`
+	Latitude         *float64            `json:"latitude,omitempty"` // Última posição do entregador na entrega
+	Longitude        *float64            `json:"longitude,omitempty"`
+	DeliveredAt      time.Time           `json:"delivered_at" gorm:"not null"`
+}
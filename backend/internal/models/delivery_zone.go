@@ -0,0 +1,71 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// GeoJSONPolygon geometria GeoJSON do tipo Polygon (RFC 7946).
+// O primeiro anel é o contorno externo e os demais são buracos; as posições são [longitude, latitude].
+type GeoJSONPolygon struct {
+	Type        string         `json:"type"`
+	Coordinates [][][2]float64 `json:"coordinates"`
+}
+
+// Value grava o polígono como JSON
+func (p GeoJSONPolygon) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
+// Scan lê o polígono gravado como JSON
+func (p *GeoJSONPolygon) Scan(value interface{}) error {
+	switch data := value.(type) {
+	case []byte:
+		return json.Unmarshal(data, p)
+	case string:
+		return json.Unmarshal([]byte(data), p)
+	default:
+		return errors.New("valor inválido para GeoJSONPolygon")
+	}
+}
+
+// Contains indica se o ponto está dentro do contorno externo e fora de todos os buracos
+func (p GeoJSONPolygon) Contains(latitude, longitude float64) bool {
+	if len(p.Coordinates) == 0 || !ringContains(p.Coordinates[0], latitude, longitude) {
+		return false
+	}
+	for _, hole := range p.Coordinates[1:] {
+		if ringContains(hole, latitude, longitude) {
+			return false
+		}
+	}
+	return true
+}
+
+// ringContains aplica o algoritmo de ray casting (par-ímpar) em um anel
+func ringContains(ring [][2]float64, latitude, longitude float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > latitude) != (yj > latitude) &&
+			longitude < (xj-xi)*(latitude-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// DeliveryZone área atendida pela loja, com taxa, pedido mínimo e tempo estimado próprios
+type DeliveryZone struct {
+	gorm.Model
+	Name             string         `json:"name" gorm:"not null"`
+	Polygon          GeoJSONPolygon `json:"polygon" gorm:"type:jsonb;not null"`
+	Fee              float64        `json:"fee"`
+	MinOrderValue    float64        `json:"minOrderValue"`
+	EstimatedMinutes int            `json:"estimatedMinutes"`
+	Active           bool           `json:"active"`
+}
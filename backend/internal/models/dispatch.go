@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// DispatchOfferStatus situação de uma oferta de pedido feita pelo despacho automático
+type DispatchOfferStatus string
+
+const (
+	OfferPending   DispatchOfferStatus = "pending"
+	OfferAccepted  DispatchOfferStatus = "accepted"
+	OfferDeclined  DispatchOfferStatus = "declined"
+	OfferExpired   DispatchOfferStatus = "expired"
+	OfferCancelled DispatchOfferStatus = "cancelled" // Pedido retirado por outro caminho antes do aceite
+)
+
+// DispatchOffer pedido pronto oferecido a um entregador, que tem até ExpiresAt para aceitar ou recusar
+type DispatchOffer struct {
+	ID          uint                `json:"id" gorm:"primaryKey"`
+	OrderID     uint                `json:"order_id" gorm:"index;uniqueIndex:idx_dispatch_offers_pending_order,where:status = 'pending';not null"` // Uma oferta pendente por pedido
+	CourierID   uint                `json:"courier_id" gorm:"index;not null"`
+	Status      DispatchOfferStatus `json:"status" gorm:"type:varchar(10);index;not null"`
+	OfferedAt   time.Time           `json:"offered_at" gorm:"not null"`
+	ExpiresAt   time.Time           `json:"expires_at" gorm:"index;not null"`
+	RespondedAt *time.Time          `json:"responded_at,omitempty"`
+}
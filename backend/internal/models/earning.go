@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// EarningType tipo de lançamento no extrato do entregador
+type EarningType string
+
+const (
+	EarningDeliveryFee EarningType = "delivery_fee" // Lançado automaticamente quando o pedido é entregue
+	EarningTip         EarningType = "tip"
+	EarningAdjustment  EarningType = "adjustment" // Correção manual, positiva ou negativa
+	EarningPayout      EarningType = "payout"     // Pagamento ao entregador, sempre negativo
+)
+
+// CourierEarning lançamento no extrato do entregador; o saldo é a soma dos valores.
+// Cada pedido tem no máximo um repasse e uma gorjeta.
+type CourierEarning struct {
+	ID          uint        `json:"id" gorm:"primaryKey"`
+	CourierID   uint        `json:"courier_id" gorm:"index;not null"`
+	OrderID     *uint       `json:"order_id,omitempty" gorm:"uniqueIndex:idx_courier_earnings_order_type,where:type IN ('delivery_fee','tip')"`
+	Type        EarningType `json:"type" gorm:"type:varchar(20);not null;uniqueIndex:idx_courier_earnings_order_type"`
+	Amount      float64     `json:"amount"`
+	Description string      `json:"description,omitempty"`
+	CreatedByID *uint       `json:"created_by_id,omitempty"` // Admin que fez o lançamento; nulo nos automáticos
+	CreatedAt   time.Time   `json:"created_at" gorm:"index"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// OrderStatusChange entrada do pedido em um status; o histórico é a base do tempo de preparo por produto
+type OrderStatusChange struct {
+	ID        uint        `json:"id" gorm:"primaryKey"`
+	OrderID   uint        `json:"order_id" gorm:"index;not null"`
+	Status    OrderStatus `json:"status" gorm:"type:varchar(20);not null"`
+	ChangedAt time.Time   `json:"changed_at" gorm:"index"`
+}
+
+// ETAPrediction previsão de entrega feita quando o pedido mudou de status. Na entrega recebe o
+// horário real e o erro, usados para calibrar a estimativa.
+type ETAPrediction struct {
+	ID           uint        `json:"id" gorm:"primaryKey"`
+	OrderID      uint        `json:"order_id" gorm:"index;not null"`
+	Status       OrderStatus `json:"status" gorm:"type:varchar(20);not null"` // Status do pedido quando a previsão foi feita
+	PredictedAt  time.Time   `json:"predicted_at"`
+	EstimatedAt  time.Time   `json:"estimated_at"`
+	DeliveredAt  *time.Time  `json:"delivered_at,omitempty" gorm:"index"`
+	ErrorMinutes *float64    `json:"error_minutes,omitempty"` // Entrega real menos a prevista: positivo é atraso
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// LoginAttempt contabiliza falhas de login por chave (conta ou IP)
+type LoginAttempt struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	Key           string     `json:"key" gorm:"type:varchar(255);uniqueIndex;not null"`
+	Failures      int        `json:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -10,15 +12,27 @@ const (
 	CustomerType UserType = "customer"
 	DeliveryType UserType = "delivery"
 	AdminType    UserType = "admin"
+	KitchenType  UserType = "kitchen"
 )
 
+// Valid indica se o tipo é um dos tipos de usuário conhecidos
+func (t UserType) Valid() bool {
+	switch t {
+	case CustomerType, DeliveryType, AdminType, KitchenType:
+		return true
+	}
+	return false
+}
+
 type User struct {
 	gorm.Model
 	Name     string   `json:"name"`
 	Email    string   `json:"email" gorm:"unique"`
+	Phone    string   `json:"phone,omitempty"`
+	Document string   `json:"document,omitempty" gorm:"type:varchar(14)"`
 	Password string   `json:"-"` // Não será retornado no JSON
 	Type     UserType `json:"type" gorm:"type:varchar(20)"`
-	Vehicle  *string  `json:"vehicle,omitempty"` // Apenas para entregadores
+	Vehicle  *Vehicle `json:"vehicle,omitempty" gorm:"foreignKey:CourierID"` // Apenas para entregadores
 }
 
 type Product struct {
@@ -27,8 +41,22 @@ type Product struct {
 	Description string  `json:"description"`
 	Price       float64 `json:"price"`
 	ImageURL    string  `json:"imageUrl"`
+
+	// O que o produto exige do veículo de entrega: caixas por unidade, porte mínimo (bolos grandes
+	// só de carro) e refrigeração
+	Boxes           int         `json:"boxes" gorm:"default:1"`
+	RequiredVehicle VehicleType `json:"requiredVehicle,omitempty" gorm:"type:varchar(20)"`
+	Refrigerated    bool        `json:"refrigerated"`
 }
 
+// PaymentStatus situação da cobrança de um pedido ou gorjeta
+type PaymentStatus string
+
+const (
+	PaymentPending PaymentStatus = "pending" // Sem resposta conclusiva do meio de pagamento (ex.: timeout)
+	PaymentPaid    PaymentStatus = "paid"
+)
+
 type OrderStatus string
 
 const (
@@ -39,6 +67,35 @@ const (
 	StatusDelivered  OrderStatus = "delivered"
 )
 
+// Desvios do fluxo normal: tentativa de entrega sem sucesso e volta do pedido para a loja
+const (
+	StatusDeliveryFailed OrderStatus = "delivery_failed"
+	StatusReturned       OrderStatus = "returned"
+)
+
+// orderStatusFlow define a sequência normal de um pedido
+var orderStatusFlow = []OrderStatus{
+	StatusPending,
+	StatusPreparing,
+	StatusReady,
+	StatusDelivering,
+	StatusDelivered,
+}
+
+// Advances indica se o status vem depois de from na sequência normal do pedido
+func (s OrderStatus) Advances(from OrderStatus) bool {
+	target, current := -1, -1
+	for i, status := range orderStatusFlow {
+		if status == s {
+			target = i
+		}
+		if status == from {
+			current = i
+		}
+	}
+	return target >= 0 && current >= 0 && target > current
+}
+
 type Order struct {
 	gorm.Model
 	CustomerID uint        `json:"customerId"`
@@ -47,8 +104,53 @@ type Order struct {
 	Delivery   *User       `json:"delivery,omitempty" gorm:"foreignKey:DeliveryID"`
 	Status     OrderStatus `json:"status" gorm:"type:varchar(20)"`
 	Total      float64     `json:"total"`
-	Address    string      `json:"address"`
+	Address    string      `json:"address"` // Cópia do endereço no momento do pedido
+	Latitude   *float64    `json:"latitude,omitempty"`
+	Longitude  *float64    `json:"longitude,omitempty"`
 	Items      []OrderItem `json:"items" gorm:"foreignKey:OrderID"`
+
+	// Endereço estruturado copiado no momento do pedido
+	PostalAddress `gorm:"embedded"`
+
+	// CPF/CNPJ para a nota fiscal e telefone de contato para o entregador
+	CustomerDocument string `json:"customerDocument,omitempty" gorm:"type:varchar(14)"`
+	ContactPhone     string `json:"contactPhone,omitempty" gorm:"type:varchar(16)"`
+
+	// Composição do total: Total = Subtotal (itens) + DeliveryFee + Tip
+	Subtotal           float64 `json:"subtotal"`
+	DeliveryFee        float64 `json:"deliveryFee"`
+	Tip                float64 `json:"tip"` // Gorjeta integral do entregador, no checkout ou após a entrega
+	DeliveryZoneID     *uint   `json:"deliveryZoneId,omitempty"`
+	DeliveryDistanceKm float64 `json:"deliveryDistanceKm,omitempty"`
+	EstimatedMinutes   int     `json:"estimatedMinutes,omitempty"` // Prazo estimado da zona de entrega
+
+	// PIN que o cliente informa ao entregador; nunca vai no JSON do pedido para não chegar ao entregador
+	DeliveryPIN         string         `json:"-" gorm:"type:varchar(4)"`
+	DeliveryPINAttempts int            `json:"-"`
+	DeliveryProof       *DeliveryProof `json:"deliveryProof,omitempty" gorm:"foreignKey:OrderID"`
+
+	// Última falha de entrega e nova data combinada com o cliente; antes dela o pedido não sai da loja
+	DeliveryFailureReason DeliveryFailureReason `json:"deliveryFailureReason,omitempty" gorm:"type:varchar(20)"`
+	ScheduledFor          *time.Time            `json:"scheduledFor,omitempty" gorm:"index"`
+
+	// Cobrança do pedido e da gorjeta dada depois da entrega. Pendente enquanto o meio de pagamento não
+	// confirma: a cobrança é repetida com a mesma chave de idempotência até ser aprovada ou recusada.
+	PaymentStatus    PaymentStatus `json:"paymentStatus" gorm:"type:varchar(20);not null;default:paid;index"`
+	TipPaymentStatus PaymentStatus `json:"tipPaymentStatus,omitempty" gorm:"type:varchar(20);index"`
+
+	// Momento da entrega; base do repasse ao entregador e da conciliação dos pagamentos
+	DeliveredAt *time.Time `json:"deliveredAt,omitempty" gorm:"index"`
+
+	// Quando o pedido entrou no status atual; base dos prazos (SLA) por status
+	StatusChangedAt *time.Time `json:"statusChangedAt,omitempty" gorm:"index"`
+
+	// Previsão de entrega, recalculada a cada mudança de status; vazia quando o pedido sai do fluxo de entrega
+	EstimatedDeliveryAt *time.Time `json:"estimatedDeliveryAt,omitempty"`
+
+	// Exigências de veículo somadas dos itens no momento do pedido
+	Boxes              int         `json:"boxes"`
+	RequiredVehicle    VehicleType `json:"requiredVehicle,omitempty" gorm:"type:varchar(20)"`
+	NeedsRefrigeration bool        `json:"needsRefrigeration,omitempty"`
 }
 
 type OrderItem struct {
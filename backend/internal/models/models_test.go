@@ -0,0 +1,180 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOrderStatusAdvances(t *testing.T) {
+	testCases := []struct {
+		name     string
+		from     OrderStatus
+		to       OrderStatus
+		expected bool
+	}{
+		{"Pending to preparing", StatusPending, StatusPreparing, true},
+		{"Preparing to ready", StatusPreparing, StatusReady, true},
+		{"Pending to ready", StatusPending, StatusReady, true},
+		{"Ready to preparing", StatusReady, StatusPreparing, false},
+		{"Same status", StatusPreparing, StatusPreparing, false},
+		{"Unknown target", StatusPending, OrderStatus("cancelled"), false},
+		{"Unknown origin", OrderStatus("cancelled"), StatusReady, false},
+		{"Failed delivery is outside the normal flow", StatusDelivering, StatusDeliveryFailed, false},
+		{"Returned back to ready", StatusReturned, StatusReady, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.to.Advances(tc.from); got != tc.expected {
+				t.Errorf("Expected %v for %s -> %s, got %v", tc.expected, tc.from, tc.to, got)
+			}
+		})
+	}
+}
+
+func TestGeoJSONPolygonContains(t *testing.T) {
+	// Quadrado de 0,1° com um buraco central de 0,02°
+	polygon := GeoJSONPolygon{
+		Type: "Polygon",
+		Coordinates: [][][2]float64{
+			{{-46.70, -23.60}, {-46.60, -23.60}, {-46.60, -23.50}, {-46.70, -23.50}, {-46.70, -23.60}},
+			{{-46.66, -23.56}, {-46.64, -23.56}, {-46.64, -23.54}, {-46.66, -23.54}, {-46.66, -23.56}},
+		},
+	}
+
+	testCases := []struct {
+		name      string
+		latitude  float64
+		longitude float64
+		expected  bool
+	}{
+		{"Inside", -23.52, -46.68, true},
+		{"Inside hole", -23.55, -46.65, false},
+		{"Outside to the east", -23.55, -46.55, false},
+		{"Outside to the north", -23.45, -46.65, false},
+		{"Swapped coordinates", -46.65, -23.52, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := polygon.Contains(tc.latitude, tc.longitude); got != tc.expected {
+				t.Errorf("Expected %v for (%v, %v), got %v", tc.expected, tc.latitude, tc.longitude, got)
+			}
+		})
+	}
+
+	if (GeoJSONPolygon{}).Contains(-23.52, -46.68) {
+		t.Errorf("Expected empty polygon to contain nothing")
+	}
+}
+
+func TestGeoJSONPolygonScan(t *testing.T) {
+	var polygon GeoJSONPolygon
+	data := `{"type":"Polygon","coordinates":[[[-46.7,-23.6],[-46.6,-23.6],[-46.6,-23.5],[-46.7,-23.6]]]}`
+	if err := polygon.Scan([]byte(data)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if polygon.Type != "Polygon" || len(polygon.Coordinates[0]) != 4 {
+		t.Errorf("Unexpected polygon: %+v", polygon)
+	}
+
+	value, err := polygon.Value()
+	if err != nil || string(value.([]byte)) != data {
+		t.Errorf("Expected round trip to %s, got %s (%v)", data, value, err)
+	}
+}
+
+func TestCourierShiftActiveAt(t *testing.T) {
+	start := time.Date(2024, 5, 10, 18, 0, 0, 0, time.UTC)
+	shift := CourierShift{StartsAt: start, EndsAt: start.Add(4 * time.Hour)}
+
+	testCases := []struct {
+		name     string
+		at       time.Time
+		expected bool
+	}{
+		{"Before start", start.Add(-time.Minute), false},
+		{"At start", start, true},
+		{"During shift", start.Add(2 * time.Hour), true},
+		{"At end", start.Add(4 * time.Hour), false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := shift.ActiveAt(tc.at); got != tc.expected {
+				t.Errorf("Expected %v at %s, got %v", tc.expected, tc.at, got)
+			}
+		})
+	}
+}
+
+func TestCourierStatusValid(t *testing.T) {
+	for _, status := range []CourierStatus{CourierOnline, CourierOffline, CourierBreak} {
+		if !status.Valid() {
+			t.Errorf("Expected %s to be valid", status)
+		}
+	}
+	if CourierStatus("busy").Valid() {
+		t.Errorf("Expected unknown status to be invalid")
+	}
+}
+
+func TestDeliveryFailureReasonValid(t *testing.T) {
+	for _, reason := range []DeliveryFailureReason{FailureCustomerAbsent, FailureWrongAddress, FailureRefused, FailureDamaged} {
+		if !reason.Valid() {
+			t.Errorf("Expected %q to be valid", reason)
+		}
+	}
+	for _, reason := range []DeliveryFailureReason{"", "lost", "CUSTOMER_ABSENT"} {
+		if reason.Valid() {
+			t.Errorf("Expected %q to be invalid", reason)
+		}
+	}
+}
+
+func TestVehicleTypeCarries(t *testing.T) {
+	testCases := []struct {
+		name     string
+		vehicle  VehicleType
+		required VehicleType
+		expected bool
+	}{
+		{"Any vehicle", VehicleBike, "", true},
+		{"Same size", VehicleMotorcycle, VehicleMotorcycle, true},
+		{"Car carries motorcycle orders", VehicleCar, VehicleMotorcycle, true},
+		{"Bike cannot carry car orders", VehicleBike, VehicleCar, false},
+		{"Motorcycle cannot carry car orders", VehicleMotorcycle, VehicleCar, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.vehicle.Carries(tc.required); got != tc.expected {
+				t.Errorf("Expected %v for %s carrying %s, got %v", tc.expected, tc.vehicle, tc.required, got)
+			}
+		})
+	}
+}
+
+func TestLegacyVehicleType(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected VehicleType
+		ok       bool
+	}{
+		{"moto", VehicleMotorcycle, true},
+		{" Carro ", VehicleCar, true},
+		{"BICICLETA", VehicleBike, true},
+		{"motorcycle", VehicleMotorcycle, true},
+		{"patinete", "", false},
+		{"", "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			got, ok := LegacyVehicleType(tc.value)
+			if got != tc.expected || ok != tc.ok {
+				t.Errorf("Expected (%q, %v) for %q, got (%q, %v)", tc.expected, tc.ok, tc.value, got, ok)
+			}
+		})
+	}
+}
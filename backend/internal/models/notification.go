@@ -29,6 +29,17 @@ const (
 	NotificationTypeOrderDelivering = "order_delivering"
 	NotificationTypeOrderDelivered  = "order_delivered"
 	NotificationTypeOrderCancelled  = "order_cancelled"
+
+	NotificationTypeOrderDeliveryFailed = "order_delivery_failed"
+	NotificationTypeOrderReturned       = "order_returned"
+	NotificationTypeOrderRescheduled    = "order_rescheduled"
+
+	NotificationTypeDispatchOffer = "dispatch_offer"
+	NotificationTypeDeliveryETA   = "delivery_eta"
+	NotificationTypeTipReceived   = "tip_received"
+
+	NotificationTypeSLABreach        = "sla_breach"
+	NotificationTypeOrderRunningLate = "order_running_late"
 )
 
 // CreateNotificationData estrutura para criação de notificações
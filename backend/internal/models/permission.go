@@ -0,0 +1,149 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Permission identifica uma ação autorizável no formato recurso:ação[:escopo]
+type Permission string
+
+const (
+	PermProductsWrite Permission = "products:write"
+
+	PermOrdersCreate       Permission = "orders:create"
+	PermOrdersReadOwn      Permission = "orders:read:own"
+	PermOrdersReadAssigned Permission = "orders:read:assigned"
+	PermOrdersReadAny      Permission = "orders:read:any"
+	PermOrdersReadKitchen  Permission = "orders:read:kitchen"
+	PermOrdersUpdateAny    Permission = "orders:update:any"
+	PermOrdersAssignSelf   Permission = "orders:assign:self"
+
+	PermKitchenQueue Permission = "kitchen:queue"
+
+	PermRolesManage Permission = "roles:manage"
+	PermUsersUnlock Permission = "users:unlock"
+
+	PermDeliveryZonesManage Permission = "delivery_zones:manage"
+
+	PermCourierStatus  Permission = "courier:status"
+	PermCouriersManage Permission = "couriers:manage"
+
+	PermEarningsReadOwn Permission = "earnings:read:own"
+	PermEarningsManage  Permission = "earnings:manage"
+)
+
+// OrderStatusPermission retorna a permissão necessária para mover um pedido para o status informado
+func OrderStatusPermission(status OrderStatus) Permission {
+	return Permission("orders:status:" + string(status))
+}
+
+// knownPermissions permissões verificadas pela API; as de status cobrem todos os status do pedido
+var knownPermissions = []Permission{
+	PermProductsWrite,
+	PermOrdersCreate,
+	PermOrdersReadOwn,
+	PermOrdersReadAssigned,
+	PermOrdersReadAny,
+	PermOrdersReadKitchen,
+	PermOrdersUpdateAny,
+	PermOrdersAssignSelf,
+	OrderStatusPermission(StatusPending),
+	OrderStatusPermission(StatusPreparing),
+	OrderStatusPermission(StatusReady),
+	OrderStatusPermission(StatusDelivering),
+	OrderStatusPermission(StatusDelivered),
+	OrderStatusPermission(StatusDeliveryFailed),
+	OrderStatusPermission(StatusReturned),
+	PermKitchenQueue,
+	PermRolesManage,
+	PermUsersUnlock,
+	PermDeliveryZonesManage,
+	PermCourierStatus,
+	PermCouriersManage,
+	PermEarningsReadOwn,
+	PermEarningsManage,
+}
+
+// Known indica se a permissão é uma das verificadas pela API; um curinga precisa cobrir ao menos uma
+func (p Permission) Known() bool {
+	if !strings.HasSuffix(string(p), "*") {
+		for _, known := range knownPermissions {
+			if p == known {
+				return true
+			}
+		}
+		return false
+	}
+	for _, known := range knownPermissions {
+		if p.Matches(known) {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches indica se a permissão concedida cobre a permissão solicitada.
+// Suporta curinga no final, ex.: "orders:status:*" cobre "orders:status:ready" e "*" cobre tudo.
+func (p Permission) Matches(requested Permission) bool {
+	if p == requested {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(string(p), "*"); ok {
+		return strings.HasPrefix(string(requested), prefix)
+	}
+	return false
+}
+
+// RolePermission armazena no banco uma permissão concedida a um papel.
+// Quando existem registros para um papel, eles substituem o mapeamento padrão.
+type RolePermission struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	Role       UserType   `json:"role" gorm:"type:varchar(20);not null;uniqueIndex:idx_role_permission"`
+	Permission Permission `json:"permission" gorm:"type:varchar(100);not null;uniqueIndex:idx_role_permission"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// RolePermissionOverride marca um papel cujas permissões vêm do banco em vez do mapeamento padrão,
+// inclusive quando o papel fica sem nenhuma permissão
+type RolePermissionOverride struct {
+	Role      UserType  `json:"role" gorm:"primaryKey;type:varchar(20)"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DefaultRolePermissions mapeamento padrão papel → permissões
+var DefaultRolePermissions = map[UserType][]Permission{
+	CustomerType: {
+		PermOrdersCreate,
+		PermOrdersReadOwn,
+	},
+	DeliveryType: {
+		PermOrdersReadAssigned,
+		PermOrdersAssignSelf,
+		OrderStatusPermission(StatusDelivering),
+		OrderStatusPermission(StatusDelivered),
+		OrderStatusPermission(StatusDeliveryFailed),
+		OrderStatusPermission(StatusReturned),
+		PermCourierStatus,
+		PermEarningsReadOwn,
+	},
+	AdminType: {
+		PermProductsWrite,
+		PermOrdersCreate,
+		PermOrdersReadAny,
+		PermOrdersUpdateAny,
+		OrderStatusPermission("*"),
+		PermKitchenQueue,
+		PermRolesManage,
+		PermUsersUnlock,
+		PermDeliveryZonesManage,
+		PermCouriersManage,
+		PermEarningsManage,
+	},
+	KitchenType: {
+		PermOrdersReadKitchen,
+		PermKitchenQueue,
+		OrderStatusPermission(StatusPreparing),
+		OrderStatusPermission(StatusReady),
+	},
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// OrderSLABreach pedido que passou do prazo (SLA) do status em que está. Cada passagem do pedido
+// pelo status gera no máximo um registro, identificado pelo momento em que ele entrou no status.
+type OrderSLABreach struct {
+	ID               uint        `json:"id" gorm:"primaryKey"`
+	OrderID          uint        `json:"order_id" gorm:"not null;uniqueIndex:idx_order_sla_breaches_stint"`
+	Status           OrderStatus `json:"status" gorm:"type:varchar(20);not null;uniqueIndex:idx_order_sla_breaches_stint"`
+	StatusSince      time.Time   `json:"status_since" gorm:"not null;uniqueIndex:idx_order_sla_breaches_stint"`
+	LimitMinutes     int         `json:"limit_minutes"`
+	BreachedAt       time.Time   `json:"breached_at" gorm:"index"`
+	ResolvedAt       *time.Time  `json:"resolved_at,omitempty"` // Quando o pedido saiu do status
+	CustomerNotified bool        `json:"customer_notified"`
+}
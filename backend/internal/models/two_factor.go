@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+)
+
+// TwoFactor configuração de TOTP (RFC 6238) de um usuário.
+// Enquanto Enabled for falso, o segredo aguarda a confirmação do primeiro código.
+type TwoFactor struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	UserID       uint       `json:"user_id" gorm:"uniqueIndex;not null"`
+	Secret       string     `json:"-" gorm:"not null"`
+	Enabled      bool       `json:"enabled" gorm:"default:false"`
+	ConfirmedAt  *time.Time `json:"confirmed_at,omitempty"`
+	LastUsedStep int64      `json:"-"` // Impede reutilizar o mesmo código
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// RecoveryCode código de recuperação de uso único, armazenado apenas como hash
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"index;not null"`
+	CodeHash  string     `json:"-" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TwoFactorPolicy define se o 2FA é obrigatório para um tipo de usuário
+type TwoFactorPolicy struct {
+	Role      UserType  `json:"role" gorm:"primaryKey;type:varchar(20)"`
+	Required  bool      `json:"required"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UsedChallengeToken desafio de 2FA já usado para concluir um login; impede reaproveitar o token.
+// Os registros só precisam durar até o token expirar.
+type UsedChallengeToken struct {
+	JTI       string    `gorm:"primaryKey;type:varchar(64)"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+	CreatedAt time.Time
+}
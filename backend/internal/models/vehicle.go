@@ -0,0 +1,106 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// VehicleType tipo de veículo do entregador, do menor para o maior
+type VehicleType string
+
+const (
+	VehicleBike       VehicleType = "bike"
+	VehicleMotorcycle VehicleType = "motorcycle"
+	VehicleCar        VehicleType = "car"
+)
+
+// vehicleSizes ordem de porte dos veículos; um veículo leva pedidos que exigem o seu porte ou menor
+var vehicleSizes = map[VehicleType]int{
+	VehicleBike:       1,
+	VehicleMotorcycle: 2,
+	VehicleCar:        3,
+}
+
+// Valid indica se o tipo é um dos veículos aceitos
+func (t VehicleType) Valid() bool {
+	_, ok := vehicleSizes[t]
+	return ok
+}
+
+// LegacyVehicleType converte o texto livre da antiga coluna users.vehicle ("moto", "carro",
+// "bicicleta") no tipo de veículo correspondente
+func LegacyVehicleType(value string) (VehicleType, bool) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "bicicleta", "bike":
+		return VehicleBike, true
+	case "moto", "motocicleta", "motorcycle":
+		return VehicleMotorcycle, true
+	case "carro", "car":
+		return VehicleCar, true
+	}
+	return "", false
+}
+
+// Carries indica se o veículo atende o porte exigido pelo pedido; vazio aceita qualquer veículo
+func (t VehicleType) Carries(required VehicleType) bool {
+	return required == "" || vehicleSizes[t] >= vehicleSizes[required]
+}
+
+// Larger retorna o maior dos dois portes exigidos
+func (t VehicleType) Larger(other VehicleType) VehicleType {
+	if vehicleSizes[other] > vehicleSizes[t] {
+		return other
+	}
+	return t
+}
+
+// NeedsPlate indica se o veículo tem placa (motos e carros)
+func (t VehicleType) NeedsPlate() bool {
+	return t == VehicleMotorcycle || t == VehicleCar
+}
+
+// DefaultCapacity caixas que o veículo leva quando o entregador não informa a capacidade
+func (t VehicleType) DefaultCapacity() int {
+	switch t {
+	case VehicleBike:
+		return 2
+	case VehicleMotorcycle:
+		return 4
+	case VehicleCar:
+		return 12
+	}
+	return 0
+}
+
+// VehicleDocumentStatus situação da verificação dos documentos do veículo pelo admin
+type VehicleDocumentStatus string
+
+const (
+	VehicleDocumentMissing  VehicleDocumentStatus = "missing" // Nenhum documento enviado
+	VehicleDocumentPending  VehicleDocumentStatus = "pending"
+	VehicleDocumentVerified VehicleDocumentStatus = "verified"
+	VehicleDocumentRejected VehicleDocumentStatus = "rejected"
+)
+
+// Vehicle veículo do entregador; cada entregador tem um. Trocar tipo ou placa, declarar refrigeração
+// ou aumentar a capacidade exige nova verificação.
+type Vehicle struct {
+	ID            uint        `json:"id" gorm:"primaryKey"`
+	CourierID     uint        `json:"courier_id" gorm:"uniqueIndex;not null"`
+	Type          VehicleType `json:"type" gorm:"type:varchar(20);not null"`
+	Plate         string      `json:"plate,omitempty" gorm:"type:varchar(7)"`
+	CapacityBoxes int         `json:"capacity_boxes"`
+	Refrigerated  bool        `json:"refrigerated"`
+
+	// Documento do veículo (CRLV ou nota da bicicleta) e a análise do admin
+	DocumentKey         string                `json:"-"`
+	DocumentContentType string                `json:"-"`
+	DocumentStatus      VehicleDocumentStatus `json:"document_status" gorm:"type:varchar(20);not null;index"`
+	DocumentUploadedAt  *time.Time            `json:"document_uploaded_at,omitempty"`
+	VerifiedByID        *uint                 `json:"verified_by_id,omitempty"`
+	VerifiedAt          *time.Time            `json:"verified_at,omitempty"`
+	RejectionReason     string                `json:"rejection_reason,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
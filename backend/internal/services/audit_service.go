@@ -0,0 +1,25 @@
+package services
+
+import (
+	"log"
+
+	"cupcake-delivery/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type AuditService struct {
+	db *gorm.DB
+}
+
+func NewAuditService(db *gorm.DB) *AuditService {
+	return &AuditService{db: db}
+}
+
+// Record grava a ação no log de auditoria; falhas são apenas registradas no log da aplicação
+// para não impedir o atendimento ao titular dos dados
+func (s *AuditService) Record(entry models.AuditLog) {
+	if err := s.db.Create(&entry).Error; err != nil {
+		log.Printf("Erro ao registrar auditoria %s do usuário %d: %v", entry.Action, entry.SubjectID, err)
+	}
+}
@@ -0,0 +1,379 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"cupcake-delivery/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrBatchNotFound    = errors.New("lote de entregas não encontrado")
+	ErrBatchTooLarge    = errors.New("lote excede o número máximo de paradas")
+	ErrCourierHasBatch  = errors.New("entregador já tem um lote em andamento")
+	ErrStopNotPending   = errors.New("parada não está pendente no lote")
+	ErrInvalidStopOrder = errors.New("sequência deve conter exatamente as paradas pendentes do lote")
+	ErrSameBatch        = errors.New("parada já pertence a este lote")
+)
+
+const (
+	// DefaultMaxBatchStops limite padrão de pedidos em um mesmo lote
+	DefaultMaxBatchStops = 5
+	// DefaultBatchStopTime tempo gasto em cada parada, somado às previsões das paradas seguintes
+	DefaultBatchStopTime = 3 * time.Minute
+)
+
+// BatchService lotes de entregas: vários pedidos prontos levados pelo mesmo entregador numa rota
+type BatchService struct {
+	db       *gorm.DB
+	store    *RoutePoint
+	loads    LoadChecker
+	maxStops int
+	now      func() time.Time
+}
+
+func NewBatchService(db *gorm.DB, maxStops int) *BatchService {
+	return &BatchService{db: db, maxStops: maxStops, now: time.Now}
+}
+
+// SetStoreLocation define a loja como ponto de partida das rotas de entregadores sem posição recente
+func (s *BatchService) SetStoreLocation(latitude, longitude float64) {
+	s.store = &RoutePoint{Latitude: latitude, Longitude: longitude}
+}
+
+// SetLoadChecker passa a exigir que o veículo do entregador comporte os pedidos do lote
+func (s *BatchService) SetLoadChecker(loads LoadChecker) {
+	s.loads = loads
+}
+
+// Create retira todos os pedidos para o entregador e monta a rota; se algum pedido não puder ser
+// retirado, nenhum é (OrderNotClaimableError). createdBy é o entregador ou o admin que montou o lote.
+func (s *BatchService) Create(courierID, createdBy uint, orderIDs []uint) (*models.DeliveryBatch, error) {
+	orderIDs = uniqueIDs(orderIDs)
+	if len(orderIDs) > s.maxStops {
+		return nil, ErrBatchTooLarge
+	}
+	now := s.now()
+	var batchID uint
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if s.loads != nil {
+			if err := s.loads.CheckLoad(tx, courierID, orderIDs); err != nil {
+				return err
+			}
+		}
+
+		var active int64
+		if err := tx.Model(&models.DeliveryBatch{}).
+			Where("courier_id = ? AND status = ?", courierID, models.BatchActive).
+			Count(&active).Error; err != nil {
+			return err
+		}
+		if active > 0 {
+			return ErrCourierHasBatch
+		}
+
+		claims := NewDBOrderClaimStore(tx)
+		for _, orderID := range orderIDs {
+			claimed, err := claims.ClaimReady(orderID, courierID, now, nil)
+			if err != nil {
+				return err
+			}
+			if !claimed {
+				return &OrderNotClaimableError{OrderID: orderID}
+			}
+		}
+
+		batch := models.DeliveryBatch{
+			CourierID:   courierID,
+			Status:      models.BatchActive,
+			CreatedByID: createdBy,
+		}
+		for _, orderID := range orderIDs {
+			batch.Stops = append(batch.Stops, models.DeliveryBatchStop{OrderID: orderID, Status: models.StopPending})
+		}
+		if err := tx.Create(&batch).Error; err != nil {
+			// O índice único parcial barra o lote criado em paralelo depois da contagem acima
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return ErrCourierHasBatch
+			}
+			return err
+		}
+		batchID = batch.ID
+
+		start, err := s.startPoint(tx, courierID, now)
+		if err != nil {
+			return err
+		}
+		return refreshBatch(tx, batchID, start, now, true)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Batch(batchID)
+}
+
+// Batch retorna o lote com as paradas na ordem da rota e os pedidos de cada uma
+func (s *BatchService) Batch(batchID uint) (*models.DeliveryBatch, error) {
+	var batch models.DeliveryBatch
+	err := s.db.Preload("Stops", func(db *gorm.DB) *gorm.DB { return db.Order("sequence") }).
+		Preload("Stops.Order").
+		First(&batch, batchID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrBatchNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// ActiveBatch retorna o lote em andamento do entregador
+func (s *BatchService) ActiveBatch(courierID uint) (*models.DeliveryBatch, error) {
+	var batch models.DeliveryBatch
+	err := s.db.Select("id").Where("courier_id = ? AND status = ?", courierID, models.BatchActive).First(&batch).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrBatchNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.Batch(batch.ID)
+}
+
+// ActiveBatches lista os lotes em andamento, para o acompanhamento pelo admin
+func (s *BatchService) ActiveBatches() ([]models.DeliveryBatch, error) {
+	var batches []models.DeliveryBatch
+	err := s.db.Preload("Stops", func(db *gorm.DB) *gorm.DB { return db.Order("sequence") }).
+		Where("status = ?", models.BatchActive).
+		Order("created_at").
+		Find(&batches).Error
+	return batches, err
+}
+
+// Move passa uma parada pendente para o lote em andamento de outro entregador, que assume o pedido.
+// As duas rotas são replanejadas.
+func (s *BatchService) Move(batchID, orderID, targetBatchID uint) error {
+	if batchID == targetBatchID {
+		return ErrSameBatch
+	}
+
+	now := s.now()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		source, err := activeBatch(tx, batchID)
+		if err != nil {
+			return err
+		}
+		target, err := activeBatch(tx, targetBatchID)
+		if err != nil {
+			return err
+		}
+
+		var pending int64
+		if err := tx.Model(&models.DeliveryBatchStop{}).
+			Where("batch_id = ? AND status = ?", target.ID, models.StopPending).
+			Count(&pending).Error; err != nil {
+			return err
+		}
+		if int(pending)+1 > s.maxStops {
+			return ErrBatchTooLarge
+		}
+		if s.loads != nil {
+			if err := s.loads.CheckLoad(tx, target.CourierID, []uint{orderID}); err != nil {
+				return err
+			}
+		}
+
+		stop := tx.Model(&models.DeliveryBatchStop{}).
+			Where("batch_id = ? AND order_id = ? AND status = ?", source.ID, orderID, models.StopPending).
+			Update("batch_id", target.ID)
+		if stop.Error != nil {
+			return stop.Error
+		}
+		if stop.RowsAffected == 0 {
+			return ErrStopNotPending
+		}
+
+		order := tx.Model(&models.Order{}).
+			Where("id = ? AND status = ? AND delivery_id = ?", orderID, models.StatusDelivering, source.CourierID).
+			Updates(map[string]interface{}{"delivery_id": target.CourierID, "updated_at": now})
+		if order.Error != nil {
+			return order.Error
+		}
+		if order.RowsAffected == 0 {
+			return ErrStopNotPending
+		}
+
+		for _, batch := range []*models.DeliveryBatch{source, target} {
+			start, err := s.startPoint(tx, batch.CourierID, now)
+			if err != nil {
+				return err
+			}
+			if err := refreshBatch(tx, batch.ID, start, now, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Reorder define à mão a ordem das paradas pendentes; orderIDs deve conter todas elas
+func (s *BatchService) Reorder(batchID uint, orderIDs []uint) error {
+	now := s.now()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		batch, err := activeBatch(tx, batchID)
+		if err != nil {
+			return err
+		}
+
+		var stops []models.DeliveryBatchStop
+		if err := tx.Where("batch_id = ? AND status = ?", batch.ID, models.StopPending).Find(&stops).Error; err != nil {
+			return err
+		}
+		sequence := make(map[uint]int, len(orderIDs))
+		for i, orderID := range uniqueIDs(orderIDs) {
+			sequence[orderID] = i
+		}
+		if len(sequence) != len(orderIDs) || len(sequence) != len(stops) {
+			return ErrInvalidStopOrder
+		}
+		for _, stop := range stops {
+			position, ok := sequence[stop.OrderID]
+			if !ok {
+				return ErrInvalidStopOrder
+			}
+			// Sequências provisórias após as atuais; refreshBatch renumera a partir das paradas concluídas
+			if err := tx.Model(&stop).Update("sequence", 1000+position).Error; err != nil {
+				return err
+			}
+		}
+
+		start, err := s.startPoint(tx, batch.CourierID, now)
+		if err != nil {
+			return err
+		}
+		return refreshBatch(tx, batch.ID, start, now, false)
+	})
+}
+
+// CloseStop tira da rota o pedido que saiu de entrega por outro caminho (status alterado pelo admin)
+func (s *BatchService) CloseStop(order *models.Order) error {
+	status := models.StopRemoved
+	if order.Status == models.StatusDelivered {
+		status = models.StopDelivered
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return completeBatchStop(tx, order, status, s.now())
+	})
+}
+
+// startPoint posição recente do entregador ou, sem ela, a loja
+func (s *BatchService) startPoint(tx *gorm.DB, courierID uint, at time.Time) (*RoutePoint, error) {
+	var position models.CourierPosition
+	err := tx.Where("courier_id = ? AND recorded_at >= ?", courierID, at.Add(-positionMaxAge)).First(&position).Error
+	if err == nil {
+		return &RoutePoint{Latitude: position.Latitude, Longitude: position.Longitude}, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return s.store, nil
+}
+
+func activeBatch(tx *gorm.DB, batchID uint) (*models.DeliveryBatch, error) {
+	var batch models.DeliveryBatch
+	err := tx.Where("id = ? AND status = ?", batchID, models.BatchActive).First(&batch).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrBatchNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// refreshBatch renumera as paradas pendentes depois das concluídas e recalcula trechos e previsões
+// saindo de start em at; com optimize, replaneja a ordem. Sem paradas pendentes, conclui o lote.
+func refreshBatch(tx *gorm.DB, batchID uint, start *RoutePoint, at time.Time, optimize bool) error {
+	var stops []models.DeliveryBatchStop
+	if err := tx.Preload("Order").Where("batch_id = ?", batchID).Order("sequence").Find(&stops).Error; err != nil {
+		return err
+	}
+
+	var route []RouteStop
+	done, distance := 0, 0.0
+	for _, stop := range stops {
+		if stop.Status != models.StopPending {
+			done++
+			distance += stop.LegKm
+			continue
+		}
+		routeStop := RouteStop{OrderID: stop.OrderID}
+		if stop.Order != nil {
+			routeStop.Latitude, routeStop.Longitude = stop.Order.Latitude, stop.Order.Longitude
+		}
+		route = append(route, routeStop)
+	}
+
+	if len(route) == 0 {
+		return tx.Model(&models.DeliveryBatch{}).
+			Where("id = ? AND status = ?", batchID, models.BatchActive).
+			Updates(map[string]interface{}{"status": models.BatchCompleted, "completed_at": at, "updated_at": at}).Error
+	}
+
+	if optimize {
+		route = PlanRoute(start, route)
+	}
+	legs := RouteLegsKm(start, route)
+	arrivals := EstimateStopArrivals(legs, at, DefaultCourierSpeedKmh, DefaultBatchStopTime)
+	for i, stop := range route {
+		leg := math.Round(legs[i]*100) / 100
+		distance += leg
+		if err := tx.Model(&models.DeliveryBatchStop{}).
+			Where("batch_id = ? AND order_id = ? AND status = ?", batchID, stop.OrderID, models.StopPending).
+			Updates(map[string]interface{}{"sequence": done + i + 1, "leg_km": leg, "eta": arrivals[i]}).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Model(&models.DeliveryBatch{}).
+		Where("id = ?", batchID).
+		Updates(map[string]interface{}{"distance_km": math.Round(distance*100) / 100, "updated_at": at}).Error
+}
+
+// completeBatchStop marca a parada do pedido como concluída, se ele estiver em um lote, e recalcula
+// as previsões das paradas seguintes a partir do endereço do pedido
+func completeBatchStop(tx *gorm.DB, order *models.Order, status models.BatchStopStatus, at time.Time) error {
+	var stop models.DeliveryBatchStop
+	err := tx.Where("order_id = ? AND status = ?", order.ID, models.StopPending).First(&stop).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Model(&stop).Updates(map[string]interface{}{"status": status, "completed_at": at}).Error; err != nil {
+		return err
+	}
+
+	var start *RoutePoint
+	if order.Latitude != nil && order.Longitude != nil {
+		start = &RoutePoint{Latitude: *order.Latitude, Longitude: *order.Longitude}
+	}
+	return refreshBatch(tx, stop.BatchID, start, at, false)
+}
+
+// uniqueIDs remove IDs repetidos mantendo a ordem
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cupcake-delivery/internal/models"
+)
+
+// TestBatchServiceConcurrentCreate monta dois lotes para o mesmo entregador ao mesmo tempo, com pedidos
+// diferentes: o índice único parcial deixa só um ficar ativo
+func TestBatchServiceConcurrentCreate(t *testing.T) {
+	db := openTestDB(t)
+
+	suffix := time.Now().Format("150405.000000")
+	customer := models.User{Name: "Cliente", Email: "batch-customer-" + suffix + "@example.com", Type: models.CustomerType}
+	courier := models.User{Name: "Entregador", Email: "batch-courier-" + suffix + "@example.com", Type: models.DeliveryType}
+	for _, user := range []*models.User{&customer, &courier} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	orders := make([]models.Order, 8)
+	for i := range orders {
+		orders[i] = models.Order{CustomerID: customer.ID, Status: models.StatusReady}
+		if err := db.Create(&orders[i]).Error; err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	t.Cleanup(func() {
+		var batchIDs []uint
+		db.Model(&models.DeliveryBatch{}).Where("courier_id = ?", courier.ID).Pluck("id", &batchIDs)
+		db.Where("batch_id IN ?", batchIDs).Delete(&models.DeliveryBatchStop{})
+		db.Where("courier_id = ?", courier.ID).Delete(&models.DeliveryBatch{})
+		db.Where("order_id IN (?)", db.Model(&models.Order{}).Select("id").Where("customer_id = ?", customer.ID)).Delete(&models.OrderStatusChange{})
+		db.Unscoped().Where("customer_id = ?", customer.ID).Delete(&models.Order{})
+		db.Unscoped().Delete(&[]models.User{customer, courier})
+	})
+
+	service := NewBatchService(db, DefaultMaxBatchStops)
+	var created, rejected int64
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < len(orders); i += 2 {
+		wg.Add(1)
+		go func(orderIDs []uint) {
+			defer wg.Done()
+			<-start
+			_, err := service.Create(courier.ID, courier.ID, orderIDs)
+			switch {
+			case err == nil:
+				atomic.AddInt64(&created, 1)
+			case errors.Is(err, ErrCourierHasBatch):
+				atomic.AddInt64(&rejected, 1)
+			default:
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}([]uint{orders[i].ID, orders[i+1].ID})
+	}
+	close(start)
+	wg.Wait()
+
+	var active int64
+	db.Model(&models.DeliveryBatch{}).Where("courier_id = ? AND status = ?", courier.ID, models.BatchActive).Count(&active)
+	if created != 1 || rejected != 3 || active != 1 {
+		t.Errorf("Expected 1 active batch and 3 rejections, got %d created, %d rejected, %d active", created, rejected, active)
+	}
+}
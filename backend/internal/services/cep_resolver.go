@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"cupcake-delivery/internal/models"
+	"cupcake-delivery/internal/validators"
+)
+
+var ErrCEPNotFound = errors.New("CEP não encontrado")
+
+// CEPResolver preenche logradouro, bairro, cidade e UF a partir de um CEP
+type CEPResolver interface {
+	Resolve(ctx context.Context, cep string) (*models.PostalAddress, error)
+}
+
+// CompletePostalAddress normaliza o endereço e preenche os campos vazios com os dados do CEP.
+// Campos informados pelo usuário têm prioridade sobre os do resolvedor.
+func CompletePostalAddress(ctx context.Context, resolver CEPResolver, address *models.PostalAddress) error {
+	address.CEP = validators.NormalizeCEP(address.CEP)
+	address.State = strings.ToUpper(strings.TrimSpace(address.State))
+	address.Number = strings.ToUpper(strings.TrimSpace(address.Number))
+	address.Street = strings.TrimSpace(address.Street)
+	address.Complement = strings.TrimSpace(address.Complement)
+	address.Neighborhood = strings.TrimSpace(address.Neighborhood)
+	address.City = strings.TrimSpace(address.City)
+
+	if resolver == nil || len(address.CEP) != 8 {
+		return nil
+	}
+	if address.Street != "" && address.Neighborhood != "" && address.City != "" && address.State != "" {
+		return nil
+	}
+
+	found, err := resolver.Resolve(ctx, address.CEP)
+	if err != nil {
+		return err
+	}
+
+	if address.Street == "" {
+		address.Street = found.Street
+	}
+	if address.Neighborhood == "" {
+		address.Neighborhood = found.Neighborhood
+	}
+	if address.City == "" {
+		address.City = found.City
+	}
+	if address.State == "" {
+		address.State = found.State
+	}
+	return nil
+}
+
+// HTTPCEPResolver consulta um serviço no formato do ViaCEP (/ws/{cep}/json/)
+type HTTPCEPResolver struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+func NewHTTPCEPResolver(baseURL string) *HTTPCEPResolver {
+	return &HTTPCEPResolver{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Client:  &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+type viaCEPResponse struct {
+	CEP        string `json:"cep"`
+	Logradouro string `json:"logradouro"`
+	Bairro     string `json:"bairro"`
+	Localidade string `json:"localidade"`
+	UF         string `json:"uf"`
+	Erro       any    `json:"erro"`
+}
+
+func (r *HTTPCEPResolver) Resolve(ctx context.Context, cep string) (*models.PostalAddress, error) {
+	cep = validators.NormalizeCEP(cep)
+	if len(cep) != 8 {
+		return nil, ErrCEPNotFound
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/ws/%s/json/", r.BaseURL, cep), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusBadRequest {
+		return nil, ErrCEPNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("serviço de CEP respondeu %d", resp.StatusCode)
+	}
+
+	var body viaCEPResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	// O ViaCEP responde 200 com {"erro": true} (ou "true") para CEPs inexistentes
+	if body.Erro != nil && body.Erro != false {
+		return nil, ErrCEPNotFound
+	}
+
+	return &models.PostalAddress{
+		CEP:          cep,
+		Street:       body.Logradouro,
+		Neighborhood: body.Bairro,
+		City:         body.Localidade,
+		State:        body.UF,
+	}, nil
+}
+
+// OfflineCEPResolver resolve CEPs a partir de uma base importada em memória
+type OfflineCEPResolver struct {
+	mu      sync.RWMutex
+	entries map[string]models.PostalAddress
+}
+
+func NewOfflineCEPResolver() *OfflineCEPResolver {
+	return &OfflineCEPResolver{entries: make(map[string]models.PostalAddress)}
+}
+
+// LoadOfflineCEPResolver cria o resolvedor importando o arquivo CSV informado
+func LoadOfflineCEPResolver(path string) (*OfflineCEPResolver, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	resolver := NewOfflineCEPResolver()
+	if _, err := resolver.Import(file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return resolver, nil
+}
+
+// Import lê um CSV com cabeçalho cep,logradouro,bairro,cidade,uf e retorna quantos CEPs foram importados.
+// CEPs repetidos substituem os anteriores.
+func (r *OfflineCEPResolver) Import(reader io.Reader) (int, error) {
+	records := csv.NewReader(reader)
+	records.TrimLeadingSpace = true
+
+	header, err := records.Read()
+	if err != nil {
+		return 0, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"cep", "logradouro", "bairro", "cidade", "uf"} {
+		if _, ok := columns[required]; !ok {
+			return 0, fmt.Errorf("coluna obrigatória ausente: %s", required)
+		}
+	}
+
+	imported := make(map[string]models.PostalAddress)
+	for line := 2; ; line++ {
+		record, err := records.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		cep := validators.NormalizeCEP(record[columns["cep"]])
+		if len(cep) != 8 {
+			return 0, fmt.Errorf("CEP inválido na linha %d: %q", line, record[columns["cep"]])
+		}
+		imported[cep] = models.PostalAddress{
+			CEP:          cep,
+			Street:       strings.TrimSpace(record[columns["logradouro"]]),
+			Neighborhood: strings.TrimSpace(record[columns["bairro"]]),
+			City:         strings.TrimSpace(record[columns["cidade"]]),
+			State:        strings.ToUpper(strings.TrimSpace(record[columns["uf"]])),
+		}
+	}
+
+	r.mu.Lock()
+	for cep, address := range imported {
+		r.entries[cep] = address
+	}
+	r.mu.Unlock()
+
+	return len(imported), nil
+}
+
+func (r *OfflineCEPResolver) Resolve(ctx context.Context, cep string) (*models.PostalAddress, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	address, ok := r.entries[validators.NormalizeCEP(cep)]
+	if !ok {
+		return nil, ErrCEPNotFound
+	}
+	return &address, nil
+}
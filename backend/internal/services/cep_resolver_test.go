@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"cupcake-delivery/internal/models"
+)
+
+const testCEPDataset = `cep,logradouro,bairro,cidade,uf
+01310-100,Avenida Paulista,Bela Vista,São Paulo,sp
+20040020,Avenida Rio Branco,Centro,Rio de Janeiro,RJ
+`
+
+func TestOfflineCEPResolver(t *testing.T) {
+	resolver := NewOfflineCEPResolver()
+	imported, err := resolver.Import(strings.NewReader(testCEPDataset))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if imported != 2 {
+		t.Errorf("Expected 2 imported CEPs, got %d", imported)
+	}
+
+	address, err := resolver.Resolve(context.Background(), "01310100")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if address.Street != "Avenida Paulista" || address.City != "São Paulo" || address.State != "SP" {
+		t.Errorf("Unexpected address: %+v", address)
+	}
+
+	if _, err := resolver.Resolve(context.Background(), "99999-999"); !errors.Is(err, ErrCEPNotFound) {
+		t.Errorf("Expected ErrCEPNotFound, got %v", err)
+	}
+}
+
+func TestOfflineCEPResolverImportErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		dataset string
+	}{
+		{"Missing column", "cep,logradouro,bairro,cidade\n01310100,Avenida Paulista,Bela Vista,São Paulo\n"},
+		{"Invalid CEP", "cep,logradouro,bairro,cidade,uf\n0131,Avenida Paulista,Bela Vista,São Paulo,SP\n"},
+		{"Empty file", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewOfflineCEPResolver().Import(strings.NewReader(tc.dataset)); err == nil {
+				t.Errorf("Expected import error")
+			}
+		})
+	}
+}
+
+func TestCompletePostalAddress(t *testing.T) {
+	resolver := NewOfflineCEPResolver()
+	if _, err := resolver.Import(strings.NewReader(testCEPDataset)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Campos informados pelo usuário são mantidos; os vazios vêm do CEP
+	address := models.PostalAddress{CEP: " 01310-100 ", Number: "s/n", Street: "Al. Santos"}
+	if err := CompletePostalAddress(context.Background(), resolver, &address); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := models.PostalAddress{
+		CEP:          "01310100",
+		Street:       "Al. Santos",
+		Number:       "S/N",
+		Neighborhood: "Bela Vista",
+		City:         "São Paulo",
+		State:        "SP",
+	}
+	if address != expected {
+		t.Errorf("Expected %+v, got %+v", expected, address)
+	}
+
+	unknown := models.PostalAddress{CEP: "99999999"}
+	if err := CompletePostalAddress(context.Background(), resolver, &unknown); !errors.Is(err, ErrCEPNotFound) {
+		t.Errorf("Expected ErrCEPNotFound, got %v", err)
+	}
+}
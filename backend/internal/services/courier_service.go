@@ -0,0 +1,258 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"cupcake-delivery/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrNoActiveShift = errors.New("entregador sem turno em andamento")
+	ErrShiftOverlap  = errors.New("turno sobreposto a outro turno do entregador")
+)
+
+// CourierRosterEntry situação de um entregador para o painel dos administradores
+type CourierRosterEntry struct {
+	CourierID        uint                 `json:"courier_id"`
+	Name             string               `json:"name"`
+	Phone            string               `json:"phone,omitempty"`
+	Status           models.CourierStatus `json:"status"`
+	Since            *time.Time           `json:"since,omitempty"`
+	Online           bool                 `json:"online"` // Online e dentro de um turno
+	CurrentShift     *models.CourierShift `json:"current_shift,omitempty"`
+	ActiveDeliveries int64                `json:"active_deliveries"`
+}
+
+// CourierService controla turnos e disponibilidade dos entregadores
+type CourierService struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+func NewCourierService(db *gorm.DB) *CourierService {
+	return &CourierService{db: db, now: time.Now}
+}
+
+// SetStatus altera a disponibilidade do entregador; ficar online exige um turno em andamento
+func (s *CourierService) SetStatus(courierID uint, status models.CourierStatus) (*models.CourierAvailability, error) {
+	now := s.now()
+	if status == models.CourierOnline {
+		shift, err := s.CurrentShift(courierID)
+		if err != nil {
+			return nil, err
+		}
+		if shift == nil {
+			return nil, ErrNoActiveShift
+		}
+	}
+
+	availability := models.CourierAvailability{UserID: courierID}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(models.CourierAvailability{UserID: courierID}).
+			Attrs(models.CourierAvailability{Status: models.CourierOffline, Since: now}).
+			FirstOrCreate(&availability).Error; err != nil {
+			return err
+		}
+		if availability.Status == status {
+			return nil
+		}
+		availability.Status = status
+		availability.Since = now
+		return tx.Save(&availability).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &availability, nil
+}
+
+// Status retorna a disponibilidade registrada; sem registro o entregador está offline
+func (s *CourierService) Status(courierID uint) (*models.CourierAvailability, error) {
+	var availability models.CourierAvailability
+	err := s.db.First(&availability, "user_id = ?", courierID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.CourierAvailability{UserID: courierID, Status: models.CourierOffline}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &availability, nil
+}
+
+// CurrentShift retorna o turno em andamento do entregador, ou nil
+func (s *CourierService) CurrentShift(courierID uint) (*models.CourierShift, error) {
+	now := s.now()
+	var shift models.CourierShift
+	err := s.db.Where("courier_id = ? AND starts_at <= ? AND ends_at > ?", courierID, now, now).
+		Order("starts_at").
+		First(&shift).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &shift, nil
+}
+
+// IsOnline indica se o entregador está online e dentro de um turno
+func (s *CourierService) IsOnline(courierID uint) (bool, error) {
+	var count int64
+	if err := s.onlineQuery().Where("courier_availabilities.user_id = ?", courierID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// OnlineCourierIDs lista os entregadores online e dentro de um turno
+func (s *CourierService) OnlineCourierIDs() ([]uint, error) {
+	var ids []uint
+	if err := s.onlineQuery().Distinct().Pluck("courier_availabilities.user_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (s *CourierService) onlineQuery() *gorm.DB {
+	now := s.now()
+	return s.db.Model(&models.CourierAvailability{}).
+		Joins("JOIN courier_shifts ON courier_shifts.courier_id = courier_availabilities.user_id AND courier_shifts.deleted_at IS NULL AND courier_shifts.starts_at <= ? AND courier_shifts.ends_at > ?", now, now).
+		Where("courier_availabilities.status = ?", models.CourierOnline)
+}
+
+// ListShifts lista os turnos que terminam após from e começam antes de to; courierID zero lista de todos
+func (s *CourierService) ListShifts(courierID uint, from, to time.Time) ([]models.CourierShift, error) {
+	query := s.db.Preload("Courier").Where("ends_at > ? AND starts_at < ?", from, to)
+	if courierID != 0 {
+		query = query.Where("courier_id = ?", courierID)
+	}
+
+	var shifts []models.CourierShift
+	if err := query.Order("starts_at").Find(&shifts).Error; err != nil {
+		return nil, err
+	}
+	return shifts, nil
+}
+
+// CreateShift agenda um turno, recusando sobreposição com outro turno do mesmo entregador
+func (s *CourierService) CreateShift(shift *models.CourierShift) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// Trava o entregador para dois agendamentos simultâneos não passarem ambos pela checagem
+		var courier models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&courier, shift.CourierID).Error; err != nil {
+			return err
+		}
+
+		var overlapping int64
+		if err := tx.Model(&models.CourierShift{}).
+			Where("courier_id = ? AND starts_at < ? AND ends_at > ?", shift.CourierID, shift.EndsAt, shift.StartsAt).
+			Count(&overlapping).Error; err != nil {
+			return err
+		}
+		if overlapping > 0 {
+			return ErrShiftOverlap
+		}
+		return tx.Create(shift).Error
+	})
+}
+
+// DeleteShift remove um turno agendado
+func (s *CourierService) DeleteShift(id uint) error {
+	result := s.db.Delete(&models.CourierShift{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Roster monta o painel com todos os entregadores, os online primeiro
+func (s *CourierService) Roster() ([]CourierRosterEntry, error) {
+	var couriers []models.User
+	if err := s.db.Where("type = ?", models.DeliveryType).Order("name").Find(&couriers).Error; err != nil {
+		return nil, err
+	}
+
+	var availabilities []models.CourierAvailability
+	if err := s.db.Find(&availabilities).Error; err != nil {
+		return nil, err
+	}
+	byCourier := make(map[uint]models.CourierAvailability, len(availabilities))
+	for _, availability := range availabilities {
+		byCourier[availability.UserID] = availability
+	}
+
+	now := s.now()
+	var shifts []models.CourierShift
+	if err := s.db.Where("starts_at <= ? AND ends_at > ?", now, now).Find(&shifts).Error; err != nil {
+		return nil, err
+	}
+	shiftByCourier := make(map[uint]models.CourierShift, len(shifts))
+	for _, shift := range shifts {
+		shiftByCourier[shift.CourierID] = shift
+	}
+
+	var deliveries []struct {
+		DeliveryID uint
+		Count      int64
+	}
+	if err := s.db.Model(&models.Order{}).
+		Select("delivery_id, COUNT(*) AS count").
+		Where("delivery_id IS NOT NULL AND status = ?", models.StatusDelivering).
+		Group("delivery_id").
+		Scan(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	deliveriesByCourier := make(map[uint]int64, len(deliveries))
+	for _, delivery := range deliveries {
+		deliveriesByCourier[delivery.DeliveryID] = delivery.Count
+	}
+
+	roster := make([]CourierRosterEntry, 0, len(couriers))
+	for _, courier := range couriers {
+		entry := CourierRosterEntry{
+			CourierID:        courier.ID,
+			Name:             courier.Name,
+			Phone:            courier.Phone,
+			Status:           models.CourierOffline,
+			ActiveDeliveries: deliveriesByCourier[courier.ID],
+		}
+		if availability, ok := byCourier[courier.ID]; ok {
+			since := availability.Since
+			entry.Status = availability.Status
+			entry.Since = &since
+		}
+		if shift, ok := shiftByCourier[courier.ID]; ok {
+			entry.CurrentShift = &shift
+		}
+		entry.Online = entry.Status == models.CourierOnline && entry.CurrentShift != nil
+		roster = append(roster, entry)
+	}
+
+	sortRoster(roster)
+	return roster, nil
+}
+
+// sortRoster ordena online, depois em pausa, depois offline, mantendo a ordem por nome dentro de cada grupo
+func sortRoster(roster []CourierRosterEntry) {
+	rank := func(entry CourierRosterEntry) int {
+		switch {
+		case entry.Online:
+			return 0
+		case entry.Status == models.CourierBreak:
+			return 1
+		default:
+			return 2
+		}
+	}
+	sort.SliceStable(roster, func(i, j int) bool {
+		return rank(roster[i]) < rank(roster[j])
+	})
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cupcake-delivery/internal/models"
+)
+
+func TestSortRoster(t *testing.T) {
+	shift := &models.CourierShift{}
+	roster := []CourierRosterEntry{
+		{Name: "Ana", Status: models.CourierOffline},
+		{Name: "Bruno", Status: models.CourierBreak, CurrentShift: shift},
+		{Name: "Carla", Status: models.CourierOnline, CurrentShift: shift, Online: true},
+		{Name: "Davi", Status: models.CourierOnline}, // Online sem turno conta como offline
+		{Name: "Eva", Status: models.CourierOnline, CurrentShift: shift, Online: true},
+	}
+
+	sortRoster(roster)
+
+	expected := []string{"Carla", "Eva", "Bruno", "Ana", "Davi"}
+	for i, name := range expected {
+		if roster[i].Name != name {
+			t.Fatalf("Expected order %v, got %+v", expected, roster)
+		}
+	}
+}
+
+// TestCourierServiceConcurrentOverlappingShifts agenda o mesmo turno em paralelo; só um pode entrar.
+func TestCourierServiceConcurrentOverlappingShifts(t *testing.T) {
+	db := openTestDB(t)
+
+	courier := models.User{Name: "Entregador", Email: "shift-test-" + time.Now().Format("150405.000000") + "@example.com", Type: models.DeliveryType}
+	if err := db.Create(&courier).Error; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Unscoped().Where("courier_id = ?", courier.ID).Delete(&models.CourierShift{})
+		db.Unscoped().Delete(&courier)
+	})
+
+	service := NewCourierService(db)
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+
+	var created, overlaps int64
+	var wg sync.WaitGroup
+	ready := make(chan struct{})
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(offset time.Duration) {
+			defer wg.Done()
+			<-ready
+			shift := models.CourierShift{CourierID: courier.ID, StartsAt: start.Add(offset), EndsAt: start.Add(offset + 4*time.Hour)}
+			switch err := service.CreateShift(&shift); {
+			case err == nil:
+				atomic.AddInt64(&created, 1)
+			case errors.Is(err, ErrShiftOverlap):
+				atomic.AddInt64(&overlaps, 1)
+			default:
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}(time.Duration(i) * time.Minute)
+	}
+	close(ready)
+	wg.Wait()
+
+	if created != 1 || overlaps != 15 {
+		t.Errorf("Expected 1 shift and 15 overlaps, got %d and %d", created, overlaps)
+	}
+}
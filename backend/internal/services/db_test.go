@@ -0,0 +1,28 @@
+package services
+
+import (
+	"os"
+	"testing"
+
+	"cupcake-delivery/internal/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB conecta ao PostgreSQL de TEST_DATABASE_URL com as mesmas migrações da aplicação.
+// Sem a variável o teste é pulado; use um banco descartável.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL não definido")
+	}
+
+	db, err := database.Connect(url)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return db.Session(&gorm.Session{Logger: logger.Default.LogMode(logger.Silent)})
+}
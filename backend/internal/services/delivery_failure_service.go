@@ -0,0 +1,266 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"cupcake-delivery/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNoFailedDelivery = errors.New("pedido não tem entrega malsucedida com este entregador")
+	ErrOrderNotReturned = errors.New("pedido não voltou para a loja")
+)
+
+// FailureStats tentativas sem sucesso de um entregador ou cliente no período
+type FailureStats struct {
+	ID          uint                                 `json:"id"`
+	Name        string                               `json:"name"`
+	Failed      int                                  `json:"failed_attempts"`
+	Delivered   int                                  `json:"delivered"`
+	FailureRate float64                              `json:"failure_rate"` // Falhas / (falhas + entregas)
+	ByReason    map[models.DeliveryFailureReason]int `json:"by_reason"`
+}
+
+// DeliveryFailureReport métricas de entregas malsucedidas para os administradores
+type DeliveryFailureReport struct {
+	From      time.Time                            `json:"from"`
+	To        time.Time                            `json:"to"`
+	Failed    int                                  `json:"failed_attempts"`
+	ByReason  map[models.DeliveryFailureReason]int `json:"by_reason"`
+	Couriers  []FailureStats                       `json:"couriers"`
+	Customers []FailureStats                       `json:"customers"`
+}
+
+// DeliveryFailureService registra entregas malsucedidas, devoluções e reagendamentos
+type DeliveryFailureService struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+func NewDeliveryFailureService(db *gorm.DB) *DeliveryFailureService {
+	return &DeliveryFailureService{db: db, now: time.Now}
+}
+
+// Fail registra que o entregador não conseguiu entregar o pedido
+func (s *DeliveryFailureService) Fail(orderID, courierID uint, reason models.DeliveryFailureReason, notes string) (*models.Order, error) {
+	now := s.now()
+	var order models.Order
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&order, orderID).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&models.Order{}).
+			Where("id = ? AND status = ? AND delivery_id = ?", orderID, models.StatusDelivering, courierID).
+			Updates(map[string]interface{}{
+				"status":                  models.StatusDeliveryFailed,
+				"status_changed_at":       now,
+				"delivery_failure_reason": reason,
+				"updated_at":              now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOrderNotDeliverable
+		}
+		if err := tx.Create(&models.DeliveryAttempt{
+			OrderID:    orderID,
+			CourierID:  courierID,
+			CustomerID: order.CustomerID,
+			Reason:     reason,
+			Notes:      notes,
+			FailedAt:   now,
+		}).Error; err != nil {
+			return err
+		}
+		if err := RecordStatusChange(tx, orderID, models.StatusDeliveryFailed, now); err != nil {
+			return err
+		}
+		return completeBatchStop(tx, &order, models.StopFailed, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	order.Status = models.StatusDeliveryFailed
+	order.StatusChangedAt = &now
+	order.DeliveryFailureReason = reason
+	order.UpdatedAt = now
+	return &order, nil
+}
+
+// Return registra que o entregador trouxe de volta à loja o pedido que não foi entregue
+func (s *DeliveryFailureService) Return(orderID, courierID uint) (*models.Order, error) {
+	now := s.now()
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Order{}).
+			Where("id = ? AND status = ? AND delivery_id = ?", orderID, models.StatusDeliveryFailed, courierID).
+			Updates(map[string]interface{}{"status": models.StatusReturned, "status_changed_at": now, "updated_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNoFailedDelivery
+		}
+		if err := RecordStatusChange(tx, orderID, models.StatusReturned, now); err != nil {
+			return err
+		}
+		return tx.Model(&models.DeliveryAttempt{}).
+			Where("order_id = ? AND returned_at IS NULL", orderID).
+			Update("returned_at", now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var order models.Order
+	if err := s.db.First(&order, orderID).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// Reschedule devolve à fila de retirada um pedido que voltou para a loja, sem entregador.
+// Com scheduledFor o pedido só fica disponível para retirada a partir desse horário.
+func (s *DeliveryFailureService) Reschedule(orderID uint, scheduledFor *time.Time) (*models.Order, error) {
+	now := s.now()
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Order{}).
+			Where("id = ? AND status = ?", orderID, models.StatusReturned).
+			Updates(map[string]interface{}{
+				"status":                models.StatusReady,
+				"status_changed_at":     now,
+				"delivery_id":           nil,
+				"scheduled_for":         scheduledFor,
+				"delivery_pin_attempts": 0,
+				"updated_at":            now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOrderNotReturned
+		}
+		return RecordStatusChange(tx, orderID, models.StatusReady, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var order models.Order
+	if err := s.db.First(&order, orderID).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// Attempts lista as tentativas sem sucesso do pedido
+func (s *DeliveryFailureService) Attempts(orderID uint) ([]models.DeliveryAttempt, error) {
+	var attempts []models.DeliveryAttempt
+	err := s.db.Where("order_id = ?", orderID).Order("failed_at").Find(&attempts).Error
+	return attempts, err
+}
+
+// Report monta as métricas de falhas de entrega por entregador e por cliente entre from e to
+func (s *DeliveryFailureService) Report(from, to time.Time) (*DeliveryFailureReport, error) {
+	var attempts []models.DeliveryAttempt
+	if err := s.db.Where("failed_at >= ? AND failed_at < ?", from, to).Find(&attempts).Error; err != nil {
+		return nil, err
+	}
+
+	var deliveries []struct {
+		CourierID  uint
+		CustomerID uint
+		Count      int
+	}
+	if err := s.db.Table("delivery_proofs").
+		Select("delivery_proofs.courier_id, orders.customer_id, COUNT(*) AS count").
+		Joins("JOIN orders ON orders.id = delivery_proofs.order_id").
+		Where("delivery_proofs.delivered_at >= ? AND delivery_proofs.delivered_at < ?", from, to).
+		Group("delivery_proofs.courier_id, orders.customer_id").
+		Scan(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	deliveredByCourier := make(map[uint]int)
+	deliveredByCustomer := make(map[uint]int)
+	for _, delivery := range deliveries {
+		deliveredByCourier[delivery.CourierID] += delivery.Count
+		deliveredByCustomer[delivery.CustomerID] += delivery.Count
+	}
+
+	report := summarizeDeliveryFailures(attempts, deliveredByCourier, deliveredByCustomer)
+	report.From, report.To = from, to
+
+	var ids []uint
+	for _, stats := range append(report.Couriers, report.Customers...) {
+		ids = append(ids, stats.ID)
+	}
+	if len(ids) > 0 {
+		var users []models.User
+		if err := s.db.Select("id", "name").Where("id IN ?", ids).Find(&users).Error; err != nil {
+			return nil, err
+		}
+		names := make(map[uint]string, len(users))
+		for _, user := range users {
+			names[user.ID] = user.Name
+		}
+		for i := range report.Couriers {
+			report.Couriers[i].Name = names[report.Couriers[i].ID]
+		}
+		for i := range report.Customers {
+			report.Customers[i].Name = names[report.Customers[i].ID]
+		}
+	}
+	return &report, nil
+}
+
+// summarizeDeliveryFailures agrupa as tentativas por entregador e por cliente, com quem mais falhou primeiro.
+// Só entram no relatório entregadores e clientes com pelo menos uma falha.
+func summarizeDeliveryFailures(attempts []models.DeliveryAttempt, deliveredByCourier, deliveredByCustomer map[uint]int) DeliveryFailureReport {
+	report := DeliveryFailureReport{
+		ByReason:  make(map[models.DeliveryFailureReason]int),
+		Couriers:  []FailureStats{},
+		Customers: []FailureStats{},
+	}
+	couriers := make(map[uint]*FailureStats)
+	customers := make(map[uint]*FailureStats)
+
+	add := func(group map[uint]*FailureStats, id uint, reason models.DeliveryFailureReason) {
+		stats, ok := group[id]
+		if !ok {
+			stats = &FailureStats{ID: id, ByReason: make(map[models.DeliveryFailureReason]int)}
+			group[id] = stats
+		}
+		stats.Failed++
+		stats.ByReason[reason]++
+	}
+	for _, attempt := range attempts {
+		report.Failed++
+		report.ByReason[attempt.Reason]++
+		add(couriers, attempt.CourierID, attempt.Reason)
+		add(customers, attempt.CustomerID, attempt.Reason)
+	}
+
+	collect := func(group map[uint]*FailureStats, delivered map[uint]int) []FailureStats {
+		result := make([]FailureStats, 0, len(group))
+		for id, stats := range group {
+			stats.Delivered = delivered[id]
+			stats.FailureRate = float64(stats.Failed) / float64(stats.Failed+stats.Delivered)
+			result = append(result, *stats)
+		}
+		sort.Slice(result, func(i, j int) bool {
+			if result[i].Failed != result[j].Failed {
+				return result[i].Failed > result[j].Failed
+			}
+			return result[i].ID < result[j].ID
+		})
+		return result
+	}
+	report.Couriers = collect(couriers, deliveredByCourier)
+	report.Customers = collect(customers, deliveredByCustomer)
+	return report
+}
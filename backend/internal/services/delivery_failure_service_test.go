@@ -0,0 +1,59 @@
+package services
+
+import (
+	"testing"
+
+	"cupcake-delivery/internal/models"
+)
+
+func TestSummarizeDeliveryFailures(t *testing.T) {
+	attempts := []models.DeliveryAttempt{
+		{CourierID: 1, CustomerID: 10, Reason: models.FailureCustomerAbsent},
+		{CourierID: 1, CustomerID: 10, Reason: models.FailureCustomerAbsent},
+		{CourierID: 2, CustomerID: 10, Reason: models.FailureWrongAddress},
+		{CourierID: 2, CustomerID: 11, Reason: models.FailureDamaged},
+		{CourierID: 3, CustomerID: 12, Reason: models.FailureRefused},
+	}
+	deliveredByCourier := map[uint]int{1: 6, 2: 2, 4: 9}
+	deliveredByCustomer := map[uint]int{10: 1, 11: 3}
+
+	report := summarizeDeliveryFailures(attempts, deliveredByCourier, deliveredByCustomer)
+
+	if report.Failed != 5 || report.ByReason[models.FailureCustomerAbsent] != 2 || report.ByReason[models.FailureRefused] != 1 {
+		t.Errorf("Unexpected totals: %d %v", report.Failed, report.ByReason)
+	}
+
+	expectedCouriers := []struct {
+		id        uint
+		failed    int
+		delivered int
+		rate      float64
+	}{
+		{1, 2, 6, 0.25},
+		{2, 2, 2, 0.5},
+		{3, 1, 0, 1},
+	}
+	if len(report.Couriers) != len(expectedCouriers) {
+		t.Fatalf("Expected %d couriers (only those with failures), got %+v", len(expectedCouriers), report.Couriers)
+	}
+	for i, expected := range expectedCouriers {
+		got := report.Couriers[i]
+		if got.ID != expected.id || got.Failed != expected.failed || got.Delivered != expected.delivered || got.FailureRate != expected.rate {
+			t.Errorf("Expected courier %+v at position %d, got %+v", expected, i, got)
+		}
+	}
+	if report.Couriers[1].ByReason[models.FailureWrongAddress] != 1 || report.Couriers[1].ByReason[models.FailureDamaged] != 1 {
+		t.Errorf("Unexpected reasons for courier 2: %v", report.Couriers[1].ByReason)
+	}
+
+	if len(report.Customers) != 3 || report.Customers[0].ID != 10 || report.Customers[0].Failed != 3 || report.Customers[0].FailureRate != 0.75 {
+		t.Errorf("Expected customer 10 first with 3 failures and rate 0.75, got %+v", report.Customers)
+	}
+}
+
+func TestSummarizeDeliveryFailuresEmpty(t *testing.T) {
+	report := summarizeDeliveryFailures(nil, map[uint]int{1: 3}, nil)
+	if report.Failed != 0 || len(report.Couriers) != 0 || report.Couriers == nil || report.Customers == nil {
+		t.Errorf("Expected empty, non-nil lists, got %+v", report)
+	}
+}
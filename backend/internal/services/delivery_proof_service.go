@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"cupcake-delivery/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrProofRequired       = errors.New("informe o PIN, uma foto ou o nome de quem recebeu")
+	ErrInvalidDeliveryPIN  = errors.New("PIN de entrega incorreto")
+	ErrDeliveryPINLocked   = errors.New("PIN de entrega bloqueado após tentativas erradas")
+	ErrUnsupportedPhoto    = errors.New("foto deve ser JPEG, PNG ou WebP")
+	ErrOrderNotDeliverable = errors.New("pedido não está em entrega com este entregador")
+)
+
+// proofPhotoExtensions tipos de imagem aceitos como foto da entrega
+var proofPhotoExtensions = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/webp": "webp",
+}
+
+// DeliveryProofInput evidências enviadas pelo entregador; basta uma delas
+type DeliveryProofInput struct {
+	PIN           string
+	RecipientName string
+	Photo         io.Reader
+}
+
+// GenerateDeliveryPIN sorteia um PIN de 4 dígitos
+func GenerateDeliveryPIN() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(10000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%04d", n.Int64()), nil
+}
+
+// proofMethod escolhe a evidência usada: PIN, depois foto, depois nome de quem recebeu
+func proofMethod(input DeliveryProofInput) (models.DeliveryProofMethod, error) {
+	switch {
+	case strings.TrimSpace(input.PIN) != "":
+		return models.ProofPIN, nil
+	case input.Photo != nil:
+		return models.ProofPhoto, nil
+	case strings.TrimSpace(input.RecipientName) != "":
+		return models.ProofRecipient, nil
+	default:
+		return "", ErrProofRequired
+	}
+}
+
+// DeliveryProofService conclui entregas mediante comprovante
+type DeliveryProofService struct {
+	db       *gorm.DB
+	storage  FileStorage
+	earnings *EarningsService // Lança o repasse na mesma transação da entrega
+	now      func() time.Time
+}
+
+func NewDeliveryProofService(db *gorm.DB, storage FileStorage) *DeliveryProofService {
+	return &DeliveryProofService{db: db, storage: storage, now: time.Now}
+}
+
+// SetEarnings passa a lançar o repasse do entregador junto com a conclusão da entrega
+func (s *DeliveryProofService) SetEarnings(earnings *EarningsService) {
+	s.earnings = earnings
+}
+
+// Deliver marca como entregue um pedido em entrega com o entregador e guarda o comprovante.
+// Após MaxDeliveryPINAttempts PINs errados, o PIN deixa de ser aceito para o pedido.
+func (s *DeliveryProofService) Deliver(ctx context.Context, orderID, courierID uint, input DeliveryProofInput) (*models.Order, error) {
+	var order models.Order
+	if err := s.db.First(&order, orderID).Error; err != nil {
+		return nil, err
+	}
+	if order.Status != models.StatusDelivering || order.DeliveryID == nil || *order.DeliveryID != courierID {
+		return nil, ErrOrderNotDeliverable
+	}
+
+	method, err := proofMethod(input)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	proof := models.DeliveryProof{
+		OrderID:     order.ID,
+		CourierID:   courierID,
+		Method:      method,
+		DeliveredAt: now,
+	}
+
+	switch method {
+	case models.ProofPIN:
+		if err := s.checkPIN(&order, strings.TrimSpace(input.PIN)); err != nil {
+			return nil, err
+		}
+	case models.ProofPhoto:
+		key, contentType, err := s.savePhoto(ctx, order.ID, now, input.Photo)
+		if err != nil {
+			return nil, err
+		}
+		proof.PhotoKey = key
+		proof.PhotoContentType = contentType
+	case models.ProofRecipient:
+		proof.RecipientName = strings.TrimSpace(input.RecipientName)
+	}
+
+	var position models.CourierPosition
+	if err := s.db.Where("courier_id = ? AND recorded_at >= ?", courierID, now.Add(-positionMaxAge)).First(&position).Error; err == nil {
+		proof.Latitude = &position.Latitude
+		proof.Longitude = &position.Longitude
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Order{}).
+			Where("id = ? AND status = ? AND delivery_id = ?", order.ID, models.StatusDelivering, courierID).
+			Updates(map[string]interface{}{"status": models.StatusDelivered, "status_changed_at": now, "delivered_at": now, "updated_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOrderNotDeliverable
+		}
+		if err := tx.Create(&proof).Error; err != nil {
+			return err
+		}
+		if err := RecordStatusChange(tx, order.ID, models.StatusDelivered, now); err != nil {
+			return err
+		}
+		if err := completeBatchStop(tx, &order, models.StopDelivered, now); err != nil {
+			return err
+		}
+		if s.earnings == nil {
+			return nil
+		}
+		delivered := order
+		delivered.Status = models.StatusDelivered
+		delivered.DeliveredAt = &now
+		return s.earnings.RecordDelivery(tx, &delivered)
+	})
+	if err != nil {
+		// Sem o comprovante gravado a foto ficaria órfã no storage
+		if proof.PhotoKey != "" {
+			if deleteErr := s.storage.Delete(ctx, proof.PhotoKey); deleteErr != nil {
+				log.Printf("Erro ao remover foto %s do pedido %d: %v", proof.PhotoKey, order.ID, deleteErr)
+			}
+		}
+		return nil, err
+	}
+
+	order.Status = models.StatusDelivered
+	order.DeliveredAt = &now
+	order.StatusChangedAt = &now
+	order.UpdatedAt = now
+	order.DeliveryProof = &proof
+	return &order, nil
+}
+
+// checkPIN reserva uma das MaxDeliveryPINAttempts tentativas antes de comparar o PIN em tempo constante.
+// A reserva é um UPDATE condicional, então tentativas simultâneas não passam do limite.
+func (s *DeliveryProofService) checkPIN(order *models.Order, pin string) error {
+	if order.DeliveryPIN == "" {
+		return ErrDeliveryPINLocked
+	}
+
+	result := s.db.Model(&models.Order{}).
+		Where("id = ? AND delivery_pin_attempts < ?", order.ID, models.MaxDeliveryPINAttempts).
+		UpdateColumn("delivery_pin_attempts", gorm.Expr("delivery_pin_attempts + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDeliveryPINLocked
+	}
+
+	if subtle.ConstantTimeCompare([]byte(pin), []byte(order.DeliveryPIN)) != 1 {
+		return ErrInvalidDeliveryPIN
+	}
+	return nil
+}
+
+// savePhoto identifica o tipo pelo conteúdo (não pelo cabeçalho enviado) e grava a foto no storage
+func (s *DeliveryProofService) savePhoto(ctx context.Context, orderID uint, at time.Time, photo io.Reader) (string, string, error) {
+	content, contentType, err := sniffContent(photo)
+	if err != nil {
+		return "", "", ErrUnsupportedPhoto
+	}
+	extension, ok := proofPhotoExtensions[contentType]
+	if !ok {
+		return "", "", ErrUnsupportedPhoto
+	}
+
+	key := fmt.Sprintf("delivery-proofs/%d/%d.%s", orderID, at.UnixNano(), extension)
+	if err := s.storage.Save(ctx, key, content); err != nil {
+		return "", "", err
+	}
+	return key, contentType, nil
+}
+
+// Proof retorna o comprovante de entrega do pedido
+func (s *DeliveryProofService) Proof(orderID uint) (*models.DeliveryProof, error) {
+	var proof models.DeliveryProof
+	if err := s.db.Where("order_id = ?", orderID).First(&proof).Error; err != nil {
+		return nil, err
+	}
+	return &proof, nil
+}
+
+// OpenPhoto abre a foto do comprovante; ErrFileNotFound se o comprovante não tem foto
+func (s *DeliveryProofService) OpenPhoto(ctx context.Context, proof *models.DeliveryProof) (io.ReadCloser, error) {
+	if proof.PhotoKey == "" {
+		return nil, ErrFileNotFound
+	}
+	return s.storage.Open(ctx, proof.PhotoKey)
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cupcake-delivery/internal/models"
+)
+
+func TestGenerateDeliveryPIN(t *testing.T) {
+	pattern := regexp.MustCompile(`^\d{4}$`)
+	for i := 0; i < 100; i++ {
+		pin, err := GenerateDeliveryPIN()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !pattern.MatchString(pin) {
+			t.Fatalf("Expected 4-digit PIN, got %q", pin)
+		}
+	}
+}
+
+func TestProofMethod(t *testing.T) {
+	photo := strings.NewReader("foto")
+
+	testCases := []struct {
+		name        string
+		input       DeliveryProofInput
+		expected    models.DeliveryProofMethod
+		expectedErr error
+	}{
+		{"PIN", DeliveryProofInput{PIN: "1234"}, models.ProofPIN, nil},
+		{"PIN wins over photo and name", DeliveryProofInput{PIN: "1234", Photo: photo, RecipientName: "Maria"}, models.ProofPIN, nil},
+		{"Photo wins over name", DeliveryProofInput{Photo: photo, RecipientName: "Maria"}, models.ProofPhoto, nil},
+		{"Recipient name", DeliveryProofInput{RecipientName: "Maria"}, models.ProofRecipient, nil},
+		{"Blank fields", DeliveryProofInput{PIN: " ", RecipientName: " "}, "", ErrProofRequired},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			method, err := proofMethod(tc.input)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if method != tc.expected {
+				t.Errorf("Expected method %q, got %q", tc.expected, method)
+			}
+		})
+	}
+}
+
+func TestLocalFileStorage(t *testing.T) {
+	storage := NewLocalFileStorage(t.TempDir())
+	ctx := context.Background()
+
+	if err := storage.Save(ctx, "delivery-proofs/1/foto.jpg", strings.NewReader("conteúdo")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	file, err := storage.Open(ctx, "delivery-proofs/1/foto.jpg")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	content, _ := io.ReadAll(file)
+	file.Close()
+	if string(content) != "conteúdo" {
+		t.Errorf("Expected saved content, got %q", content)
+	}
+
+	if err := storage.Save(ctx, "delivery-proofs/1/foto.jpg", strings.NewReader("outro")); err == nil {
+		t.Errorf("Expected error when overwriting an existing file")
+	}
+	if _, err := storage.Open(ctx, "delivery-proofs/2/foto.jpg"); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("Expected ErrFileNotFound, got %v", err)
+	}
+	for _, key := range []string{"", "../fora.jpg", "/etc/passwd", "a/../../fora.jpg"} {
+		if err := storage.Save(ctx, key, strings.NewReader("x")); err == nil {
+			t.Errorf("Expected key %q to be rejected", key)
+		}
+		if err := storage.Delete(ctx, key); err == nil {
+			t.Errorf("Expected delete of key %q to be rejected", key)
+		}
+	}
+
+	if err := storage.Delete(ctx, "delivery-proofs/1/foto.jpg"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := storage.Open(ctx, "delivery-proofs/1/foto.jpg"); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("Expected deleted file to be gone, got %v", err)
+	}
+	if err := storage.Delete(ctx, "delivery-proofs/1/foto.jpg"); err != nil {
+		t.Errorf("Expected deleting a missing file to succeed, got %v", err)
+	}
+}
+
+func TestSaveProofPhoto(t *testing.T) {
+	s := &DeliveryProofService{storage: NewLocalFileStorage(t.TempDir())}
+	at := time.Date(2024, 5, 10, 18, 0, 0, 0, time.UTC)
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 1024)...)
+
+	key, contentType, err := s.savePhoto(context.Background(), 7, at, bytes.NewReader(png))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if contentType != "image/png" || !strings.HasPrefix(key, "delivery-proofs/7/") || !strings.HasSuffix(key, ".png") {
+		t.Errorf("Unexpected key %q or content type %q", key, contentType)
+	}
+
+	file, _ := s.storage.Open(context.Background(), key)
+	saved, _ := io.ReadAll(file)
+	file.Close()
+	if !bytes.Equal(saved, png) {
+		t.Errorf("Expected the whole photo to be saved, got %d of %d bytes", len(saved), len(png))
+	}
+
+	if _, _, err := s.savePhoto(context.Background(), 7, at, strings.NewReader("<html>não é foto</html>")); !errors.Is(err, ErrUnsupportedPhoto) {
+		t.Errorf("Expected ErrUnsupportedPhoto, got %v", err)
+	}
+}
+
+// TestDeliveryProofServicePINAttemptsAreClaimedAtomically dispara PINs errados em paralelo contra o
+// PostgreSQL: só MaxDeliveryPINAttempts chegam a ser comparados, os demais encontram o PIN bloqueado
+func TestDeliveryProofServicePINAttemptsAreClaimedAtomically(t *testing.T) {
+	db := openTestDB(t)
+
+	suffix := time.Now().Format("150405.000000")
+	customer := models.User{Name: "Cliente", Email: "pin-customer-" + suffix + "@example.com", Type: models.CustomerType}
+	courier := models.User{Name: "Entregador", Email: "pin-courier-" + suffix + "@example.com", Type: models.DeliveryType}
+	for _, user := range []*models.User{&customer, &courier} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	order := models.Order{CustomerID: customer.ID, DeliveryID: &courier.ID, Status: models.StatusDelivering, DeliveryPIN: "1234"}
+	if err := db.Create(&order).Error; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Unscoped().Delete(&order)
+		db.Unscoped().Delete(&[]models.User{customer, courier})
+	})
+
+	service := NewDeliveryProofService(db, NewLocalFileStorage(t.TempDir()))
+	var invalid, locked int64
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, err := service.Deliver(context.Background(), order.ID, courier.ID, DeliveryProofInput{PIN: "0000"})
+			switch {
+			case errors.Is(err, ErrInvalidDeliveryPIN):
+				atomic.AddInt64(&invalid, 1)
+			case errors.Is(err, ErrDeliveryPINLocked):
+				atomic.AddInt64(&locked, 1)
+			default:
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if invalid != models.MaxDeliveryPINAttempts || locked != 20-models.MaxDeliveryPINAttempts {
+		t.Errorf("Expected %d invalid and %d locked attempts, got %d and %d", models.MaxDeliveryPINAttempts, 20-models.MaxDeliveryPINAttempts, invalid, locked)
+	}
+	if _, err := service.Deliver(context.Background(), order.ID, courier.ID, DeliveryProofInput{PIN: "1234"}); !errors.Is(err, ErrDeliveryPINLocked) {
+		t.Errorf("Expected the right PIN to be refused after the lockout, got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"cupcake-delivery/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOutsideDeliveryArea    = errors.New("endereço fora da área de entrega")
+	ErrDeliveryLocationNeeded = errors.New("localização do endereço de entrega é obrigatória")
+)
+
+// MinimumOrderError pedido abaixo do valor mínimo exigido para a área de entrega
+type MinimumOrderError struct {
+	Zone    string
+	Minimum float64
+}
+
+func (e *MinimumOrderError) Error() string {
+	return fmt.Sprintf("pedido mínimo para entrega em %s é R$ %.2f", e.Zone, e.Minimum)
+}
+
+// DeliveryQuote taxa e prazo de entrega calculados para um pedido
+type DeliveryQuote struct {
+	Fee              float64 `json:"fee"`
+	ZoneID           *uint   `json:"zoneId,omitempty"`
+	ZoneName         string  `json:"zoneName,omitempty"`
+	DistanceKm       float64 `json:"distanceKm,omitempty"`
+	EstimatedMinutes int     `json:"estimatedMinutes,omitempty"`
+}
+
+// DeliveryPricer calcula a taxa de entrega de um pedido a partir do subtotal e das coordenadas do endereço
+type DeliveryPricer interface {
+	Quote(subtotal float64, latitude, longitude *float64) (*DeliveryQuote, error)
+}
+
+type DeliveryZoneService struct {
+	db *gorm.DB
+}
+
+func NewDeliveryZoneService(db *gorm.DB) *DeliveryZoneService {
+	return &DeliveryZoneService{db: db}
+}
+
+// Quote localiza a zona ativa que contém as coordenadas e calcula a taxa de entrega
+func (s *DeliveryZoneService) Quote(subtotal float64, latitude, longitude *float64) (*DeliveryQuote, error) {
+	if latitude == nil || longitude == nil {
+		return nil, ErrDeliveryLocationNeeded
+	}
+
+	var zones []models.DeliveryZone
+	if err := s.db.Where("active = ?", true).Order("id").Find(&zones).Error; err != nil {
+		return nil, err
+	}
+
+	return QuoteDeliveryZones(zones, subtotal, *latitude, *longitude)
+}
+
+// QuoteDeliveryZones escolhe a primeira zona (menor ID) que contém o ponto; zonas sobrepostas
+// não se somam. Retorna ErrOutsideDeliveryArea se nenhuma zona atende o endereço.
+func QuoteDeliveryZones(zones []models.DeliveryZone, subtotal, latitude, longitude float64) (*DeliveryQuote, error) {
+	for i := range zones {
+		zone := &zones[i]
+		if !zone.Polygon.Contains(latitude, longitude) {
+			continue
+		}
+		if subtotal < zone.MinOrderValue {
+			return nil, &MinimumOrderError{Zone: zone.Name, Minimum: zone.MinOrderValue}
+		}
+		return &DeliveryQuote{
+			Fee:              zone.Fee,
+			ZoneID:           &zone.ID,
+			ZoneName:         zone.Name,
+			EstimatedMinutes: zone.EstimatedMinutes,
+		}, nil
+	}
+
+	return nil, ErrOutsideDeliveryArea
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"cupcake-delivery/internal/models"
+)
+
+func testZone(id uint, name string, fee, minimum float64, ring [][2]float64) models.DeliveryZone {
+	zone := models.DeliveryZone{
+		Name:             name,
+		Polygon:          models.GeoJSONPolygon{Type: "Polygon", Coordinates: [][][2]float64{ring}},
+		Fee:              fee,
+		MinOrderValue:    minimum,
+		EstimatedMinutes: 40,
+		Active:           true,
+	}
+	zone.ID = id
+	return zone
+}
+
+func TestQuoteDeliveryZones(t *testing.T) {
+	center := [][2]float64{{-46.66, -23.56}, {-46.62, -23.56}, {-46.62, -23.52}, {-46.66, -23.52}, {-46.66, -23.56}}
+	outer := [][2]float64{{-46.80, -23.70}, {-46.50, -23.70}, {-46.50, -23.40}, {-46.80, -23.40}, {-46.80, -23.70}}
+	zones := []models.DeliveryZone{
+		testZone(1, "Centro", 5, 20, center),
+		testZone(2, "Grande SP", 12, 50, outer),
+	}
+
+	quote, err := QuoteDeliveryZones(zones, 30, -23.54, -46.64)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if quote.Fee != 5 || *quote.ZoneID != 1 || quote.EstimatedMinutes != 40 {
+		t.Errorf("Expected first matching zone (Centro), got %+v", quote)
+	}
+
+	quote, err = QuoteDeliveryZones(zones, 60, -23.45, -46.75)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if quote.ZoneName != "Grande SP" || quote.Fee != 12 {
+		t.Errorf("Expected outer zone, got %+v", quote)
+	}
+
+	var minimumErr *MinimumOrderError
+	if _, err := QuoteDeliveryZones(zones, 30, -23.45, -46.75); !errors.As(err, &minimumErr) || minimumErr.Minimum != 50 {
+		t.Errorf("Expected minimum order error, got %v", err)
+	}
+
+	if _, err := QuoteDeliveryZones(zones, 100, -22.90, -43.20); !errors.Is(err, ErrOutsideDeliveryArea) {
+		t.Errorf("Expected ErrOutsideDeliveryArea, got %v", err)
+	}
+}
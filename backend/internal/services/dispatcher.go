@@ -0,0 +1,493 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"cupcake-delivery/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOfferNotFound   = errors.New("oferta não encontrada")
+	ErrOfferNotPending = errors.New("oferta já respondida ou expirada")
+	ErrOrderOffered    = errors.New("pedido já tem oferta pendente")
+)
+
+// DispatchCandidate entregador online que pode receber a oferta de um pedido
+type DispatchCandidate struct {
+	CourierID        uint
+	DistanceKm       *float64 // Distância até a loja; nil sem posição conhecida
+	ActiveDeliveries int
+	LastOfferedAt    time.Time // Zero se nunca recebeu oferta
+}
+
+// DispatchStrategy ordena os candidatos do mais indicado para o menos indicado
+type DispatchStrategy interface {
+	Rank(candidates []DispatchCandidate) []DispatchCandidate
+}
+
+// RoundRobinStrategy reveza as ofertas: primeiro quem está há mais tempo sem receber uma
+type RoundRobinStrategy struct{}
+
+func (RoundRobinStrategy) Rank(candidates []DispatchCandidate) []DispatchCandidate {
+	ranked := append([]DispatchCandidate(nil), candidates...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if !a.LastOfferedAt.Equal(b.LastOfferedAt) {
+			return a.LastOfferedAt.Before(b.LastOfferedAt)
+		}
+		return a.CourierID < b.CourierID
+	})
+	return ranked
+}
+
+// NearestFirstStrategy oferece a quem está mais perto da loja; empates vão para quem tem menos
+// entregas em andamento e depois para quem está há mais tempo sem oferta
+type NearestFirstStrategy struct{}
+
+func (NearestFirstStrategy) Rank(candidates []DispatchCandidate) []DispatchCandidate {
+	ranked := append([]DispatchCandidate(nil), candidates...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if (a.DistanceKm == nil) != (b.DistanceKm == nil) {
+			return a.DistanceKm != nil
+		}
+		if a.DistanceKm != nil && *a.DistanceKm != *b.DistanceKm {
+			return *a.DistanceKm < *b.DistanceKm
+		}
+		if a.ActiveDeliveries != b.ActiveDeliveries {
+			return a.ActiveDeliveries < b.ActiveDeliveries
+		}
+		if !a.LastOfferedAt.Equal(b.LastOfferedAt) {
+			return a.LastOfferedAt.Before(b.LastOfferedAt)
+		}
+		return a.CourierID < b.CourierID
+	})
+	return ranked
+}
+
+// DispatchStore persistência das ofertas e consulta dos candidatos
+type DispatchStore interface {
+	// Dispatchable indica se o pedido continua pronto, sem entregador e liberado para retirada
+	Dispatchable(orderID uint) (bool, error)
+	// Candidates lista os entregadores online que ainda não receberam oferta deste pedido
+	// e não estão decidindo outra oferta
+	Candidates(orderID uint) ([]DispatchCandidate, error)
+	// CreateOffer grava a oferta; ErrOrderOffered se o pedido já tem outra pendente
+	CreateOffer(offer *models.DispatchOffer) error
+	Offer(id uint) (*models.DispatchOffer, error)
+	// ResolveOffer muda o status apenas se a oferta ainda estiver em from; retorna se mudou
+	ResolveOffer(id uint, from, to models.DispatchOfferStatus, at time.Time) (bool, error)
+	ExpiredOffers(now time.Time) ([]models.DispatchOffer, error)
+	PendingOffers(courierID uint, now time.Time) ([]models.DispatchOffer, error)
+	// UndispatchedOrders pedidos prontos, liberados para retirada, sem entregador e sem oferta pendente
+	UndispatchedOrders() ([]uint, error)
+}
+
+// DispatchNotifier avisa o entregador de que recebeu uma oferta
+type DispatchNotifier interface {
+	NotifyDispatchOffer(offer *models.DispatchOffer) error
+}
+
+// Dispatcher oferece pedidos prontos aos entregadores online, um por vez. Quem recebe a oferta
+// tem uma janela para aceitar; recusa ou tempo esgotado passam a oferta ao próximo candidato.
+type Dispatcher struct {
+	store    DispatchStore
+	strategy DispatchStrategy
+	claims   *OrderClaimService
+	notifier DispatchNotifier
+	window   time.Duration
+	maxLoad  int // Entregas em andamento a partir das quais o entregador não recebe ofertas; zero desativa
+	now      func() time.Time
+
+	// Serializa as ofertas desta instância; entre instâncias o índice único das ofertas pendentes
+	// impede que um pedido tenha duas
+	mu sync.Mutex
+}
+
+func NewDispatcher(store DispatchStore, strategy DispatchStrategy, claims *OrderClaimService, notifier DispatchNotifier, window time.Duration, maxLoad int) *Dispatcher {
+	return &Dispatcher{
+		store:    store,
+		strategy: strategy,
+		claims:   claims,
+		notifier: notifier,
+		window:   window,
+		maxLoad:  maxLoad,
+		now:      time.Now,
+	}
+}
+
+// Dispatch oferece o pedido ao melhor candidato. Retorna nil sem erro quando o pedido não está mais
+// pronto ou não há candidatos; nesse caso ele continua na fila para retirada manual.
+func (d *Dispatcher) Dispatch(orderID uint) (*models.DispatchOffer, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ready, err := d.store.Dispatchable(orderID)
+	if err != nil || !ready {
+		return nil, err
+	}
+
+	candidates, err := d.store.Candidates(orderID)
+	if err != nil {
+		return nil, err
+	}
+	var eligible []DispatchCandidate
+	for _, candidate := range candidates {
+		if d.maxLoad > 0 && candidate.ActiveDeliveries >= d.maxLoad {
+			continue
+		}
+		eligible = append(eligible, candidate)
+	}
+	if len(eligible) == 0 {
+		return nil, nil
+	}
+
+	now := d.now()
+	offer := &models.DispatchOffer{
+		OrderID:   orderID,
+		CourierID: d.strategy.Rank(eligible)[0].CourierID,
+		Status:    models.OfferPending,
+		OfferedAt: now,
+		ExpiresAt: now.Add(d.window),
+	}
+	if err := d.store.CreateOffer(offer); err != nil {
+		// Outra instância ofereceu o pedido entre a consulta dos candidatos e a gravação
+		if errors.Is(err, ErrOrderOffered) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if d.notifier != nil {
+		if err := d.notifier.NotifyDispatchOffer(offer); err != nil {
+			log.Printf("Erro ao notificar oferta %d do pedido %d: %v", offer.ID, orderID, err)
+		}
+	}
+	return offer, nil
+}
+
+// Accept aceita a oferta dentro da janela e atribui o pedido ao entregador.
+// Se o pedido já foi retirado por outro caminho, a oferta é cancelada e retorna ErrOrderNotClaimable.
+func (d *Dispatcher) Accept(offerID, courierID uint) (*models.DispatchOffer, error) {
+	offer, err := d.courierOffer(offerID, courierID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := d.now()
+	if !now.Before(offer.ExpiresAt) {
+		return nil, ErrOfferNotPending
+	}
+	accepted, err := d.store.ResolveOffer(offer.ID, models.OfferPending, models.OfferAccepted, now)
+	if err != nil {
+		return nil, err
+	}
+	if !accepted {
+		return nil, ErrOfferNotPending
+	}
+
+	if err := d.claims.Claim(offer.OrderID, courierID); err != nil {
+		if _, resolveErr := d.store.ResolveOffer(offer.ID, models.OfferAccepted, models.OfferCancelled, now); resolveErr != nil {
+			log.Printf("Erro ao cancelar oferta %d: %v", offer.ID, resolveErr)
+		}
+		return nil, err
+	}
+
+	offer.Status = models.OfferAccepted
+	offer.RespondedAt = &now
+	return offer, nil
+}
+
+// Decline recusa a oferta e passa o pedido ao próximo candidato
+func (d *Dispatcher) Decline(offerID, courierID uint) (*models.DispatchOffer, error) {
+	offer, err := d.courierOffer(offerID, courierID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := d.now()
+	declined, err := d.store.ResolveOffer(offer.ID, models.OfferPending, models.OfferDeclined, now)
+	if err != nil {
+		return nil, err
+	}
+	if !declined {
+		return nil, ErrOfferNotPending
+	}
+
+	if _, err := d.Dispatch(offer.OrderID); err != nil {
+		log.Printf("Erro ao repassar pedido %d após recusa: %v", offer.OrderID, err)
+	}
+
+	offer.Status = models.OfferDeclined
+	offer.RespondedAt = &now
+	return offer, nil
+}
+
+// PendingOffers lista as ofertas que o entregador ainda pode responder
+func (d *Dispatcher) PendingOffers(courierID uint) ([]models.DispatchOffer, error) {
+	return d.store.PendingOffers(courierID, d.now())
+}
+
+// ExpireOffers encerra as ofertas sem resposta dentro da janela e repassa cada pedido ao próximo candidato.
+// Uma falha ao repassar um pedido é registrada e não impede o repasse dos demais.
+func (d *Dispatcher) ExpireOffers() (int, error) {
+	now := d.now()
+	offers, err := d.store.ExpiredOffers(now)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, offer := range offers {
+		ok, err := d.store.ResolveOffer(offer.ID, models.OfferPending, models.OfferExpired, now)
+		if err != nil {
+			return expired, err
+		}
+		if !ok {
+			continue // Respondida enquanto expirava
+		}
+		expired++
+		if _, err := d.Dispatch(offer.OrderID); err != nil {
+			log.Printf("Erro ao repassar pedido %d após expirar a oferta %d: %v", offer.OrderID, offer.ID, err)
+		}
+	}
+	return expired, nil
+}
+
+// Tick expira as ofertas vencidas e oferece os pedidos prontos que estão sem oferta,
+// por exemplo porque não havia entregador online quando ficaram prontos
+func (d *Dispatcher) Tick() error {
+	if _, err := d.ExpireOffers(); err != nil {
+		return err
+	}
+
+	orderIDs, err := d.store.UndispatchedOrders()
+	if err != nil {
+		return err
+	}
+	for _, orderID := range orderIDs {
+		if _, err := d.Dispatch(orderID); err != nil {
+			log.Printf("Erro ao oferecer pedido %d: %v", orderID, err)
+		}
+	}
+	return nil
+}
+
+// Run executa Tick periodicamente até o contexto ser cancelado
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.Tick(); err != nil {
+				log.Printf("Erro no despacho automático: %v", err)
+			}
+		}
+	}
+}
+
+func (d *Dispatcher) courierOffer(offerID, courierID uint) (*models.DispatchOffer, error) {
+	offer, err := d.store.Offer(offerID)
+	if err != nil {
+		return nil, err
+	}
+	if offer.CourierID != courierID {
+		return nil, ErrOfferNotFound
+	}
+	return offer, nil
+}
+
+// positionMaxAge idade máxima da última posição do entregador para calcular a distância até a loja
+const positionMaxAge = 10 * time.Minute
+
+// DBDispatchStore guarda as ofertas no banco e monta os candidatos a partir dos entregadores online
+type DBDispatchStore struct {
+	db       *gorm.DB
+	couriers *CourierService
+	vehicles *VehicleService // Com veículos, só recebe a oferta quem comporta o pedido
+
+	// Localização da loja; sem ela os candidatos ficam sem distância
+	store *[2]float64
+	now   func() time.Time
+}
+
+func NewDBDispatchStore(db *gorm.DB, couriers *CourierService) *DBDispatchStore {
+	return &DBDispatchStore{db: db, couriers: couriers, now: time.Now}
+}
+
+// SetStoreLocation define a loja como referência de distância para a estratégia nearest-first
+func (s *DBDispatchStore) SetStoreLocation(latitude, longitude float64) {
+	s.store = &[2]float64{latitude, longitude}
+}
+
+// SetVehicles passa a oferecer o pedido apenas a entregadores com veículo adequado e espaço livre
+func (s *DBDispatchStore) SetVehicles(vehicles *VehicleService) {
+	s.vehicles = vehicles
+}
+
+func (s *DBDispatchStore) Dispatchable(orderID uint) (bool, error) {
+	var count int64
+	err := s.db.Model(&models.Order{}).
+		Where("id = ? AND delivery_id IS NULL AND status = ?", orderID, models.StatusReady).
+		Where("scheduled_for IS NULL OR scheduled_for <= ?", s.now()).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (s *DBDispatchStore) Candidates(orderID uint) ([]DispatchCandidate, error) {
+	online, err := s.couriers.OnlineCourierIDs()
+	if err != nil || len(online) == 0 {
+		return nil, err
+	}
+
+	var excluded []uint
+	if err := s.db.Model(&models.DispatchOffer{}).
+		Where("order_id = ? OR status = ?", orderID, models.OfferPending).
+		Distinct().
+		Pluck("courier_id", &excluded).Error; err != nil {
+		return nil, err
+	}
+	skip := make(map[uint]bool, len(excluded))
+	for _, id := range excluded {
+		skip[id] = true
+	}
+	if s.vehicles != nil {
+		fits, err := s.vehicles.Fits(orderID, online)
+		if err != nil {
+			return nil, err
+		}
+		for _, courierID := range online {
+			if !fits[courierID] {
+				skip[courierID] = true
+			}
+		}
+	}
+
+	var loads []struct {
+		DeliveryID uint
+		Count      int
+	}
+	if err := s.db.Model(&models.Order{}).
+		Select("delivery_id, COUNT(*) AS count").
+		Where("delivery_id IN ? AND status = ?", online, models.StatusDelivering).
+		Group("delivery_id").
+		Scan(&loads).Error; err != nil {
+		return nil, err
+	}
+	loadByCourier := make(map[uint]int, len(loads))
+	for _, load := range loads {
+		loadByCourier[load.DeliveryID] = load.Count
+	}
+
+	var lastOffers []struct {
+		CourierID     uint
+		LastOfferedAt time.Time
+	}
+	if err := s.db.Model(&models.DispatchOffer{}).
+		Select("courier_id, MAX(offered_at) AS last_offered_at").
+		Where("courier_id IN ?", online).
+		Group("courier_id").
+		Scan(&lastOffers).Error; err != nil {
+		return nil, err
+	}
+	lastOfferByCourier := make(map[uint]time.Time, len(lastOffers))
+	for _, last := range lastOffers {
+		lastOfferByCourier[last.CourierID] = last.LastOfferedAt
+	}
+
+	distanceByCourier := make(map[uint]float64)
+	if s.store != nil {
+		var positions []models.CourierPosition
+		if err := s.db.Where("courier_id IN ? AND recorded_at >= ?", online, s.now().Add(-positionMaxAge)).
+			Find(&positions).Error; err != nil {
+			return nil, err
+		}
+		for _, position := range positions {
+			distanceByCourier[position.CourierID] = Haversine(position.Latitude, position.Longitude, s.store[0], s.store[1])
+		}
+	}
+
+	candidates := make([]DispatchCandidate, 0, len(online))
+	for _, courierID := range online {
+		if skip[courierID] {
+			continue
+		}
+		candidate := DispatchCandidate{
+			CourierID:        courierID,
+			ActiveDeliveries: loadByCourier[courierID],
+			LastOfferedAt:    lastOfferByCourier[courierID],
+		}
+		if distance, ok := distanceByCourier[courierID]; ok {
+			candidate.DistanceKm = &distance
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, nil
+}
+
+func (s *DBDispatchStore) CreateOffer(offer *models.DispatchOffer) error {
+	err := s.db.Create(offer).Error
+	// O índice único parcial barra a segunda oferta pendente do mesmo pedido
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrOrderOffered
+	}
+	return err
+}
+
+func (s *DBDispatchStore) Offer(id uint) (*models.DispatchOffer, error) {
+	var offer models.DispatchOffer
+	err := s.db.First(&offer, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrOfferNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &offer, nil
+}
+
+func (s *DBDispatchStore) ResolveOffer(id uint, from, to models.DispatchOfferStatus, at time.Time) (bool, error) {
+	result := s.db.Model(&models.DispatchOffer{}).
+		Where("id = ? AND status = ?", id, from).
+		Updates(map[string]interface{}{"status": to, "responded_at": at})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (s *DBDispatchStore) ExpiredOffers(now time.Time) ([]models.DispatchOffer, error) {
+	var offers []models.DispatchOffer
+	err := s.db.Where("status = ? AND expires_at <= ?", models.OfferPending, now).Order("expires_at").Find(&offers).Error
+	return offers, err
+}
+
+func (s *DBDispatchStore) PendingOffers(courierID uint, now time.Time) ([]models.DispatchOffer, error) {
+	var offers []models.DispatchOffer
+	err := s.db.Where("courier_id = ? AND status = ? AND expires_at > ?", courierID, models.OfferPending, now).
+		Order("offered_at").
+		Find(&offers).Error
+	return offers, err
+}
+
+func (s *DBDispatchStore) UndispatchedOrders() ([]uint, error) {
+	var ids []uint
+	err := s.db.Model(&models.Order{}).
+		Where("delivery_id IS NULL AND status = ?", models.StatusReady).
+		Where("scheduled_for IS NULL OR scheduled_for <= ?", s.now()).
+		Where("NOT EXISTS (SELECT 1 FROM dispatch_offers WHERE dispatch_offers.order_id = orders.id AND dispatch_offers.status = ?)", models.OfferPending).
+		Order("id").
+		Pluck("id", &ids).Error
+	return ids, err
+}
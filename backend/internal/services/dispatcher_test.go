@@ -0,0 +1,449 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"cupcake-delivery/internal/models"
+)
+
+// fakeClock relógio controlado pelo teste
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// memoryDispatchStore reproduz em memória as consultas do DBDispatchStore.
+// Os pedidos são compartilhados com o memoryOrderClaimStore usado na retirada.
+type memoryDispatchStore struct {
+	claims   *memoryOrderClaimStore
+	couriers []DispatchCandidate // Entregadores online, com distância e carga
+	offers   []models.DispatchOffer
+	failing  map[uint]error // Erro devolvido por Candidates para o pedido
+}
+
+func (s *memoryDispatchStore) Dispatchable(orderID uint) (bool, error) {
+	order, ok := s.claims.orders[orderID]
+	return ok && order.DeliveryID == nil && order.Status == models.StatusReady, nil
+}
+
+func (s *memoryDispatchStore) Candidates(orderID uint) ([]DispatchCandidate, error) {
+	if err := s.failing[orderID]; err != nil {
+		return nil, err
+	}
+	var candidates []DispatchCandidate
+	for _, courier := range s.couriers {
+		skip := false
+		for _, offer := range s.offers {
+			if offer.CourierID != courier.CourierID {
+				continue
+			}
+			if offer.OrderID == orderID || offer.Status == models.OfferPending {
+				skip = true
+			}
+			if offer.OfferedAt.After(courier.LastOfferedAt) {
+				courier.LastOfferedAt = offer.OfferedAt
+			}
+		}
+		if !skip {
+			candidates = append(candidates, courier)
+		}
+	}
+	return candidates, nil
+}
+
+func (s *memoryDispatchStore) CreateOffer(offer *models.DispatchOffer) error {
+	for _, existing := range s.offers {
+		if existing.OrderID == offer.OrderID && existing.Status == models.OfferPending {
+			return ErrOrderOffered
+		}
+	}
+	offer.ID = uint(len(s.offers) + 1)
+	s.offers = append(s.offers, *offer)
+	return nil
+}
+
+func (s *memoryDispatchStore) Offer(id uint) (*models.DispatchOffer, error) {
+	if id == 0 || int(id) > len(s.offers) {
+		return nil, ErrOfferNotFound
+	}
+	offer := s.offers[id-1]
+	return &offer, nil
+}
+
+func (s *memoryDispatchStore) ResolveOffer(id uint, from, to models.DispatchOfferStatus, at time.Time) (bool, error) {
+	offer := &s.offers[id-1]
+	if offer.Status != from {
+		return false, nil
+	}
+	offer.Status = to
+	offer.RespondedAt = &at
+	return true, nil
+}
+
+func (s *memoryDispatchStore) ExpiredOffers(now time.Time) ([]models.DispatchOffer, error) {
+	var expired []models.DispatchOffer
+	for _, offer := range s.offers {
+		if offer.Status == models.OfferPending && !offer.ExpiresAt.After(now) {
+			expired = append(expired, offer)
+		}
+	}
+	return expired, nil
+}
+
+func (s *memoryDispatchStore) PendingOffers(courierID uint, now time.Time) ([]models.DispatchOffer, error) {
+	var pending []models.DispatchOffer
+	for _, offer := range s.offers {
+		if offer.CourierID == courierID && offer.Status == models.OfferPending && offer.ExpiresAt.After(now) {
+			pending = append(pending, offer)
+		}
+	}
+	return pending, nil
+}
+
+func (s *memoryDispatchStore) UndispatchedOrders() ([]uint, error) {
+	var ids []uint
+	for id := uint(1); id <= uint(len(s.claims.orders)); id++ {
+		if ready, _ := s.Dispatchable(id); !ready {
+			continue
+		}
+		pending := false
+		for _, offer := range s.offers {
+			pending = pending || (offer.OrderID == id && offer.Status == models.OfferPending)
+		}
+		if !pending {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+type recordingDispatchNotifier struct {
+	offers []models.DispatchOffer
+}
+
+func (n *recordingDispatchNotifier) NotifyDispatchOffer(offer *models.DispatchOffer) error {
+	n.offers = append(n.offers, *offer)
+	return nil
+}
+
+func float(v float64) *float64 { return &v }
+
+// newTestDispatcher cria um despachante com readyOrders pedidos prontos (ids 1..n) e relógio falso
+func newTestDispatcher(strategy DispatchStrategy, readyOrders int, couriers ...DispatchCandidate) (*Dispatcher, *memoryDispatchStore, *fakeClock) {
+	claims := &memoryOrderClaimStore{orders: make(map[uint]*models.Order)}
+	for id := uint(1); id <= uint(readyOrders); id++ {
+		claims.orders[id] = &models.Order{Status: models.StatusReady}
+	}
+	store := &memoryDispatchStore{claims: claims, couriers: couriers}
+	clock := &fakeClock{now: time.Date(2024, 5, 10, 18, 0, 0, 0, time.UTC)}
+
+	d := NewDispatcher(store, strategy, NewOrderClaimService(claims), &recordingDispatchNotifier{}, time.Minute, 1)
+	d.now = clock.Now
+	return d, store, clock
+}
+
+func offeredTo(offer *models.DispatchOffer) uint {
+	if offer == nil {
+		return 0
+	}
+	return offer.CourierID
+}
+
+func TestDispatchStrategiesRank(t *testing.T) {
+	base := time.Date(2024, 5, 10, 18, 0, 0, 0, time.UTC)
+	candidates := []DispatchCandidate{
+		{CourierID: 1, DistanceKm: float(3), LastOfferedAt: base.Add(-time.Minute)},
+		{CourierID: 2, DistanceKm: nil},
+		{CourierID: 3, DistanceKm: float(1), ActiveDeliveries: 1, LastOfferedAt: base.Add(-10 * time.Minute)},
+		{CourierID: 4, DistanceKm: float(1), LastOfferedAt: base.Add(-time.Minute)},
+		{CourierID: 5, DistanceKm: float(1), LastOfferedAt: base.Add(-5 * time.Minute)},
+	}
+
+	testCases := []struct {
+		name     string
+		strategy DispatchStrategy
+		expected []uint
+	}{
+		{"Round robin: never offered first, then oldest offer", RoundRobinStrategy{}, []uint{2, 3, 5, 1, 4}},
+		{"Nearest first: distance, load, oldest offer; unknown position last", NearestFirstStrategy{}, []uint{5, 4, 3, 1, 2}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []uint
+			for _, candidate := range tc.strategy.Rank(candidates) {
+				got = append(got, candidate.CourierID)
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+			if candidates[0].CourierID != 1 {
+				t.Errorf("Expected Rank not to reorder the input slice")
+			}
+		})
+	}
+}
+
+func TestDispatcherCascadesOnTimeout(t *testing.T) {
+	d, store, clock := newTestDispatcher(NearestFirstStrategy{}, 1,
+		DispatchCandidate{CourierID: 1, DistanceKm: float(0.5)},
+		DispatchCandidate{CourierID: 2, DistanceKm: float(2)},
+	)
+
+	offer, err := d.Dispatch(1)
+	if err != nil || offeredTo(offer) != 1 {
+		t.Fatalf("Expected offer to courier 1, got %+v (%v)", offer, err)
+	}
+
+	// Ainda dentro da janela: nada expira
+	clock.Advance(59 * time.Second)
+	if expired, _ := d.ExpireOffers(); expired != 0 {
+		t.Errorf("Expected no expired offers before the window, got %d", expired)
+	}
+
+	clock.Advance(time.Second)
+	if expired, _ := d.ExpireOffers(); expired != 1 {
+		t.Fatalf("Expected 1 expired offer, got %d", expired)
+	}
+	if store.offers[0].Status != models.OfferExpired || len(store.offers) != 2 || store.offers[1].CourierID != 2 {
+		t.Fatalf("Expected offer to cascade to courier 2, got %+v", store.offers)
+	}
+	if !store.offers[1].ExpiresAt.Equal(clock.Now().Add(time.Minute)) {
+		t.Errorf("Expected new window from the cascade time, got %v", store.offers[1].ExpiresAt)
+	}
+
+	// Tarde demais para o primeiro entregador
+	if _, err := d.Accept(store.offers[0].ID, 1); !errors.Is(err, ErrOfferNotPending) {
+		t.Errorf("Expected ErrOfferNotPending for expired offer, got %v", err)
+	}
+
+	// Sem mais candidatos o pedido fica na fila para retirada manual
+	clock.Advance(time.Minute)
+	if _, err := d.ExpireOffers(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(store.offers) != 2 {
+		t.Errorf("Expected no further offers after candidates are exhausted, got %+v", store.offers)
+	}
+	if ready, _ := store.Dispatchable(1); !ready {
+		t.Errorf("Expected order to remain ready for manual claim")
+	}
+}
+
+func TestDispatcherSkipsOrderOfferedByAnotherInstance(t *testing.T) {
+	d, store, _ := newTestDispatcher(NearestFirstStrategy{}, 1,
+		DispatchCandidate{CourierID: 1, DistanceKm: float(0.5)},
+		DispatchCandidate{CourierID: 2, DistanceKm: float(2)},
+	)
+	other := NewDispatcher(store, NearestFirstStrategy{}, d.claims, nil, time.Minute, 1)
+
+	if offer, err := d.Dispatch(1); err != nil || offeredTo(offer) != 1 {
+		t.Fatalf("Expected offer to courier 1, got %+v (%v)", offer, err)
+	}
+	offer, err := other.Dispatch(1)
+	if err != nil || offer != nil {
+		t.Errorf("Expected the second instance to skip the offered order, got %+v (%v)", offer, err)
+	}
+	if len(store.offers) != 1 {
+		t.Errorf("Expected a single pending offer, got %+v", store.offers)
+	}
+}
+
+func TestDispatcherAcceptAndDecline(t *testing.T) {
+	d, store, clock := newTestDispatcher(RoundRobinStrategy{}, 1,
+		DispatchCandidate{CourierID: 1},
+		DispatchCandidate{CourierID: 2},
+	)
+
+	offer, _ := d.Dispatch(1)
+	if _, err := d.Accept(offer.ID, 2); !errors.Is(err, ErrOfferNotFound) {
+		t.Errorf("Expected ErrOfferNotFound when accepting another courier's offer, got %v", err)
+	}
+
+	clock.Advance(10 * time.Second)
+	if _, err := d.Decline(offer.ID, 1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(store.offers) != 2 || store.offers[1].CourierID != 2 {
+		t.Fatalf("Expected decline to cascade to courier 2, got %+v", store.offers)
+	}
+
+	accepted, err := d.Accept(store.offers[1].ID, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if accepted.Status != models.OfferAccepted {
+		t.Errorf("Expected accepted offer, got %s", accepted.Status)
+	}
+	order := store.claims.orders[1]
+	if order.DeliveryID == nil || *order.DeliveryID != 2 || order.Status != models.StatusDelivering {
+		t.Errorf("Expected order assigned to courier 2 and delivering, got %+v", order)
+	}
+	if _, err := d.Decline(store.offers[1].ID, 2); !errors.Is(err, ErrOfferNotPending) {
+		t.Errorf("Expected ErrOfferNotPending after accepting, got %v", err)
+	}
+}
+
+func TestDispatcherAcceptAfterManualClaim(t *testing.T) {
+	d, store, _ := newTestDispatcher(RoundRobinStrategy{}, 1, DispatchCandidate{CourierID: 1})
+
+	offer, _ := d.Dispatch(1)
+	if err := d.claims.Claim(1, 9); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := d.Accept(offer.ID, 1); !errors.Is(err, ErrOrderNotClaimable) {
+		t.Errorf("Expected ErrOrderNotClaimable, got %v", err)
+	}
+	if store.offers[0].Status != models.OfferCancelled {
+		t.Errorf("Expected offer cancelled, got %s", store.offers[0].Status)
+	}
+}
+
+func TestDispatcherRoundRobinIsFair(t *testing.T) {
+	d, _, clock := newTestDispatcher(RoundRobinStrategy{}, 6,
+		DispatchCandidate{CourierID: 1},
+		DispatchCandidate{CourierID: 2},
+		DispatchCandidate{CourierID: 3},
+	)
+
+	// Cada pedido é aceito por quem recebeu a oferta; a próxima vai para quem esperou mais
+	var first []uint
+	for orderID := uint(1); orderID <= 6; orderID++ {
+		clock.Advance(time.Second)
+		offer, err := d.Dispatch(orderID)
+		if err != nil || offer == nil {
+			t.Fatalf("Expected offer for order %d, got %v", orderID, err)
+		}
+		first = append(first, offer.CourierID)
+		if _, err := d.Accept(offer.ID, offer.CourierID); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	expected := []uint{1, 2, 3, 1, 2, 3}
+	if !reflect.DeepEqual(first, expected) {
+		t.Errorf("Expected first offers %v, got %v", expected, first)
+	}
+}
+
+func TestDispatcherSkipsBusyCouriers(t *testing.T) {
+	d, store, clock := newTestDispatcher(NearestFirstStrategy{}, 2,
+		DispatchCandidate{CourierID: 1, DistanceKm: float(0.2), ActiveDeliveries: 1},
+		DispatchCandidate{CourierID: 2, DistanceKm: float(4)},
+	)
+
+	offer, _ := d.Dispatch(1)
+	if offeredTo(offer) != 2 {
+		t.Fatalf("Expected courier at max load to be skipped, got offer to %d", offeredTo(offer))
+	}
+
+	// Entregador 2 está decidindo a oferta do pedido 1: o pedido 2 fica sem oferta até ele responder
+	if offer, _ := d.Dispatch(2); offer != nil {
+		t.Errorf("Expected no offer while the only free courier is deciding, got %+v", offer)
+	}
+
+	clock.Advance(5 * time.Second)
+	if _, err := d.Decline(offer.ID, 2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := d.Tick(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	last := store.offers[len(store.offers)-1]
+	if last.OrderID != 2 || last.CourierID != 2 || last.Status != models.OfferPending {
+		t.Errorf("Expected Tick to offer order 2 to courier 2, got %+v", last)
+	}
+}
+
+func TestDispatcherExpireOffersContinuesAfterDispatchError(t *testing.T) {
+	d, store, clock := newTestDispatcher(NearestFirstStrategy{}, 2,
+		DispatchCandidate{CourierID: 1, DistanceKm: float(0.5)},
+		DispatchCandidate{CourierID: 2, DistanceKm: float(1)},
+		DispatchCandidate{CourierID: 3, DistanceKm: float(2)},
+	)
+	d.Dispatch(1)
+	d.Dispatch(2)
+
+	clock.Advance(time.Minute)
+	store.failing = map[uint]error{1: errors.New("banco indisponível")}
+	expired, err := d.ExpireOffers()
+	if err != nil || expired != 2 {
+		t.Fatalf("Expected 2 expired offers without error, got %d (%v)", expired, err)
+	}
+	if pending, _ := store.PendingOffers(1, clock.Now()); len(pending) != 1 || pending[0].OrderID != 2 {
+		t.Fatalf("Expected order 2 to cascade despite the failure on order 1, got %+v", store.offers)
+	}
+
+	if err := d.Tick(); err != nil {
+		t.Fatalf("Expected Tick to log dispatch failures, got %v", err)
+	}
+	store.failing = nil
+	if err := d.Tick(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	last := store.offers[len(store.offers)-1]
+	if last.OrderID != 1 || last.Status != models.OfferPending {
+		t.Errorf("Expected order 1 to be offered once the store recovers, got %+v", last)
+	}
+}
+
+// TestDBDispatchStoreCandidatesDistance confere a distância até a loja calculada a partir da última posição.
+func TestDBDispatchStoreCandidatesDistance(t *testing.T) {
+	db := openTestDB(t)
+
+	suffix := time.Now().Format("150405.000000")
+	customer := models.User{Name: "Cliente", Email: "dispatch-customer-" + suffix + "@example.com", Type: models.CustomerType}
+	near := models.User{Name: "Perto", Email: "dispatch-near-" + suffix + "@example.com", Type: models.DeliveryType}
+	stale := models.User{Name: "Sem posição", Email: "dispatch-stale-" + suffix + "@example.com", Type: models.DeliveryType}
+	for _, user := range []*models.User{&customer, &near, &stale} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	order := models.Order{CustomerID: customer.ID, Status: models.StatusReady}
+	if err := db.Create(&order).Error; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	now := time.Now()
+	couriers := []uint{near.ID, stale.ID}
+	for _, courierID := range couriers {
+		db.Create(&models.CourierAvailability{UserID: courierID, Status: models.CourierOnline, Since: now})
+		db.Create(&models.CourierShift{CourierID: courierID, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)})
+	}
+	db.Create(&models.CourierPosition{CourierID: near.ID, Latitude: -23.5600, Longitude: -46.6400, RecordedAt: now})
+	db.Create(&models.CourierPosition{CourierID: stale.ID, Latitude: -23.5600, Longitude: -46.6400, RecordedAt: now.Add(-time.Hour)})
+	t.Cleanup(func() {
+		db.Unscoped().Where("courier_id IN ?", couriers).Delete(&models.CourierPosition{})
+		db.Unscoped().Where("courier_id IN ?", couriers).Delete(&models.CourierShift{})
+		db.Unscoped().Where("user_id IN ?", couriers).Delete(&models.CourierAvailability{})
+		db.Unscoped().Delete(&order)
+		db.Unscoped().Delete(&[]models.User{customer, near, stale})
+	})
+
+	store := NewDBDispatchStore(db, NewCourierService(db))
+	store.SetStoreLocation(-23.5614, -46.6559)
+	candidates, err := store.Candidates(order.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	distances := map[uint]*float64{}
+	for _, candidate := range candidates {
+		distances[candidate.CourierID] = candidate.DistanceKm
+	}
+	expected := Haversine(-23.5600, -46.6400, -23.5614, -46.6559)
+	if distance := distances[near.ID]; distance == nil || *distance != expected {
+		t.Errorf("Expected distance %.3f km for the courier with a recent position, got %v", expected, distance)
+	}
+	if distance, ok := distances[stale.ID]; !ok || distance != nil {
+		t.Errorf("Expected courier with a stale position to be a candidate without distance, got %v (%v)", distance, ok)
+	}
+}
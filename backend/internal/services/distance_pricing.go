@@ -0,0 +1,73 @@
+package services
+
+import (
+	"errors"
+	"math"
+)
+
+var ErrBeyondMaxDistance = errors.New("endereço além da distância máxima de entrega")
+
+const earthRadiusKm = 6371.0
+
+// DistanceCalculator mede a distância em km entre a loja e o cliente.
+// HaversineDistance usa a linha reta; um provedor de rotas pode fornecer a distância pelas ruas.
+type DistanceCalculator interface {
+	DistanceKm(fromLatitude, fromLongitude, toLatitude, toLongitude float64) (float64, error)
+}
+
+// HaversineDistance distância em linha reta sobre a superfície da Terra
+type HaversineDistance struct{}
+
+func (HaversineDistance) DistanceKm(fromLatitude, fromLongitude, toLatitude, toLongitude float64) (float64, error) {
+	return Haversine(fromLatitude, fromLongitude, toLatitude, toLongitude), nil
+}
+
+// Haversine retorna a distância em km entre dois pontos (graus decimais)
+func Haversine(fromLatitude, fromLongitude, toLatitude, toLongitude float64) float64 {
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+
+	dLat := toRadians(toLatitude - fromLatitude)
+	dLng := toRadians(toLongitude - fromLongitude)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(fromLatitude))*math.Cos(toRadians(toLatitude))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// DistancePricing taxa de entrega proporcional à distância entre a loja e o cliente
+type DistancePricing struct {
+	StoreLatitude  float64
+	StoreLongitude float64
+	BaseFee        float64
+	PerKm          float64
+	// Subtotal a partir do qual a entrega é grátis; zero desativa
+	FreeDeliveryThreshold float64
+	// Distância máxima atendida; zero não limita
+	MaxDistanceKm float64
+	Distance      DistanceCalculator
+}
+
+// Quote calcula a taxa como BaseFee + PerKm × distância, arredondada em centavos
+func (p *DistancePricing) Quote(subtotal float64, latitude, longitude *float64) (*DeliveryQuote, error) {
+	if latitude == nil || longitude == nil {
+		return nil, ErrDeliveryLocationNeeded
+	}
+
+	calculator := p.Distance
+	if calculator == nil {
+		calculator = HaversineDistance{}
+	}
+	distance, err := calculator.DistanceKm(p.StoreLatitude, p.StoreLongitude, *latitude, *longitude)
+	if err != nil {
+		return nil, err
+	}
+	if p.MaxDistanceKm > 0 && distance > p.MaxDistanceKm {
+		return nil, ErrBeyondMaxDistance
+	}
+
+	quote := &DeliveryQuote{DistanceKm: math.Round(distance*100) / 100}
+	if p.FreeDeliveryThreshold > 0 && subtotal >= p.FreeDeliveryThreshold {
+		return quote, nil
+	}
+	quote.Fee = math.Round((p.BaseFee+p.PerKm*distance)*100) / 100
+	return quote, nil
+}
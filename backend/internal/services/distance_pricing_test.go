@@ -0,0 +1,71 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestHaversine(t *testing.T) {
+	// Praça da Sé (SP) até a Praça Mauá (RJ): cerca de 357 km em linha reta
+	distance := Haversine(-23.5503, -46.6339, -22.8973, -43.1802)
+	if math.Abs(distance-357) > 5 {
+		t.Errorf("Expected about 357 km, got %.1f", distance)
+	}
+	if Haversine(-23.55, -46.63, -23.55, -46.63) != 0 {
+		t.Errorf("Expected zero distance for the same point")
+	}
+}
+
+type fixedDistance float64
+
+func (d fixedDistance) DistanceKm(_, _, _, _ float64) (float64, error) {
+	return float64(d), nil
+}
+
+func TestDistancePricingQuote(t *testing.T) {
+	latitude, longitude := -23.56, -46.65
+
+	testCases := []struct {
+		name        string
+		distance    float64
+		subtotal    float64
+		expectedFee float64
+		expectedErr error
+	}{
+		{"Base fee plus distance", 4, 40, 11, nil},
+		{"Rounded to cents", 3.322, 40, 9.98, nil},
+		{"Free above threshold", 4, 100, 0, nil},
+		{"Beyond max distance", 12.5, 100, 0, ErrBeyondMaxDistance},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pricing := &DistancePricing{
+				BaseFee:               5,
+				PerKm:                 1.5,
+				FreeDeliveryThreshold: 100,
+				MaxDistanceKm:         10,
+				Distance:              fixedDistance(tc.distance),
+			}
+
+			quote, err := pricing.Quote(tc.subtotal, &latitude, &longitude)
+			if tc.expectedErr != nil {
+				if !errors.Is(err, tc.expectedErr) {
+					t.Errorf("Expected %v, got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if quote.Fee != tc.expectedFee {
+				t.Errorf("Expected fee %.2f, got %.2f", tc.expectedFee, quote.Fee)
+			}
+		})
+	}
+
+	if _, err := (&DistancePricing{}).Quote(50, nil, &longitude); !errors.Is(err, ErrDeliveryLocationNeeded) {
+		t.Errorf("Expected ErrDeliveryLocationNeeded, got %v", err)
+	}
+}
@@ -0,0 +1,429 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"cupcake-delivery/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidEarningType   = errors.New("lançamento manual deve ser gorjeta ou ajuste")
+	ErrInvalidEarningAmount = errors.New("valor do lançamento inválido")
+	ErrPayoutExceedsBalance = errors.New("pagamento maior que o saldo do entregador")
+	ErrNothingToPay         = errors.New("entregador sem saldo a receber")
+	ErrDuplicateEarning     = errors.New("pedido já tem lançamento desse tipo")
+)
+
+// CourierPayRate quanto o entregador recebe por entrega: valor fixo mais um valor por km da loja ao cliente
+type CourierPayRate struct {
+	PerDelivery float64
+	PerKm       float64
+}
+
+// Fee repasse do pedido, arredondado em centavos
+func (r CourierPayRate) Fee(order *models.Order) float64 {
+	return roundCents(r.PerDelivery + r.PerKm*order.DeliveryDistanceKm)
+}
+
+// EarningsTotals soma dos lançamentos por tipo; Net é o resultado do período, com os pagamentos descontados
+type EarningsTotals struct {
+	Deliveries  int     `json:"deliveries"`
+	Fees        float64 `json:"fees"`
+	Tips        float64 `json:"tips"`
+	Adjustments float64 `json:"adjustments"`
+	Payouts     float64 `json:"payouts"`
+	Net         float64 `json:"net"`
+}
+
+// EarningsStatement extrato do entregador no período e saldo atual
+type EarningsStatement struct {
+	From    time.Time               `json:"from"`
+	To      time.Time               `json:"to"`
+	Entries []models.CourierEarning `json:"entries"`
+	Totals  EarningsTotals          `json:"totals"`
+	Balance float64                 `json:"balance"`
+}
+
+// CourierPayout linha do relatório de pagamentos; Balance é o saldo a pagar ao fim do período
+type CourierPayout struct {
+	CourierID uint   `json:"courier_id"`
+	Name      string `json:"name"`
+	EarningsTotals
+	Balance float64 `json:"balance"`
+}
+
+// PayoutReconciliation confronta os pedidos entregues no período com os repasses lançados
+type PayoutReconciliation struct {
+	DeliveredOrders int     `json:"delivered_orders"`
+	FeeEntries      int     `json:"fee_entries"`
+	MissingFees     []uint  `json:"missing_fee_order_ids"`   // Pedidos entregues sem repasse
+	UnmatchedFees   []uint  `json:"unmatched_fee_order_ids"` // Repasses sem pedido entregue pelo mesmo entregador no período
+	DeliveryFees    float64 `json:"delivery_fees_charged"`   // Taxas de entrega cobradas dos clientes nesses pedidos
+	Tips            float64 `json:"tips_charged"`            // Gorjetas cobradas nesses pedidos, devidas integralmente aos entregadores
+}
+
+// PayoutReport pagamentos dos entregadores no período, com totais e conciliação
+type PayoutReport struct {
+	From           time.Time            `json:"from"`
+	To             time.Time            `json:"to"`
+	Couriers       []CourierPayout      `json:"couriers"`
+	Totals         EarningsTotals       `json:"totals"`
+	Reconciliation PayoutReconciliation `json:"reconciliation"`
+}
+
+// deliveredOrder pedido entregue no período, para a conciliação
+type deliveredOrder struct {
+	ID          uint
+	DeliveryID  uint
+	DeliveryFee float64
+	Tip         float64
+}
+
+// EarningsInput lançamento manual de gorjeta ou ajuste
+type EarningsInput struct {
+	Type        models.EarningType
+	Amount      float64
+	OrderID     *uint
+	Description string
+}
+
+// EarningsService extrato dos entregadores: repasses por entrega, gorjetas, ajustes e pagamentos
+type EarningsService struct {
+	db   *gorm.DB
+	rate CourierPayRate
+	now  func() time.Time
+}
+
+func NewEarningsService(db *gorm.DB, rate CourierPayRate) *EarningsService {
+	return &EarningsService{db: db, rate: rate, now: time.Now}
+}
+
+// RecordDelivery lança o repasse do pedido entregue e a gorjeta dada no checkout ao entregador;
+// repetir não duplica os lançamentos. Roda na transação que marca o pedido como entregue, para que
+// não exista entrega sem repasse.
+func (s *EarningsService) RecordDelivery(tx *gorm.DB, order *models.Order) error {
+	if order.Status != models.StatusDelivered || order.DeliveryID == nil {
+		return nil
+	}
+	at := s.now()
+	if order.DeliveredAt != nil {
+		at = *order.DeliveredAt
+	}
+
+	entries := []models.CourierEarning{{
+		CourierID:   *order.DeliveryID,
+		OrderID:     &order.ID,
+		Type:        models.EarningDeliveryFee,
+		Amount:      s.rate.Fee(order),
+		Description: fmt.Sprintf("Entrega do pedido #%d", order.ID),
+		CreatedAt:   at,
+	}}
+	if order.Tip > 0 {
+		entries = append(entries, models.CourierEarning{
+			CourierID:   *order.DeliveryID,
+			OrderID:     &order.ID,
+			Type:        models.EarningTip,
+			Amount:      order.Tip,
+			Description: fmt.Sprintf("Gorjeta do pedido #%d", order.ID),
+			CreatedAt:   at,
+		})
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&entries).Error
+}
+
+// AddEntry lança uma gorjeta (positiva) ou um ajuste (diferente de zero) feito por um admin
+func (s *EarningsService) AddEntry(courierID, createdBy uint, input EarningsInput) (*models.CourierEarning, error) {
+	switch input.Type {
+	case models.EarningTip:
+		if input.Amount <= 0 {
+			return nil, ErrInvalidEarningAmount
+		}
+	case models.EarningAdjustment:
+		if input.Amount == 0 {
+			return nil, ErrInvalidEarningAmount
+		}
+	default:
+		return nil, ErrInvalidEarningType
+	}
+
+	entry := &models.CourierEarning{
+		CourierID:   courierID,
+		OrderID:     input.OrderID,
+		Type:        input.Type,
+		Amount:      roundCents(input.Amount),
+		Description: input.Description,
+		CreatedByID: &createdBy,
+		CreatedAt:   s.now(),
+	}
+	if err := s.db.Create(entry).Error; err != nil {
+		// Gorjeta de um pedido que já tem gorjeta lançada
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrDuplicateEarning
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Payout registra um pagamento ao entregador; sem amount, paga o saldo inteiro
+func (s *EarningsService) Payout(courierID, createdBy uint, amount *float64, description string) (*models.CourierEarning, error) {
+	var entry *models.CourierEarning
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Trava o entregador para dois pagamentos simultâneos não passarem do saldo
+		var courier models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&courier, courierID).Error; err != nil {
+			return err
+		}
+		balance, err := balanceOf(tx, courierID)
+		if err != nil {
+			return err
+		}
+
+		value := balance
+		if amount != nil {
+			value = roundCents(*amount)
+			if value <= 0 {
+				return ErrInvalidEarningAmount
+			}
+		}
+		if balance <= 0 {
+			return ErrNothingToPay
+		}
+		if value > balance {
+			return ErrPayoutExceedsBalance
+		}
+
+		entry = &models.CourierEarning{
+			CourierID:   courierID,
+			Type:        models.EarningPayout,
+			Amount:      -value,
+			Description: description,
+			CreatedByID: &createdBy,
+			CreatedAt:   s.now(),
+		}
+		return tx.Create(entry).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Statement extrato do entregador entre from e to, com o saldo atual
+func (s *EarningsService) Statement(courierID uint, from, to time.Time) (*EarningsStatement, error) {
+	statement := &EarningsStatement{From: from, To: to, Entries: []models.CourierEarning{}}
+	if err := s.db.Where("courier_id = ? AND created_at >= ? AND created_at < ?", courierID, from, to).
+		Order("created_at, id").
+		Find(&statement.Entries).Error; err != nil {
+		return nil, err
+	}
+	statement.Totals = summarizeEarnings(statement.Entries)
+
+	balance, err := balanceOf(s.db, courierID)
+	if err != nil {
+		return nil, err
+	}
+	statement.Balance = balance
+	return statement, nil
+}
+
+// PayoutReport resume os lançamentos do período por entregador e confere os repasses com os pedidos entregues
+func (s *EarningsService) PayoutReport(from, to time.Time) (*PayoutReport, error) {
+	var entries []models.CourierEarning
+	if err := s.db.Where("created_at >= ? AND created_at < ?", from, to).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	var balances []struct {
+		CourierID uint
+		Balance   float64
+	}
+	if err := s.db.Model(&models.CourierEarning{}).
+		Select("courier_id, SUM(amount) AS balance").
+		Where("created_at < ?", to).
+		Group("courier_id").
+		Scan(&balances).Error; err != nil {
+		return nil, err
+	}
+	balanceByCourier := make(map[uint]float64, len(balances))
+	for _, row := range balances {
+		balanceByCourier[row.CourierID] = row.Balance
+	}
+
+	var delivered []deliveredOrder
+	if err := s.db.Model(&models.Order{}).
+		Select("id, delivery_id, delivery_fee, tip").
+		Where("status = ? AND delivery_id IS NOT NULL AND delivered_at >= ? AND delivered_at < ?", models.StatusDelivered, from, to).
+		Scan(&delivered).Error; err != nil {
+		return nil, err
+	}
+
+	report := buildPayoutReport(entries, balanceByCourier, delivered)
+	report.From, report.To = from, to
+
+	if len(report.Couriers) > 0 {
+		ids := make([]uint, 0, len(report.Couriers))
+		for _, payout := range report.Couriers {
+			ids = append(ids, payout.CourierID)
+		}
+		var users []models.User
+		if err := s.db.Select("id", "name").Where("id IN ?", ids).Find(&users).Error; err != nil {
+			return nil, err
+		}
+		names := make(map[uint]string, len(users))
+		for _, user := range users {
+			names[user.ID] = user.Name
+		}
+		for i := range report.Couriers {
+			report.Couriers[i].Name = names[report.Couriers[i].CourierID]
+		}
+	}
+	return &report, nil
+}
+
+// WriteCSV exporta uma linha por entregador e a linha de totais
+func (r *PayoutReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	money := func(value float64) string { return strconv.FormatFloat(value, 'f', 2, 64) }
+
+	rows := [][]string{{"courier_id", "courier", "deliveries", "fees", "tips", "adjustments", "payouts", "net", "balance"}}
+	balance := 0.0
+	for _, payout := range r.Couriers {
+		balance += payout.Balance
+		rows = append(rows, []string{
+			strconv.FormatUint(uint64(payout.CourierID), 10),
+			payout.Name,
+			strconv.Itoa(payout.Deliveries),
+			money(payout.Fees),
+			money(payout.Tips),
+			money(payout.Adjustments),
+			money(payout.Payouts),
+			money(payout.Net),
+			money(payout.Balance),
+		})
+	}
+	rows = append(rows, []string{
+		"",
+		"TOTAL",
+		strconv.Itoa(r.Totals.Deliveries),
+		money(r.Totals.Fees),
+		money(r.Totals.Tips),
+		money(r.Totals.Adjustments),
+		money(r.Totals.Payouts),
+		money(r.Totals.Net),
+		money(roundCents(balance)),
+	})
+
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// balanceOf saldo do entregador: soma de todos os lançamentos
+func balanceOf(db *gorm.DB, courierID uint) (float64, error) {
+	var balance float64
+	if err := db.Model(&models.CourierEarning{}).
+		Where("courier_id = ?", courierID).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&balance).Error; err != nil {
+		return 0, err
+	}
+	return roundCents(balance), nil
+}
+
+// summarizeEarnings soma os lançamentos por tipo; cada repasse conta como uma entrega
+func summarizeEarnings(entries []models.CourierEarning) EarningsTotals {
+	var totals EarningsTotals
+	for _, entry := range entries {
+		switch entry.Type {
+		case models.EarningDeliveryFee:
+			totals.Deliveries++
+			totals.Fees += entry.Amount
+		case models.EarningTip:
+			totals.Tips += entry.Amount
+		case models.EarningAdjustment:
+			totals.Adjustments += entry.Amount
+		case models.EarningPayout:
+			totals.Payouts += entry.Amount
+		}
+		totals.Net += entry.Amount
+	}
+
+	totals.Fees = roundCents(totals.Fees)
+	totals.Tips = roundCents(totals.Tips)
+	totals.Adjustments = roundCents(totals.Adjustments)
+	totals.Payouts = roundCents(totals.Payouts)
+	totals.Net = roundCents(totals.Net)
+	return totals
+}
+
+// buildPayoutReport agrupa os lançamentos por entregador (em ordem de ID) e confere os repasses com os pedidos
+func buildPayoutReport(entries []models.CourierEarning, balances map[uint]float64, delivered []deliveredOrder) PayoutReport {
+	byCourier := make(map[uint][]models.CourierEarning)
+	for _, entry := range entries {
+		byCourier[entry.CourierID] = append(byCourier[entry.CourierID], entry)
+	}
+
+	report := PayoutReport{Couriers: []CourierPayout{}, Totals: summarizeEarnings(entries)}
+	for courierID, courierEntries := range byCourier {
+		report.Couriers = append(report.Couriers, CourierPayout{
+			CourierID:      courierID,
+			EarningsTotals: summarizeEarnings(courierEntries),
+			Balance:        roundCents(balances[courierID]),
+		})
+	}
+	sort.Slice(report.Couriers, func(i, j int) bool { return report.Couriers[i].CourierID < report.Couriers[j].CourierID })
+
+	reconciliation := PayoutReconciliation{
+		DeliveredOrders: len(delivered),
+		MissingFees:     []uint{},
+		UnmatchedFees:   []uint{},
+	}
+	deliveredBy := make(map[uint]uint, len(delivered))
+	for _, order := range delivered {
+		deliveredBy[order.ID] = order.DeliveryID
+		reconciliation.DeliveryFees += order.DeliveryFee
+		reconciliation.Tips += order.Tip
+	}
+	reconciliation.DeliveryFees = roundCents(reconciliation.DeliveryFees)
+	reconciliation.Tips = roundCents(reconciliation.Tips)
+
+	paid := make(map[uint]bool)
+	for _, entry := range entries {
+		if entry.Type != models.EarningDeliveryFee || entry.OrderID == nil {
+			continue
+		}
+		reconciliation.FeeEntries++
+		courierID, ok := deliveredBy[*entry.OrderID]
+		if !ok || courierID != entry.CourierID {
+			reconciliation.UnmatchedFees = append(reconciliation.UnmatchedFees, *entry.OrderID)
+			continue
+		}
+		paid[*entry.OrderID] = true
+	}
+	for _, order := range delivered {
+		if !paid[order.ID] {
+			reconciliation.MissingFees = append(reconciliation.MissingFees, order.ID)
+		}
+	}
+	sort.Slice(reconciliation.MissingFees, func(i, j int) bool { return reconciliation.MissingFees[i] < reconciliation.MissingFees[j] })
+	sort.Slice(reconciliation.UnmatchedFees, func(i, j int) bool { return reconciliation.UnmatchedFees[i] < reconciliation.UnmatchedFees[j] })
+
+	report.Reconciliation = reconciliation
+	return report
+}
+
+func roundCents(value float64) float64 {
+	return math.Round(value*100) / 100
+}
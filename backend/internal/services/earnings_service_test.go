@@ -0,0 +1,162 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cupcake-delivery/internal/models"
+)
+
+func orderRef(id uint) *uint { return &id }
+
+func TestCourierPayRateFee(t *testing.T) {
+	testCases := []struct {
+		name       string
+		rate       CourierPayRate
+		distanceKm float64
+		expected   float64
+	}{
+		{"Flat fee", CourierPayRate{PerDelivery: 6}, 4.2, 6},
+		{"Per km", CourierPayRate{PerDelivery: 4, PerKm: 1.25}, 3.1, 7.88},
+		{"Unknown distance", CourierPayRate{PerDelivery: 4, PerKm: 1.25}, 0, 4},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			order := &models.Order{DeliveryDistanceKm: tc.distanceKm}
+			if got := tc.rate.Fee(order); got != tc.expected {
+				t.Errorf("Expected fee %.2f, got %.2f", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSummarizeEarnings(t *testing.T) {
+	entries := []models.CourierEarning{
+		{Type: models.EarningDeliveryFee, Amount: 6.1},
+		{Type: models.EarningDeliveryFee, Amount: 6.2},
+		{Type: models.EarningTip, Amount: 5},
+		{Type: models.EarningAdjustment, Amount: -2.5},
+		{Type: models.EarningPayout, Amount: -10},
+	}
+
+	totals := summarizeEarnings(entries)
+	expected := EarningsTotals{Deliveries: 2, Fees: 12.3, Tips: 5, Adjustments: -2.5, Payouts: -10, Net: 4.8}
+	if totals != expected {
+		t.Errorf("Expected %+v, got %+v", expected, totals)
+	}
+}
+
+func TestBuildPayoutReport(t *testing.T) {
+	entries := []models.CourierEarning{
+		{CourierID: 2, OrderID: orderRef(10), Type: models.EarningDeliveryFee, Amount: 6},
+		{CourierID: 2, OrderID: orderRef(11), Type: models.EarningDeliveryFee, Amount: 6},
+		{CourierID: 2, OrderID: orderRef(11), Type: models.EarningTip, Amount: 4},
+		{CourierID: 1, OrderID: orderRef(12), Type: models.EarningDeliveryFee, Amount: 7},
+		{CourierID: 1, Type: models.EarningPayout, Amount: -20},
+		// Repasse lançado para quem não entregou o pedido
+		{CourierID: 1, OrderID: orderRef(13), Type: models.EarningDeliveryFee, Amount: 6},
+	}
+	balances := map[uint]float64{1: 3, 2: 16}
+	delivered := []deliveredOrder{
+		{ID: 10, DeliveryID: 2, DeliveryFee: 8},
+		{ID: 11, DeliveryID: 2, DeliveryFee: 8, Tip: 4},
+		{ID: 12, DeliveryID: 1, DeliveryFee: 9.5},
+		{ID: 13, DeliveryID: 2, DeliveryFee: 8},
+		{ID: 14, DeliveryID: 1, DeliveryFee: 7},
+	}
+
+	report := buildPayoutReport(entries, balances, delivered)
+
+	if len(report.Couriers) != 2 || report.Couriers[0].CourierID != 1 || report.Couriers[1].CourierID != 2 {
+		t.Fatalf("Expected couriers 1 and 2 in order, got %+v", report.Couriers)
+	}
+	first, second := report.Couriers[0], report.Couriers[1]
+	if first.Deliveries != 2 || first.Fees != 13 || first.Payouts != -20 || first.Net != -7 || first.Balance != 3 {
+		t.Errorf("Unexpected totals for courier 1: %+v", first)
+	}
+	if second.Deliveries != 2 || second.Fees != 12 || second.Tips != 4 || second.Net != 16 || second.Balance != 16 {
+		t.Errorf("Unexpected totals for courier 2: %+v", second)
+	}
+	if report.Totals.Deliveries != 4 || report.Totals.Net != 9 {
+		t.Errorf("Unexpected report totals: %+v", report.Totals)
+	}
+
+	reconciliation := report.Reconciliation
+	if reconciliation.DeliveredOrders != 5 || reconciliation.FeeEntries != 4 || reconciliation.DeliveryFees != 40.5 || reconciliation.Tips != 4 {
+		t.Errorf("Unexpected reconciliation counts: %+v", reconciliation)
+	}
+	if !equalIDs(reconciliation.MissingFees, []uint{13, 14}) {
+		t.Errorf("Expected orders 13 and 14 without fee, got %v", reconciliation.MissingFees)
+	}
+	if !equalIDs(reconciliation.UnmatchedFees, []uint{13}) {
+		t.Errorf("Expected fee for order 13 to be unmatched, got %v", reconciliation.UnmatchedFees)
+	}
+}
+
+func TestPayoutReportWriteCSV(t *testing.T) {
+	report := buildPayoutReport([]models.CourierEarning{
+		{CourierID: 1, OrderID: orderRef(10), Type: models.EarningDeliveryFee, Amount: 6},
+		{CourierID: 1, Type: models.EarningAdjustment, Amount: 1.5},
+	}, map[uint]float64{1: 7.5}, nil)
+	report.Couriers[0].Name = "Silva, João"
+
+	var buf bytes.Buffer
+	if err := report.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+
+	expected := "courier_id,courier,deliveries,fees,tips,adjustments,payouts,net,balance\n" +
+		"1,\"Silva, João\",1,6.00,0.00,1.50,0.00,7.50,7.50\n" +
+		",TOTAL,1,6.00,0.00,1.50,0.00,7.50,7.50\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected CSV:\n%s", buf.String())
+	}
+}
+
+// TestDeliverRecordsEarningsInTransaction conclui a entrega com comprovante contra o PostgreSQL e confere
+// que o repasse e a gorjeta foram lançados junto; uma segunda gorjeta manual para o pedido é recusada
+func TestDeliverRecordsEarningsInTransaction(t *testing.T) {
+	db := openTestDB(t)
+
+	suffix := time.Now().Format("150405.000000")
+	customer := models.User{Name: "Cliente", Email: "earnings-customer-" + suffix + "@example.com", Type: models.CustomerType}
+	courier := models.User{Name: "Entregador", Email: "earnings-courier-" + suffix + "@example.com", Type: models.DeliveryType}
+	for _, user := range []*models.User{&customer, &courier} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	order := models.Order{CustomerID: customer.ID, DeliveryID: &courier.ID, Status: models.StatusDelivering, DeliveryDistanceKm: 2, Tip: 5}
+	if err := db.Create(&order).Error; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Where("courier_id = ?", courier.ID).Delete(&models.CourierEarning{})
+		db.Where("order_id = ?", order.ID).Delete(&models.DeliveryProof{})
+		db.Where("order_id = ?", order.ID).Delete(&models.OrderStatusChange{})
+		db.Unscoped().Delete(&order)
+		db.Unscoped().Delete(&[]models.User{customer, courier})
+	})
+
+	earnings := NewEarningsService(db, CourierPayRate{PerDelivery: 4, PerKm: 1.5})
+	proofs := NewDeliveryProofService(db, NewLocalFileStorage(t.TempDir()))
+	proofs.SetEarnings(earnings)
+	if _, err := proofs.Deliver(context.Background(), order.ID, courier.ID, DeliveryProofInput{RecipientName: "Porteiro"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var entries []models.CourierEarning
+	db.Where("order_id = ?", order.ID).Order("type").Find(&entries)
+	if len(entries) != 2 || entries[0].Type != models.EarningDeliveryFee || entries[0].Amount != 7 || entries[1].Type != models.EarningTip || entries[1].Amount != 5 {
+		t.Fatalf("Expected delivery fee 7 and tip 5, got %+v", entries)
+	}
+
+	_, err := earnings.AddEntry(courier.ID, courier.ID, EarningsInput{Type: models.EarningTip, Amount: 2, OrderID: &order.ID})
+	if !errors.Is(err, ErrDuplicateEarning) {
+		t.Errorf("Expected ErrDuplicateEarning for a second tip on the order, got %v", err)
+	}
+}
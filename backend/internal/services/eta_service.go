@@ -0,0 +1,351 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"time"
+
+	"cupcake-delivery/internal/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// etaPickupTime espera média entre o pedido ficar pronto e um entregador retirá-lo
+	etaPickupTime = 5 * time.Minute
+	// etaDefaultTravelTime trajeto usado sem distância nem prazo da zona de entrega
+	etaDefaultTravelTime = 20 * time.Minute
+	// etaHistoryWindow período do histórico de status usado no tempo de preparo
+	etaHistoryWindow = 30 * 24 * time.Hour
+	// etaMinSamples pedidos preparados a partir dos quais o histórico do produto substitui o padrão
+	etaMinSamples = 3
+)
+
+// RecordStatusChange registra a entrada do pedido no status, no histórico usado pela previsão de entrega
+func RecordStatusChange(db *gorm.DB, orderID uint, status models.OrderStatus, at time.Time) error {
+	return db.Create(&models.OrderStatusChange{OrderID: orderID, Status: status, ChangedAt: at}).Error
+}
+
+// etaStatuses status em que o pedido está a caminho do cliente e tem previsão de entrega
+var etaStatuses = []models.OrderStatus{
+	models.StatusPending,
+	models.StatusPreparing,
+	models.StatusReady,
+	models.StatusDelivering,
+}
+
+// etaInputs o que a previsão de entrega considera do pedido no status atual
+type etaInputs struct {
+	Status       models.OrderStatus
+	Since        time.Time // Entrada no status atual
+	ScheduledFor *time.Time
+	OrdersAhead  int // Pedidos pendentes ou em preparo criados antes deste
+	KitchenSlots int // Pedidos que a cozinha prepara ao mesmo tempo
+	PrepTime     time.Duration
+	TravelTime   time.Duration
+}
+
+// estimateDelivery previsão de entrega: fila da cozinha, preparo, retirada e trajeto até o cliente.
+// Etapas já em andamento contam a partir da entrada no status, sem nunca prever um horário passado.
+func estimateDelivery(in etaInputs, now time.Time) time.Time {
+	var ready time.Time
+	switch in.Status {
+	case models.StatusPending:
+		slots := in.KitchenSlots
+		if slots < 1 {
+			slots = 1
+		}
+		queue := time.Duration(in.OrdersAhead) * in.PrepTime / time.Duration(slots)
+		ready = now.Add(queue + in.PrepTime)
+	case models.StatusPreparing:
+		ready = latest(now, in.Since.Add(in.PrepTime))
+	case models.StatusDelivering:
+		return latest(now, in.Since.Add(in.TravelTime)).Round(time.Minute)
+	default:
+		ready = now
+	}
+	if in.ScheduledFor != nil {
+		ready = latest(ready, *in.ScheduledFor)
+	}
+	return ready.Add(etaPickupTime + in.TravelTime).Round(time.Minute)
+}
+
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// orderPrepTime os itens são preparados juntos, então vale o produto mais demorado.
+// Produtos sem histórico usam o tempo padrão.
+func orderPrepTime(productIDs []uint, history map[uint]time.Duration, fallback time.Duration) time.Duration {
+	if len(productIDs) == 0 {
+		return fallback
+	}
+	var longest time.Duration
+	for _, id := range productIDs {
+		prep, ok := history[id]
+		if !ok {
+			prep = fallback
+		}
+		if prep > longest {
+			longest = prep
+		}
+	}
+	return longest
+}
+
+// ETAAccuracy erro das previsões feitas em um status, em minutos
+type ETAAccuracy struct {
+	Status              models.OrderStatus `json:"status"`
+	Samples             int                `json:"samples"`
+	MeanErrorMinutes    float64            `json:"mean_error_minutes"` // Positivo: as entregas chegam depois do previsto
+	MeanAbsErrorMinutes float64            `json:"mean_absolute_error_minutes"`
+}
+
+// ETAAccuracyReport previsto contra realizado das entregas do período, por status em que a previsão foi feita
+type ETAAccuracyReport struct {
+	From     time.Time     `json:"from"`
+	To       time.Time     `json:"to"`
+	Overall  ETAAccuracy   `json:"overall"`
+	ByStatus []ETAAccuracy `json:"by_status"`
+}
+
+// summarizeETAErrors agrupa os erros das previsões já conferidas na entrega, na ordem do fluxo do pedido
+func summarizeETAErrors(predictions []models.ETAPrediction) ETAAccuracyReport {
+	type sums struct {
+		count           int
+		total, absolute float64
+	}
+	accuracy := func(status models.OrderStatus, s sums) ETAAccuracy {
+		result := ETAAccuracy{Status: status, Samples: s.count}
+		if s.count > 0 {
+			result.MeanErrorMinutes = math.Round(s.total/float64(s.count)*10) / 10
+			result.MeanAbsErrorMinutes = math.Round(s.absolute/float64(s.count)*10) / 10
+		}
+		return result
+	}
+
+	var overall sums
+	byStatus := make(map[models.OrderStatus]*sums)
+	for _, prediction := range predictions {
+		if prediction.ErrorMinutes == nil {
+			continue
+		}
+		errorMinutes := *prediction.ErrorMinutes
+		group, ok := byStatus[prediction.Status]
+		if !ok {
+			group = &sums{}
+			byStatus[prediction.Status] = group
+		}
+		for _, s := range []*sums{group, &overall} {
+			s.count++
+			s.total += errorMinutes
+			s.absolute += math.Abs(errorMinutes)
+		}
+	}
+
+	report := ETAAccuracyReport{Overall: accuracy("", overall), ByStatus: []ETAAccuracy{}}
+	for _, status := range etaStatuses {
+		if group, ok := byStatus[status]; ok {
+			report.ByStatus = append(report.ByStatus, accuracy(status, *group))
+		}
+	}
+	return report
+}
+
+// ETAService calcula a previsão de entrega dos pedidos e mede o erro das previsões
+type ETAService struct {
+	db           *gorm.DB
+	distance     DistanceCalculator
+	store        *RoutePoint
+	kitchenSlots int
+	defaultPrep  time.Duration
+	speedKmh     float64
+	now          func() time.Time
+}
+
+func NewETAService(db *gorm.DB, kitchenSlots int, defaultPrep time.Duration) *ETAService {
+	return &ETAService{
+		db:           db,
+		distance:     HaversineDistance{},
+		kitchenSlots: kitchenSlots,
+		defaultPrep:  defaultPrep,
+		speedKmh:     DefaultCourierSpeedKmh,
+		now:          time.Now,
+	}
+}
+
+// SetStoreLocation ponto de partida das entregas, para pedidos sem distância calculada no checkout
+func (s *ETAService) SetStoreLocation(latitude, longitude float64) {
+	s.store = &RoutePoint{Latitude: latitude, Longitude: longitude}
+}
+
+// Refresh recalcula a previsão de entrega do pedido no status atual e registra a previsão feita.
+// Na entrega, confere as previsões anteriores com o horário real.
+func (s *ETAService) Refresh(order *models.Order) error {
+	now := s.now()
+	eta, err := s.Estimate(order, now)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Order{}).
+			Where("id = ?", order.ID).
+			UpdateColumn("estimated_delivery_at", eta).Error; err != nil {
+			return err
+		}
+		if eta != nil {
+			if err := tx.Create(&models.ETAPrediction{
+				OrderID:     order.ID,
+				Status:      order.Status,
+				PredictedAt: now,
+				EstimatedAt: *eta,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		if order.Status == models.StatusDelivered && order.DeliveredAt != nil {
+			return tx.Model(&models.ETAPrediction{}).
+				Where("order_id = ? AND delivered_at IS NULL", order.ID).
+				Updates(map[string]interface{}{
+					"delivered_at":  *order.DeliveredAt,
+					"error_minutes": gorm.Expr("EXTRACT(EPOCH FROM (? - estimated_at)) / 60", *order.DeliveredAt),
+				}).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	order.EstimatedDeliveryAt = eta
+	return nil
+}
+
+// Estimate previsão de entrega do pedido; nil quando o pedido não está a caminho do cliente
+// (entregue, cancelado, com falha na entrega ou devolvido)
+func (s *ETAService) Estimate(order *models.Order, now time.Time) (*time.Time, error) {
+	if !slices.Contains(etaStatuses, order.Status) {
+		return nil, nil
+	}
+
+	// Em um lote de entregas, vale a previsão da parada na rota planejada
+	if order.Status == models.StatusDelivering {
+		var stop models.DeliveryBatchStop
+		err := s.db.Where("order_id = ? AND status = ? AND eta IS NOT NULL", order.ID, models.StopPending).First(&stop).Error
+		if err == nil {
+			eta := latest(now, *stop.ETA).Round(time.Minute)
+			return &eta, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	in := etaInputs{
+		Status:       order.Status,
+		Since:        order.UpdatedAt,
+		ScheduledFor: order.ScheduledFor,
+		KitchenSlots: s.kitchenSlots,
+		TravelTime:   s.travelTime(order),
+	}
+	if order.StatusChangedAt != nil {
+		in.Since = *order.StatusChangedAt
+	}
+
+	if order.Status == models.StatusPending || order.Status == models.StatusPreparing {
+		prep, err := s.prepTime(order.ID)
+		if err != nil {
+			return nil, err
+		}
+		in.PrepTime = prep
+	}
+	if order.Status == models.StatusPending {
+		var ahead int64
+		if err := s.db.Model(&models.Order{}).
+			Where("status IN ? AND id < ?", []models.OrderStatus{models.StatusPending, models.StatusPreparing}, order.ID).
+			Count(&ahead).Error; err != nil {
+			return nil, err
+		}
+		in.OrdersAhead = int(ahead)
+	}
+
+	eta := estimateDelivery(in, now)
+	return &eta, nil
+}
+
+// prepTime tempo de preparo do pedido pelo histórico de cada produto: do início do preparo até ficar pronto
+func (s *ETAService) prepTime(orderID uint) (time.Duration, error) {
+	var productIDs []uint
+	if err := s.db.Model(&models.OrderItem{}).Where("order_id = ?", orderID).Pluck("product_id", &productIDs).Error; err != nil {
+		return 0, err
+	}
+	if len(productIDs) == 0 {
+		return s.defaultPrep, nil
+	}
+
+	var rows []struct {
+		ProductID uint
+		Seconds   float64
+		Samples   int
+	}
+	if err := s.db.Raw(`
+		SELECT order_items.product_id, AVG(EXTRACT(EPOCH FROM prep.ready_at - prep.preparing_at)) AS seconds, COUNT(*) AS samples
+		FROM (
+			SELECT order_id,
+				MIN(changed_at) FILTER (WHERE status = ?) AS preparing_at,
+				MIN(changed_at) FILTER (WHERE status = ?) AS ready_at
+			FROM order_status_changes
+			WHERE changed_at >= ?
+			GROUP BY order_id
+		) prep
+		JOIN order_items ON order_items.order_id = prep.order_id AND order_items.deleted_at IS NULL
+		WHERE prep.ready_at > prep.preparing_at AND order_items.product_id IN ?
+		GROUP BY order_items.product_id`,
+		models.StatusPreparing, models.StatusReady, s.now().Add(-etaHistoryWindow), productIDs,
+	).Scan(&rows).Error; err != nil {
+		return 0, err
+	}
+
+	history := make(map[uint]time.Duration, len(rows))
+	for _, row := range rows {
+		if row.Samples >= etaMinSamples {
+			history[row.ProductID] = time.Duration(row.Seconds * float64(time.Second))
+		}
+	}
+	return orderPrepTime(productIDs, history, s.defaultPrep), nil
+}
+
+// travelTime trajeto da loja ao cliente: pela distância do checkout ou das coordenadas,
+// senão pelo prazo da zona de entrega
+func (s *ETAService) travelTime(order *models.Order) time.Duration {
+	distanceKm := order.DeliveryDistanceKm
+	if distanceKm <= 0 && s.store != nil && order.Latitude != nil && order.Longitude != nil {
+		if km, err := s.distance.DistanceKm(s.store.Latitude, s.store.Longitude, *order.Latitude, *order.Longitude); err == nil {
+			distanceKm = km
+		}
+	}
+	if distanceKm > 0 {
+		return time.Duration(EstimateArrivalMinutes(distanceKm, s.speedKmh)) * time.Minute
+	}
+	if order.EstimatedMinutes > 0 {
+		return time.Duration(order.EstimatedMinutes) * time.Minute
+	}
+	return etaDefaultTravelTime
+}
+
+// Accuracy erro das previsões das entregas feitas entre from e to
+func (s *ETAService) Accuracy(from, to time.Time) (*ETAAccuracyReport, error) {
+	var predictions []models.ETAPrediction
+	if err := s.db.Where("delivered_at >= ? AND delivered_at < ?", from, to).Find(&predictions).Error; err != nil {
+		return nil, err
+	}
+
+	report := summarizeETAErrors(predictions)
+	report.From, report.To = from, to
+	return &report, nil
+}
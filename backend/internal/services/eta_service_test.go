@@ -0,0 +1,91 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"cupcake-delivery/internal/models"
+)
+
+func TestEstimateDelivery(t *testing.T) {
+	now := time.Date(2024, 5, 10, 18, 0, 0, 0, time.UTC)
+	scheduled := now.Add(2 * time.Hour)
+
+	testCases := []struct {
+		name     string
+		in       etaInputs
+		expected time.Duration // A partir de now
+	}{
+		{"Pending with empty queue", etaInputs{Status: models.StatusPending, PrepTime: 20 * time.Minute, TravelTime: 15 * time.Minute}, 40 * time.Minute},
+		{"Pending behind the queue", etaInputs{Status: models.StatusPending, OrdersAhead: 6, KitchenSlots: 3, PrepTime: 20 * time.Minute, TravelTime: 15 * time.Minute}, 80 * time.Minute},
+		{"Pending without kitchen slots", etaInputs{Status: models.StatusPending, OrdersAhead: 1, PrepTime: 20 * time.Minute, TravelTime: 15 * time.Minute}, 60 * time.Minute},
+		{"Preparing halfway", etaInputs{Status: models.StatusPreparing, Since: now.Add(-10 * time.Minute), PrepTime: 20 * time.Minute, TravelTime: 15 * time.Minute}, 30 * time.Minute},
+		{"Preparing longer than expected", etaInputs{Status: models.StatusPreparing, Since: now.Add(-time.Hour), PrepTime: 20 * time.Minute, TravelTime: 15 * time.Minute}, 20 * time.Minute},
+		{"Ready", etaInputs{Status: models.StatusReady, TravelTime: 15 * time.Minute}, 20 * time.Minute},
+		{"Ready and rescheduled", etaInputs{Status: models.StatusReady, ScheduledFor: &scheduled, TravelTime: 15 * time.Minute}, 140 * time.Minute},
+		{"Delivering", etaInputs{Status: models.StatusDelivering, Since: now.Add(-5 * time.Minute), TravelTime: 15 * time.Minute}, 10 * time.Minute},
+		{"Delivering late", etaInputs{Status: models.StatusDelivering, Since: now.Add(-time.Hour), TravelTime: 15 * time.Minute}, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := estimateDelivery(tc.in, now)
+			if want := now.Add(tc.expected); !got.Equal(want) {
+				t.Errorf("Expected %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestOrderPrepTime(t *testing.T) {
+	history := map[uint]time.Duration{
+		1: 10 * time.Minute,
+		2: 35 * time.Minute,
+	}
+	fallback := 20 * time.Minute
+
+	testCases := []struct {
+		name       string
+		productIDs []uint
+		expected   time.Duration
+	}{
+		{"Slowest product wins", []uint{1, 2}, 35 * time.Minute},
+		{"Fast product only", []uint{1}, 10 * time.Minute},
+		{"Product without history", []uint{1, 3}, 20 * time.Minute},
+		{"No items", nil, 20 * time.Minute},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := orderPrepTime(tc.productIDs, history, fallback); got != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSummarizeETAErrors(t *testing.T) {
+	minutes := func(value float64) *float64 { return &value }
+	predictions := []models.ETAPrediction{
+		{OrderID: 1, Status: models.StatusPending, ErrorMinutes: minutes(10)},
+		{OrderID: 2, Status: models.StatusPending, ErrorMinutes: minutes(-4)},
+		{OrderID: 1, Status: models.StatusDelivering, ErrorMinutes: minutes(1)},
+		{OrderID: 3, Status: models.StatusReady}, // Ainda não entregue
+	}
+
+	report := summarizeETAErrors(predictions)
+
+	if report.Overall.Samples != 3 || report.Overall.MeanErrorMinutes != 2.3 || report.Overall.MeanAbsErrorMinutes != 5 {
+		t.Errorf("Unexpected overall accuracy: %+v", report.Overall)
+	}
+	if len(report.ByStatus) != 2 {
+		t.Fatalf("Expected 2 statuses, got %+v", report.ByStatus)
+	}
+	pending, delivering := report.ByStatus[0], report.ByStatus[1]
+	if pending.Status != models.StatusPending || pending.Samples != 2 || pending.MeanErrorMinutes != 3 || pending.MeanAbsErrorMinutes != 7 {
+		t.Errorf("Unexpected pending accuracy: %+v", pending)
+	}
+	if delivering.Status != models.StatusDelivering || delivering.Samples != 1 || delivering.MeanErrorMinutes != 1 {
+		t.Errorf("Unexpected delivering accuracy: %+v", delivering)
+	}
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrFileNotFound = errors.New("arquivo não encontrado")
+
+// FileStorage guarda arquivos enviados pelos usuários; chaves usam "/" como separador.
+// LocalFileStorage grava em disco; um bucket S3 ou GCS pode implementar a mesma interface.
+type FileStorage interface {
+	Save(ctx context.Context, key string, content io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete remove o arquivo; chave inexistente não é erro
+	Delete(ctx context.Context, key string) error
+}
+
+// sniffContent identifica o tipo do arquivo enviado pelos primeiros bytes, sem confiar no cabeçalho.
+// O leitor retornado devolve o conteúdo completo, incluindo os bytes já lidos.
+func sniffContent(content io.Reader) (io.Reader, string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(content, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, "", err
+	}
+	head = head[:n]
+	return io.MultiReader(bytes.NewReader(head), content), http.DetectContentType(head), nil
+}
+
+// LocalFileStorage guarda os arquivos em um diretório local
+type LocalFileStorage struct {
+	Dir string
+}
+
+func NewLocalFileStorage(dir string) *LocalFileStorage {
+	return &LocalFileStorage{Dir: dir}
+}
+
+func (s *LocalFileStorage) Save(ctx context.Context, key string, content io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, content); err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+	return file.Close()
+}
+
+func (s *LocalFileStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrFileNotFound
+	}
+	return file, err
+}
+
+func (s *LocalFileStorage) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path converte a chave em caminho dentro de Dir, recusando chaves que escapem do diretório
+func (s *LocalFileStorage) path(key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if key == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("chave de arquivo inválida: %q", key)
+	}
+	return filepath.Join(s.Dir, clean), nil
+}
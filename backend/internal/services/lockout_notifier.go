@@ -0,0 +1,48 @@
+package services
+
+import (
+	"cupcake-delivery/internal/models"
+	"fmt"
+	"log"
+	"net/smtp"
+	"time"
+)
+
+// LockoutNotifier avisa o titular da conta quando ela é bloqueada por excesso de tentativas
+type LockoutNotifier interface {
+	NotifyLockout(user *models.User, lockedUntil time.Time) error
+}
+
+// LogLockoutNotifier apenas registra o bloqueio no log (padrão em desenvolvimento)
+type LogLockoutNotifier struct{}
+
+func (LogLockoutNotifier) NotifyLockout(user *models.User, lockedUntil time.Time) error {
+	log.Printf("Conta %s bloqueada até %s por excesso de tentativas de login", user.Email, lockedUntil.Format(time.RFC3339))
+	return nil
+}
+
+// SMTPLockoutNotifier envia o aviso de bloqueio por email
+type SMTPLockoutNotifier struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (n *SMTPLockoutNotifier) NotifyLockout(user *models.User, lockedUntil time.Time) error {
+	subject := "Sua conta foi bloqueada temporariamente"
+	body := fmt.Sprintf(
+		"Olá %s,\r\n\r\nDetectamos várias tentativas de login sem sucesso na sua conta. "+
+			"Por segurança, ela ficará bloqueada até %s.\r\n\r\n"+
+			"Se não foi você, recomendamos alterar sua senha assim que possível.\r\n",
+		user.Name, lockedUntil.Format("02/01/2006 15:04"),
+	)
+	msg := []byte(fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s", n.From, user.Email, subject, body))
+
+	var auth smtp.Auth
+	if n.Username != "" {
+		auth = smtp.PlainAuth("", n.Username, n.Password, n.Host)
+	}
+	return smtp.SendMail(n.Host+":"+n.Port, auth, n.From, []string{user.Email}, msg)
+}
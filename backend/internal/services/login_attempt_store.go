@@ -0,0 +1,131 @@
+package services
+
+import (
+	"cupcake-delivery/internal/models"
+	"errors"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginAttemptStore persiste as falhas de login por chave (ex.: "account:email", "ip:1.2.3.4")
+type LoginAttemptStore interface {
+	// Get retorna o registro da chave ou nil se não houver falhas registradas
+	Get(key string) (*models.LoginAttempt, error)
+	// RecordFailure incrementa atomicamente as falhas da chave e retorna o registro atualizado.
+	// Se a última falha for anterior a resetBefore, a contagem e o bloqueio recomeçam na mesma operação.
+	RecordFailure(key string, at, resetBefore time.Time) (*models.LoginAttempt, error)
+	// Lock bloqueia a chave até o instante informado
+	Lock(key string, until time.Time) error
+	// Reset remove as falhas e o bloqueio da chave
+	Reset(key string) error
+}
+
+// MemoryLoginAttemptStore mantém as tentativas em memória (uma única instância da API)
+type MemoryLoginAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]models.LoginAttempt
+}
+
+func NewMemoryLoginAttemptStore() *MemoryLoginAttemptStore {
+	return &MemoryLoginAttemptStore{attempts: make(map[string]models.LoginAttempt)}
+}
+
+func (s *MemoryLoginAttemptStore) Get(key string) (*models.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	return &attempt, nil
+}
+
+func (s *MemoryLoginAttemptStore) RecordFailure(key string, at, resetBefore time.Time) (*models.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if ok && attempt.LastFailureAt.Before(resetBefore) {
+		attempt = models.LoginAttempt{}
+	}
+	attempt.Key = key
+	attempt.Failures++
+	attempt.LastFailureAt = at
+	attempt.UpdatedAt = at
+	s.attempts[key] = attempt
+	return &attempt, nil
+}
+
+func (s *MemoryLoginAttemptStore) Lock(key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt := s.attempts[key]
+	attempt.Key = key
+	attempt.LockedUntil = &until
+	s.attempts[key] = attempt
+	return nil
+}
+
+func (s *MemoryLoginAttemptStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
+
+// DBLoginAttemptStore mantém as tentativas no banco, compartilhadas entre réplicas
+type DBLoginAttemptStore struct {
+	db *gorm.DB
+}
+
+func NewDBLoginAttemptStore(db *gorm.DB) *DBLoginAttemptStore {
+	return &DBLoginAttemptStore{db: db}
+}
+
+func (s *DBLoginAttemptStore) Get(key string) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	if err := s.db.Where("key = ?", key).First(&attempt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (s *DBLoginAttemptStore) RecordFailure(key string, at, resetBefore time.Time) (*models.LoginAttempt, error) {
+	attempt := models.LoginAttempt{Key: key, Failures: 1, LastFailureAt: at}
+
+	// Esquecer as falhas antigas e contar a nova no mesmo upsert evita que duas falhas simultâneas
+	// apaguem a contagem uma da outra
+	err := s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"failures":        gorm.Expr("CASE WHEN login_attempts.last_failure_at < ? THEN 1 ELSE login_attempts.failures + 1 END", resetBefore),
+			"locked_until":    gorm.Expr("CASE WHEN login_attempts.last_failure_at < ? THEN NULL ELSE login_attempts.locked_until END", resetBefore),
+			"last_failure_at": at,
+			"updated_at":      at,
+		}),
+	}).Create(&attempt).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Get(key)
+}
+
+func (s *DBLoginAttemptStore) Lock(key string, until time.Time) error {
+	return s.db.Model(&models.LoginAttempt{}).
+		Where("key = ?", key).
+		Update("locked_until", until).Error
+}
+
+func (s *DBLoginAttemptStore) Reset(key string) error {
+	return s.db.Where("key = ?", key).Delete(&models.LoginAttempt{}).Error
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"cupcake-delivery/internal/models"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// LockoutPolicy define quantas falhas são toleradas e por quanto tempo a chave fica bloqueada
+type LockoutPolicy struct {
+	MaxFailures int           // falhas antes do primeiro bloqueio
+	BaseLockout time.Duration // duração do primeiro bloqueio; dobra a cada nova falha
+	MaxLockout  time.Duration // limite do bloqueio exponencial
+	ResetAfter  time.Duration // falhas mais antigas que isso são esquecidas
+}
+
+// LockoutDuration retorna o bloqueio aplicável após o número de falhas informado (zero se nenhum)
+func (p LockoutPolicy) LockoutDuration(failures int) time.Duration {
+	if failures < p.MaxFailures {
+		return 0
+	}
+	lockout := p.BaseLockout
+	for i := p.MaxFailures; i < failures && lockout < p.MaxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > p.MaxLockout {
+		lockout = p.MaxLockout
+	}
+	return lockout
+}
+
+var (
+	DefaultAccountLockoutPolicy = LockoutPolicy{MaxFailures: 5, BaseLockout: time.Minute, MaxLockout: time.Hour, ResetAfter: time.Hour}
+	DefaultIPLockoutPolicy      = LockoutPolicy{MaxFailures: 20, BaseLockout: time.Minute, MaxLockout: time.Hour, ResetAfter: time.Hour}
+)
+
+// LockoutError indica que a conta ou o IP está bloqueado
+type LockoutError struct {
+	Until time.Time
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("login bloqueado até %s", e.Until.Format(time.RFC3339))
+}
+
+// LoginThrottle controla tentativas de login por conta e por IP
+type LoginThrottle struct {
+	store         LoginAttemptStore
+	notifier      LockoutNotifier
+	accountPolicy LockoutPolicy
+	ipPolicy      LockoutPolicy
+	now           func() time.Time
+}
+
+func NewLoginThrottle(store LoginAttemptStore, notifier LockoutNotifier) *LoginThrottle {
+	return &LoginThrottle{
+		store:         store,
+		notifier:      notifier,
+		accountPolicy: DefaultAccountLockoutPolicy,
+		ipPolicy:      DefaultIPLockoutPolicy,
+		now:           time.Now,
+	}
+}
+
+func AccountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func IPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Check retorna *LockoutError se a conta ou o IP estiverem bloqueados.
+// A consulta não reserva a tentativa: requisições simultâneas que passam pelo Check antes de a
+// falha ser registrada ainda conferem a senha, então o limite pode ser excedido por essa rajada.
+// O bloqueio é aplicado assim que as falhas delas forem registradas.
+func (t *LoginThrottle) Check(email, ip string) error {
+	now := t.now()
+	var until time.Time
+
+	for _, key := range []string{AccountKey(email), IPKey(ip)} {
+		attempt, err := t.store.Get(key)
+		if err != nil {
+			return err
+		}
+		if attempt != nil && attempt.LockedUntil != nil && attempt.LockedUntil.After(now) && attempt.LockedUntil.After(until) {
+			until = *attempt.LockedUntil
+		}
+	}
+
+	if !until.IsZero() {
+		return &LockoutError{Until: until}
+	}
+	return nil
+}
+
+// RecordFailure registra uma senha errada; user é nil quando o email não existe.
+// O titular é avisado quando a conta passa a ficar bloqueada.
+func (t *LoginThrottle) RecordFailure(email, ip string, user *models.User) error {
+	accountUntil, err := t.recordFailure(AccountKey(email), t.accountPolicy)
+	if err != nil {
+		return err
+	}
+	if _, err := t.recordFailure(IPKey(ip), t.ipPolicy); err != nil {
+		return err
+	}
+
+	if !accountUntil.IsZero() && user != nil && t.notifier != nil {
+		if err := t.notifier.NotifyLockout(user, accountUntil); err != nil {
+			log.Printf("Erro ao notificar bloqueio da conta %s: %v", user.Email, err)
+		}
+	}
+	return nil
+}
+
+// RecordSuccess limpa as falhas da conta; as falhas do IP continuam valendo
+func (t *LoginThrottle) RecordSuccess(email string) error {
+	return t.store.Reset(AccountKey(email))
+}
+
+// Unlock remove o bloqueio e as falhas de uma conta
+func (t *LoginThrottle) Unlock(email string) error {
+	return t.store.Reset(AccountKey(email))
+}
+
+// recordFailure retorna o fim do bloqueio aplicado, ou zero se a chave não foi bloqueada
+func (t *LoginThrottle) recordFailure(key string, policy LockoutPolicy) (time.Time, error) {
+	now := t.now()
+
+	attempt, err := t.store.RecordFailure(key, now, now.Add(-policy.ResetAfter))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	lockout := policy.LockoutDuration(attempt.Failures)
+	if lockout == 0 {
+		return time.Time{}, nil
+	}
+
+	until := now.Add(lockout)
+	if err := t.store.Lock(key, until); err != nil {
+		return time.Time{}, err
+	}
+	return until, nil
+}
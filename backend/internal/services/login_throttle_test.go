@@ -0,0 +1,195 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"cupcake-delivery/internal/models"
+)
+
+type recordingNotifier struct {
+	calls []time.Time
+}
+
+func (n *recordingNotifier) NotifyLockout(user *models.User, lockedUntil time.Time) error {
+	n.calls = append(n.calls, lockedUntil)
+	return nil
+}
+
+func newTestThrottle(now *time.Time) (*LoginThrottle, *recordingNotifier) {
+	notifier := &recordingNotifier{}
+	throttle := NewLoginThrottle(NewMemoryLoginAttemptStore(), notifier)
+	throttle.now = func() time.Time { return *now }
+	return throttle, notifier
+}
+
+func TestLockoutDuration(t *testing.T) {
+	policy := LockoutPolicy{MaxFailures: 3, BaseLockout: time.Minute, MaxLockout: 10 * time.Minute}
+
+	testCases := []struct {
+		failures int
+		expected time.Duration
+	}{
+		{1, 0},
+		{2, 0},
+		{3, time.Minute},
+		{4, 2 * time.Minute},
+		{5, 4 * time.Minute},
+		{6, 8 * time.Minute},
+		{7, 10 * time.Minute},
+		{50, 10 * time.Minute},
+	}
+
+	for _, tc := range testCases {
+		if got := policy.LockoutDuration(tc.failures); got != tc.expected {
+			t.Errorf("Expected %v after %d failures, got %v", tc.expected, tc.failures, got)
+		}
+	}
+}
+
+func TestLoginThrottleLocksAccount(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	throttle, notifier := newTestThrottle(&now)
+	user := &models.User{Email: "joao@email.com"}
+
+	for i := 0; i < DefaultAccountLockoutPolicy.MaxFailures; i++ {
+		if err := throttle.Check(user.Email, "10.0.0.1"); err != nil {
+			t.Fatalf("Expected no lockout before attempt %d, got %v", i+1, err)
+		}
+		if err := throttle.RecordFailure(user.Email, "10.0.0.1", user); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	var lockout *LockoutError
+	if err := throttle.Check("JOAO@email.com ", "10.0.0.2"); !errors.As(err, &lockout) {
+		t.Fatalf("Expected account lockout from another IP, got %v", err)
+	}
+	if !lockout.Until.Equal(now.Add(DefaultAccountLockoutPolicy.BaseLockout)) {
+		t.Errorf("Unexpected lockout end: %v", lockout.Until)
+	}
+	if len(notifier.calls) != 1 {
+		t.Errorf("Expected 1 lockout notification, got %d", len(notifier.calls))
+	}
+
+	now = now.Add(DefaultAccountLockoutPolicy.BaseLockout + time.Second)
+	if err := throttle.Check(user.Email, "10.0.0.1"); err != nil {
+		t.Errorf("Expected lockout to expire, got %v", err)
+	}
+
+	// Nova falha após o bloqueio dobra a duração
+	if err := throttle.RecordFailure(user.Email, "10.0.0.1", user); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := throttle.Check(user.Email, "10.0.0.1"); !errors.As(err, &lockout) {
+		t.Fatalf("Expected lockout, got %v", err)
+	}
+	if !lockout.Until.Equal(now.Add(2 * DefaultAccountLockoutPolicy.BaseLockout)) {
+		t.Errorf("Expected doubled lockout, got %v", lockout.Until.Sub(now))
+	}
+}
+
+func TestLoginThrottleLocksIP(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	throttle, notifier := newTestThrottle(&now)
+
+	for i := 0; i < DefaultIPLockoutPolicy.MaxFailures; i++ {
+		email := "user" + string(rune('a'+i)) + "@email.com"
+		if err := throttle.RecordFailure(email, "10.0.0.1", nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	var lockout *LockoutError
+	if err := throttle.Check("outro@email.com", "10.0.0.1"); !errors.As(err, &lockout) {
+		t.Errorf("Expected IP lockout, got %v", err)
+	}
+	if err := throttle.Check("outro@email.com", "10.0.0.9"); err != nil {
+		t.Errorf("Expected other IPs to be unaffected, got %v", err)
+	}
+	if len(notifier.calls) != 0 {
+		t.Errorf("Expected no notifications for unknown accounts, got %d", len(notifier.calls))
+	}
+}
+
+func TestLoginThrottleSuccessAndUnlock(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	throttle, _ := newTestThrottle(&now)
+	email := "maria@email.com"
+
+	for i := 0; i < DefaultAccountLockoutPolicy.MaxFailures-1; i++ {
+		throttle.RecordFailure(email, "10.0.0.1", nil)
+	}
+	throttle.RecordSuccess(email)
+	throttle.RecordFailure(email, "10.0.0.1", nil)
+	if err := throttle.Check(email, "10.0.0.1"); err != nil {
+		t.Errorf("Expected success to reset account failures, got %v", err)
+	}
+
+	for i := 0; i < DefaultAccountLockoutPolicy.MaxFailures; i++ {
+		throttle.RecordFailure(email, "10.0.0.1", nil)
+	}
+	if err := throttle.Check(email, "10.0.0.1"); err == nil {
+		t.Fatalf("Expected lockout")
+	}
+	throttle.Unlock(email)
+	if err := throttle.Check(email, "10.0.0.1"); err != nil {
+		t.Errorf("Expected unlock to clear lockout, got %v", err)
+	}
+}
+
+func TestLoginThrottleForgetsOldFailures(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	throttle, _ := newTestThrottle(&now)
+	email := "ana@email.com"
+
+	for i := 0; i < DefaultAccountLockoutPolicy.MaxFailures-1; i++ {
+		throttle.RecordFailure(email, "10.0.0.1", nil)
+	}
+
+	now = now.Add(DefaultAccountLockoutPolicy.ResetAfter + time.Minute)
+	throttle.RecordFailure(email, "10.0.0.1", nil)
+	if err := throttle.Check(email, "10.0.0.1"); err != nil {
+		t.Errorf("Expected old failures to be forgotten, got %v", err)
+	}
+}
+
+func TestDBLoginAttemptStoreConcurrentFailuresAfterReset(t *testing.T) {
+	db := openTestDB(t)
+	store := NewDBLoginAttemptStore(db)
+
+	key := AccountKey("throttle-" + time.Now().Format("150405.000000") + "@example.com")
+	t.Cleanup(func() { store.Reset(key) })
+
+	old := time.Now().Add(-2 * time.Hour)
+	if _, err := store.RecordFailure(key, old, old.Add(-time.Hour)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := store.Lock(key, old.Add(time.Minute)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Falhas simultâneas depois da janela recomeçam a contagem uma única vez
+	now := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := store.RecordFailure(key, now, now.Add(-time.Hour)); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	attempt, err := store.Get(key)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if attempt.Failures != 5 || attempt.LockedUntil != nil {
+		t.Errorf("Expected 5 failures without the old lock, got %+v", attempt)
+	}
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"sync"
+
+	"cupcake-delivery/internal/models"
+)
+
+// NotificationBroker repassa as notificações recém-gravadas aos usuários conectados ao stream.
+// A implementação em memória atende uma única réplica; com várias, uma implementação sobre
+// LISTEN/NOTIFY do PostgreSQL publica no canal e cada réplica entrega aos próprios assinantes.
+type NotificationBroker interface {
+	Publish(notification models.Notification) error
+	// Subscribe retorna o canal das notificações do usuário e a função que encerra a assinatura.
+	// O canal é fechado se o assinante não acompanhar o ritmo; o cliente retoma pelo último ID recebido.
+	Subscribe(userID uint) (<-chan models.Notification, func())
+}
+
+// MemoryNotificationBroker distribui as notificações entre as conexões desta réplica
+type MemoryNotificationBroker struct {
+	mu          sync.Mutex
+	buffer      int
+	subscribers map[uint]map[chan models.Notification]struct{}
+}
+
+// NewMemoryNotificationBroker cria o broker; buffer é quantas notificações cada conexão pode acumular
+func NewMemoryNotificationBroker(buffer int) *MemoryNotificationBroker {
+	return &MemoryNotificationBroker{
+		buffer:      buffer,
+		subscribers: make(map[uint]map[chan models.Notification]struct{}),
+	}
+}
+
+func (b *MemoryNotificationBroker) Publish(notification models.Notification) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[notification.UserID] {
+		select {
+		case ch <- notification:
+		default:
+			// Conexão lenta: encerra em vez de bloquear quem publica
+			b.remove(notification.UserID, ch)
+		}
+	}
+	return nil
+}
+
+func (b *MemoryNotificationBroker) Subscribe(userID uint) (<-chan models.Notification, func()) {
+	ch := make(chan models.Notification, b.buffer)
+
+	b.mu.Lock()
+	if b.subscribers[userID] == nil {
+		b.subscribers[userID] = make(map[chan models.Notification]struct{})
+	}
+	b.subscribers[userID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.remove(userID, ch)
+	}
+}
+
+// remove fecha o canal se ainda estiver assinado; chamado com o lock
+func (b *MemoryNotificationBroker) remove(userID uint, ch chan models.Notification) {
+	if _, ok := b.subscribers[userID][ch]; !ok {
+		return
+	}
+	delete(b.subscribers[userID], ch)
+	if len(b.subscribers[userID]) == 0 {
+		delete(b.subscribers, userID)
+	}
+	close(ch)
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"testing"
+
+	"cupcake-delivery/internal/models"
+)
+
+func TestMemoryNotificationBrokerDeliversToUser(t *testing.T) {
+	broker := NewMemoryNotificationBroker(4)
+	first, unsubscribeFirst := broker.Subscribe(1)
+	defer unsubscribeFirst()
+	second, unsubscribeSecond := broker.Subscribe(1) // Mesmo usuário em outra aba
+	defer unsubscribeSecond()
+	other, unsubscribeOther := broker.Subscribe(2)
+	defer unsubscribeOther()
+
+	broker.Publish(models.Notification{ID: 10, UserID: 1})
+
+	for _, ch := range []<-chan models.Notification{first, second} {
+		select {
+		case notification := <-ch:
+			if notification.ID != 10 {
+				t.Errorf("Expected notification 10, got %d", notification.ID)
+			}
+		default:
+			t.Error("Expected notification to be delivered")
+		}
+	}
+	select {
+	case notification := <-other:
+		t.Errorf("Unexpected notification for another user: %+v", notification)
+	default:
+	}
+}
+
+func TestMemoryNotificationBrokerClosesSlowSubscriber(t *testing.T) {
+	broker := NewMemoryNotificationBroker(2)
+	events, unsubscribe := broker.Subscribe(1)
+
+	for id := uint(1); id <= 3; id++ {
+		broker.Publish(models.Notification{ID: id, UserID: 1})
+	}
+
+	var received []uint
+	for notification := range events {
+		received = append(received, notification.ID)
+	}
+	if len(received) != 2 || received[0] != 1 || received[1] != 2 {
+		t.Errorf("Expected buffered notifications 1 and 2 before closing, got %v", received)
+	}
+
+	// Encerrar depois de fechado pelo broker não deve entrar em pânico
+	unsubscribe()
+	broker.Publish(models.Notification{ID: 4, UserID: 1})
+}
+
+func TestMemoryNotificationBrokerUnsubscribe(t *testing.T) {
+	broker := NewMemoryNotificationBroker(2)
+	events, unsubscribe := broker.Subscribe(1)
+	unsubscribe()
+	unsubscribe()
+
+	broker.Publish(models.Notification{ID: 1, UserID: 1})
+	if _, ok := <-events; ok {
+		t.Error("Expected channel to be closed after unsubscribe")
+	}
+}
@@ -3,16 +3,25 @@ package services
 import (
 	"cupcake-delivery/internal/models"
 	"fmt"
+	"log"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 type NotificationService struct {
-	db *gorm.DB
+	db       *gorm.DB
+	couriers *CourierService
+	broker   NotificationBroker // Com broker, cada notificação gravada vai na hora para o stream do usuário
 }
 
-func NewNotificationService(db *gorm.DB) *NotificationService {
-	return &NotificationService{db: db}
+func NewNotificationService(db *gorm.DB, couriers *CourierService) *NotificationService {
+	return &NotificationService{db: db, couriers: couriers}
+}
+
+// SetBroker passa a publicar as notificações criadas para os usuários conectados ao stream
+func (s *NotificationService) SetBroker(broker NotificationBroker) {
+	s.broker = broker
 }
 
 // CreateNotification cria uma nova notificação
@@ -30,9 +39,27 @@ func (s *NotificationService) CreateNotification(data models.CreateNotificationD
 		return nil, err
 	}
 
+	// A notificação já está gravada: falhar ao publicar só atrasa a entrega até o cliente retomar
+	if s.broker != nil {
+		if err := s.broker.Publish(*notification); err != nil {
+			log.Printf("Erro ao publicar notificação %d: %v", notification.ID, err)
+		}
+	}
+
 	return notification, nil
 }
 
+// NotificationsAfter notificações do usuário com ID maior que afterID, da mais antiga para a mais recente;
+// usado para retomar o stream a partir do Last-Event-ID
+func (s *NotificationService) NotificationsAfter(userID, afterID uint, limit int) ([]models.Notification, error) {
+	var notifications []models.Notification
+	err := s.db.Where("user_id = ? AND id > ?", userID, afterID).
+		Order("id").
+		Limit(limit).
+		Find(&notifications).Error
+	return notifications, err
+}
+
 // GetUserNotifications busca notificações de um usuário
 func (s *NotificationService) GetUserNotifications(userID uint, limit int) ([]models.Notification, error) {
 	var notifications []models.Notification
@@ -111,6 +138,24 @@ func (s *NotificationService) NotifyOrderStatusChange(order *models.Order, newSt
 			adminTitle:      "Pedido Entregue",
 			adminMessage:    fmt.Sprintf("Pedido #%d foi entregue com sucesso.", order.ID),
 		},
+		"delivery_failed": {
+			customerTitle:   "Não Conseguimos Entregar",
+			customerMessage: fmt.Sprintf("Não foi possível entregar seu pedido #%d (%s). Ele voltará para a loja e você poderá reagendar a entrega.", order.ID, order.DeliveryFailureReason.Label()),
+			adminTitle:      "Entrega Malsucedida",
+			adminMessage:    fmt.Sprintf("Pedido #%d não foi entregue: %s.", order.ID, order.DeliveryFailureReason.Label()),
+		},
+		"returned": {
+			customerTitle:   "Pedido de Volta à Loja",
+			customerMessage: fmt.Sprintf("Seu pedido #%d voltou para a loja. Reagende a entrega quando preferir.", order.ID),
+			adminTitle:      "Pedido Devolvido",
+			adminMessage:    fmt.Sprintf("Pedido #%d voltou para a loja e aguarda reagendamento.", order.ID),
+		},
+		"rescheduled": {
+			customerTitle:   "Entrega Reagendada",
+			customerMessage: rescheduledMessage(order),
+			adminTitle:      "Entrega Reagendada",
+			adminMessage:    fmt.Sprintf("Pedido #%d voltou para a fila de entrega.", order.ID),
+		},
 		"cancelled": {
 			customerTitle:   "Pedido Cancelado",
 			customerMessage: fmt.Sprintf("Seu pedido #%d foi cancelado. Entre em contato conosco para mais informações.", order.ID),
@@ -124,6 +169,11 @@ func (s *NotificationService) NotifyOrderStatusChange(order *models.Order, newSt
 		return fmt.Errorf("status de pedido não reconhecido: %s", newStatus)
 	}
 
+	// Pedido a caminho do cliente: a mensagem leva a previsão de entrega recalculada
+	if order.EstimatedDeliveryAt != nil && messages.customerMessage != "" {
+		messages.customerMessage += fmt.Sprintf(" Previsão de entrega: %s.", order.EstimatedDeliveryAt.Format("15:04"))
+	}
+
 	// Notificar o cliente (sempre)
 	if messages.customerTitle != "" {
 		customerNotification := models.CreateNotificationData{
@@ -155,13 +205,12 @@ func (s *NotificationService) NotifyOrderStatusChange(order *models.Order, newSt
 		}
 	}
 
-	// Buscar usuários entregadores para notificar (apenas para pedidos prontos)
+	// Notificar apenas os entregadores online e em turno (apenas para pedidos prontos)
 	if messages.deliveryTitle != "" {
-		var deliveryUsers []models.User
-		if err := s.db.Where("type = ?", "delivery").Find(&deliveryUsers).Error; err == nil {
-			for _, delivery := range deliveryUsers {
+		if courierIDs, err := s.couriers.OnlineCourierIDs(); err == nil {
+			for _, courierID := range courierIDs {
 				deliveryNotification := models.CreateNotificationData{
-					UserID:  delivery.ID,
+					UserID:  courierID,
 					OrderID: &order.ID,
 					Type:    fmt.Sprintf("order_%s", newStatus),
 					Title:   messages.deliveryTitle,
@@ -174,3 +223,85 @@ func (s *NotificationService) NotifyOrderStatusChange(order *models.Order, newSt
 
 	return nil
 }
+
+// NotifyDispatchOffer avisa o entregador de que um pedido foi oferecido a ele pelo despacho automático
+func (s *NotificationService) NotifyDispatchOffer(offer *models.DispatchOffer) error {
+	_, err := s.CreateNotification(models.CreateNotificationData{
+		UserID:  offer.CourierID,
+		OrderID: &offer.OrderID,
+		Type:    models.NotificationTypeDispatchOffer,
+		Title:   "Nova Oferta de Entrega",
+		Message: fmt.Sprintf("Pedido #%d foi oferecido a você. Aceite até %s.", offer.OrderID, offer.ExpiresAt.Format("15:04:05")),
+	})
+	return err
+}
+
+// NotifyDeliveryETA informa ao cliente a posição do pedido na rota do lote e a previsão de chegada
+func (s *NotificationService) NotifyDeliveryETA(order *models.Order, stopsAhead int, eta time.Time) error {
+	message := fmt.Sprintf("Seu pedido #%d é a próxima parada do entregador. Previsão de chegada: %s.", order.ID, eta.Format("15:04"))
+	if stopsAhead > 0 {
+		message = fmt.Sprintf("Seu pedido #%d está na rota do entregador, com %d parada(s) antes da sua. Previsão de chegada: %s.", order.ID, stopsAhead, eta.Format("15:04"))
+	}
+	_, err := s.CreateNotification(models.CreateNotificationData{
+		UserID:  order.CustomerID,
+		OrderID: &order.ID,
+		Type:    models.NotificationTypeDeliveryETA,
+		Title:   "Previsão de Entrega",
+		Message: message,
+	})
+	return err
+}
+
+// NotifyTipReceived avisa o entregador da gorjeta dada pelo cliente após a entrega
+func (s *NotificationService) NotifyTipReceived(order *models.Order) error {
+	if order.DeliveryID == nil {
+		return nil
+	}
+	_, err := s.CreateNotification(models.CreateNotificationData{
+		UserID:  *order.DeliveryID,
+		OrderID: &order.ID,
+		Type:    models.NotificationTypeTipReceived,
+		Title:   "Você Recebeu uma Gorjeta",
+		Message: fmt.Sprintf("O cliente do pedido #%d deixou uma gorjeta de R$ %.2f.", order.ID, order.Tip),
+	})
+	return err
+}
+
+// NotifySLABreach avisa os administradores de que o pedido passou do prazo do status
+func (s *NotificationService) NotifySLABreach(order *models.Order, breach *models.OrderSLABreach) error {
+	var admins []models.User
+	if err := s.db.Where("type = ?", models.AdminType).Find(&admins).Error; err != nil {
+		return err
+	}
+	for _, admin := range admins {
+		if _, err := s.CreateNotification(models.CreateNotificationData{
+			UserID:  admin.ID,
+			OrderID: &order.ID,
+			Type:    models.NotificationTypeSLABreach,
+			Title:   "Pedido Atrasado",
+			Message: fmt.Sprintf("Pedido #%d passou do prazo de %d min em %s.", order.ID, breach.LimitMinutes, breach.Status),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NotifyRunningLate pede desculpas ao cliente pelo atraso antes que ele precise perguntar
+func (s *NotificationService) NotifyRunningLate(order *models.Order) error {
+	_, err := s.CreateNotification(models.CreateNotificationData{
+		UserID:  order.CustomerID,
+		OrderID: &order.ID,
+		Type:    models.NotificationTypeOrderRunningLate,
+		Title:   "Desculpe a Demora",
+		Message: fmt.Sprintf("Seu pedido #%d está demorando mais que o previsto. Já estamos cuidando disso!", order.ID),
+	})
+	return err
+}
+
+func rescheduledMessage(order *models.Order) string {
+	if order.ScheduledFor != nil {
+		return fmt.Sprintf("A nova entrega do seu pedido #%d foi agendada para %s.", order.ID, order.ScheduledFor.Format("02/01 15:04"))
+	}
+	return fmt.Sprintf("Seu pedido #%d sairá para entrega novamente em breve.", order.ID)
+}
@@ -0,0 +1,294 @@
+package services
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"cupcake-delivery/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// OrderEventType tipo de evento enviado ao quadro de pedidos
+type OrderEventType string
+
+const (
+	OrderEventCreated       OrderEventType = "order_created"
+	OrderEventStatusChanged OrderEventType = "order_status_changed"
+)
+
+// OrderEvent pedido criado ou com status alterado, já gravado
+type OrderEvent struct {
+	Type  OrderEventType `json:"type"`
+	Order BoardOrder     `json:"order"`
+	At    time.Time      `json:"at"`
+}
+
+// BoardOrder o que o quadro mostra de um pedido: nada do cliente (nome, CPF, telefone, endereço)
+type BoardOrder struct {
+	ID                  uint               `json:"id"`
+	Status              models.OrderStatus `json:"status"`
+	Items               []BoardOrderItem   `json:"items"`
+	CreatedAt           time.Time          `json:"createdAt"`
+	StatusChangedAt     *time.Time         `json:"statusChangedAt,omitempty"`
+	EstimatedDeliveryAt *time.Time         `json:"estimatedDeliveryAt,omitempty"`
+	Area                *BoardOrderArea    `json:"area,omitempty"` // Apenas para quem entrega ou acompanha a entrega
+}
+
+// BoardOrderItem produto e quantidade, o suficiente para a cozinha preparar o pedido
+type BoardOrderItem struct {
+	ProductID uint   `json:"productId"`
+	Name      string `json:"name"`
+	Quantity  int    `json:"quantity"`
+}
+
+// BoardOrderArea região da entrega, sem rua nem número
+type BoardOrderArea struct {
+	Neighborhood string `json:"neighborhood"`
+	City         string `json:"city"`
+}
+
+// NewBoardOrder reduz o pedido ao que vai para o quadro; os itens precisam vir com o produto carregado
+func NewBoardOrder(order models.Order) BoardOrder {
+	items := make([]BoardOrderItem, len(order.Items))
+	for i, item := range order.Items {
+		items[i] = BoardOrderItem{ProductID: item.ProductID, Name: item.Product.Name, Quantity: item.Quantity}
+	}
+	return BoardOrder{
+		ID:                  order.ID,
+		Status:              order.Status,
+		Items:               items,
+		CreatedAt:           order.CreatedAt,
+		StatusChangedAt:     order.StatusChangedAt,
+		EstimatedDeliveryAt: order.EstimatedDeliveryAt,
+		Area:                &BoardOrderArea{Neighborhood: order.Neighborhood, City: order.City},
+	}
+}
+
+// WithoutArea cópia do pedido sem a região da entrega, para papéis que não entregam (cozinha)
+func (o BoardOrder) WithoutArea() BoardOrder {
+	o.Area = nil
+	return o
+}
+
+// OrderBoardStore busca os pedidos no formato do quadro
+type OrderBoardStore interface {
+	// ActiveOrders pedidos nos status informados, dos mais antigos para os mais recentes
+	ActiveOrders(statuses []models.OrderStatus, limit int) ([]BoardOrder, error)
+	// OrderItems itens do pedido, para os eventos de pedidos gravados sem os itens carregados
+	OrderItems(orderID uint) ([]BoardOrderItem, error)
+}
+
+type DBOrderBoardStore struct {
+	db *gorm.DB
+}
+
+func NewDBOrderBoardStore(db *gorm.DB) *DBOrderBoardStore {
+	return &DBOrderBoardStore{db: db}
+}
+
+func (s *DBOrderBoardStore) ActiveOrders(statuses []models.OrderStatus, limit int) ([]BoardOrder, error) {
+	var orders []models.Order
+	if err := s.db.Preload("Items.Product").
+		Where("status IN ?", statuses).
+		Order("created_at").
+		Limit(limit).
+		Find(&orders).Error; err != nil {
+		return nil, err
+	}
+
+	board := make([]BoardOrder, len(orders))
+	for i, order := range orders {
+		board[i] = NewBoardOrder(order)
+	}
+	return board, nil
+}
+
+func (s *DBOrderBoardStore) OrderItems(orderID uint) ([]BoardOrderItem, error) {
+	var items []models.OrderItem
+	if err := s.db.Preload("Product").Where("order_id = ?", orderID).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return NewBoardOrder(models.Order{Items: items}).Items, nil
+}
+
+// CourierPresence informa se o entregador está online e dentro de um turno (CourierService)
+type CourierPresence interface {
+	IsOnline(courierID uint) (bool, error)
+}
+
+// orderBoardActive status dos pedidos em andamento, enviados na foto do quadro; entregues só chegam
+// como eventos, para o quadro não acumular o histórico inteiro
+var orderBoardActive = []models.OrderStatus{
+	models.StatusPending,
+	models.StatusPreparing,
+	models.StatusReady,
+	models.StatusDelivering,
+	models.StatusDeliveryFailed,
+	models.StatusReturned,
+}
+
+// OrderBoard distribui os eventos de pedidos às conexões do quadro ao vivo desta réplica.
+// Cada conexão tem um buffer próprio: a que não acompanha o ritmo é encerrada (Lagged) em vez de
+// atrasar as demais, e o cliente reconecta recebendo uma nova foto do quadro.
+type OrderBoard struct {
+	mu     sync.Mutex
+	buffer int
+	subs   map[*OrderBoardSubscription]struct{}
+	store  OrderBoardStore
+}
+
+func NewOrderBoard(buffer int) *OrderBoard {
+	return &OrderBoard{buffer: buffer, subs: make(map[*OrderBoardSubscription]struct{})}
+}
+
+// SetStore define de onde PublishOrder busca os itens dos pedidos
+func (b *OrderBoard) SetStore(store OrderBoardStore) {
+	b.store = store
+}
+
+// OrderBoardSubscription conexão do quadro com os filtros dela
+type OrderBoardSubscription struct {
+	events   chan OrderEvent
+	allowed  map[models.OrderStatus]bool // Status que o papel pode ver; nil para todos
+	statuses map[models.OrderStatus]bool // Filtro escolhido pela conexão, dentro dos permitidos; nil para todos
+	shown    map[uint]bool               // Pedidos exibidos: recebem a mudança que os tira do filtro
+	lagged   bool
+}
+
+// Events canal dos eventos da conexão; fechado ao encerrar a assinatura
+func (s *OrderBoardSubscription) Events() <-chan OrderEvent {
+	return s.events
+}
+
+// Lagged indica se a assinatura foi encerrada por não acompanhar o ritmo; válido após o canal fechar
+func (s *OrderBoardSubscription) Lagged() bool {
+	return s.lagged
+}
+
+// visible indica se o status passa pelos filtros do papel e da conexão; chamado com o lock do quadro
+func (s *OrderBoardSubscription) visible(status models.OrderStatus) bool {
+	return (s.allowed == nil || s.allowed[status]) && (s.statuses == nil || s.statuses[status])
+}
+
+// relevant decide se a conexão recebe o evento e atualiza os pedidos exibidos por ela
+func (s *OrderBoardSubscription) relevant(event OrderEvent) bool {
+	id := event.Order.ID
+	if s.visible(event.Order.Status) {
+		s.shown[id] = true
+		return true
+	}
+	if s.shown[id] {
+		delete(s.shown, id)
+		return true
+	}
+	return false
+}
+
+// Subscribe abre uma conexão que vê apenas os status permitidos ao papel (nil para todos)
+func (b *OrderBoard) Subscribe(allowed []models.OrderStatus) *OrderBoardSubscription {
+	sub := &OrderBoardSubscription{
+		events:  make(chan OrderEvent, b.buffer),
+		allowed: statusSet(allowed),
+		shown:   make(map[uint]bool),
+	}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+	return sub
+}
+
+// Unsubscribe encerra a conexão; pode ser chamado mais de uma vez
+func (b *OrderBoard) Unsubscribe(sub *OrderBoardSubscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(sub)
+}
+
+// SetFilter restringe a conexão aos status informados, dentro dos permitidos ao papel; vazio volta a todos.
+// Os pedidos exibidos são esquecidos: a conexão deve receber uma nova foto do quadro.
+func (b *OrderBoard) SetFilter(sub *OrderBoardSubscription, statuses []models.OrderStatus) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sub.statuses = statusSet(statuses)
+	sub.shown = make(map[uint]bool)
+}
+
+// ActiveStatuses status em andamento que passam pelos filtros da conexão, para montar a foto do quadro
+func (b *OrderBoard) ActiveStatuses(sub *OrderBoardSubscription) []models.OrderStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	statuses := []models.OrderStatus{}
+	for _, status := range orderBoardActive {
+		if sub.visible(status) {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// Show marca pedidos como exibidos pela conexão (ex.: os enviados na foto inicial)
+func (b *OrderBoard) Show(sub *OrderBoardSubscription, orderIDs []uint) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, id := range orderIDs {
+		sub.shown[id] = true
+	}
+}
+
+// PublishOrder publica o pedido recém-gravado no formato do quadro. Os itens são buscados no store,
+// já que as mudanças de status gravam o pedido sem eles; em caso de erro o evento segue sem os itens.
+func (b *OrderBoard) PublishOrder(eventType OrderEventType, order *models.Order) {
+	boardOrder := NewBoardOrder(*order)
+	if b.store != nil {
+		items, err := b.store.OrderItems(order.ID)
+		if err != nil {
+			log.Printf("Erro ao buscar itens do pedido %d para o quadro: %v", order.ID, err)
+		} else {
+			boardOrder.Items = items
+		}
+	}
+	b.Publish(OrderEvent{Type: eventType, Order: boardOrder, At: time.Now()})
+}
+
+// Publish envia o evento às conexões interessadas sem bloquear
+func (b *OrderBoard) Publish(event OrderEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		if !sub.relevant(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			sub.lagged = true
+			b.remove(sub)
+		}
+	}
+}
+
+// remove fecha o canal se a conexão ainda estiver assinada; chamado com o lock
+func (b *OrderBoard) remove(sub *OrderBoardSubscription) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+	delete(b.subs, sub)
+	close(sub.events)
+}
+
+func statusSet(statuses []models.OrderStatus) map[models.OrderStatus]bool {
+	if len(statuses) == 0 {
+		return nil
+	}
+	set := make(map[models.OrderStatus]bool, len(statuses))
+	for _, status := range statuses {
+		set[status] = true
+	}
+	return set
+}
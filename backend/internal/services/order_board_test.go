@@ -0,0 +1,174 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cupcake-delivery/internal/models"
+)
+
+func boardEvent(id uint, status models.OrderStatus) OrderEvent {
+	return OrderEvent{Type: OrderEventStatusChanged, Order: BoardOrder{ID: id, Status: status}}
+}
+
+// receivedIDs esvazia o canal da conexão e retorna os pedidos recebidos
+func receivedIDs(sub *OrderBoardSubscription) []uint {
+	var ids []uint
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				return ids
+			}
+			ids = append(ids, event.Order.ID)
+		default:
+			return ids
+		}
+	}
+}
+
+func TestOrderBoardFiltersByRole(t *testing.T) {
+	board := NewOrderBoard(8)
+	admin := board.Subscribe(nil)
+	kitchen := board.Subscribe([]models.OrderStatus{models.StatusPending, models.StatusPreparing})
+	courier := board.Subscribe([]models.OrderStatus{models.StatusReady})
+
+	board.Publish(OrderEvent{Type: OrderEventCreated, Order: boardEvent(1, models.StatusPending).Order})
+	board.Publish(boardEvent(1, models.StatusPreparing))
+	board.Publish(boardEvent(1, models.StatusReady))      // Sai da cozinha, entra na fila dos entregadores
+	board.Publish(boardEvent(1, models.StatusDelivering)) // Sai da fila dos entregadores
+	board.Publish(boardEvent(1, models.StatusDelivered))
+
+	testCases := []struct {
+		name     string
+		sub      *OrderBoardSubscription
+		expected int
+	}{
+		{"Admin sees everything", admin, 5},
+		{"Kitchen sees the order until it leaves preparing", kitchen, 3},
+		{"Courier sees the order while ready and when it is claimed", courier, 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := receivedIDs(tc.sub); len(got) != tc.expected {
+				t.Errorf("Expected %d events, got %d", tc.expected, len(got))
+			}
+		})
+	}
+}
+
+func TestOrderBoardConnectionFilter(t *testing.T) {
+	board := NewOrderBoard(8)
+	kitchen := board.Subscribe([]models.OrderStatus{models.StatusPending, models.StatusPreparing})
+
+	// O filtro da conexão não amplia o que o papel pode ver
+	board.SetFilter(kitchen, []models.OrderStatus{models.StatusPreparing, models.StatusReady})
+	if got := board.ActiveStatuses(kitchen); !reflect.DeepEqual(got, []models.OrderStatus{models.StatusPreparing}) {
+		t.Errorf("Expected only preparing, got %v", got)
+	}
+
+	board.Publish(boardEvent(1, models.StatusPending))
+	board.Publish(boardEvent(2, models.StatusPreparing))
+	board.Publish(boardEvent(3, models.StatusReady))
+	if got := receivedIDs(kitchen); !reflect.DeepEqual(got, []uint{2}) {
+		t.Errorf("Expected only order 2, got %v", got)
+	}
+
+	// Pedido exibido na foto recebe a mudança que o tira do quadro
+	board.SetFilter(kitchen, nil)
+	board.Show(kitchen, []uint{4})
+	board.Publish(boardEvent(4, models.StatusReady))
+	board.Publish(boardEvent(4, models.StatusDelivering))
+	if got := receivedIDs(kitchen); !reflect.DeepEqual(got, []uint{4}) {
+		t.Errorf("Expected a single event for order 4, got %v", got)
+	}
+}
+
+func TestOrderBoardClosesSlowConnection(t *testing.T) {
+	board := NewOrderBoard(2)
+	slow := board.Subscribe(nil)
+	fast := board.Subscribe(nil)
+
+	for id := uint(1); id <= 3; id++ {
+		board.Publish(boardEvent(id, models.StatusPending))
+		if id <= 2 {
+			<-fast.Events()
+		}
+	}
+
+	if got := receivedIDs(slow); len(got) != 2 {
+		t.Errorf("Expected the 2 buffered events before closing, got %v", got)
+	}
+	if _, ok := <-slow.Events(); ok || !slow.Lagged() {
+		t.Error("Expected slow connection to be closed as lagged")
+	}
+	if got := receivedIDs(fast); !reflect.DeepEqual(got, []uint{3}) || fast.Lagged() {
+		t.Errorf("Expected fast connection to keep receiving, got %v", got)
+	}
+
+	// Encerrar depois de fechado pelo quadro não deve entrar em pânico
+	board.Unsubscribe(slow)
+	board.Unsubscribe(fast)
+}
+
+func TestNewBoardOrderLeavesOutCustomerData(t *testing.T) {
+	order := models.Order{
+		CustomerID:       4,
+		Customer:         models.User{Name: "Maria", Phone: "41999990000"},
+		Status:           models.StatusReady,
+		Address:          "Rua XV de Novembro, 100",
+		PostalAddress:    models.PostalAddress{Street: "Rua XV de Novembro", Number: "100", Neighborhood: "Centro", City: "Curitiba"},
+		CustomerDocument: "52998224725",
+		ContactPhone:     "41999990000",
+		Items:            []models.OrderItem{{ProductID: 3, Product: models.Product{Name: "Red Velvet"}, Quantity: 2}},
+	}
+	order.ID = 9
+
+	data, err := json.Marshal(NewBoardOrder(order))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, private := range []string{"Maria", "41999990000", "52998224725", "XV de Novembro", "customer"} {
+		if strings.Contains(string(data), private) {
+			t.Errorf("Expected %q to be left out, got %s", private, data)
+		}
+	}
+	for _, expected := range []string{`"id":9`, `"Red Velvet"`, `"neighborhood":"Centro"`} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Expected %s in %s", expected, data)
+		}
+	}
+
+	if withoutArea := NewBoardOrder(order).WithoutArea(); withoutArea.Area != nil {
+		t.Errorf("Expected no area, got %+v", withoutArea.Area)
+	}
+}
+
+// itemsOrderBoardStore devolve os mesmos itens para qualquer pedido
+type itemsOrderBoardStore struct{ items []BoardOrderItem }
+
+func (s itemsOrderBoardStore) ActiveOrders(statuses []models.OrderStatus, limit int) ([]BoardOrder, error) {
+	return nil, nil
+}
+
+func (s itemsOrderBoardStore) OrderItems(orderID uint) ([]BoardOrderItem, error) {
+	return s.items, nil
+}
+
+func TestOrderBoardPublishOrderLoadsItems(t *testing.T) {
+	board := NewOrderBoard(8)
+	board.SetStore(itemsOrderBoardStore{items: []BoardOrderItem{{ProductID: 3, Name: "Red Velvet", Quantity: 2}}})
+	sub := board.Subscribe(nil)
+
+	order := &models.Order{Status: models.StatusPreparing}
+	order.ID = 5
+	board.PublishOrder(OrderEventStatusChanged, order)
+
+	event := <-sub.Events()
+	if event.Order.ID != 5 || event.Order.Status != models.StatusPreparing || len(event.Order.Items) != 1 || event.Order.Items[0].Name != "Red Velvet" {
+		t.Errorf("Expected order 5 with its items, got %+v", event.Order)
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"cupcake-delivery/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var ErrOrderNotClaimable = errors.New("pedido não está disponível para retirada")
+
+// OrderNotClaimableError identifica o pedido do lote que não pôde ser retirado
+type OrderNotClaimableError struct {
+	OrderID uint
+}
+
+func (e *OrderNotClaimableError) Error() string {
+	return fmt.Sprintf("pedido %d não está disponível para retirada", e.OrderID)
+}
+
+func (e *OrderNotClaimableError) Unwrap() error {
+	return ErrOrderNotClaimable
+}
+
+// OrderClaimStore atribui um entregador a um pedido pronto de forma atômica:
+// retorna true apenas para quem efetivamente fez a atribuição. check, se informado, roda na mesma
+// transação antes da atribuição e pode recusá-la.
+type OrderClaimStore interface {
+	ClaimReady(orderID, courierID uint, at time.Time, check func(tx *gorm.DB) error) (bool, error)
+}
+
+// DBOrderClaimStore usa um UPDATE condicional; o banco garante que só uma transação
+// encontra o pedido ainda sem entregador, com status ready e já liberado para retirada
+type DBOrderClaimStore struct {
+	db *gorm.DB
+}
+
+func NewDBOrderClaimStore(db *gorm.DB) *DBOrderClaimStore {
+	return &DBOrderClaimStore{db: db}
+}
+
+func (s *DBOrderClaimStore) ClaimReady(orderID, courierID uint, at time.Time, check func(tx *gorm.DB) error) (bool, error) {
+	claimed := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if check != nil {
+			if err := check(tx); err != nil {
+				return err
+			}
+		}
+		result := tx.Model(&models.Order{}).
+			Where("id = ? AND delivery_id IS NULL AND status = ?", orderID, models.StatusReady).
+			Where("scheduled_for IS NULL OR scheduled_for <= ?", at).
+			Updates(map[string]interface{}{
+				"delivery_id":       courierID,
+				"status":            models.StatusDelivering,
+				"status_changed_at": at,
+				"updated_at":        at,
+			})
+		if result.Error != nil || result.RowsAffected != 1 {
+			return result.Error
+		}
+		claimed = true
+		return RecordStatusChange(tx, orderID, models.StatusDelivering, at)
+	})
+	return claimed && err == nil, err
+}
+
+// OrderClaimService retirada de pedidos prontos pelos entregadores
+type OrderClaimService struct {
+	store OrderClaimStore
+	loads LoadChecker
+	now   func() time.Time
+}
+
+func NewOrderClaimService(store OrderClaimStore) *OrderClaimService {
+	return &OrderClaimService{store: store, now: time.Now}
+}
+
+// SetLoadChecker passa a exigir que o veículo do entregador comporte o pedido retirado
+func (s *OrderClaimService) SetLoadChecker(loads LoadChecker) {
+	s.loads = loads
+}
+
+// Claim atribui o pedido ao entregador e o coloca em entrega.
+// Retorna ErrOrderNotClaimable se outro entregador chegou antes ou se o pedido não está pronto.
+func (s *OrderClaimService) Claim(orderID, courierID uint) error {
+	var check func(tx *gorm.DB) error
+	if s.loads != nil {
+		check = func(tx *gorm.DB) error {
+			return s.loads.CheckLoad(tx, courierID, []uint{orderID})
+		}
+	}
+
+	claimed, err := s.store.ClaimReady(orderID, courierID, s.now(), check)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return ErrOrderNotClaimable
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cupcake-delivery/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// memoryOrderClaimStore reproduz em memória a semântica do UPDATE condicional
+type memoryOrderClaimStore struct {
+	mu     sync.Mutex
+	orders map[uint]*models.Order
+}
+
+func (s *memoryOrderClaimStore) ClaimReady(orderID, courierID uint, at time.Time, check func(tx *gorm.DB) error) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if check != nil {
+		if err := check(nil); err != nil {
+			return false, err
+		}
+	}
+
+	order, ok := s.orders[orderID]
+	if !ok || order.DeliveryID != nil || order.Status != models.StatusReady || (order.ScheduledFor != nil && order.ScheduledFor.After(at)) {
+		return false, nil
+	}
+	order.DeliveryID = &courierID
+	order.Status = models.StatusDelivering
+	order.UpdatedAt = at
+	return true, nil
+}
+
+// hammerClaim dispara claimers goroutines ao mesmo tempo e retorna quantas venceram e quantas receberam ErrOrderNotClaimable
+func hammerClaim(t *testing.T, service *OrderClaimService, orderID uint, claimers int) (int64, int64) {
+	t.Helper()
+
+	var wins, losses int64
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < claimers; i++ {
+		wg.Add(1)
+		go func(courierID uint) {
+			defer wg.Done()
+			<-start
+			err := service.Claim(orderID, courierID)
+			switch {
+			case err == nil:
+				atomic.AddInt64(&wins, 1)
+			case errors.Is(err, ErrOrderNotClaimable):
+				atomic.AddInt64(&losses, 1)
+			default:
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}(uint(i + 1))
+	}
+	close(start)
+	wg.Wait()
+
+	return wins, losses
+}
+
+func TestOrderNotClaimableError(t *testing.T) {
+	var err error = &OrderNotClaimableError{OrderID: 42}
+	if !errors.Is(err, ErrOrderNotClaimable) {
+		t.Errorf("Expected error to match ErrOrderNotClaimable")
+	}
+}
+
+func TestOrderClaimServiceConcurrentClaims(t *testing.T) {
+	store := &memoryOrderClaimStore{orders: map[uint]*models.Order{}}
+	for id := uint(1); id <= 20; id++ {
+		store.orders[id] = &models.Order{Status: models.StatusReady}
+	}
+	service := NewOrderClaimService(store)
+
+	for id := uint(1); id <= 20; id++ {
+		wins, losses := hammerClaim(t, service, id, 50)
+		if wins != 1 || losses != 49 {
+			t.Fatalf("Order %d: expected exactly 1 winner and 49 losers, got %d and %d", id, wins, losses)
+		}
+		if store.orders[id].DeliveryID == nil || store.orders[id].Status != models.StatusDelivering {
+			t.Errorf("Order %d should be assigned and delivering: %+v", id, store.orders[id])
+		}
+	}
+}
+
+func TestOrderClaimServiceRejectsUnavailableOrders(t *testing.T) {
+	courier := uint(7)
+	store := &memoryOrderClaimStore{orders: map[uint]*models.Order{
+		1: {Status: models.StatusPreparing},
+		2: {Status: models.StatusDelivering, DeliveryID: &courier},
+	}}
+	service := NewOrderClaimService(store)
+
+	for _, id := range []uint{1, 2, 3} {
+		if err := service.Claim(id, 9); !errors.Is(err, ErrOrderNotClaimable) {
+			t.Errorf("Order %d: expected ErrOrderNotClaimable, got %v", id, err)
+		}
+	}
+}
+
+// TestDBOrderClaimStoreConcurrentClaims executa o UPDATE condicional contra um PostgreSQL real.
+// Roda no CI com TEST_DATABASE_URL; localmente é pulado sem a variável.
+func TestDBOrderClaimStoreConcurrentClaims(t *testing.T) {
+	db := openTestDB(t)
+
+	customer := models.User{Name: "Cliente", Email: "claim-test-" + time.Now().Format("150405.000000") + "@example.com", Type: models.CustomerType}
+	if err := db.Create(&customer).Error; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Unscoped().Where("customer_id = ?", customer.ID).Delete(&models.Order{})
+		db.Unscoped().Delete(&customer)
+	})
+
+	service := NewOrderClaimService(NewDBOrderClaimStore(db))
+	for i := 0; i < 5; i++ {
+		order := models.Order{CustomerID: customer.ID, Status: models.StatusReady}
+		if err := db.Create(&order).Error; err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		wins, losses := hammerClaim(t, service, order.ID, 32)
+		if wins != 1 || losses != 31 {
+			t.Fatalf("Order %d: expected exactly 1 winner and 31 losers, got %d and %d", order.ID, wins, losses)
+		}
+
+		var stored models.Order
+		db.First(&stored, order.ID)
+		if stored.DeliveryID == nil || stored.Status != models.StatusDelivering {
+			t.Errorf("Order %d should be assigned and delivering: %+v", order.ID, stored)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"cupcake-delivery/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// OrderPlacementStore grava os pedidos novos
+type OrderPlacementStore interface {
+	// Create grava pedido, itens e histórico de status numa transação; nil só depois do commit
+	Create(order *models.Order, items []models.OrderItem, at time.Time) error
+	// Discard apaga o pedido cuja cobrança foi recusada
+	Discard(orderID uint) error
+	// MarkPaid registra a cobrança aprovada
+	MarkPaid(orderID uint) error
+	// PendingPayments pedidos com a cobrança pendente criados antes de before
+	PendingPayments(before time.Time) ([]models.Order, error)
+}
+
+// DBOrderPlacementStore grava os pedidos no banco
+type DBOrderPlacementStore struct {
+	db *gorm.DB
+}
+
+func NewDBOrderPlacementStore(db *gorm.DB) *DBOrderPlacementStore {
+	return &DBOrderPlacementStore{db: db}
+}
+
+func (s *DBOrderPlacementStore) Create(order *models.Order, items []models.OrderItem, at time.Time) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Items").Create(order).Error; err != nil {
+			return err
+		}
+		if err := RecordStatusChange(tx, order.ID, order.Status, at); err != nil {
+			return err
+		}
+		order.Items = nil
+		for _, item := range items {
+			item.OrderID = order.ID
+			if err := tx.Omit("Product").Create(&item).Error; err != nil {
+				return err
+			}
+			order.Items = append(order.Items, item)
+		}
+		return nil
+	})
+}
+
+func (s *DBOrderPlacementStore) Discard(orderID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("order_id = ?", orderID).Delete(&models.OrderItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("order_id = ?", orderID).Delete(&models.OrderStatusChange{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.Order{}, orderID).Error
+	})
+}
+
+func (s *DBOrderPlacementStore) MarkPaid(orderID uint) error {
+	return s.db.Model(&models.Order{}).
+		Where("id = ? AND payment_status = ?", orderID, models.PaymentPending).
+		Update("payment_status", models.PaymentPaid).Error
+}
+
+func (s *DBOrderPlacementStore) PendingPayments(before time.Time) ([]models.Order, error) {
+	var orders []models.Order
+	err := s.db.Where("payment_status = ? AND created_at < ?", models.PaymentPending, before).
+		Order("id").
+		Find(&orders).Error
+	return orders, err
+}
+
+// OrderPlacementService cria o pedido e cobra o cliente. A cobrança fica fora da transação: o pedido
+// é gravado primeiro, com a cobrança pendente, e cobrado com uma chave de idempotência ligada ao seu ID,
+// de modo que nenhum cliente é cobrado por um pedido que não chegou ao banco. Só a recusa descarta o
+// pedido; sem resposta conclusiva do meio de pagamento ele continua pendente e RetryPending repete a
+// cobrança com a mesma chave.
+type OrderPlacementService struct {
+	store    OrderPlacementStore
+	payments PaymentProcessor
+	now      func() time.Time
+}
+
+func NewOrderPlacementService(store OrderPlacementStore, payments PaymentProcessor) *OrderPlacementService {
+	return &OrderPlacementService{store: store, payments: payments, now: time.Now}
+}
+
+// Place grava o pedido com seus itens e cobra o total, com a gorjeta em linha própria. Recusada, o pedido
+// é descartado e Place retorna ErrPaymentDeclined; sem resposta conclusiva retorna ErrPaymentPending e o
+// pedido fica gravado aguardando a cobrança.
+func (s *OrderPlacementService) Place(ctx context.Context, order *models.Order, items []models.OrderItem) error {
+	now := s.now()
+	order.StatusChangedAt = &now
+	order.PaymentStatus = models.PaymentPending
+	if err := s.store.Create(order, items, now); err != nil {
+		return err
+	}
+	return s.charge(ctx, order)
+}
+
+// RetryPending repete a cobrança dos pedidos pendentes criados antes de olderThan atrás, com a mesma chave
+// de idempotência; o intervalo evita repetir a de um pedido que ainda está sendo criado. Retorna quantos
+// foram resolvidos (cobrados ou descartados).
+func (s *OrderPlacementService) RetryPending(ctx context.Context, olderThan time.Duration) (int, error) {
+	orders, err := s.store.PendingPayments(s.now().Add(-olderThan))
+	if err != nil {
+		return 0, err
+	}
+
+	resolved := 0
+	for i := range orders {
+		err := s.charge(ctx, &orders[i])
+		if errors.Is(err, ErrPaymentPending) {
+			continue
+		}
+		resolved++
+	}
+	return resolved, nil
+}
+
+// RunPaymentRetrier executa RetryPending periodicamente até o contexto ser cancelado
+func (s *OrderPlacementService) RunPaymentRetrier(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if resolved, err := s.RetryPending(ctx, interval); err != nil {
+			log.Printf("Erro ao repetir cobranças pendentes: %v", err)
+		} else if resolved > 0 {
+			log.Printf("%d cobrança(s) pendente(s) resolvida(s)", resolved)
+		}
+	}
+}
+
+// charge cobra o pedido gravado. Aprovada, marca como pago; recusada, descarta o pedido; qualquer outro
+// erro deixa a cobrança pendente e retorna ErrPaymentPending.
+func (s *OrderPlacementService) charge(ctx context.Context, order *models.Order) error {
+	err := s.payments.Charge(ctx, PaymentCharge{
+		IdempotencyKey: OrderChargeKey(order.ID),
+		OrderID:        order.ID,
+		CustomerID:     order.CustomerID,
+		Subtotal:       order.Subtotal,
+		DeliveryFee:    order.DeliveryFee,
+		Tip:            order.Tip,
+		Amount:         order.Total,
+		Description:    fmt.Sprintf("Pedido #%d", order.ID),
+	})
+	switch {
+	case err == nil:
+		// Se a marcação falhar a cobrança é repetida depois com a mesma chave, sem cobrar de novo
+		if markErr := s.store.MarkPaid(order.ID); markErr != nil {
+			log.Printf("Erro ao marcar pedido %d como pago: %v", order.ID, markErr)
+			return nil
+		}
+		order.PaymentStatus = models.PaymentPaid
+		return nil
+	case errors.Is(err, ErrPaymentDeclined):
+		if discardErr := s.store.Discard(order.ID); discardErr != nil {
+			log.Printf("Erro ao descartar pedido %d após recusa da cobrança: %v", order.ID, discardErr)
+		}
+		return err
+	default:
+		log.Printf("Cobrança do pedido %d sem resposta conclusiva; fica pendente: %v", order.ID, err)
+		return ErrPaymentPending
+	}
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cupcake-delivery/internal/models"
+)
+
+// recordingPaymentProcessor guarda as cobranças recebidas e responde com err
+type recordingPaymentProcessor struct {
+	charges []PaymentCharge
+	err     error
+}
+
+func (p *recordingPaymentProcessor) Charge(ctx context.Context, charge PaymentCharge) error {
+	p.charges = append(p.charges, charge)
+	return p.err
+}
+
+// memoryOrderPlacementStore grava os pedidos em memória; createErr simula falha no commit
+type memoryOrderPlacementStore struct {
+	orders    map[uint]*models.Order
+	createErr error
+}
+
+func (s *memoryOrderPlacementStore) MarkPaid(orderID uint) error {
+	if order, ok := s.orders[orderID]; ok {
+		order.PaymentStatus = models.PaymentPaid
+	}
+	return nil
+}
+
+func (s *memoryOrderPlacementStore) PendingPayments(before time.Time) ([]models.Order, error) {
+	var pending []models.Order
+	for id := uint(1); id <= uint(len(s.orders)); id++ {
+		if order, ok := s.orders[id]; ok && order.PaymentStatus == models.PaymentPending {
+			pending = append(pending, *order)
+		}
+	}
+	return pending, nil
+}
+
+func (s *memoryOrderPlacementStore) Create(order *models.Order, items []models.OrderItem, at time.Time) error {
+	if s.createErr != nil {
+		return s.createErr
+	}
+	order.ID = uint(len(s.orders) + 1)
+	order.Items = items
+	s.orders[order.ID] = order
+	return nil
+}
+
+func (s *memoryOrderPlacementStore) Discard(orderID uint) error {
+	delete(s.orders, orderID)
+	return nil
+}
+
+func newTestOrderPlacement(createErr, chargeErr error) (*OrderPlacementService, *memoryOrderPlacementStore, *recordingPaymentProcessor) {
+	store := &memoryOrderPlacementStore{orders: map[uint]*models.Order{}, createErr: createErr}
+	payments := &recordingPaymentProcessor{err: chargeErr}
+	return NewOrderPlacementService(store, payments), store, payments
+}
+
+func testPlacementOrder() (*models.Order, []models.OrderItem) {
+	order := &models.Order{CustomerID: 7, Status: models.StatusPending, Subtotal: 30, DeliveryFee: 8, Tip: 2, Total: 40}
+	return order, []models.OrderItem{{ProductID: 1, Quantity: 2, Price: 15}}
+}
+
+func TestOrderPlacementChargesAfterCommit(t *testing.T) {
+	service, store, payments := newTestOrderPlacement(nil, nil)
+	order, items := testPlacementOrder()
+
+	if err := service.Place(context.Background(), order, items); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := store.orders[order.ID]; !ok || order.StatusChangedAt == nil || order.PaymentStatus != models.PaymentPaid {
+		t.Fatalf("Expected order to be stored with its status timestamp and paid, got %+v", order)
+	}
+	if len(payments.charges) != 1 {
+		t.Fatalf("Expected 1 charge, got %d", len(payments.charges))
+	}
+	charge := payments.charges[0]
+	if charge.IdempotencyKey != OrderChargeKey(order.ID) || charge.OrderID != order.ID || charge.Amount != 40 || charge.Tip != 2 {
+		t.Errorf("Unexpected charge: %+v", charge)
+	}
+}
+
+func TestOrderPlacementDiscardsOrderWhenChargeIsDeclined(t *testing.T) {
+	service, store, payments := newTestOrderPlacement(nil, ErrPaymentDeclined)
+	order, items := testPlacementOrder()
+
+	if err := service.Place(context.Background(), order, items); !errors.Is(err, ErrPaymentDeclined) {
+		t.Errorf("Expected ErrPaymentDeclined, got %v", err)
+	}
+	if len(payments.charges) != 1 || len(store.orders) != 0 {
+		t.Errorf("Expected one charge attempt and the order discarded, got %d charges and %d orders", len(payments.charges), len(store.orders))
+	}
+}
+
+func TestOrderPlacementKeepsOrderPendingWhenChargeIsInconclusive(t *testing.T) {
+	service, store, payments := newTestOrderPlacement(nil, errors.New("timeout do gateway"))
+	order, items := testPlacementOrder()
+
+	if err := service.Place(context.Background(), order, items); !errors.Is(err, ErrPaymentPending) {
+		t.Fatalf("Expected ErrPaymentPending, got %v", err)
+	}
+	if stored, ok := store.orders[order.ID]; !ok || stored.PaymentStatus != models.PaymentPending {
+		t.Fatalf("Expected the order kept with the charge pending, got %+v", store.orders)
+	}
+
+	// O gateway volta: a nova tentativa usa a mesma chave e conclui a cobrança
+	payments.err = nil
+	resolved, err := service.RetryPending(context.Background(), time.Minute)
+	if err != nil || resolved != 1 {
+		t.Fatalf("Expected 1 resolved charge, got %d (%v)", resolved, err)
+	}
+	if len(payments.charges) != 2 || payments.charges[1].IdempotencyKey != payments.charges[0].IdempotencyKey {
+		t.Errorf("Expected the retry to reuse the idempotency key, got %+v", payments.charges)
+	}
+	if store.orders[order.ID].PaymentStatus != models.PaymentPaid {
+		t.Errorf("Expected the order paid after the retry, got %+v", store.orders[order.ID])
+	}
+}
+
+func TestOrderPlacementDoesNotChargeWhenCommitFails(t *testing.T) {
+	commitErr := errors.New("commit falhou")
+	service, _, payments := newTestOrderPlacement(commitErr, nil)
+	order, items := testPlacementOrder()
+
+	if err := service.Place(context.Background(), order, items); !errors.Is(err, commitErr) {
+		t.Errorf("Expected commit error, got %v", err)
+	}
+	if len(payments.charges) != 0 {
+		t.Errorf("Expected no charge for an order that was not stored, got %+v", payments.charges)
+	}
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+var (
+	ErrPaymentDeclined = errors.New("pagamento recusado")
+	// ErrPaymentPending a cobrança não teve resposta conclusiva (timeout, erro do gateway); o valor pode
+	// ter sido cobrado, então o registro fica pendente e a cobrança é repetida com a mesma chave
+	ErrPaymentPending = errors.New("pagamento em processamento")
+)
+
+// PaymentCharge cobrança enviada ao meio de pagamento, com a gorjeta em linha própria.
+// Amount = Subtotal + DeliveryFee + Tip; uma gorjeta após a entrega é cobrada sozinha.
+// O gateway não cobra duas vezes a mesma IdempotencyKey, então repetir a cobrança é seguro.
+type PaymentCharge struct {
+	IdempotencyKey string
+	OrderID        uint
+	CustomerID     uint
+	Subtotal       float64
+	DeliveryFee    float64
+	Tip            float64
+	Amount         float64
+	Description    string
+}
+
+// OrderChargeKey chave de idempotência da cobrança do pedido no checkout
+func OrderChargeKey(orderID uint) string {
+	return fmt.Sprintf("order-%d", orderID)
+}
+
+// TipChargeKey chave de idempotência da gorjeta dada depois da entrega. Leva o ID do crédito da gorjeta:
+// uma nova tentativa depois de uma recusa (talvez com outro valor) é outra cobrança para o gateway.
+func TipChargeKey(orderID, tipID uint) string {
+	return fmt.Sprintf("order-%d-tip-%d", orderID, tipID)
+}
+
+// PaymentProcessor cobra o cliente; um gateway de pagamento implementa a interface e
+// retorna ErrPaymentDeclined quando a cobrança é recusada
+type PaymentProcessor interface {
+	Charge(ctx context.Context, charge PaymentCharge) error
+}
+
+// LogPaymentProcessor apenas registra a cobrança no log (padrão sem gateway configurado)
+type LogPaymentProcessor struct{}
+
+func (LogPaymentProcessor) Charge(ctx context.Context, charge PaymentCharge) error {
+	log.Printf("Cobrança %s do pedido #%d ao cliente %d: R$ %.2f (itens %.2f, entrega %.2f, gorjeta %.2f) - %s",
+		charge.IdempotencyKey, charge.OrderID, charge.CustomerID, charge.Amount, charge.Subtotal, charge.DeliveryFee, charge.Tip, charge.Description)
+	return nil
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"cupcake-delivery/internal/models"
+	"errors"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownRole        = errors.New("unknown role")
+	ErrRolesManageLockout = errors.New("role would lose roles:manage")
+	ErrUnknownPermission  = errors.New("unknown permission")
+)
+
+// UnknownPermissionError indica a permissão que não existe na API
+type UnknownPermissionError struct {
+	Permission models.Permission
+}
+
+func (e *UnknownPermissionError) Error() string {
+	return "unknown permission " + string(e.Permission)
+}
+
+func (e *UnknownPermissionError) Unwrap() error {
+	return ErrUnknownPermission
+}
+
+type PermissionService struct {
+	db    *gorm.DB
+	mu    sync.RWMutex
+	roles map[models.UserType][]models.Permission
+}
+
+// NewPermissionService cria o serviço com o mapeamento padrão de permissões.
+// Chame Reload para aplicar as permissões armazenadas no banco.
+func NewPermissionService(db *gorm.DB) *PermissionService {
+	s := &PermissionService{db: db}
+	s.roles = copyRolePermissions(models.DefaultRolePermissions)
+	return s
+}
+
+// Reload recarrega o mapeamento a partir do banco; papéis não sobrescritos mantêm o padrão
+func (s *PermissionService) Reload() error {
+	roles := copyRolePermissions(models.DefaultRolePermissions)
+
+	if s.db != nil {
+		var overrides []models.RolePermissionOverride
+		if err := s.db.Find(&overrides).Error; err != nil {
+			return err
+		}
+		var stored []models.RolePermission
+		if err := s.db.Order("role, permission").Find(&stored).Error; err != nil {
+			return err
+		}
+
+		// Papéis sobrescritos começam vazios: sem registros de permissão, ficam sem nenhuma
+		overridden := make(map[models.UserType]bool)
+		for _, override := range overrides {
+			roles[override.Role] = nil
+			overridden[override.Role] = true
+		}
+		for _, rp := range stored {
+			if !overridden[rp.Role] {
+				roles[rp.Role] = nil
+				overridden[rp.Role] = true
+			}
+			roles[rp.Role] = append(roles[rp.Role], rp.Permission)
+		}
+	}
+
+	s.mu.Lock()
+	s.roles = roles
+	s.mu.Unlock()
+	return nil
+}
+
+// HasPermission indica se o papel possui a permissão solicitada
+func (s *PermissionService) HasPermission(role models.UserType, perm models.Permission) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, granted := range s.roles[role] {
+		if granted.Matches(perm) {
+			return true
+		}
+	}
+	return false
+}
+
+// RolePermissions retorna uma cópia do mapeamento atual
+func (s *PermissionService) RolePermissions() map[models.UserType][]models.Permission {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return copyRolePermissions(s.roles)
+}
+
+// SetRolePermissions substitui no banco as permissões de um papel e recarrega o mapeamento; lista vazia
+// deixa o papel sem permissões. actor é o papel de quem faz a alteração.
+func (s *PermissionService) SetRolePermissions(actor, role models.UserType, perms []models.Permission) error {
+	if err := checkRoleChange(actor, role, perms); err != nil {
+		return err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role = ?", role).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Save(&models.RolePermissionOverride{Role: role}).Error; err != nil {
+			return err
+		}
+		for _, perm := range perms {
+			if err := tx.Create(&models.RolePermission{Role: role, Permission: perm}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return s.Reload()
+}
+
+// ResetRolePermissions remove do banco as permissões de um papel, que volta ao mapeamento padrão
+func (s *PermissionService) ResetRolePermissions(actor, role models.UserType) error {
+	if err := checkRoleChange(actor, role, models.DefaultRolePermissions[role]); err != nil {
+		return err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role = ?", role).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("role = ?", role).Delete(&models.RolePermissionOverride{}).Error
+	})
+	if err != nil {
+		return err
+	}
+	return s.Reload()
+}
+
+// checkRoleChange valida o papel e as permissões e impede que o admin tire do próprio papel a
+// gestão de papéis, o que deixaria todos sem como desfazer a alteração
+func checkRoleChange(actor, role models.UserType, perms []models.Permission) error {
+	if !role.Valid() {
+		return ErrUnknownRole
+	}
+	for _, perm := range perms {
+		if !perm.Known() {
+			return &UnknownPermissionError{Permission: perm}
+		}
+	}
+	if role != actor {
+		return nil
+	}
+	for _, perm := range perms {
+		if perm.Matches(models.PermRolesManage) {
+			return nil
+		}
+	}
+	return ErrRolesManageLockout
+}
+
+func copyRolePermissions(src map[models.UserType][]models.Permission) map[models.UserType][]models.Permission {
+	dst := make(map[models.UserType][]models.Permission, len(src))
+	for role, perms := range src {
+		dst[role] = append([]models.Permission(nil), perms...)
+	}
+	return dst
+}
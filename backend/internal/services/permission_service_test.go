@@ -0,0 +1,107 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"cupcake-delivery/internal/models"
+)
+
+func TestPermissionMatches(t *testing.T) {
+	testCases := []struct {
+		name      string
+		granted   models.Permission
+		requested models.Permission
+		expected  bool
+	}{
+		{"Exact match", "products:write", "products:write", true},
+		{"Different permission", "products:write", "orders:read:any", false},
+		{"Wildcard suffix", "orders:status:*", "orders:status:ready", true},
+		{"Wildcard other resource", "orders:status:*", "orders:read:any", false},
+		{"Global wildcard", "*", "roles:manage", true},
+		{"Prefix without wildcard", "orders:status", "orders:status:ready", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.granted.Matches(tc.requested); got != tc.expected {
+				t.Errorf("Expected %v for %s matching %s, got %v", tc.expected, tc.granted, tc.requested, got)
+			}
+		})
+	}
+}
+
+func TestDefaultRolePermissions(t *testing.T) {
+	s := NewPermissionService(nil)
+
+	testCases := []struct {
+		name     string
+		role     models.UserType
+		perm     models.Permission
+		expected bool
+	}{
+		{"Customer creates order", models.CustomerType, models.PermOrdersCreate, true},
+		{"Customer reads own orders", models.CustomerType, models.PermOrdersReadOwn, true},
+		{"Customer cannot read any order", models.CustomerType, models.PermOrdersReadAny, false},
+		{"Customer cannot write products", models.CustomerType, models.PermProductsWrite, false},
+		{"Delivery moves to delivering", models.DeliveryType, models.OrderStatusPermission(models.StatusDelivering), true},
+		{"Delivery moves to delivered", models.DeliveryType, models.OrderStatusPermission(models.StatusDelivered), true},
+		{"Delivery cannot move to preparing", models.DeliveryType, models.OrderStatusPermission(models.StatusPreparing), false},
+		{"Admin moves to any status", models.AdminType, models.OrderStatusPermission(models.StatusPreparing), true},
+		{"Admin writes products", models.AdminType, models.PermProductsWrite, true},
+		{"Admin is not assigned as courier", models.AdminType, models.PermOrdersAssignSelf, false},
+		{"Kitchen reads kitchen orders", models.KitchenType, models.PermOrdersReadKitchen, true},
+		{"Kitchen sees the queue", models.KitchenType, models.PermKitchenQueue, true},
+		{"Kitchen moves to ready", models.KitchenType, models.OrderStatusPermission(models.StatusReady), true},
+		{"Kitchen cannot move to delivering", models.KitchenType, models.OrderStatusPermission(models.StatusDelivering), false},
+		{"Kitchen cannot read any order", models.KitchenType, models.PermOrdersReadAny, false},
+		{"Unknown role has no permissions", models.UserType("guest"), models.PermOrdersReadOwn, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := s.HasPermission(tc.role, tc.perm); got != tc.expected {
+				t.Errorf("Expected %v for role %s and permission %s, got %v", tc.expected, tc.role, tc.perm, got)
+			}
+		})
+	}
+}
+
+func TestRolePermissionsReturnsCopy(t *testing.T) {
+	s := NewPermissionService(nil)
+
+	roles := s.RolePermissions()
+	roles[models.CustomerType] = append(roles[models.CustomerType], models.PermProductsWrite)
+
+	if s.HasPermission(models.CustomerType, models.PermProductsWrite) {
+		t.Errorf("Expected mapping to be unaffected by changes to the returned copy")
+	}
+}
+
+func TestCheckRoleChange(t *testing.T) {
+	testCases := []struct {
+		name     string
+		actor    models.UserType
+		role     models.UserType
+		perms    []models.Permission
+		expected error
+	}{
+		{"Admin clears another role", models.AdminType, models.KitchenType, nil, nil},
+		{"Admin keeps roles:manage", models.AdminType, models.AdminType, []models.Permission{models.PermRolesManage}, nil},
+		{"Admin keeps roles:manage by wildcard", models.AdminType, models.AdminType, []models.Permission{"*"}, nil},
+		{"Admin removes own roles:manage", models.AdminType, models.AdminType, []models.Permission{models.PermProductsWrite}, ErrRolesManageLockout},
+		{"Admin clears own role", models.AdminType, models.AdminType, []models.Permission{}, ErrRolesManageLockout},
+		{"Unknown role", models.AdminType, models.UserType("guest"), nil, ErrUnknownRole},
+		{"Status wildcard", models.AdminType, models.KitchenType, []models.Permission{"orders:status:*"}, nil},
+		{"Unknown permission", models.AdminType, models.KitchenType, []models.Permission{"orders:delete"}, ErrUnknownPermission},
+		{"Wildcard matching nothing", models.AdminType, models.KitchenType, []models.Permission{"reports:*"}, ErrUnknownPermission},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := checkRoleChange(tc.actor, tc.role, tc.perms); !errors.Is(err, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,368 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"cupcake-delivery/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrDeletionAlreadyRequested = errors.New("exclusão da conta já solicitada")
+	ErrDeletionNotRequested     = errors.New("não há exclusão de conta pendente")
+)
+
+// Texto gravado no lugar do endereço dos pedidos de contas expurgadas
+const anonymizedAddress = "Endereço removido a pedido do titular (LGPD)"
+
+// DataExport cópia dos dados pessoais de um usuário (direito de acesso da LGPD)
+type DataExport struct {
+	ExportedAt    time.Time             `json:"exported_at"`
+	Profile       models.User           `json:"profile"`
+	Addresses     []models.UserAddress  `json:"addresses"`
+	Orders        []models.Order        `json:"orders"` // Com itens e comprovante de entrega
+	Notifications []models.Notification `json:"notifications"`
+	Files         []ExportFile          `json:"files"`
+}
+
+// ExportFile arquivo guardado sobre o usuário, como a foto do comprovante de entrega
+type ExportFile struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Content     []byte `json:"content"` // Base64 no JSON; arquivo próprio no ZIP
+}
+
+// WriteZip grava a exportação como um ZIP com um arquivo JSON por categoria de dados
+func (e *DataExport) WriteZip(w io.Writer) error {
+	archive := zip.NewWriter(w)
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", e.Profile},
+		{"addresses.json", e.Addresses},
+		{"orders.json", e.Orders},
+		{"notifications.json", e.Notifications},
+	}
+	for _, file := range files {
+		entry, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: e.ExportedAt,
+		})
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(entry)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range e.Files {
+		entry, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     "files/" + file.Name,
+			Method:   zip.Deflate,
+			Modified: e.ExportedAt,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := entry.Write(file.Content); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// PrivacyService atende os pedidos de exportação e exclusão de dados pessoais
+type PrivacyService struct {
+	db          *gorm.DB
+	audit       *AuditService
+	storage     FileStorage
+	gracePeriod time.Duration
+	now         func() time.Time
+}
+
+// NewPrivacyService cria o serviço; contas com exclusão solicitada são expurgadas após gracePeriod
+func NewPrivacyService(db *gorm.DB, audit *AuditService, storage FileStorage, gracePeriod time.Duration) *PrivacyService {
+	return &PrivacyService{
+		db:          db,
+		audit:       audit,
+		storage:     storage,
+		gracePeriod: gracePeriod,
+		now:         time.Now,
+	}
+}
+
+// Export reúne perfil, endereços, pedidos (com itens e comprovantes), notificações e as fotos
+// dos comprovantes de entrega do usuário
+func (s *PrivacyService) Export(ctx context.Context, userID uint) (*DataExport, error) {
+	export := &DataExport{ExportedAt: s.now()}
+
+	if err := s.db.Preload("Vehicle").First(&export.Profile, userID).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Where("user_id = ?", userID).Order("created_at").Find(&export.Addresses).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Preload("Items.Product").Preload("DeliveryProof").
+		Where("customer_id = ?", userID).
+		Order("created_at").
+		Find(&export.Orders).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Where("user_id = ?", userID).Order("created_at").Find(&export.Notifications).Error; err != nil {
+		return nil, err
+	}
+
+	for _, order := range export.Orders {
+		if order.DeliveryProof == nil || order.DeliveryProof.PhotoKey == "" {
+			continue
+		}
+		file, err := s.exportFile(ctx, order.DeliveryProof.PhotoKey, order.DeliveryProof.PhotoContentType)
+		if errors.Is(err, ErrFileNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		export.Files = append(export.Files, *file)
+	}
+
+	return export, nil
+}
+
+func (s *PrivacyService) exportFile(ctx context.Context, key, contentType string) (*ExportFile, error) {
+	reader, err := s.storage.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	return &ExportFile{Name: key, ContentType: contentType, Content: content}, nil
+}
+
+// PendingDeletion retorna o pedido de exclusão ainda não expurgado do usuário, se houver
+func (s *PrivacyService) PendingDeletion(userID uint) (*models.AccountDeletion, error) {
+	var deletion models.AccountDeletion
+	err := s.db.Where("user_id = ? AND purged_at IS NULL", userID).First(&deletion).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &deletion, nil
+}
+
+// RequestDeletion agenda o expurgo da conta para o fim do período de carência
+func (s *PrivacyService) RequestDeletion(userID uint) (*models.AccountDeletion, error) {
+	pending, err := s.PendingDeletion(userID)
+	if err != nil {
+		return nil, err
+	}
+	if pending != nil {
+		return pending, ErrDeletionAlreadyRequested
+	}
+
+	now := s.now()
+	deletion := models.AccountDeletion{
+		UserID:      userID,
+		RequestedAt: now,
+		PurgeAfter:  now.Add(s.gracePeriod),
+	}
+	if err := s.db.Create(&deletion).Error; err != nil {
+		return nil, err
+	}
+	return &deletion, nil
+}
+
+// CancelDeletion desiste da exclusão enquanto o período de carência não terminou
+func (s *PrivacyService) CancelDeletion(userID uint) error {
+	result := s.db.Where("user_id = ? AND purged_at IS NULL", userID).Delete(&models.AccountDeletion{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDeletionNotRequested
+	}
+	return nil
+}
+
+// PurgeDue expurga todas as contas cujo período de carência terminou e retorna quantas foram expurgadas
+func (s *PrivacyService) PurgeDue() (int, error) {
+	var due []models.AccountDeletion
+	if err := s.db.Where("purged_at IS NULL AND purge_after <= ?", s.now()).Find(&due).Error; err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, deletion := range due {
+		if err := s.purge(deletion); err != nil {
+			return purged, fmt.Errorf("expurgo do usuário %d: %w", deletion.UserID, err)
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// purge anonimiza os pedidos (mantendo valores e itens para a contabilidade) e remove os demais dados pessoais
+func (s *PrivacyService) purge(deletion models.AccountDeletion) error {
+	userID := deletion.UserID
+	now := s.now()
+
+	// Fotos e documentos só são apagados depois do commit, quando nenhum registro aponta para eles
+	var photoKeys, documentKeys []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Order{}).Where("customer_id = ?", userID).Updates(map[string]interface{}{
+			"address":           anonymizedAddress,
+			"latitude":          nil,
+			"longitude":         nil,
+			"cep":               "",
+			"street":            "",
+			"number":            "",
+			"complement":        "",
+			"neighborhood":      "",
+			"city":              "",
+			"state":             "",
+			"customer_document": "",
+			"contact_phone":     "",
+		}).Error; err != nil {
+			return err
+		}
+
+		// Comprovantes das entregas ao titular: nome de quem recebeu, foto e local
+		customerOrders := tx.Model(&models.Order{}).Select("id").Where("customer_id = ?", userID)
+		if err := tx.Model(&models.DeliveryProof{}).
+			Where("order_id IN (?) AND photo_key <> ''", customerOrders).
+			Pluck("photo_key", &photoKeys).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.DeliveryProof{}).Where("order_id IN (?)", customerOrders).Updates(map[string]interface{}{
+			"recipient_name":     "",
+			"photo_key":          "",
+			"photo_content_type": "",
+			"latitude":           nil,
+			"longitude":          nil,
+		}).Error; err != nil {
+			return err
+		}
+
+		// Observações do entregador sobre as tentativas de entrega ao titular
+		if err := tx.Model(&models.DeliveryAttempt{}).Where("customer_id = ?", userID).Update("notes", "").Error; err != nil {
+			return err
+		}
+
+		// Trajetos das entregas ao titular e, se for entregador, suas posições
+		if err := tx.Where("order_id IN (?) OR courier_id = ?", customerOrders, userID).
+			Delete(&models.CourierLocation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("courier_id = ?", userID).Delete(&models.CourierPosition{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", userID).Delete(&models.Notification{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.UserAddress{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.TwoFactor{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Vehicle{}).
+			Where("courier_id = ? AND document_key <> ''", userID).
+			Pluck("document_key", &documentKeys).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("courier_id = ?", userID).Delete(&models.Vehicle{}).Error; err != nil {
+			return err
+		}
+
+		var user models.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("key = ?", AccountKey(user.Email)).Delete(&models.LoginAttempt{}).Error; err != nil {
+			return err
+		}
+
+		// O registro do usuário continua existindo (excluído logicamente) para manter a referência
+		// dos pedidos; o email é trocado para liberar o endereço original para um novo cadastro
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"name":     "Usuário removido",
+			"email":    fmt.Sprintf("removido-%d@anonimizado.invalid", userID),
+			"phone":    "",
+			"document": "",
+			"password": "",
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&user).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&deletion).Update("purged_at", now).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range photoKeys {
+		if err := s.storage.Delete(context.Background(), key); err != nil {
+			log.Printf("Erro ao remover foto %s do usuário %d: %v", key, userID, err)
+		}
+	}
+	for _, key := range documentKeys {
+		if err := s.storage.Delete(context.Background(), key); err != nil {
+			log.Printf("Erro ao remover documento do veículo %s do usuário %d: %v", key, userID, err)
+		}
+	}
+
+	s.audit.Record(models.AuditLog{
+		SubjectID: userID,
+		Action:    models.AuditActionAccountPurged,
+		Details:   fmt.Sprintf("Solicitado em %s", deletion.RequestedAt.Format(time.RFC3339)),
+	})
+	return nil
+}
+
+// RunPurger executa PurgeDue periodicamente até o contexto ser cancelado
+func (s *PrivacyService) RunPurger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if purged, err := s.PurgeDue(); err != nil {
+			log.Printf("Erro ao expurgar contas: %v", err)
+		} else if purged > 0 {
+			log.Printf("%d conta(s) expurgada(s) após o período de carência", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"cupcake-delivery/internal/models"
+)
+
+func TestDataExportWriteZip(t *testing.T) {
+	orderID := uint(3)
+	export := &DataExport{
+		ExportedAt: time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC),
+		Profile:    models.User{Name: "Maria", Email: "maria@example.com", Document: "52998224725"},
+		Orders: []models.Order{{
+			Total: 25.5,
+			Items: []models.OrderItem{{ProductID: 1, Quantity: 2, Price: 12.75}},
+		}},
+		Notifications: []models.Notification{{UserID: 1, OrderID: &orderID, Title: "Pedido Criado"}},
+		Files:         []ExportFile{{Name: "delivery-proofs/3/1.jpg", ContentType: "image/jpeg", Content: []byte("foto")}},
+	}
+
+	var buf bytes.Buffer
+	if err := export.WriteZip(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Invalid ZIP: %v", err)
+	}
+
+	files := map[string][]byte{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		data, _ := io.ReadAll(reader)
+		reader.Close()
+		files[file.Name] = data
+	}
+
+	for _, name := range []string{"profile.json", "addresses.json", "orders.json", "notifications.json"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("Expected %s in export", name)
+		}
+	}
+
+	if string(files["files/delivery-proofs/3/1.jpg"]) != "foto" {
+		t.Errorf("Expected proof photo in export, got %q", files["files/delivery-proofs/3/1.jpg"])
+	}
+
+	var profile models.User
+	if err := json.Unmarshal(files["profile.json"], &profile); err != nil || profile.Email != "maria@example.com" {
+		t.Errorf("Unexpected profile: %s", files["profile.json"])
+	}
+
+	var orders []models.Order
+	if err := json.Unmarshal(files["orders.json"], &orders); err != nil {
+		t.Fatalf("Invalid orders.json: %v", err)
+	}
+	if len(orders) != 1 || len(orders[0].Items) != 1 || orders[0].Items[0].Quantity != 2 {
+		t.Errorf("Expected order items in export, got %s", files["orders.json"])
+	}
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"time"
+)
+
+// RoutePoint ponto de partida de uma rota (posição do entregador ou loja)
+type RoutePoint struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// RouteStop parada a ordenar; pedidos sem coordenadas ficam no fim da rota, na ordem recebida
+type RouteStop struct {
+	OrderID   uint
+	Latitude  *float64
+	Longitude *float64
+}
+
+func (s RouteStop) located() bool {
+	return s.Latitude != nil && s.Longitude != nil
+}
+
+// PlanRoute ordena as paradas pelo vizinho mais próximo a partir de start e melhora a ordem com 2-opt.
+// A rota é aberta: termina na última parada, sem voltar ao ponto de partida. Sem start, a primeira
+// parada é livre.
+func PlanRoute(start *RoutePoint, stops []RouteStop) []RouteStop {
+	var located, unlocated []RouteStop
+	for _, stop := range stops {
+		if stop.located() {
+			located = append(located, stop)
+		} else {
+			unlocated = append(unlocated, stop)
+		}
+	}
+
+	route := twoOpt(start, nearestNeighbour(start, located))
+	return append(route, unlocated...)
+}
+
+// nearestNeighbour visita sempre a parada mais próxima da atual; empates ficam com a que veio antes
+func nearestNeighbour(start *RoutePoint, stops []RouteStop) []RouteStop {
+	remaining := append([]RouteStop(nil), stops...)
+	route := make([]RouteStop, 0, len(stops))
+	current := start
+
+	for len(remaining) > 0 {
+		next := 0
+		if current != nil {
+			best := -1.0
+			for i, stop := range remaining {
+				distance := Haversine(current.Latitude, current.Longitude, *stop.Latitude, *stop.Longitude)
+				if best < 0 || distance < best {
+					next, best = i, distance
+				}
+			}
+		}
+		route = append(route, remaining[next])
+		current = &RoutePoint{Latitude: *remaining[next].Latitude, Longitude: *remaining[next].Longitude}
+		remaining = append(remaining[:next], remaining[next+1:]...)
+	}
+	return route
+}
+
+// twoOpt inverte trechos da rota enquanto isso a encurtar
+func twoOpt(start *RoutePoint, route []RouteStop) []RouteStop {
+	const epsilon = 1e-9
+
+	// leg distância entre o ponto antes de i (ou start) e a parada j; sem ponto anterior, zero
+	leg := func(i, j int) float64 {
+		if i < 0 {
+			if start == nil {
+				return 0
+			}
+			return Haversine(start.Latitude, start.Longitude, *route[j].Latitude, *route[j].Longitude)
+		}
+		return Haversine(*route[i].Latitude, *route[i].Longitude, *route[j].Latitude, *route[j].Longitude)
+	}
+
+	for improved := true; improved; {
+		improved = false
+		for i := 0; i < len(route)-1; i++ {
+			for j := i + 1; j < len(route); j++ {
+				// Trocar as arestas (i-1, i) e (j, j+1) por (i-1, j) e (i, j+1)
+				before := leg(i-1, i)
+				after := leg(i-1, j)
+				if j+1 < len(route) {
+					before += leg(j, j+1)
+					after += leg(i, j+1)
+				}
+				if after < before-epsilon {
+					for a, b := i, j; a < b; a, b = a+1, b-1 {
+						route[a], route[b] = route[b], route[a]
+					}
+					improved = true
+				}
+			}
+		}
+	}
+	return route
+}
+
+// RouteLegsKm distância de cada parada até a anterior (a primeira, até start). Paradas sem coordenadas
+// têm trecho zero e o trecho seguinte parte da última parada localizada.
+func RouteLegsKm(start *RoutePoint, route []RouteStop) []float64 {
+	legs := make([]float64, len(route))
+	current := start
+	for i, stop := range route {
+		if !stop.located() {
+			continue
+		}
+		if current != nil {
+			legs[i] = Haversine(current.Latitude, current.Longitude, *stop.Latitude, *stop.Longitude)
+		}
+		current = &RoutePoint{Latitude: *stop.Latitude, Longitude: *stop.Longitude}
+	}
+	return legs
+}
+
+// EstimateStopArrivals previsão de chegada a cada parada saindo em at: o deslocamento na velocidade
+// média mais stopTime em cada parada anterior
+func EstimateStopArrivals(legs []float64, at time.Time, speedKmh float64, stopTime time.Duration) []time.Time {
+	arrivals := make([]time.Time, len(legs))
+	elapsed := time.Duration(0)
+	for i, leg := range legs {
+		if i > 0 {
+			elapsed += stopTime
+		}
+		elapsed += time.Duration(leg / speedKmh * float64(time.Hour))
+		arrivals[i] = at.Add(elapsed)
+	}
+	return arrivals
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// stopAt parada na linha do equador; 0,01° de longitude ≈ 1,11 km
+func stopAt(orderID uint, longitude float64) RouteStop {
+	latitude := 0.0
+	return RouteStop{OrderID: orderID, Latitude: &latitude, Longitude: &longitude}
+}
+
+func routeIDs(route []RouteStop) []uint {
+	ids := make([]uint, 0, len(route))
+	for _, stop := range route {
+		ids = append(ids, stop.OrderID)
+	}
+	return ids
+}
+
+func equalIDs(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPlanRoute(t *testing.T) {
+	origin := &RoutePoint{Latitude: 0, Longitude: 0}
+
+	testCases := []struct {
+		name     string
+		start    *RoutePoint
+		stops    []RouteStop
+		expected []uint
+	}{
+		{
+			"Nearest first from the store",
+			origin,
+			[]RouteStop{stopAt(1, 0.03), stopAt(2, 0.01), stopAt(3, 0.02)},
+			[]uint{2, 3, 1},
+		},
+		{
+			"Stops on both sides",
+			origin,
+			[]RouteStop{stopAt(1, -0.05), stopAt(2, 0.01), stopAt(3, -0.02)},
+			[]uint{2, 3, 1},
+		},
+		{
+			"Without start the route runs end to end",
+			nil,
+			[]RouteStop{stopAt(1, 0.02), stopAt(2, 0.04), stopAt(3, 0.01), stopAt(4, 0.03)},
+			[]uint{2, 4, 1, 3},
+		},
+		{
+			"Stops without coordinates go last",
+			origin,
+			[]RouteStop{{OrderID: 1}, stopAt(2, 0.02), stopAt(3, 0.01)},
+			[]uint{3, 2, 1},
+		},
+		{
+			"Single stop",
+			origin,
+			[]RouteStop{stopAt(7, 0.02)},
+			[]uint{7},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := routeIDs(PlanRoute(tc.start, tc.stops))
+			if !equalIDs(got, tc.expected) {
+				t.Errorf("Expected route %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestTwoOptRemovesDetour(t *testing.T) {
+	start := &RoutePoint{Latitude: 0, Longitude: 0}
+	route := []RouteStop{stopAt(1, 0.02), stopAt(2, 0.01), stopAt(3, 0.03)}
+
+	got := routeIDs(twoOpt(start, route))
+	if expected := []uint{2, 1, 3}; !equalIDs(got, expected) {
+		t.Errorf("Expected route %v, got %v", expected, got)
+	}
+}
+
+func TestRouteLegsKm(t *testing.T) {
+	start := &RoutePoint{Latitude: 0, Longitude: 0}
+	route := []RouteStop{stopAt(1, 0.01), {OrderID: 2}, stopAt(3, 0.03)}
+	unit := Haversine(0, 0, 0, 0.01)
+
+	legs := RouteLegsKm(start, route)
+	expected := []float64{unit, 0, 2 * unit}
+	for i := range expected {
+		if math.Abs(legs[i]-expected[i]) > 1e-6 {
+			t.Errorf("Leg %d: expected %.4f km, got %.4f km", i, expected[i], legs[i])
+		}
+	}
+
+	if legs := RouteLegsKm(nil, route[:1]); legs[0] != 0 {
+		t.Errorf("Expected first leg without start to be 0, got %.4f", legs[0])
+	}
+}
+
+func TestEstimateStopArrivals(t *testing.T) {
+	at := time.Date(2024, 5, 10, 18, 0, 0, 0, time.UTC)
+
+	arrivals := EstimateStopArrivals([]float64{5, 2.5, 0}, at, 20, 3*time.Minute)
+	expected := []time.Duration{
+		15 * time.Minute,
+		15*time.Minute + 3*time.Minute + 7*time.Minute + 30*time.Second,
+		25*time.Minute + 30*time.Second + 3*time.Minute,
+	}
+	for i, offset := range expected {
+		if !arrivals[i].Equal(at.Add(offset)) {
+			t.Errorf("Stop %d: expected arrival %s, got %s", i, at.Add(offset).Format(time.TimeOnly), arrivals[i].Format(time.TimeOnly))
+		}
+	}
+}
+
+func TestUniqueIDs(t *testing.T) {
+	got := uniqueIDs([]uint{3, 1, 3, 2, 1})
+	if expected := []uint{3, 1, 2}; !equalIDs(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
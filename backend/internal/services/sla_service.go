@@ -0,0 +1,338 @@
+package services
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cupcake-delivery/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// slaAtRiskRatio fração do prazo a partir da qual o pedido aparece no painel de pedidos em risco
+const slaAtRiskRatio = 0.8
+
+// slaStatuses status em que o pedido ainda depende da loja ou do entregador e pode ter prazo
+var slaStatuses = map[models.OrderStatus]bool{
+	models.StatusPending:        true,
+	models.StatusPreparing:      true,
+	models.StatusReady:          true,
+	models.StatusDelivering:     true,
+	models.StatusDeliveryFailed: true,
+	models.StatusReturned:       true,
+}
+
+// SLAPolicy tempo máximo que o pedido pode ficar em cada status; status fora do mapa não têm prazo
+type SLAPolicy map[models.OrderStatus]time.Duration
+
+// ParseSLAPolicy lê os prazos em minutos no formato "pending=15,preparing=90"
+func ParseSLAPolicy(value string) (SLAPolicy, error) {
+	policy := make(SLAPolicy)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, minutes, ok := strings.Cut(entry, "=")
+		status := models.OrderStatus(strings.TrimSpace(name))
+		if !ok || !slaStatuses[status] {
+			return nil, fmt.Errorf("prazo inválido: %q", entry)
+		}
+		if _, repeated := policy[status]; repeated {
+			return nil, fmt.Errorf("status com mais de um prazo: %q", status)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(minutes))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("prazo inválido: %q", entry)
+		}
+		policy[status] = time.Duration(n) * time.Minute
+	}
+	return policy, nil
+}
+
+func (p SLAPolicy) statuses() []models.OrderStatus {
+	statuses := make([]models.OrderStatus, 0, len(p))
+	for status := range p {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i] < statuses[j] })
+	return statuses
+}
+
+// AtRiskOrder pedido perto de estourar ou já fora do prazo do status atual
+type AtRiskOrder struct {
+	OrderID        uint               `json:"order_id"`
+	CustomerID     uint               `json:"customer_id"`
+	DeliveryID     *uint              `json:"delivery_id,omitempty"`
+	Status         models.OrderStatus `json:"status"`
+	Since          time.Time          `json:"since"`
+	ElapsedMinutes int                `json:"elapsed_minutes"`
+	LimitMinutes   int                `json:"limit_minutes"`
+	OverageMinutes int                `json:"overage_minutes"` // Negativo enquanto ainda está no prazo
+	Breached       bool               `json:"breached"`
+}
+
+// slaOrder pedido em andamento e o momento em que entrou no status atual
+type slaOrder struct {
+	ID         uint
+	CustomerID uint
+	DeliveryID *uint
+	Status     models.OrderStatus
+	Since      time.Time
+}
+
+// evaluateSLA seleciona os pedidos que já usaram slaAtRiskRatio do prazo, do mais atrasado para o menos
+func evaluateSLA(orders []slaOrder, policy SLAPolicy, now time.Time) []AtRiskOrder {
+	type evaluated struct {
+		AtRiskOrder
+		overage time.Duration
+	}
+
+	var risky []evaluated
+	for _, order := range orders {
+		limit, ok := policy[order.Status]
+		if !ok {
+			continue
+		}
+		elapsed := now.Sub(order.Since)
+		if float64(elapsed) < slaAtRiskRatio*float64(limit) {
+			continue
+		}
+		risky = append(risky, evaluated{
+			AtRiskOrder: AtRiskOrder{
+				OrderID:        order.ID,
+				CustomerID:     order.CustomerID,
+				DeliveryID:     order.DeliveryID,
+				Status:         order.Status,
+				Since:          order.Since,
+				ElapsedMinutes: int(elapsed / time.Minute),
+				LimitMinutes:   int(limit / time.Minute),
+				OverageMinutes: int((elapsed - limit) / time.Minute),
+				Breached:       elapsed > limit,
+			},
+			overage: elapsed - limit,
+		})
+	}
+
+	sort.SliceStable(risky, func(i, j int) bool {
+		if risky[i].overage != risky[j].overage {
+			return risky[i].overage > risky[j].overage
+		}
+		return risky[i].OrderID < risky[j].OrderID
+	})
+
+	result := make([]AtRiskOrder, 0, len(risky))
+	for _, item := range risky {
+		result = append(result, item.AtRiskOrder)
+	}
+	return result
+}
+
+// SLANotifier avisa os administradores do atraso e pede desculpas ao cliente
+type SLANotifier interface {
+	NotifySLABreach(order *models.Order, breach *models.OrderSLABreach) error
+	NotifyRunningLate(order *models.Order) error
+}
+
+// SLAMetrics recebe as métricas de prazo a cada verificação
+type SLAMetrics interface {
+	// Breached conta um novo estouro de prazo no status
+	Breached(status models.OrderStatus)
+	// AtRisk substitui a contagem de pedidos em risco e fora do prazo por status
+	AtRisk(orders []AtRiskOrder)
+}
+
+// ExpvarSLAMetrics publica as métricas em /debug/vars: order_sla_breaches_total, order_sla_at_risk e
+// order_sla_breaching, todas por status
+type ExpvarSLAMetrics struct {
+	breaches  *expvar.Map
+	atRisk    *expvar.Map
+	breaching *expvar.Map
+}
+
+// NewExpvarSLAMetrics registra as variáveis no expvar; deve ser chamado uma única vez
+func NewExpvarSLAMetrics() *ExpvarSLAMetrics {
+	return &ExpvarSLAMetrics{
+		breaches:  expvar.NewMap("order_sla_breaches_total"),
+		atRisk:    expvar.NewMap("order_sla_at_risk"),
+		breaching: expvar.NewMap("order_sla_breaching"),
+	}
+}
+
+func (m *ExpvarSLAMetrics) Breached(status models.OrderStatus) {
+	m.breaches.Add(string(status), 1)
+}
+
+func (m *ExpvarSLAMetrics) AtRisk(orders []AtRiskOrder) {
+	atRisk := make(map[models.OrderStatus]int64)
+	breaching := make(map[models.OrderStatus]int64)
+	for _, order := range orders {
+		atRisk[order.Status]++
+		if order.Breached {
+			breaching[order.Status]++
+		}
+	}
+
+	for status := range slaStatuses {
+		setGauge(m.atRisk, string(status), atRisk[status])
+		setGauge(m.breaching, string(status), breaching[status])
+	}
+}
+
+func setGauge(m *expvar.Map, key string, value int64) {
+	gauge, ok := m.Get(key).(*expvar.Int)
+	if !ok {
+		gauge = new(expvar.Int)
+		m.Set(key, gauge)
+	}
+	gauge.Set(value)
+}
+
+// SLAMonitor acompanha os prazos por status dos pedidos em andamento
+type SLAMonitor struct {
+	db       *gorm.DB
+	policy   SLAPolicy
+	notifier SLANotifier
+	metrics  SLAMetrics
+	now      func() time.Time
+}
+
+func NewSLAMonitor(db *gorm.DB, policy SLAPolicy, notifier SLANotifier, metrics SLAMetrics) *SLAMonitor {
+	return &SLAMonitor{
+		db:       db,
+		policy:   policy,
+		notifier: notifier,
+		metrics:  metrics,
+		now:      time.Now,
+	}
+}
+
+// Policy prazos configurados
+func (m *SLAMonitor) Policy() SLAPolicy {
+	return m.policy
+}
+
+// AtRisk lista os pedidos em risco ou fora do prazo, do mais atrasado para o menos
+func (m *SLAMonitor) AtRisk() ([]AtRiskOrder, error) {
+	orders, err := m.activeOrders()
+	if err != nil {
+		return nil, err
+	}
+	return evaluateSLA(orders, m.policy, m.now()), nil
+}
+
+// activeOrders pedidos nos status com prazo. Pedidos anteriores ao registro de status_changed_at usam
+// updated_at; pedidos reagendados só começam a contar o prazo no horário combinado.
+func (m *SLAMonitor) activeOrders() ([]slaOrder, error) {
+	if len(m.policy) == 0 {
+		return nil, nil
+	}
+
+	var orders []slaOrder
+	err := m.db.Model(&models.Order{}).
+		Select("id, customer_id, delivery_id, status, GREATEST(COALESCE(status_changed_at, updated_at), scheduled_for) AS since").
+		Where("status IN ?", m.policy.statuses()).
+		Scan(&orders).Error
+	return orders, err
+}
+
+// Check registra os novos estouros de prazo, avisa administradores e clientes e atualiza as métricas.
+// Retorna quantos pedidos estouraram o prazo desde a última verificação.
+func (m *SLAMonitor) Check() (int, error) {
+	now := m.now()
+
+	// Estouros de pedidos que já saíram do status deixam de estar em aberto
+	if err := m.db.Model(&models.OrderSLABreach{}).
+		Where("resolved_at IS NULL").
+		Where("NOT EXISTS (SELECT 1 FROM orders WHERE orders.id = order_sla_breaches.order_id AND orders.status = order_sla_breaches.status AND orders.deleted_at IS NULL)").
+		Update("resolved_at", now).Error; err != nil {
+		return 0, err
+	}
+
+	orders, err := m.activeOrders()
+	if err != nil {
+		return 0, err
+	}
+	atRisk := evaluateSLA(orders, m.policy, now)
+	m.metrics.AtRisk(atRisk)
+
+	breached := 0
+	for _, item := range atRisk {
+		if !item.Breached {
+			continue
+		}
+		breach := models.OrderSLABreach{
+			OrderID:      item.OrderID,
+			Status:       item.Status,
+			StatusSince:  item.Since,
+			LimitMinutes: item.LimitMinutes,
+			BreachedAt:   now,
+		}
+		result := m.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&breach)
+		if result.Error != nil {
+			return breached, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue // Já avisado nesta passagem pelo status
+		}
+
+		breached++
+		m.metrics.Breached(item.Status)
+		m.notify(&breach)
+	}
+	return breached, nil
+}
+
+// notify avisa os administradores de cada estouro; o cliente recebe o pedido de desculpas uma vez por pedido
+func (m *SLAMonitor) notify(breach *models.OrderSLABreach) {
+	var order models.Order
+	if err := m.db.First(&order, breach.OrderID).Error; err != nil {
+		log.Printf("Erro ao buscar pedido %d com prazo estourado: %v", breach.OrderID, err)
+		return
+	}
+
+	if err := m.notifier.NotifySLABreach(&order, breach); err != nil {
+		log.Printf("Erro ao avisar atraso do pedido %d: %v", order.ID, err)
+	}
+
+	var notified int64
+	if err := m.db.Model(&models.OrderSLABreach{}).
+		Where("order_id = ? AND customer_notified", order.ID).
+		Count(&notified).Error; err != nil || notified > 0 {
+		return
+	}
+	if err := m.notifier.NotifyRunningLate(&order); err != nil {
+		log.Printf("Erro ao avisar o cliente do atraso do pedido %d: %v", order.ID, err)
+		return
+	}
+	if err := m.db.Model(breach).Update("customer_notified", true).Error; err != nil {
+		log.Printf("Erro ao registrar aviso de atraso do pedido %d: %v", order.ID, err)
+	}
+}
+
+// Run verifica os prazos a cada interval até o contexto ser cancelado
+func (m *SLAMonitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if breached, err := m.Check(); err != nil {
+			log.Printf("Erro ao verificar prazos dos pedidos: %v", err)
+		} else if breached > 0 {
+			log.Printf("%d pedido(s) estouraram o prazo do status", breached)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
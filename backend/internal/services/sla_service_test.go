@@ -0,0 +1,95 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"cupcake-delivery/internal/models"
+)
+
+func TestParseSLAPolicy(t *testing.T) {
+	testCases := []struct {
+		name      string
+		value     string
+		expected  SLAPolicy
+		expectErr bool
+	}{
+		{"Default policy", "pending=15,preparing=90,ready=20,delivering=60", SLAPolicy{
+			models.StatusPending:    15 * time.Minute,
+			models.StatusPreparing:  90 * time.Minute,
+			models.StatusReady:      20 * time.Minute,
+			models.StatusDelivering: 60 * time.Minute,
+		}, false},
+		{"Spaces and trailing comma", " returned = 30 , ", SLAPolicy{models.StatusReturned: 30 * time.Minute}, false},
+		{"Empty", "", SLAPolicy{}, false},
+		{"Terminal status", "delivered=10", nil, true},
+		{"Unknown status", "cooking=10", nil, true},
+		{"Missing minutes", "pending", nil, true},
+		{"Zero minutes", "pending=0", nil, true},
+		{"Not a number", "pending=abc", nil, true},
+		{"Repeated status", "pending=10,pending=20", nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			policy, err := ParseSLAPolicy(tc.value)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("Expected error, got %v", policy)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(policy) != len(tc.expected) {
+				t.Fatalf("Expected %v, got %v", tc.expected, policy)
+			}
+			for status, limit := range tc.expected {
+				if policy[status] != limit {
+					t.Errorf("Expected %s=%v, got %v", status, limit, policy[status])
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluateSLA(t *testing.T) {
+	now := time.Date(2024, 5, 10, 18, 0, 0, 0, time.UTC)
+	policy := SLAPolicy{
+		models.StatusPreparing: 90 * time.Minute,
+		models.StatusReady:     20 * time.Minute,
+	}
+	orders := []slaOrder{
+		{ID: 1, Status: models.StatusPreparing, Since: now.Add(-60 * time.Minute)},  // 67% do prazo
+		{ID: 2, Status: models.StatusPreparing, Since: now.Add(-100 * time.Minute)}, // 10 min atrasado
+		{ID: 3, Status: models.StatusReady, Since: now.Add(-17 * time.Minute)},      // 85% do prazo
+		{ID: 4, Status: models.StatusReady, Since: now.Add(-45 * time.Minute)},      // 25 min atrasado
+		{ID: 5, Status: models.StatusDelivering, Since: now.Add(-5 * time.Hour)},    // Sem prazo
+		{ID: 6, Status: models.StatusReady, Since: now.Add(30 * time.Minute)},       // Reagendado
+	}
+
+	result := evaluateSLA(orders, policy, now)
+
+	expected := []struct {
+		orderID  uint
+		overage  int
+		breached bool
+	}{
+		{4, 25, true},
+		{2, 10, true},
+		{3, -3, false},
+	}
+	if len(result) != len(expected) {
+		t.Fatalf("Expected %d orders, got %+v", len(expected), result)
+	}
+	for i, want := range expected {
+		got := result[i]
+		if got.OrderID != want.orderID || got.OverageMinutes != want.overage || got.Breached != want.breached {
+			t.Errorf("Position %d: expected order %d (overage %d, breached %v), got %+v", i, want.orderID, want.overage, want.breached, got)
+		}
+	}
+	if result[0].ElapsedMinutes != 45 || result[0].LimitMinutes != 20 {
+		t.Errorf("Expected 45 of 20 minutes, got %d of %d", result[0].ElapsedMinutes, result[0].LimitMinutes)
+	}
+}
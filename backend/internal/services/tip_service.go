@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"cupcake-delivery/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTipWindowClosed = errors.New("prazo para gorjeta encerrado ou pedido ainda não entregue")
+	ErrAlreadyTipped   = errors.New("pedido já tem gorjeta")
+	ErrTipNoCourier    = errors.New("pedido sem entregador para receber a gorjeta")
+)
+
+// TipStore grava as gorjetas dadas depois da entrega
+type TipStore interface {
+	// Order busca o pedido do cliente
+	Order(orderID, customerID uint) (*models.Order, error)
+	// Apply grava a gorjeta no pedido, com a cobrança pendente, e o crédito ao entregador numa transação;
+	// retorna o ID do crédito, que identifica esta gorjeta na cobrança. nil só depois do commit;
+	// ErrAlreadyTipped se outra gorjeta chegou antes.
+	Apply(order *models.Order, amount float64, at time.Time) (uint, error)
+	// Revert desfaz a gorjeta e o crédito quando a cobrança é recusada
+	Revert(order *models.Order, amount float64) error
+	// MarkPaid registra a cobrança da gorjeta aprovada
+	MarkPaid(orderID uint) error
+	// PendingTips gorjetas com a cobrança pendente lançadas antes de before
+	PendingTips(before time.Time) ([]PendingTip, error)
+}
+
+// PendingTip gorjeta aguardando a cobrança e o crédito que a identifica
+type PendingTip struct {
+	Order models.Order
+	TipID uint
+}
+
+// DBTipStore grava as gorjetas no banco
+type DBTipStore struct {
+	db *gorm.DB
+}
+
+func NewDBTipStore(db *gorm.DB) *DBTipStore {
+	return &DBTipStore{db: db}
+}
+
+func (s *DBTipStore) Order(orderID, customerID uint) (*models.Order, error) {
+	var order models.Order
+	if err := s.db.Where("id = ? AND customer_id = ?", orderID, customerID).First(&order).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (s *DBTipStore) Apply(order *models.Order, amount float64, at time.Time) (uint, error) {
+	var tipID uint
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Order{}).
+			Where("id = ? AND status = ? AND tip = 0", order.ID, models.StatusDelivered).
+			Updates(map[string]interface{}{
+				"tip":                amount,
+				"total":              gorm.Expr("total + ?", amount),
+				"tip_payment_status": models.PaymentPending,
+				"updated_at":         at,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrAlreadyTipped
+		}
+
+		earning := models.CourierEarning{
+			CourierID:   *order.DeliveryID,
+			OrderID:     &order.ID,
+			Type:        models.EarningTip,
+			Amount:      amount,
+			Description: fmt.Sprintf("Gorjeta do pedido #%d", order.ID),
+			CreatedAt:   at,
+		}
+		err := tx.Create(&earning).Error
+		// Gorjeta já lançada por um admin para o pedido
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrAlreadyTipped
+		}
+		tipID = earning.ID
+		return err
+	})
+	return tipID, err
+}
+
+func (s *DBTipStore) Revert(order *models.Order, amount float64) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Order{}).
+			Where("id = ? AND tip = ?", order.ID, amount).
+			Updates(map[string]interface{}{
+				"tip":                0,
+				"total":              gorm.Expr("total - ?", amount),
+				"tip_payment_status": "",
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Where("order_id = ? AND type = ?", order.ID, models.EarningTip).Delete(&models.CourierEarning{}).Error
+	})
+}
+
+func (s *DBTipStore) MarkPaid(orderID uint) error {
+	return s.db.Model(&models.Order{}).
+		Where("id = ? AND tip_payment_status = ?", orderID, models.PaymentPending).
+		Update("tip_payment_status", models.PaymentPaid).Error
+}
+
+func (s *DBTipStore) PendingTips(before time.Time) ([]PendingTip, error) {
+	var earnings []models.CourierEarning
+	if err := s.db.Joins("JOIN orders ON orders.id = courier_earnings.order_id").
+		Where("courier_earnings.type = ? AND courier_earnings.created_at < ? AND orders.tip_payment_status = ?", models.EarningTip, before, models.PaymentPending).
+		Order("courier_earnings.id").
+		Find(&earnings).Error; err != nil {
+		return nil, err
+	}
+
+	pending := make([]PendingTip, 0, len(earnings))
+	for _, earning := range earnings {
+		var order models.Order
+		if err := s.db.First(&order, *earning.OrderID).Error; err != nil {
+			return nil, err
+		}
+		pending = append(pending, PendingTip{Order: order, TipID: earning.ID})
+	}
+	return pending, nil
+}
+
+// TipService gorjetas dadas pelo cliente depois da entrega
+type TipService struct {
+	store    TipStore
+	payments PaymentProcessor
+	window   time.Duration
+	now      func() time.Time
+}
+
+func NewTipService(store TipStore, payments PaymentProcessor, window time.Duration) *TipService {
+	return &TipService{store: store, payments: payments, window: window, now: time.Now}
+}
+
+// AddTip credita a gorjeta ao entregador do pedido e cobra o cliente. Cada pedido aceita uma gorjeta,
+// no checkout ou até window após a entrega. A gorjeta é gravada antes da cobrança, que usa uma chave de
+// idempotência da gorjeta; recusada, a gorjeta e o crédito são desfeitos e AddTip retorna
+// ErrPaymentDeclined. Sem resposta conclusiva a gorjeta fica gravada com a cobrança pendente e AddTip
+// retorna o pedido com ErrPaymentPending; RetryPending repete a cobrança com a mesma chave.
+func (s *TipService) AddTip(ctx context.Context, orderID, customerID uint, amount float64) (*models.Order, error) {
+	order, err := s.store.Order(orderID, customerID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Tip > 0 {
+		return nil, ErrAlreadyTipped
+	}
+	now := s.now()
+	if !tipWindowOpen(order, s.window, now) {
+		return nil, ErrTipWindowClosed
+	}
+	if order.DeliveryID == nil {
+		return nil, ErrTipNoCourier
+	}
+
+	amount = roundCents(amount)
+	tipID, err := s.store.Apply(order, amount, now)
+	if err != nil {
+		return nil, err
+	}
+	order.Tip = amount
+	order.Total = roundCents(order.Total + amount)
+	order.TipPaymentStatus = models.PaymentPending
+	order.UpdatedAt = now
+
+	if err := s.charge(ctx, order, tipID); err != nil {
+		if errors.Is(err, ErrPaymentPending) {
+			return order, err
+		}
+		return nil, err
+	}
+	return order, nil
+}
+
+// RetryPending repete a cobrança das gorjetas pendentes lançadas antes de olderThan atrás, com a mesma
+// chave de idempotência. Retorna quantas foram resolvidas (cobradas ou desfeitas).
+func (s *TipService) RetryPending(ctx context.Context, olderThan time.Duration) (int, error) {
+	tips, err := s.store.PendingTips(s.now().Add(-olderThan))
+	if err != nil {
+		return 0, err
+	}
+
+	resolved := 0
+	for i := range tips {
+		err := s.charge(ctx, &tips[i].Order, tips[i].TipID)
+		if errors.Is(err, ErrPaymentPending) {
+			continue
+		}
+		resolved++
+	}
+	return resolved, nil
+}
+
+// RunPaymentRetrier executa RetryPending periodicamente até o contexto ser cancelado
+func (s *TipService) RunPaymentRetrier(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if resolved, err := s.RetryPending(ctx, interval); err != nil {
+			log.Printf("Erro ao repetir cobranças de gorjetas pendentes: %v", err)
+		} else if resolved > 0 {
+			log.Printf("%d cobrança(s) de gorjeta pendente(s) resolvida(s)", resolved)
+		}
+	}
+}
+
+// charge cobra a gorjeta já gravada no pedido. Aprovada, marca como paga; recusada, desfaz a gorjeta;
+// qualquer outro erro deixa a cobrança pendente e retorna ErrPaymentPending.
+func (s *TipService) charge(ctx context.Context, order *models.Order, tipID uint) error {
+	amount := order.Tip
+	err := s.payments.Charge(ctx, PaymentCharge{
+		IdempotencyKey: TipChargeKey(order.ID, tipID),
+		OrderID:        order.ID,
+		CustomerID:     order.CustomerID,
+		Tip:            amount,
+		Amount:         amount,
+		Description:    fmt.Sprintf("Gorjeta do pedido #%d", order.ID),
+	})
+	switch {
+	case err == nil:
+		if markErr := s.store.MarkPaid(order.ID); markErr != nil {
+			log.Printf("Erro ao marcar gorjeta do pedido %d como paga: %v", order.ID, markErr)
+			return nil
+		}
+		order.TipPaymentStatus = models.PaymentPaid
+		return nil
+	case errors.Is(err, ErrPaymentDeclined):
+		if revertErr := s.store.Revert(order, amount); revertErr != nil {
+			log.Printf("Erro ao desfazer gorjeta do pedido %d após recusa da cobrança: %v", order.ID, revertErr)
+		}
+		return err
+	default:
+		log.Printf("Cobrança da gorjeta do pedido %d sem resposta conclusiva; fica pendente: %v", order.ID, err)
+		return ErrPaymentPending
+	}
+}
+
+// tipWindowOpen indica se o pedido foi entregue há no máximo window
+func tipWindowOpen(order *models.Order, window time.Duration, now time.Time) bool {
+	if order.Status != models.StatusDelivered || order.DeliveredAt == nil {
+		return false
+	}
+	return !now.After(order.DeliveredAt.Add(window))
+}
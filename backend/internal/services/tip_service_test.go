@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cupcake-delivery/internal/models"
+)
+
+func TestTipWindowOpen(t *testing.T) {
+	deliveredAt := time.Date(2024, 5, 10, 18, 0, 0, 0, time.UTC)
+	window := 24 * time.Hour
+
+	testCases := []struct {
+		name        string
+		status      models.OrderStatus
+		deliveredAt *time.Time
+		now         time.Time
+		expected    bool
+	}{
+		{"Right after delivery", models.StatusDelivered, &deliveredAt, deliveredAt.Add(time.Minute), true},
+		{"Last moment", models.StatusDelivered, &deliveredAt, deliveredAt.Add(window), true},
+		{"Window closed", models.StatusDelivered, &deliveredAt, deliveredAt.Add(window + time.Second), false},
+		{"Still delivering", models.StatusDelivering, nil, deliveredAt, false},
+		{"Delivered without timestamp", models.StatusDelivered, nil, deliveredAt, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			order := &models.Order{Status: tc.status, DeliveredAt: tc.deliveredAt}
+			if got := tipWindowOpen(order, window, tc.now); got != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+// memoryTipStore guarda um pedido em memória; applyErr simula falha no commit
+type memoryTipStore struct {
+	order    models.Order
+	earnings []float64 // Gorjetas creditadas ao entregador
+	tipID    uint      // Crédito da gorjeta atual; cada Apply cria um novo
+	applyErr error
+}
+
+func (s *memoryTipStore) Order(orderID, customerID uint) (*models.Order, error) {
+	order := s.order
+	return &order, nil
+}
+
+func (s *memoryTipStore) Apply(order *models.Order, amount float64, at time.Time) (uint, error) {
+	if s.applyErr != nil {
+		return 0, s.applyErr
+	}
+	if s.order.Tip > 0 {
+		return 0, ErrAlreadyTipped
+	}
+	s.order.Tip = amount
+	s.order.Total += amount
+	s.order.TipPaymentStatus = models.PaymentPending
+	s.earnings = append(s.earnings, amount)
+	s.tipID++
+	return s.tipID, nil
+}
+
+func (s *memoryTipStore) Revert(order *models.Order, amount float64) error {
+	s.order.Tip = 0
+	s.order.Total -= amount
+	s.order.TipPaymentStatus = ""
+	s.earnings = nil
+	return nil
+}
+
+func (s *memoryTipStore) MarkPaid(orderID uint) error {
+	s.order.TipPaymentStatus = models.PaymentPaid
+	return nil
+}
+
+func (s *memoryTipStore) PendingTips(before time.Time) ([]PendingTip, error) {
+	if s.order.TipPaymentStatus != models.PaymentPending {
+		return nil, nil
+	}
+	return []PendingTip{{Order: s.order, TipID: s.tipID}}, nil
+}
+
+func newTestTipService(order models.Order, applyErr, chargeErr error) (*TipService, *memoryTipStore, *recordingPaymentProcessor) {
+	store := &memoryTipStore{order: order, applyErr: applyErr}
+	payments := &recordingPaymentProcessor{err: chargeErr}
+	service := NewTipService(store, payments, 24*time.Hour)
+	deliveredAt := *order.DeliveredAt
+	service.now = func() time.Time { return deliveredAt.Add(time.Hour) }
+	return service, store, payments
+}
+
+func deliveredTestOrder(courierID *uint) models.Order {
+	deliveredAt := time.Date(2024, 5, 10, 18, 0, 0, 0, time.UTC)
+	return models.Order{CustomerID: 7, DeliveryID: courierID, Status: models.StatusDelivered, DeliveredAt: &deliveredAt, Total: 40}
+}
+
+func TestTipServiceAddTip(t *testing.T) {
+	courierID := uint(3)
+	service, store, payments := newTestTipService(deliveredTestOrder(&courierID), nil, nil)
+
+	order, err := service.AddTip(context.Background(), 1, 7, 5.555)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if order.Tip != 5.56 || order.Total != 45.56 || len(store.earnings) != 1 {
+		t.Errorf("Expected tip 5.56 credited to the courier, got order %+v and earnings %v", order, store.earnings)
+	}
+	if len(payments.charges) != 1 || payments.charges[0].IdempotencyKey != TipChargeKey(order.ID, 1) || payments.charges[0].Amount != 5.56 {
+		t.Errorf("Unexpected charges: %+v", payments.charges)
+	}
+	if store.order.TipPaymentStatus != models.PaymentPaid {
+		t.Errorf("Expected the tip charge marked as paid, got %q", store.order.TipPaymentStatus)
+	}
+}
+
+func TestTipServiceRevertsWhenChargeIsDeclined(t *testing.T) {
+	courierID := uint(3)
+	service, store, payments := newTestTipService(deliveredTestOrder(&courierID), nil, ErrPaymentDeclined)
+
+	if _, err := service.AddTip(context.Background(), 1, 7, 5); !errors.Is(err, ErrPaymentDeclined) {
+		t.Fatalf("Expected ErrPaymentDeclined, got %v", err)
+	}
+	if store.order.Tip != 0 || store.order.Total != 40 || len(store.earnings) != 0 {
+		t.Errorf("Expected tip and credit to be reverted, got order %+v and earnings %v", store.order, store.earnings)
+	}
+
+	// Nova tentativa, com outro valor, é outra cobrança para o gateway
+	payments.err = nil
+	if _, err := service.AddTip(context.Background(), 1, 7, 8); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(payments.charges) != 2 || payments.charges[0].IdempotencyKey == payments.charges[1].IdempotencyKey {
+		t.Errorf("Expected a new idempotency key for the retried tip, got %+v", payments.charges)
+	}
+}
+
+func TestTipServiceKeepsTipPendingWhenChargeIsInconclusive(t *testing.T) {
+	courierID := uint(3)
+	service, store, payments := newTestTipService(deliveredTestOrder(&courierID), nil, errors.New("timeout do gateway"))
+
+	order, err := service.AddTip(context.Background(), 1, 7, 5)
+	if !errors.Is(err, ErrPaymentPending) || order == nil {
+		t.Fatalf("Expected the order with ErrPaymentPending, got %+v (%v)", order, err)
+	}
+	if store.order.Tip != 5 || len(store.earnings) != 1 || store.order.TipPaymentStatus != models.PaymentPending {
+		t.Fatalf("Expected the tip kept with the charge pending, got order %+v and earnings %v", store.order, store.earnings)
+	}
+
+	payments.err = nil
+	resolved, err := service.RetryPending(context.Background(), time.Minute)
+	if err != nil || resolved != 1 {
+		t.Fatalf("Expected 1 resolved charge, got %d (%v)", resolved, err)
+	}
+	if len(payments.charges) != 2 || payments.charges[1].IdempotencyKey != payments.charges[0].IdempotencyKey {
+		t.Errorf("Expected the retry to reuse the idempotency key, got %+v", payments.charges)
+	}
+	if store.order.TipPaymentStatus != models.PaymentPaid {
+		t.Errorf("Expected the tip charge marked as paid, got %q", store.order.TipPaymentStatus)
+	}
+}
+
+func TestTipServiceDoesNotChargeWhenCommitFails(t *testing.T) {
+	courierID := uint(3)
+	commitErr := errors.New("commit falhou")
+	service, _, payments := newTestTipService(deliveredTestOrder(&courierID), commitErr, nil)
+
+	if _, err := service.AddTip(context.Background(), 1, 7, 5); !errors.Is(err, commitErr) {
+		t.Errorf("Expected commit error, got %v", err)
+	}
+	if len(payments.charges) != 0 {
+		t.Errorf("Expected no charge when the tip was not stored, got %+v", payments.charges)
+	}
+}
+
+func TestTipServiceRejectsOrderWithoutCourier(t *testing.T) {
+	service, store, payments := newTestTipService(deliveredTestOrder(nil), nil, nil)
+
+	if _, err := service.AddTip(context.Background(), 1, 7, 5); !errors.Is(err, ErrTipNoCourier) {
+		t.Errorf("Expected ErrTipNoCourier, got %v", err)
+	}
+	if store.order.Tip != 0 || len(payments.charges) != 0 {
+		t.Errorf("Expected nothing stored or charged, got order %+v and charges %+v", store.order, payments.charges)
+	}
+}
@@ -0,0 +1,305 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrUnknownSigningKey = errors.New("chave de assinatura desconhecida")
+
+// SigningKey chave assimétrica identificada por kid.
+// Chaves sem Private servem apenas para verificar tokens emitidos antes da rotação.
+type SigningKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// NewSigningKey monta a chave a partir da privada, escolhendo RS256 ou EdDSA conforme o tipo
+func NewSigningKey(id string, private crypto.Signer) (*SigningKey, error) {
+	method, err := signingMethodFor(private.Public())
+	if err != nil {
+		return nil, err
+	}
+	return &SigningKey{ID: id, Method: method, Private: private, Public: private.Public()}, nil
+}
+
+// NewVerificationKey monta uma chave somente de verificação
+func NewVerificationKey(id string, public crypto.PublicKey) (*SigningKey, error) {
+	method, err := signingMethodFor(public)
+	if err != nil {
+		return nil, err
+	}
+	return &SigningKey{ID: id, Method: method, Public: public}, nil
+}
+
+func signingMethodFor(public crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch public.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("tipo de chave não suportado: %T", public)
+	}
+}
+
+// TokenService emite e valida JWTs assinados com chaves assimétricas rotacionáveis
+type TokenService struct {
+	active   *SigningKey
+	keys     map[string]*SigningKey
+	issuer   string
+	audience string
+	now      func() time.Time
+}
+
+// NewTokenService usa a chave ativa para assinar e todas as chaves informadas para verificar
+func NewTokenService(active *SigningKey, verification []*SigningKey, issuer, audience string) (*TokenService, error) {
+	if active == nil || active.Private == nil {
+		return nil, errors.New("chave ativa precisa ter a parte privada")
+	}
+
+	keys := map[string]*SigningKey{active.ID: active}
+	for _, key := range verification {
+		if _, exists := keys[key.ID]; exists && key != active {
+			return nil, fmt.Errorf("kid duplicado: %s", key.ID)
+		}
+		keys[key.ID] = key
+	}
+
+	return &TokenService{
+		active:   active,
+		keys:     keys,
+		issuer:   issuer,
+		audience: audience,
+		now:      time.Now,
+	}, nil
+}
+
+// Sign assina as claims com a chave ativa, preenchendo iss, aud, iat e o cabeçalho kid
+func (s *TokenService) Sign(claims jwt.MapClaims, ttl time.Duration) (string, error) {
+	now := s.now()
+	claims["iss"] = s.issuer
+	claims["aud"] = s.audience
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(ttl).Unix()
+
+	token := jwt.NewWithClaims(s.active.Method, claims)
+	token.Header["kid"] = s.active.ID
+	return token.SignedString(s.active.Private)
+}
+
+// Parse valida assinatura, algoritmo, kid, expiração, emissor e audiência
+func (s *TokenService) Parse(tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := s.keys[kid]
+		if !ok {
+			return nil, ErrUnknownSigningKey
+		}
+		// O algoritmo é fixado pela chave, nunca pelo cabeçalho do token
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("algoritmo %s não permitido para a chave %s", token.Method.Alg(), kid)
+		}
+		return key.Public, nil
+	},
+		jwt.WithValidMethods(s.allowedAlgorithms()),
+		jwt.WithIssuer(s.issuer),
+		jwt.WithAudience(s.audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithTimeFunc(s.now),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (s *TokenService) allowedAlgorithms() []string {
+	seen := map[string]bool{}
+	var algs []string
+	for _, key := range s.keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			algs = append(algs, alg)
+		}
+	}
+	return algs
+}
+
+// JWK chave pública no formato JSON Web Key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet conjunto publicado em /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS retorna as chaves públicas de verificação, começando pela ativa
+func (s *TokenService) JWKS() JWKSet {
+	ids := make([]string, 0, len(s.keys))
+	for id := range s.keys {
+		if id != s.active.ID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	ids = append([]string{s.active.ID}, ids...)
+
+	set := JWKSet{Keys: make([]JWK, 0, len(ids))}
+	for _, id := range ids {
+		key := s.keys[id]
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+		switch pub := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// LoadSigningKeys lê as chaves PEM de um diretório: <kid>.pem guarda a chave privada, que pode assinar,
+// e <kid>.pub.pem a pública. Uma pública sozinha verifica tokens de chaves já rotacionadas; ao lado da
+// privada do mesmo kid, precisa corresponder a ela. A chave usada para assinar é a de activeKID.
+func LoadSigningKeys(dir, activeKID string) (*SigningKey, []*SigningKey, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	private := make(map[string]*SigningKey)
+	public := make(map[string]*SigningKey)
+	for _, path := range paths {
+		name := filepath.Base(path)
+		kid, isPublic := strings.CutSuffix(name, ".pub.pem")
+		if !isPublic {
+			kid = strings.TrimSuffix(name, ".pem")
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		key, err := parsePEMKey(kid, data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		if isPublic {
+			if key.Private != nil {
+				return nil, nil, fmt.Errorf("%s: arquivo .pub.pem com chave privada", path)
+			}
+			public[kid] = key
+			continue
+		}
+		if key.Private == nil {
+			return nil, nil, fmt.Errorf("%s: chave pública deve estar em %s.pub.pem", path, kid)
+		}
+		private[kid] = key
+	}
+
+	for kid, pub := range public {
+		priv, ok := private[kid]
+		if !ok {
+			continue
+		}
+		if matcher, ok := priv.Public.(interface{ Equal(crypto.PublicKey) bool }); !ok || !matcher.Equal(pub.Public) {
+			return nil, nil, fmt.Errorf("%s.pub.pem não corresponde à chave privada %s.pem", kid, kid)
+		}
+		delete(public, kid)
+	}
+
+	active := private[activeKID]
+	if active == nil {
+		return nil, nil, fmt.Errorf("chave privada ativa %q não encontrada em %s", activeKID, dir)
+	}
+
+	var keys []*SigningKey
+	for kid, key := range private {
+		if kid != activeKID {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range public {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return active, keys, nil
+}
+
+func parsePEMKey(kid string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("PEM inválido")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("tipo de chave não suportado: %T", parsed)
+		}
+		return NewSigningKey(kid, signer)
+	case "RSA PRIVATE KEY":
+		parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return NewSigningKey(kid, parsed)
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return NewVerificationKey(kid, parsed)
+	default:
+		return nil, fmt.Errorf("bloco PEM não suportado: %s", block.Type)
+	}
+}
+
+// GenerateEd25519SigningKey gera uma chave efêmera (desenvolvimento: tokens não sobrevivem a reinícios)
+func GenerateEd25519SigningKey(kid string) (*SigningKey, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return NewSigningKey(kid, private)
+}
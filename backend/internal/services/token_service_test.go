@@ -0,0 +1,238 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestRSAKey(t *testing.T, kid string) *SigningKey {
+	t.Helper()
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	key, err := NewSigningKey(kid, private)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return key
+}
+
+func newTestEdKey(t *testing.T, kid string) *SigningKey {
+	t.Helper()
+	key, err := GenerateEd25519SigningKey(kid)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return key
+}
+
+func TestTokenServiceRoundTrip(t *testing.T) {
+	for _, key := range []*SigningKey{newTestRSAKey(t, "rsa-1"), newTestEdKey(t, "ed-1")} {
+		t.Run(key.Method.Alg(), func(t *testing.T) {
+			s, err := NewTokenService(key, nil, "cupcake-delivery", "cupcake-delivery-api")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			token, err := s.Sign(jwt.MapClaims{"user_id": 7, "type": "admin"}, time.Hour)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			claims, err := s.Parse(token)
+			if err != nil {
+				t.Fatalf("Expected valid token, got %v", err)
+			}
+			if claims["user_id"].(float64) != 7 || claims["type"] != "admin" {
+				t.Errorf("Unexpected claims: %v", claims)
+			}
+		})
+	}
+}
+
+func TestTokenServiceRejectsInvalidTokens(t *testing.T) {
+	key := newTestRSAKey(t, "rsa-1")
+	s, _ := NewTokenService(key, nil, "cupcake-delivery", "cupcake-delivery-api")
+	other, _ := NewTokenService(newTestRSAKey(t, "rsa-1"), nil, "cupcake-delivery", "cupcake-delivery-api")
+	wrongIssuer, _ := NewTokenService(key, nil, "outro-servico", "cupcake-delivery-api")
+	wrongAudience, _ := NewTokenService(key, nil, "cupcake-delivery", "outra-api")
+
+	sign := func(s *TokenService, ttl time.Duration) string {
+		token, err := s.Sign(jwt.MapClaims{"user_id": 1}, ttl)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return token
+	}
+
+	// Ataque de confusão de algoritmo: HS256 usando a chave pública como segredo
+	publicDER, _ := x509.MarshalPKIXPublicKey(key.Public)
+	hmacToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": 1, "iss": "cupcake-delivery", "aud": "cupcake-delivery-api", "exp": time.Now().Add(time.Hour).Unix(),
+	})
+	hmacToken.Header["kid"] = "rsa-1"
+	hmacString, _ := hmacToken.SignedString(publicDER)
+
+	noneToken := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{
+		"user_id": 1, "iss": "cupcake-delivery", "aud": "cupcake-delivery-api", "exp": time.Now().Add(time.Hour).Unix(),
+	})
+	noneToken.Header["kid"] = "rsa-1"
+	noneString, _ := noneToken.SignedString(jwt.UnsafeAllowNoneSignatureType)
+
+	unknownKid := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"user_id": 1, "iss": "cupcake-delivery", "aud": "cupcake-delivery-api", "exp": time.Now().Add(time.Hour).Unix(),
+	})
+	unknownKid.Header["kid"] = "desconhecida"
+	unknownKidString, _ := unknownKid.SignedString(key.Private)
+
+	testCases := []struct {
+		name  string
+		token string
+	}{
+		{"Signed by another key with same kid", sign(other, time.Hour)},
+		{"Expired", sign(s, -time.Minute)},
+		{"Wrong issuer", sign(wrongIssuer, time.Hour)},
+		{"Wrong audience", sign(wrongAudience, time.Hour)},
+		{"HS256 with public key", hmacString},
+		{"Algorithm none", noneString},
+		{"Unknown kid", unknownKidString},
+		{"Garbage", "not-a-token"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := s.Parse(tc.token); err == nil {
+				t.Errorf("Expected token to be rejected")
+			}
+		})
+	}
+}
+
+func TestTokenServiceKeyRotation(t *testing.T) {
+	oldKey := newTestRSAKey(t, "2024-01")
+	newKey := newTestEdKey(t, "2024-02")
+
+	before, _ := NewTokenService(oldKey, nil, "cupcake-delivery", "cupcake-delivery-api")
+	oldToken, _ := before.Sign(jwt.MapClaims{"user_id": 1}, time.Hour)
+
+	// Após a rotação a chave antiga continua apenas como chave de verificação
+	retired, _ := NewVerificationKey(oldKey.ID, oldKey.Public)
+	after, err := NewTokenService(newKey, []*SigningKey{retired}, "cupcake-delivery", "cupcake-delivery-api")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := after.Parse(oldToken); err != nil {
+		t.Errorf("Expected token from retired key to remain valid, got %v", err)
+	}
+
+	newToken, _ := after.Sign(jwt.MapClaims{"user_id": 1}, time.Hour)
+	parsed, _, _ := jwt.NewParser().ParseUnverified(newToken, jwt.MapClaims{})
+	if parsed.Header["kid"] != "2024-02" || parsed.Method.Alg() != "EdDSA" {
+		t.Errorf("Expected new tokens signed by active key, got kid=%v alg=%s", parsed.Header["kid"], parsed.Method.Alg())
+	}
+
+	jwks := after.JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].Kid != "2024-02" || jwks.Keys[1].Kid != "2024-01" {
+		t.Fatalf("Unexpected JWKS: %+v", jwks)
+	}
+	if jwks.Keys[0].Kty != "OKP" || jwks.Keys[0].Crv != "Ed25519" || jwks.Keys[0].X == "" {
+		t.Errorf("Unexpected Ed25519 JWK: %+v", jwks.Keys[0])
+	}
+	if jwks.Keys[1].Kty != "RSA" || jwks.Keys[1].E != "AQAB" || jwks.Keys[1].N == "" {
+		t.Errorf("Unexpected RSA JWK: %+v", jwks.Keys[1])
+	}
+}
+
+func TestLoadSigningKeys(t *testing.T) {
+	dir := t.TempDir()
+
+	_, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	edDER, _ := x509.MarshalPKCS8PrivateKey(edPrivate)
+	writePEM(t, filepath.Join(dir, "2024-02.pem"), "PRIVATE KEY", edDER)
+
+	rsaPrivate, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rsaPublicDER, _ := x509.MarshalPKIXPublicKey(&rsaPrivate.PublicKey)
+	writePEM(t, filepath.Join(dir, "2024-01.pub.pem"), "PUBLIC KEY", rsaPublicDER)
+
+	active, verification, err := LoadSigningKeys(dir, "2024-02")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if active.ID != "2024-02" || active.Method.Alg() != "EdDSA" {
+		t.Errorf("Unexpected active key: %s %s", active.ID, active.Method.Alg())
+	}
+	if len(verification) != 1 || verification[0].ID != "2024-01" || verification[0].Private != nil {
+		t.Errorf("Expected retired public key 2024-01, got %+v", verification)
+	}
+
+	if _, _, err := LoadSigningKeys(dir, "2024-01"); err == nil {
+		t.Errorf("Expected error when active key has no private part")
+	}
+}
+
+func TestLoadSigningKeysPairsPublicWithPrivate(t *testing.T) {
+	dir := t.TempDir()
+
+	_, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	edDER, _ := x509.MarshalPKCS8PrivateKey(edPrivate)
+	edPublicDER, _ := x509.MarshalPKIXPublicKey(edPrivate.Public())
+	writePEM(t, filepath.Join(dir, "k1.pem"), "PRIVATE KEY", edDER)
+	writePEM(t, filepath.Join(dir, "k1.pub.pem"), "PUBLIC KEY", edPublicDER)
+
+	active, verification, err := LoadSigningKeys(dir, "k1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if active.ID != "k1" || active.Private == nil || len(verification) != 0 {
+		t.Errorf("Expected only the private k1, got %s and %d verification keys", active.ID, len(verification))
+	}
+	if _, err := NewTokenService(active, verification, "iss", "aud"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// Uma pública que não é a da privada de mesmo kid indica arquivo trocado
+	_, otherPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	otherPublicDER, _ := x509.MarshalPKIXPublicKey(otherPrivate.Public())
+	writePEM(t, filepath.Join(dir, "k1.pub.pem"), "PUBLIC KEY", otherPublicDER)
+	if _, _, err := LoadSigningKeys(dir, "k1"); err == nil {
+		t.Errorf("Expected error for mismatched public key")
+	}
+
+	// A chave pública vai em <kid>.pub.pem, não em <kid>.pem
+	writePEM(t, filepath.Join(dir, "k1.pub.pem"), "PUBLIC KEY", edPublicDER)
+	writePEM(t, filepath.Join(dir, "k0.pem"), "PUBLIC KEY", otherPublicDER)
+	if _, _, err := LoadSigningKeys(dir, "k1"); err == nil {
+		t.Errorf("Expected error for public key in a private key file")
+	}
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestTokenServiceRequiresPrivateActiveKey(t *testing.T) {
+	key := newTestEdKey(t, "ed-1")
+	public, _ := NewVerificationKey("ed-1", key.Public)
+
+	if _, err := NewTokenService(public, nil, "iss", "aud"); err == nil {
+		t.Errorf("Expected error for active key without private part")
+	}
+	if _, err := NewTokenService(key, []*SigningKey{public}, "iss", "aud"); err == nil {
+		t.Errorf("Expected error for duplicated kid")
+	}
+}
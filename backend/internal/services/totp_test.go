@@ -0,0 +1,111 @@
+package services
+
+import (
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Segredo dos vetores de teste SHA1 da RFC 6238 ("12345678901234567890")
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeRFC6238Vectors(t *testing.T) {
+	testCases := []struct {
+		unix     int64
+		expected string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tc := range testCases {
+		code, err := TOTPCode(rfc6238Secret, time.Unix(tc.unix, 0))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if code != tc.expected {
+			t.Errorf("Expected %s at %d, got %s", tc.expected, tc.unix, code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	code, _ := TOTPCode(rfc6238Secret, now)
+	previous, _ := TOTPCode(rfc6238Secret, now.Add(-30*time.Second))
+	old, _ := TOTPCode(rfc6238Secret, now.Add(-90*time.Second))
+
+	testCases := []struct {
+		name     string
+		code     string
+		expected bool
+	}{
+		{"Current code", code, true},
+		{"Code with spaces", " " + code + " ", true},
+		{"Previous step within skew", previous, true},
+		{"Code outside skew", old, false},
+		{"Wrong length", "12345", false},
+		{"Wrong code", "000000", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, ok := ValidateTOTP(rfc6238Secret, tc.code, now); ok != tc.expected {
+				t.Errorf("Expected %v for code %q, got %v", tc.expected, tc.code, ok)
+			}
+		})
+	}
+
+	if step, _ := ValidateTOTP(rfc6238Secret, previous, now); step != TOTPStep(now)-1 {
+		t.Errorf("Expected accepted step %d, got %d", TOTPStep(now)-1, step)
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(secret) != 32 {
+		t.Errorf("Expected 32 base32 characters, got %d", len(secret))
+	}
+	if _, err := TOTPCode(secret, time.Now()); err != nil {
+		t.Errorf("Generated secret should be decodable: %v", err)
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("Cupcake Delivery", "admin@cupcakedelivery.com", "JBSWY3DPEHPK3PXP")
+
+	if !strings.HasPrefix(uri, "otpauth://totp/") {
+		t.Fatalf("Unexpected URI scheme: %s", uri)
+	}
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("Invalid URI: %v", err)
+	}
+	if parsed.Query().Get("secret") != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("Expected secret in URI, got %s", uri)
+	}
+	if parsed.Query().Get("issuer") != "Cupcake Delivery" {
+		t.Errorf("Expected issuer in URI, got %s", uri)
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	code, err := generateRecoveryCode()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(code) != 11 || code[5] != '-' {
+		t.Errorf("Unexpected recovery code format: %s", code)
+	}
+	if NormalizeRecoveryCode(" "+strings.ToUpper(code)+" ") != strings.ReplaceAll(code, "-", "") {
+		t.Errorf("Expected normalization to ignore case, spaces and hyphens")
+	}
+}
@@ -0,0 +1,265 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"time"
+
+	"cupcake-delivery/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrNoActiveDelivery = errors.New("entregador sem pedido em entrega")
+
+const (
+	// DefaultBreadcrumbInterval intervalo mínimo entre dois pontos do trajeto do mesmo entregador
+	DefaultBreadcrumbInterval = 15 * time.Second
+	// DefaultCourierSpeedKmh velocidade média usada na previsão de chegada
+	DefaultCourierSpeedKmh = 20.0
+)
+
+// OrderTracking acompanhamento de um pedido para o cliente
+type OrderTracking struct {
+	OrderID    uint                     `json:"order_id"`
+	Status     models.OrderStatus       `json:"status"`
+	Position   *models.CourierPosition  `json:"position,omitempty"`
+	DistanceKm *float64                 `json:"distance_km,omitempty"`
+	ETAMinutes *int                     `json:"eta_minutes,omitempty"`
+	Trail      []models.CourierLocation `json:"trail"`
+
+	// Em um lote de entregas: paradas pendentes antes da do pedido
+	StopsAhead *int `json:"stops_ahead,omitempty"`
+
+	// PIN que o cliente informa ao entregador; preenchido apenas para o próprio cliente
+	DeliveryPIN string `json:"delivery_pin,omitempty"`
+}
+
+// TrackingService registra a posição dos entregadores durante as entregas
+type TrackingService struct {
+	db                 *gorm.DB
+	distance           DistanceCalculator
+	breadcrumbInterval time.Duration
+	retention          time.Duration
+	speedKmh           float64
+	now                func() time.Time
+}
+
+func NewTrackingService(db *gorm.DB, breadcrumbInterval, retention time.Duration) *TrackingService {
+	return &TrackingService{
+		db:                 db,
+		distance:           HaversineDistance{},
+		breadcrumbInterval: breadcrumbInterval,
+		retention:          retention,
+		speedKmh:           DefaultCourierSpeedKmh,
+		now:                time.Now,
+	}
+}
+
+// RecordPing atualiza a última posição do entregador e, respeitando o intervalo mínimo, acrescenta um
+// ponto ao trajeto de cada pedido em entrega. Retorna quantos pontos foram gravados (zero se limitado).
+func (s *TrackingService) RecordPing(courierID uint, latitude, longitude float64, accuracy *float64) (int, error) {
+	var orderIDs []uint
+	if err := s.db.Model(&models.Order{}).
+		Where("delivery_id = ? AND status = ?", courierID, models.StatusDelivering).
+		Pluck("id", &orderIDs).Error; err != nil {
+		return 0, err
+	}
+	if len(orderIDs) == 0 {
+		return 0, ErrNoActiveDelivery
+	}
+
+	now := s.now()
+	recorded := 0
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		position := models.CourierPosition{
+			CourierID:  courierID,
+			Latitude:   latitude,
+			Longitude:  longitude,
+			Accuracy:   accuracy,
+			RecordedAt: now,
+		}
+		if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&position).Error; err != nil {
+			return err
+		}
+
+		var last models.CourierLocation
+		err := tx.Where("courier_id = ?", courierID).Order("recorded_at DESC").First(&last).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil && !breadcrumbDue(last.RecordedAt, now, s.breadcrumbInterval) {
+			return nil
+		}
+
+		trail := make([]models.CourierLocation, 0, len(orderIDs))
+		for _, orderID := range orderIDs {
+			trail = append(trail, models.CourierLocation{
+				OrderID:    orderID,
+				CourierID:  courierID,
+				Latitude:   latitude,
+				Longitude:  longitude,
+				RecordedAt: now,
+			})
+		}
+		if err := tx.Create(&trail).Error; err != nil {
+			return err
+		}
+		recorded = len(trail)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return recorded, nil
+}
+
+// Tracking monta o acompanhamento do pedido; posição e previsão só existem durante a entrega
+func (s *TrackingService) Tracking(order *models.Order) (*OrderTracking, error) {
+	tracking := &OrderTracking{
+		OrderID: order.ID,
+		Status:  order.Status,
+		Trail:   []models.CourierLocation{},
+	}
+	if order.Status != models.StatusDelivering || order.DeliveryID == nil {
+		return tracking, nil
+	}
+
+	if err := s.db.Where("order_id = ?", order.ID).Order("recorded_at").Find(&tracking.Trail).Error; err != nil {
+		return nil, err
+	}
+
+	route, plannedETA, err := s.batchRouteAhead(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(route) > 0 {
+		ahead := len(route) - 1
+		tracking.StopsAhead = &ahead
+	}
+
+	var position models.CourierPosition
+	err = s.db.First(&position, "courier_id = ?", *order.DeliveryID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Sem posição do entregador, vale a previsão feita ao planejar o lote
+		if plannedETA != nil {
+			eta := int(math.Ceil(plannedETA.Sub(s.now()).Minutes()))
+			if eta < 1 {
+				eta = 1
+			}
+			tracking.ETAMinutes = &eta
+		}
+		return tracking, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	tracking.Position = &position
+
+	if order.Latitude != nil && order.Longitude != nil {
+		distance, err := s.distance.DistanceKm(position.Latitude, position.Longitude, *order.Latitude, *order.Longitude)
+		if err != nil {
+			return nil, err
+		}
+		distance = math.Round(distance*100) / 100
+		eta := EstimateArrivalMinutes(distance, s.speedKmh)
+		// Em lote, a previsão segue a rota passando pelas paradas anteriores
+		if len(route) > 1 {
+			legs := RouteLegsKm(&RoutePoint{Latitude: position.Latitude, Longitude: position.Longitude}, route)
+			now := s.now()
+			arrivals := EstimateStopArrivals(legs, now, s.speedKmh, DefaultBatchStopTime)
+			eta = int(math.Ceil(arrivals[len(arrivals)-1].Sub(now).Minutes()))
+		}
+		tracking.DistanceKm = &distance
+		tracking.ETAMinutes = &eta
+	}
+	return tracking, nil
+}
+
+// batchRouteAhead paradas pendentes do lote até a do pedido, na ordem da rota, e a previsão
+// planejada para ela; vazio se o pedido não está em um lote
+func (s *TrackingService) batchRouteAhead(orderID uint) ([]RouteStop, *time.Time, error) {
+	var stop models.DeliveryBatchStop
+	err := s.db.Where("order_id = ? AND status = ?", orderID, models.StopPending).First(&stop).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var stops []models.DeliveryBatchStop
+	if err := s.db.Preload("Order").
+		Where("batch_id = ? AND status = ? AND sequence <= ?", stop.BatchID, models.StopPending, stop.Sequence).
+		Order("sequence").
+		Find(&stops).Error; err != nil {
+		return nil, nil, err
+	}
+	route := make([]RouteStop, 0, len(stops))
+	for _, ahead := range stops {
+		routeStop := RouteStop{OrderID: ahead.OrderID}
+		if ahead.Order != nil {
+			routeStop.Latitude, routeStop.Longitude = ahead.Order.Latitude, ahead.Order.Longitude
+		}
+		route = append(route, routeStop)
+	}
+	return route, stop.ETA, nil
+}
+
+// PurgeExpired apaga os trajetos dos pedidos que saíram de entrega há mais que o prazo de retenção,
+// contado da entrega (ou da última troca de status) e não da última alteração do pedido, e as últimas
+// posições que não são atualizadas há mais que esse prazo
+func (s *TrackingService) PurgeExpired() (int64, error) {
+	cutoff := s.now().Add(-s.retention)
+
+	trail := s.db.Where("order_id IN (?)", s.db.Model(&models.Order{}).
+		Select("id").
+		Where("status <> ? AND COALESCE(delivered_at, status_changed_at, updated_at) < ?", models.StatusDelivering, cutoff)).
+		Delete(&models.CourierLocation{})
+	if trail.Error != nil {
+		return 0, trail.Error
+	}
+
+	positions := s.db.Where("recorded_at < ?", cutoff).Delete(&models.CourierPosition{})
+	if positions.Error != nil {
+		return trail.RowsAffected, positions.Error
+	}
+	return trail.RowsAffected + positions.RowsAffected, nil
+}
+
+// RunPurger executa PurgeExpired periodicamente até o contexto ser cancelado
+func (s *TrackingService) RunPurger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if purged, err := s.PurgeExpired(); err != nil {
+			log.Printf("Erro ao expurgar localizações: %v", err)
+		} else if purged > 0 {
+			log.Printf("%d registro(s) de localização expurgado(s)", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// EstimateArrivalMinutes previsão de chegada em minutos inteiros, arredondada para cima (mínimo 1)
+func EstimateArrivalMinutes(distanceKm, speedKmh float64) int {
+	minutes := int(math.Ceil(distanceKm / speedKmh * 60))
+	if minutes < 1 {
+		return 1
+	}
+	return minutes
+}
+
+// breadcrumbDue indica se já passou o intervalo mínimo desde o último ponto do trajeto
+func breadcrumbDue(last, at time.Time, interval time.Duration) bool {
+	return at.Sub(last) >= interval
+}
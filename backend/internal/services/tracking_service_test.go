@@ -0,0 +1,97 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"cupcake-delivery/internal/models"
+)
+
+func TestEstimateArrivalMinutes(t *testing.T) {
+	testCases := []struct {
+		name       string
+		distanceKm float64
+		speedKmh   float64
+		expected   int
+	}{
+		{"Exact minutes", 5, 20, 15},
+		{"Rounded up", 2.1, 20, 7},
+		{"Already there", 0, 20, 1},
+		{"Faster courier", 6, 30, 12},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := EstimateArrivalMinutes(tc.distanceKm, tc.speedKmh); got != tc.expected {
+				t.Errorf("Expected %d minutes, got %d", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestBreadcrumbDue(t *testing.T) {
+	last := time.Date(2024, 5, 10, 18, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		at       time.Time
+		expected bool
+	}{
+		{"Too soon", last.Add(14 * time.Second), false},
+		{"Exactly the interval", last.Add(15 * time.Second), true},
+		{"After the interval", last.Add(time.Minute), true},
+		{"Clock went back", last.Add(-time.Second), false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := breadcrumbDue(last, tc.at, DefaultBreadcrumbInterval); got != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+// TestTrackingServicePurgeExpiredUsesDeliveryTime garante que alterar o pedido depois da entrega
+// (avaliação, gorjeta) não adie o expurgo do trajeto
+func TestTrackingServicePurgeExpiredUsesDeliveryTime(t *testing.T) {
+	db := openTestDB(t)
+
+	suffix := time.Now().Format("150405.000000")
+	customer := models.User{Name: "Cliente", Email: "purge-customer-" + suffix + "@example.com", Type: models.CustomerType}
+	courier := models.User{Name: "Entregador", Email: "purge-courier-" + suffix + "@example.com", Type: models.DeliveryType}
+	for _, user := range []*models.User{&customer, &courier} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	now := time.Now()
+	longAgo, recently := now.Add(-48*time.Hour), now.Add(-time.Hour)
+	old := models.Order{CustomerID: customer.ID, DeliveryID: &courier.ID, Status: models.StatusDelivered, DeliveredAt: &longAgo, StatusChangedAt: &longAgo}
+	fresh := models.Order{CustomerID: customer.ID, DeliveryID: &courier.ID, Status: models.StatusDelivered, DeliveredAt: &recently, StatusChangedAt: &recently}
+	for _, order := range []*models.Order{&old, &fresh} {
+		if err := db.Create(order).Error; err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		db.Create(&models.CourierLocation{OrderID: order.ID, CourierID: courier.ID, Latitude: -23.56, Longitude: -46.65, RecordedAt: *order.DeliveredAt})
+	}
+	// Avaliado agora: updated_at recente, entrega antiga
+	db.Model(&old).Update("updated_at", now)
+	t.Cleanup(func() {
+		db.Where("courier_id = ?", courier.ID).Delete(&models.CourierLocation{})
+		db.Unscoped().Where("customer_id = ?", customer.ID).Delete(&models.Order{})
+		db.Unscoped().Delete(&[]models.User{customer, courier})
+	})
+
+	service := NewTrackingService(db, time.Minute, 24*time.Hour)
+	if _, err := service.PurgeExpired(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var remaining []uint
+	db.Model(&models.CourierLocation{}).Where("courier_id = ?", courier.ID).Pluck("order_id", &remaining)
+	if len(remaining) != 1 || remaining[0] != fresh.ID {
+		t.Errorf("Expected only the trail of order %d to remain, got orders %v", fresh.ID, remaining)
+	}
+}
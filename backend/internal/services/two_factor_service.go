@@ -0,0 +1,233 @@
+package services
+
+import (
+	"crypto/rand"
+	"cupcake-delivery/internal/models"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const recoveryCodeCount = 10
+
+// recoveryCodeCost custo do bcrypt dos códigos de recuperação; os testes usam o mínimo
+var recoveryCodeCost = bcrypt.DefaultCost
+
+var (
+	ErrTwoFactorNotEnrolled    = errors.New("2FA não configurado")
+	ErrTwoFactorAlreadyEnabled = errors.New("2FA já está ativo")
+	ErrInvalidTwoFactorCode    = errors.New("código de verificação inválido")
+)
+
+type TwoFactorService struct {
+	store  TwoFactorStore
+	issuer string
+	now    func() time.Time
+}
+
+func NewTwoFactorService(store TwoFactorStore, issuer string) *TwoFactorService {
+	return &TwoFactorService{store: store, issuer: issuer, now: time.Now}
+}
+
+// Get retorna a configuração de 2FA do usuário ou nil se ele nunca iniciou o cadastro
+func (s *TwoFactorService) Get(userID uint) (*models.TwoFactor, error) {
+	return s.store.Get(userID)
+}
+
+// IsEnabled indica se o usuário concluiu o cadastro do 2FA
+func (s *TwoFactorService) IsEnabled(userID uint) (bool, error) {
+	tf, err := s.Get(userID)
+	if err != nil {
+		return false, err
+	}
+	return tf != nil && tf.Enabled, nil
+}
+
+// IsRequired indica se o 2FA é obrigatório para o tipo de usuário
+func (s *TwoFactorService) IsRequired(role models.UserType) (bool, error) {
+	policy, err := s.store.Policy(role)
+	if err != nil || policy == nil {
+		return false, err
+	}
+	return policy.Required, nil
+}
+
+// Policies lista as políticas de 2FA configuradas
+func (s *TwoFactorService) Policies() ([]models.TwoFactorPolicy, error) {
+	return s.store.Policies()
+}
+
+// SetRequired torna o 2FA obrigatório (ou opcional) para um tipo de usuário
+func (s *TwoFactorService) SetRequired(role models.UserType, required bool) error {
+	return s.store.SetPolicy(models.TwoFactorPolicy{Role: role, Required: required})
+}
+
+// BeginEnrollment gera um novo segredo pendente e retorna o segredo e a URI otpauth://
+func (s *TwoFactorService) BeginEnrollment(user *models.User) (string, string, error) {
+	tf, err := s.Get(user.ID)
+	if err != nil {
+		return "", "", err
+	}
+	if tf != nil && tf.Enabled {
+		return "", "", ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	if tf == nil {
+		tf = &models.TwoFactor{UserID: user.ID}
+	}
+	tf.Secret = secret
+	tf.LastUsedStep = 0
+	if err := s.store.Save(tf); err != nil {
+		return "", "", err
+	}
+
+	return secret, TOTPURI(s.issuer, user.Email, secret), nil
+}
+
+// ConfirmEnrollment ativa o 2FA após o primeiro código válido e retorna os códigos de recuperação
+func (s *TwoFactorService) ConfirmEnrollment(userID uint, code string) ([]string, error) {
+	tf, err := s.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	if tf == nil {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	if tf.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	step, ok := ValidateTOTP(tf.Secret, code, s.now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	tf.Enabled = true
+	tf.ConfirmedAt = &now
+	tf.LastUsedStep = step
+	if err := s.store.Enable(tf, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify aceita um código TOTP ou um código de recuperação ainda não utilizado
+func (s *TwoFactorService) Verify(userID uint, code string) error {
+	tf, err := s.Get(userID)
+	if err != nil {
+		return err
+	}
+	if tf == nil || !tf.Enabled {
+		return ErrTwoFactorNotEnrolled
+	}
+
+	if step, ok := ValidateTOTP(tf.Secret, code, s.now()); ok {
+		if step <= tf.LastUsedStep {
+			return ErrInvalidTwoFactorCode
+		}
+		claimed, err := s.store.ClaimStep(tf.ID, step)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	return s.useRecoveryCode(userID, code)
+}
+
+// Disable remove o 2FA e os códigos de recuperação do usuário
+func (s *TwoFactorService) Disable(userID uint) error {
+	return s.store.Delete(userID)
+}
+
+// RegenerateRecoveryCodes invalida os códigos anteriores e gera novos
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID uint) ([]string, error) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *TwoFactorService) useRecoveryCode(userID uint, code string) error {
+	code = NormalizeRecoveryCode(code)
+	if code == "" {
+		return ErrInvalidTwoFactorCode
+	}
+
+	stored, err := s.store.UnusedRecoveryCodes(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, rc := range stored {
+		if bcrypt.CompareHashAndPassword([]byte(rc.CodeHash), []byte(code)) != nil {
+			continue
+		}
+		used, err := s.store.UseRecoveryCode(rc.ID, s.now())
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+	return ErrInvalidTwoFactorCode
+}
+
+// generateRecoveryCodes gera os códigos exibidos ao usuário e os hashes que são armazenados
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, nil, err
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(NormalizeRecoveryCode(code)), recoveryCodeCost)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, string(hash))
+	}
+	return codes, hashes, nil
+}
+
+// generateRecoveryCode gera um código no formato xxxxx-xxxxx
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 5)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	h := hex.EncodeToString(b)
+	return h[:5] + "-" + h[5:], nil
+}
+
+// NormalizeRecoveryCode remove espaços e hífens para comparar códigos digitados de formas diferentes
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
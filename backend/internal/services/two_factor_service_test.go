@@ -0,0 +1,363 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cupcake-delivery/internal/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func init() {
+	recoveryCodeCost = bcrypt.MinCost
+}
+
+// memoryTwoFactorStore reproduz em memória as atualizações condicionais do DBTwoFactorStore
+type memoryTwoFactorStore struct {
+	mu       sync.Mutex
+	configs  map[uint]models.TwoFactor
+	codes    []models.RecoveryCode
+	policies map[models.UserType]models.TwoFactorPolicy
+}
+
+func newMemoryTwoFactorStore() *memoryTwoFactorStore {
+	return &memoryTwoFactorStore{
+		configs:  make(map[uint]models.TwoFactor),
+		policies: make(map[models.UserType]models.TwoFactorPolicy),
+	}
+}
+
+func (s *memoryTwoFactorStore) Get(userID uint) (*models.TwoFactor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tf, ok := s.configs[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &tf, nil
+}
+
+func (s *memoryTwoFactorStore) Save(tf *models.TwoFactor) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if tf.ID == 0 {
+		tf.ID = uint(len(s.configs) + 1)
+	}
+	s.configs[tf.UserID] = *tf
+	return nil
+}
+
+func (s *memoryTwoFactorStore) Enable(tf *models.TwoFactor, codeHashes []string) error {
+	if err := s.Save(tf); err != nil {
+		return err
+	}
+	return s.ReplaceRecoveryCodes(tf.UserID, codeHashes)
+}
+
+func (s *memoryTwoFactorStore) ClaimStep(id uint, step int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for userID, tf := range s.configs {
+		if tf.ID == id && tf.LastUsedStep < step {
+			tf.LastUsedStep = step
+			s.configs[userID] = tf
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *memoryTwoFactorStore) UnusedRecoveryCodes(userID uint) ([]models.RecoveryCode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var unused []models.RecoveryCode
+	for _, rc := range s.codes {
+		if rc.UserID == userID && rc.UsedAt == nil {
+			unused = append(unused, rc)
+		}
+	}
+	return unused, nil
+}
+
+func (s *memoryTwoFactorStore) UseRecoveryCode(id uint, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.codes {
+		if s.codes[i].ID == id && s.codes[i].UsedAt == nil {
+			s.codes[i].UsedAt = &at
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *memoryTwoFactorStore) ReplaceRecoveryCodes(userID uint, codeHashes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.codes[:0]
+	for _, rc := range s.codes {
+		if rc.UserID != userID {
+			kept = append(kept, rc)
+		}
+	}
+	s.codes = kept
+	for _, hash := range codeHashes {
+		s.codes = append(s.codes, models.RecoveryCode{ID: uint(len(s.codes) + 1000), UserID: userID, CodeHash: hash})
+	}
+	return nil
+}
+
+func (s *memoryTwoFactorStore) Delete(userID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.configs, userID)
+	return nil
+}
+
+func (s *memoryTwoFactorStore) Policy(role models.UserType) (*models.TwoFactorPolicy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	policy, ok := s.policies[role]
+	if !ok {
+		return nil, nil
+	}
+	return &policy, nil
+}
+
+func (s *memoryTwoFactorStore) Policies() ([]models.TwoFactorPolicy, error) {
+	return nil, nil
+}
+
+func (s *memoryTwoFactorStore) SetPolicy(policy models.TwoFactorPolicy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policies[policy.Role] = policy
+	return nil
+}
+
+// enrolledTwoFactor cadastra o 2FA do usuário e retorna o serviço, o segredo e os códigos de recuperação
+func enrolledTwoFactor(t *testing.T, now *time.Time) (*TwoFactorService, string, []string) {
+	t.Helper()
+	service := NewTwoFactorService(newMemoryTwoFactorStore(), "Cupcake Delivery")
+	service.now = func() time.Time { return *now }
+
+	secret, _, err := service.BeginEnrollment(&models.User{Email: "joao@email.com"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	code, _ := TOTPCode(secret, *now)
+	codes, err := service.ConfirmEnrollment(0, code)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return service, secret, codes
+}
+
+func TestTwoFactorEnrollment(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	service := NewTwoFactorService(newMemoryTwoFactorStore(), "Cupcake Delivery")
+	service.now = func() time.Time { return now }
+	user := &models.User{Email: "joao@email.com"}
+
+	if _, err := service.ConfirmEnrollment(user.ID, "123456"); !errors.Is(err, ErrTwoFactorNotEnrolled) {
+		t.Fatalf("Expected ErrTwoFactorNotEnrolled before enrollment, got %v", err)
+	}
+
+	secret, _, err := service.BeginEnrollment(user)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if enabled, _ := service.IsEnabled(user.ID); enabled {
+		t.Fatal("Expected 2FA to stay disabled until the first code is confirmed")
+	}
+	if _, err := service.ConfirmEnrollment(user.ID, "000000"); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Fatalf("Expected ErrInvalidTwoFactorCode for a wrong code, got %v", err)
+	}
+
+	code, _ := TOTPCode(secret, now)
+	codes, err := service.ConfirmEnrollment(user.ID, code)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Errorf("Expected %d recovery codes, got %d", recoveryCodeCount, len(codes))
+	}
+	if enabled, _ := service.IsEnabled(user.ID); !enabled {
+		t.Error("Expected 2FA to be enabled after confirmation")
+	}
+	if _, _, err := service.BeginEnrollment(user); !errors.Is(err, ErrTwoFactorAlreadyEnabled) {
+		t.Errorf("Expected ErrTwoFactorAlreadyEnabled, got %v", err)
+	}
+
+	// O código que confirmou o cadastro não serve para o próximo login
+	if err := service.Verify(user.ID, code); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("Expected the enrollment code to be rejected on login, got %v", err)
+	}
+}
+
+func TestTwoFactorVerifyWindow(t *testing.T) {
+	enrolledAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		codeAt   time.Duration // instante do código em relação ao login
+		expected error
+	}{
+		{"Current step", 0, nil},
+		{"Previous step within skew", -30 * time.Second, nil},
+		{"Next step within skew", 30 * time.Second, nil},
+		{"Two steps behind", -60 * time.Second, ErrInvalidTwoFactorCode},
+		{"Two steps ahead", 60 * time.Second, ErrInvalidTwoFactorCode},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			now := enrolledAt
+			service, secret, _ := enrolledTwoFactor(t, &now)
+			now = enrolledAt.Add(5 * time.Minute)
+
+			code, _ := TOTPCode(secret, now.Add(tc.codeAt))
+			if err := service.Verify(0, code); !errors.Is(err, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestTwoFactorVerifyRejectsReplay(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	service, secret, _ := enrolledTwoFactor(t, &now)
+	now = now.Add(time.Minute)
+
+	current, _ := TOTPCode(secret, now)
+	previous, _ := TOTPCode(secret, now.Add(-30*time.Second))
+
+	if err := service.Verify(0, current); err != nil {
+		t.Fatalf("Expected current code to be accepted, got %v", err)
+	}
+	if err := service.Verify(0, current); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("Expected reused code to be rejected, got %v", err)
+	}
+	// Um código anterior ao último usado também é recusado, ainda que dentro da tolerância
+	if err := service.Verify(0, previous); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("Expected older code to be rejected, got %v", err)
+	}
+}
+
+func TestTwoFactorRecoveryCodesAreSingleUse(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	service, _, codes := enrolledTwoFactor(t, &now)
+
+	if err := service.Verify(0, codes[0]); err != nil {
+		t.Fatalf("Expected recovery code to be accepted, got %v", err)
+	}
+	if err := service.Verify(0, codes[0]); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("Expected used recovery code to be rejected, got %v", err)
+	}
+	// Digitado em maiúsculas e sem hífen, como o usuário costuma copiar
+	other := strings.ToUpper(codes[1][:5] + codes[1][6:])
+	if err := service.Verify(0, " "+other+" "); err != nil {
+		t.Errorf("Expected normalized recovery code to be accepted, got %v", err)
+	}
+
+	regenerated, err := service.RegenerateRecoveryCodes(0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := service.Verify(0, codes[2]); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("Expected codes replaced by regeneration to be rejected, got %v", err)
+	}
+	if err := service.Verify(0, regenerated[0]); err != nil {
+		t.Errorf("Expected regenerated code to be accepted, got %v", err)
+	}
+}
+
+func TestTwoFactorRecoveryCodeConcurrentUse(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	service, _, codes := enrolledTwoFactor(t, &now)
+
+	var wg sync.WaitGroup
+	results := make(chan error, 8)
+	for i := 0; i < cap(results); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- service.Verify(0, codes[0])
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	accepted := 0
+	for err := range results {
+		if err == nil {
+			accepted++
+		} else if !errors.Is(err, ErrInvalidTwoFactorCode) {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	if accepted != 1 {
+		t.Errorf("Expected the recovery code to be accepted exactly once, got %d", accepted)
+	}
+}
+
+func TestTwoFactorFailuresLockAccount(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	service, secret, _ := enrolledTwoFactor(t, &now)
+	throttle, _ := newTestThrottle(&now)
+	user := &models.User{Email: "joao@email.com"}
+
+	// Como no login em dois passos, cada código errado conta como falha de login da conta
+	for i := 0; i < DefaultAccountLockoutPolicy.MaxFailures; i++ {
+		if err := throttle.Check(user.Email, "10.0.0.1"); err != nil {
+			t.Fatalf("Expected no lockout before attempt %d, got %v", i+1, err)
+		}
+		if err := service.Verify(0, "000000"); !errors.Is(err, ErrInvalidTwoFactorCode) {
+			t.Fatalf("Expected ErrInvalidTwoFactorCode, got %v", err)
+		}
+		if err := throttle.RecordFailure(user.Email, "10.0.0.1", user); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	var lockout *LockoutError
+	if err := throttle.Check(user.Email, "10.0.0.1"); !errors.As(err, &lockout) {
+		t.Fatalf("Expected account to be locked after %d wrong codes, got %v", DefaultAccountLockoutPolicy.MaxFailures, err)
+	}
+
+	// Depois do bloqueio, o código certo volta a valer
+	now = lockout.Until.Add(time.Second)
+	if err := throttle.Check(user.Email, "10.0.0.1"); err != nil {
+		t.Fatalf("Expected lockout to expire, got %v", err)
+	}
+	code, _ := TOTPCode(secret, now)
+	if err := service.Verify(0, code); err != nil {
+		t.Errorf("Expected valid code after lockout, got %v", err)
+	}
+}
+
+func TestTwoFactorPolicyAndDisable(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	service, _, _ := enrolledTwoFactor(t, &now)
+
+	if required, _ := service.IsRequired(models.AdminType); required {
+		t.Error("Expected 2FA to be optional without a policy")
+	}
+	if err := service.SetRequired(models.AdminType, true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if required, _ := service.IsRequired(models.AdminType); !required {
+		t.Error("Expected 2FA to be required for admins")
+	}
+
+	if err := service.Disable(0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := service.Verify(0, "123456"); !errors.Is(err, ErrTwoFactorNotEnrolled) {
+		t.Errorf("Expected ErrTwoFactorNotEnrolled after disabling, got %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"cupcake-delivery/internal/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TwoFactorStore persiste a configuração de 2FA, os códigos de recuperação e as políticas por tipo de usuário
+type TwoFactorStore interface {
+	// Get retorna a configuração do usuário ou nil se ele nunca iniciou o cadastro
+	Get(userID uint) (*models.TwoFactor, error)
+	Save(tf *models.TwoFactor) error
+	// Enable grava a configuração ativada e substitui os códigos de recuperação na mesma transação
+	Enable(tf *models.TwoFactor, codeHashes []string) error
+	// ClaimStep registra o passo TOTP usado se for posterior ao último; false se outro login já o usou
+	ClaimStep(id uint, step int64) (bool, error)
+	UnusedRecoveryCodes(userID uint) ([]models.RecoveryCode, error)
+	// UseRecoveryCode marca o código como usado; false se outro login já o usou
+	UseRecoveryCode(id uint, at time.Time) (bool, error)
+	ReplaceRecoveryCodes(userID uint, codeHashes []string) error
+	// Delete remove a configuração e os códigos de recuperação do usuário
+	Delete(userID uint) error
+
+	// Policy retorna a política do tipo de usuário ou nil se não houver
+	Policy(role models.UserType) (*models.TwoFactorPolicy, error)
+	Policies() ([]models.TwoFactorPolicy, error)
+	SetPolicy(policy models.TwoFactorPolicy) error
+}
+
+// DBTwoFactorStore guarda o 2FA no PostgreSQL; os usos de código são atualizações condicionais
+type DBTwoFactorStore struct {
+	db *gorm.DB
+}
+
+func NewDBTwoFactorStore(db *gorm.DB) *DBTwoFactorStore {
+	return &DBTwoFactorStore{db: db}
+}
+
+func (s *DBTwoFactorStore) Get(userID uint) (*models.TwoFactor, error) {
+	var tf models.TwoFactor
+	if err := s.db.Where("user_id = ?", userID).First(&tf).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &tf, nil
+}
+
+func (s *DBTwoFactorStore) Save(tf *models.TwoFactor) error {
+	return s.db.Save(tf).Error
+}
+
+func (s *DBTwoFactorStore) Enable(tf *models.TwoFactor, codeHashes []string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(tf).Error; err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(tx, tf.UserID, codeHashes)
+	})
+}
+
+func (s *DBTwoFactorStore) ClaimStep(id uint, step int64) (bool, error) {
+	// Atualização condicional evita que duas requisições usem o mesmo código
+	result := s.db.Model(&models.TwoFactor{}).
+		Where("id = ? AND last_used_step < ?", id, step).
+		Update("last_used_step", step)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (s *DBTwoFactorStore) UnusedRecoveryCodes(userID uint) ([]models.RecoveryCode, error) {
+	var codes []models.RecoveryCode
+	err := s.db.Where("user_id = ? AND used_at IS NULL", userID).Find(&codes).Error
+	return codes, err
+}
+
+func (s *DBTwoFactorStore) UseRecoveryCode(id uint, at time.Time) (bool, error) {
+	result := s.db.Model(&models.RecoveryCode{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", at)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (s *DBTwoFactorStore) ReplaceRecoveryCodes(userID uint, codeHashes []string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+func (s *DBTwoFactorStore) Delete(userID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.TwoFactor{}).Error
+	})
+}
+
+func (s *DBTwoFactorStore) Policy(role models.UserType) (*models.TwoFactorPolicy, error) {
+	var policy models.TwoFactorPolicy
+	if err := s.db.Where("role = ?", role).First(&policy).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (s *DBTwoFactorStore) Policies() ([]models.TwoFactorPolicy, error) {
+	var policies []models.TwoFactorPolicy
+	err := s.db.Order("role").Find(&policies).Error
+	return policies, err
+}
+
+func (s *DBTwoFactorStore) SetPolicy(policy models.TwoFactorPolicy) error {
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "role"}},
+		DoUpdates: clause.AssignmentColumns([]string{"required", "updated_at"}),
+	}).Create(&policy).Error
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		if err := tx.Create(&models.RecoveryCode{UserID: userID, CodeHash: hash}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,348 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"cupcake-delivery/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrNoVehicle             = errors.New("entregador sem veículo cadastrado")
+	ErrVehicleNotVerified    = errors.New("documentos do veículo não verificados")
+	ErrVehicleTooSmall       = errors.New("pedido exige um veículo de maior porte")
+	ErrRefrigerationRequired = errors.New("pedido exige veículo refrigerado")
+	ErrVehicleFull           = errors.New("pedidos excedem a capacidade do veículo")
+	ErrUnsupportedDocument   = errors.New("documento deve ser JPEG, PNG ou PDF")
+	ErrDocumentNotPending    = errors.New("documento do veículo não aguarda verificação")
+)
+
+// vehicleDocumentExtensions tipos de arquivo aceitos como documento do veículo
+var vehicleDocumentExtensions = map[string]string{
+	"image/jpeg":      "jpg",
+	"image/png":       "png",
+	"application/pdf": "pdf",
+}
+
+// LoadChecker confere se o veículo do entregador comporta os pedidos, além dos que ele já leva.
+// Roda na transação que atribui os pedidos, para que duas retiradas simultâneas não passem da capacidade.
+type LoadChecker interface {
+	CheckLoad(tx *gorm.DB, courierID uint, orderIDs []uint) error
+}
+
+// VehicleInput dados do veículo informados pelo entregador; capacidade zero usa o padrão do tipo
+type VehicleInput struct {
+	Type          models.VehicleType
+	Plate         string
+	CapacityBoxes int
+	Refrigerated  bool
+}
+
+// ApplyVehicleInput atualiza o veículo; trocar tipo ou placa, passar a declarar refrigeração ou
+// aumentar a capacidade invalida a verificação feita
+func ApplyVehicleInput(vehicle *models.Vehicle, input VehicleInput) {
+	capacity := input.CapacityBoxes
+	if capacity == 0 {
+		capacity = input.Type.DefaultCapacity()
+	}
+
+	if vehicle.Type != input.Type || vehicle.Plate != input.Plate ||
+		(input.Refrigerated && !vehicle.Refrigerated) || capacity > vehicle.CapacityBoxes {
+		vehicle.DocumentStatus = models.VehicleDocumentMissing
+		if vehicle.DocumentKey != "" {
+			vehicle.DocumentStatus = models.VehicleDocumentPending
+		}
+		vehicle.VerifiedByID = nil
+		vehicle.VerifiedAt = nil
+		vehicle.RejectionReason = ""
+	}
+	if vehicle.DocumentStatus == "" {
+		vehicle.DocumentStatus = models.VehicleDocumentMissing
+	}
+
+	vehicle.Type = input.Type
+	vehicle.Plate = input.Plate
+	vehicle.CapacityBoxes = capacity
+	vehicle.Refrigerated = input.Refrigerated
+}
+
+// OrderVehicleNeeds soma o que os itens exigem do veículo: caixas, porte mínimo e refrigeração.
+// Os itens precisam estar com o produto carregado.
+func OrderVehicleNeeds(items []models.OrderItem) (boxes int, vehicle models.VehicleType, refrigerated bool) {
+	for _, item := range items {
+		perUnit := item.Product.Boxes
+		if perUnit < 1 {
+			perUnit = 1
+		}
+		boxes += perUnit * item.Quantity
+		vehicle = vehicle.Larger(item.Product.RequiredVehicle)
+		refrigerated = refrigerated || item.Product.Refrigerated
+	}
+	return boxes, vehicle, refrigerated
+}
+
+// orderLoad o que um pedido exige do veículo
+type orderLoad struct {
+	Boxes              int
+	RequiredVehicle    models.VehicleType
+	NeedsRefrigeration bool
+}
+
+// checkVehicleLoad confere o veículo contra os pedidos novos, somando as caixas que o entregador já leva
+func checkVehicleLoad(vehicle *models.Vehicle, requireVerified bool, carrying int, orders []orderLoad) error {
+	if vehicle == nil {
+		return ErrNoVehicle
+	}
+	if vehicle.DocumentStatus == models.VehicleDocumentRejected ||
+		(requireVerified && vehicle.DocumentStatus != models.VehicleDocumentVerified) {
+		return ErrVehicleNotVerified
+	}
+
+	boxes := carrying
+	for _, order := range orders {
+		if !vehicle.Type.Carries(order.RequiredVehicle) {
+			return ErrVehicleTooSmall
+		}
+		if order.NeedsRefrigeration && !vehicle.Refrigerated {
+			return ErrRefrigerationRequired
+		}
+		boxes += order.Boxes
+	}
+	if boxes > vehicle.CapacityBoxes {
+		return ErrVehicleFull
+	}
+	return nil
+}
+
+// VehicleService cadastro e verificação dos veículos dos entregadores e conferência de capacidade
+type VehicleService struct {
+	db              *gorm.DB
+	storage         FileStorage
+	requireVerified bool // Sem verificação, basta o documento não ter sido recusado
+	now             func() time.Time
+}
+
+func NewVehicleService(db *gorm.DB, storage FileStorage, requireVerified bool) *VehicleService {
+	return &VehicleService{db: db, storage: storage, requireVerified: requireVerified, now: time.Now}
+}
+
+// Vehicle veículo do entregador; gorm.ErrRecordNotFound se não cadastrado
+func (s *VehicleService) Vehicle(courierID uint) (*models.Vehicle, error) {
+	var vehicle models.Vehicle
+	if err := s.db.Where("courier_id = ?", courierID).First(&vehicle).Error; err != nil {
+		return nil, err
+	}
+	return &vehicle, nil
+}
+
+// Save cadastra ou atualiza o veículo do entregador
+func (s *VehicleService) Save(courierID uint, input VehicleInput) (*models.Vehicle, error) {
+	vehicle, err := s.Vehicle(courierID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		vehicle, err = &models.Vehicle{CourierID: courierID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ApplyVehicleInput(vehicle, input)
+	if err := s.db.Save(vehicle).Error; err != nil {
+		return nil, err
+	}
+	return vehicle, nil
+}
+
+// SaveDocument guarda o documento do veículo, que passa a aguardar a verificação de um admin.
+// O tipo é identificado pelo conteúdo, não pelo cabeçalho enviado.
+func (s *VehicleService) SaveDocument(ctx context.Context, courierID uint, document io.Reader) (*models.Vehicle, error) {
+	vehicle, err := s.Vehicle(courierID)
+	if err != nil {
+		return nil, err
+	}
+
+	content, contentType, err := sniffContent(document)
+	if err != nil {
+		return nil, ErrUnsupportedDocument
+	}
+	extension, ok := vehicleDocumentExtensions[contentType]
+	if !ok {
+		return nil, ErrUnsupportedDocument
+	}
+
+	now := s.now()
+	key := fmt.Sprintf("vehicle-documents/%d/%d.%s", courierID, now.UnixNano(), extension)
+	if err := s.storage.Save(ctx, key, content); err != nil {
+		return nil, err
+	}
+
+	previousKey := vehicle.DocumentKey
+	vehicle.DocumentKey = key
+	vehicle.DocumentContentType = contentType
+	vehicle.DocumentStatus = models.VehicleDocumentPending
+	vehicle.DocumentUploadedAt = &now
+	vehicle.VerifiedByID = nil
+	vehicle.VerifiedAt = nil
+	vehicle.RejectionReason = ""
+	if err := s.db.Save(vehicle).Error; err != nil {
+		// Sem o veículo apontando para ele o documento ficaria órfão no storage
+		if deleteErr := s.storage.Delete(ctx, key); deleteErr != nil {
+			log.Printf("Erro ao remover documento %s do entregador %d: %v", key, courierID, deleteErr)
+		}
+		return nil, err
+	}
+
+	// O documento anterior só é removido depois que o novo foi gravado
+	if previousKey != "" {
+		if err := s.storage.Delete(ctx, previousKey); err != nil {
+			log.Printf("Erro ao remover documento antigo %s do entregador %d: %v", previousKey, courierID, err)
+		}
+	}
+	return vehicle, nil
+}
+
+// OpenDocument abre o documento do veículo; ErrFileNotFound se nenhum foi enviado
+func (s *VehicleService) OpenDocument(ctx context.Context, vehicle *models.Vehicle) (io.ReadCloser, error) {
+	if vehicle.DocumentKey == "" {
+		return nil, ErrFileNotFound
+	}
+	return s.storage.Open(ctx, vehicle.DocumentKey)
+}
+
+// Get veículo pelo ID
+func (s *VehicleService) Get(id uint) (*models.Vehicle, error) {
+	var vehicle models.Vehicle
+	if err := s.db.First(&vehicle, id).Error; err != nil {
+		return nil, err
+	}
+	return &vehicle, nil
+}
+
+// List veículos com os documentos na situação informada (todos se vazia), os enviados há mais tempo primeiro
+func (s *VehicleService) List(status models.VehicleDocumentStatus) ([]models.Vehicle, error) {
+	query := s.db.Order("document_uploaded_at, id")
+	if status != "" {
+		query = query.Where("document_status = ?", status)
+	}
+	vehicles := []models.Vehicle{}
+	err := query.Find(&vehicles).Error
+	return vehicles, err
+}
+
+// Verify aprova ou recusa o documento enviado; a recusa exige o motivo
+func (s *VehicleService) Verify(id, adminID uint, approved bool, reason string) (*models.Vehicle, error) {
+	now := s.now()
+	updates := map[string]interface{}{
+		"document_status":  models.VehicleDocumentVerified,
+		"verified_by_id":   adminID,
+		"verified_at":      now,
+		"rejection_reason": "",
+		"updated_at":       now,
+	}
+	if !approved {
+		updates["document_status"] = models.VehicleDocumentRejected
+		updates["rejection_reason"] = reason
+	}
+
+	result := s.db.Model(&models.Vehicle{}).
+		Where("id = ? AND document_status = ?", id, models.VehicleDocumentPending).
+		Updates(updates)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := s.Get(id); err != nil {
+			return nil, err
+		}
+		return nil, ErrDocumentNotPending
+	}
+	return s.Get(id)
+}
+
+// CheckLoad confere, na transação tx, se o veículo do entregador comporta os pedidos junto com as
+// entregas em andamento. Trava o entregador até o fim da transação: outra retirada para ele espera
+// e já soma os pedidos atribuídos por esta.
+func (s *VehicleService) CheckLoad(tx *gorm.DB, courierID uint, orderIDs []uint) error {
+	var courier models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&courier, courierID).Error; err != nil {
+		return err
+	}
+
+	var vehicle models.Vehicle
+	err := tx.Where("courier_id = ?", courierID).First(&vehicle).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNoVehicle
+	}
+	if err != nil {
+		return err
+	}
+
+	var orders []orderLoad
+	if err := tx.Model(&models.Order{}).
+		Select("boxes, required_vehicle, needs_refrigeration").
+		Where("id IN ?", orderIDs).
+		Scan(&orders).Error; err != nil {
+		return err
+	}
+
+	var carrying int
+	if err := tx.Model(&models.Order{}).
+		Select("COALESCE(SUM(boxes), 0)").
+		Where("delivery_id = ? AND status = ? AND id NOT IN ?", courierID, models.StatusDelivering, orderIDs).
+		Scan(&carrying).Error; err != nil {
+		return err
+	}
+
+	return checkVehicleLoad(&vehicle, s.requireVerified, carrying, orders)
+}
+
+// Fits entregadores, entre os informados, cujo veículo comporta o pedido junto com as entregas em andamento
+func (s *VehicleService) Fits(orderID uint, courierIDs []uint) (map[uint]bool, error) {
+	fits := make(map[uint]bool, len(courierIDs))
+	if len(courierIDs) == 0 {
+		return fits, nil
+	}
+
+	var order orderLoad
+	if err := s.db.Model(&models.Order{}).
+		Select("boxes, required_vehicle, needs_refrigeration").
+		Where("id = ?", orderID).
+		Scan(&order).Error; err != nil {
+		return nil, err
+	}
+
+	var vehicles []models.Vehicle
+	if err := s.db.Where("courier_id IN ?", courierIDs).Find(&vehicles).Error; err != nil {
+		return nil, err
+	}
+
+	var loads []struct {
+		DeliveryID uint
+		Boxes      int
+	}
+	if err := s.db.Model(&models.Order{}).
+		Select("delivery_id, SUM(boxes) AS boxes").
+		Where("delivery_id IN ? AND status = ?", courierIDs, models.StatusDelivering).
+		Group("delivery_id").
+		Scan(&loads).Error; err != nil {
+		return nil, err
+	}
+	carrying := make(map[uint]int, len(loads))
+	for _, load := range loads {
+		carrying[load.DeliveryID] = load.Boxes
+	}
+
+	for i := range vehicles {
+		vehicle := &vehicles[i]
+		if checkVehicleLoad(vehicle, s.requireVerified, carrying[vehicle.CourierID], []orderLoad{order}) == nil {
+			fits[vehicle.CourierID] = true
+		}
+	}
+	return fits, nil
+}
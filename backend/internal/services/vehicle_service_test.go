@@ -0,0 +1,195 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cupcake-delivery/internal/models"
+
+	"gorm.io/gorm"
+)
+
+func TestCheckVehicleLoad(t *testing.T) {
+	verifiedMoto := &models.Vehicle{Type: models.VehicleMotorcycle, CapacityBoxes: 4, DocumentStatus: models.VehicleDocumentVerified}
+	pendingMoto := &models.Vehicle{Type: models.VehicleMotorcycle, CapacityBoxes: 4, DocumentStatus: models.VehicleDocumentPending}
+	rejectedMoto := &models.Vehicle{Type: models.VehicleMotorcycle, CapacityBoxes: 4, DocumentStatus: models.VehicleDocumentRejected}
+	coldCar := &models.Vehicle{Type: models.VehicleCar, CapacityBoxes: 12, Refrigerated: true, DocumentStatus: models.VehicleDocumentVerified}
+
+	cupcakes := orderLoad{Boxes: 2}
+	largeCake := orderLoad{Boxes: 3, RequiredVehicle: models.VehicleCar}
+	iceCreamCake := orderLoad{Boxes: 1, NeedsRefrigeration: true}
+
+	testCases := []struct {
+		name            string
+		vehicle         *models.Vehicle
+		requireVerified bool
+		carrying        int
+		orders          []orderLoad
+		expected        error
+	}{
+		{"Fits", verifiedMoto, true, 0, []orderLoad{cupcakes}, nil},
+		{"No vehicle", nil, true, 0, []orderLoad{cupcakes}, ErrNoVehicle},
+		{"Pending document when verification is required", pendingMoto, true, 0, []orderLoad{cupcakes}, ErrVehicleNotVerified},
+		{"Pending document when verification is optional", pendingMoto, false, 0, []orderLoad{cupcakes}, nil},
+		{"Rejected document is always blocked", rejectedMoto, false, 0, []orderLoad{cupcakes}, ErrVehicleNotVerified},
+		{"Large cake needs a car", verifiedMoto, true, 0, []orderLoad{largeCake}, ErrVehicleTooSmall},
+		{"Large cake by car", coldCar, true, 0, []orderLoad{largeCake}, nil},
+		{"Refrigeration required", verifiedMoto, true, 0, []orderLoad{iceCreamCake}, ErrRefrigerationRequired},
+		{"Batch exceeds capacity", verifiedMoto, true, 0, []orderLoad{cupcakes, cupcakes, cupcakes}, ErrVehicleFull},
+		{"Deliveries in progress count", verifiedMoto, true, 3, []orderLoad{cupcakes}, ErrVehicleFull},
+		{"Exactly full", verifiedMoto, true, 2, []orderLoad{cupcakes}, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := checkVehicleLoad(tc.vehicle, tc.requireVerified, tc.carrying, tc.orders); !errors.Is(err, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestApplyVehicleInput(t *testing.T) {
+	verifiedAt := time.Date(2024, 5, 10, 18, 0, 0, 0, time.UTC)
+	adminID := uint(1)
+
+	testCases := []struct {
+		name     string
+		input    VehicleInput
+		expected models.VehicleDocumentStatus
+	}{
+		{"Same vehicle", VehicleInput{Type: models.VehicleMotorcycle, Plate: "ABC1D23", CapacityBoxes: 4}, models.VehicleDocumentVerified},
+		{"Smaller capacity", VehicleInput{Type: models.VehicleMotorcycle, Plate: "ABC1D23", CapacityBoxes: 3}, models.VehicleDocumentVerified},
+		{"Default capacity of the type", VehicleInput{Type: models.VehicleMotorcycle, Plate: "ABC1D23"}, models.VehicleDocumentVerified},
+		{"Plate changed", VehicleInput{Type: models.VehicleMotorcycle, Plate: "XYZ9K87", CapacityBoxes: 4}, models.VehicleDocumentPending},
+		{"Type changed", VehicleInput{Type: models.VehicleCar, Plate: "ABC1D23", CapacityBoxes: 4}, models.VehicleDocumentPending},
+		{"Refrigeration declared", VehicleInput{Type: models.VehicleMotorcycle, Plate: "ABC1D23", CapacityBoxes: 4, Refrigerated: true}, models.VehicleDocumentPending},
+		{"Larger capacity", VehicleInput{Type: models.VehicleMotorcycle, Plate: "ABC1D23", CapacityBoxes: 6}, models.VehicleDocumentPending},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			vehicle := &models.Vehicle{
+				Type: models.VehicleMotorcycle, Plate: "ABC1D23", CapacityBoxes: 4, DocumentKey: "vehicle-documents/1/1.pdf",
+				DocumentStatus: models.VehicleDocumentVerified, VerifiedByID: &adminID, VerifiedAt: &verifiedAt,
+			}
+			ApplyVehicleInput(vehicle, tc.input)
+			if vehicle.DocumentStatus != tc.expected {
+				t.Errorf("Expected status %q, got %q", tc.expected, vehicle.DocumentStatus)
+			}
+			if tc.expected == models.VehicleDocumentPending && vehicle.VerifiedByID != nil {
+				t.Errorf("Expected the previous verification to be cleared, got %+v", vehicle)
+			}
+		})
+	}
+}
+
+func TestOrderVehicleNeeds(t *testing.T) {
+	items := []models.OrderItem{
+		{Quantity: 3, Product: models.Product{Boxes: 1}},
+		{Quantity: 1, Product: models.Product{Boxes: 4, RequiredVehicle: models.VehicleCar}},
+		{Quantity: 2, Product: models.Product{Refrigerated: true}}, // Sem caixas informadas ocupa uma
+	}
+
+	boxes, vehicle, refrigerated := OrderVehicleNeeds(items)
+	if boxes != 9 || vehicle != models.VehicleCar || !refrigerated {
+		t.Errorf("Expected 9 boxes by refrigerated car, got %d boxes, %q, refrigerated %v", boxes, vehicle, refrigerated)
+	}
+
+	boxes, vehicle, refrigerated = OrderVehicleNeeds(items[:1])
+	if boxes != 3 || vehicle != "" || refrigerated {
+		t.Errorf("Expected 3 boxes by any vehicle, got %d boxes, %q, refrigerated %v", boxes, vehicle, refrigerated)
+	}
+}
+
+// staticLoadChecker recusa ou aceita qualquer carga
+type staticLoadChecker struct{ err error }
+
+func (c staticLoadChecker) CheckLoad(tx *gorm.DB, courierID uint, orderIDs []uint) error {
+	return c.err
+}
+
+func TestOrderClaimServiceChecksVehicleLoad(t *testing.T) {
+	store := &memoryOrderClaimStore{orders: map[uint]*models.Order{1: {Status: models.StatusReady}}}
+	service := NewOrderClaimService(store)
+	service.SetLoadChecker(staticLoadChecker{err: ErrVehicleTooSmall})
+
+	if err := service.Claim(1, 9); !errors.Is(err, ErrVehicleTooSmall) {
+		t.Fatalf("Expected ErrVehicleTooSmall, got %v", err)
+	}
+	if store.orders[1].DeliveryID != nil {
+		t.Errorf("Order should not be claimed: %+v", store.orders[1])
+	}
+
+	service.SetLoadChecker(staticLoadChecker{})
+	if err := service.Claim(1, 9); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+// TestOrderClaimServiceConcurrentClaimsRespectCapacity retira ao mesmo tempo mais pedidos do que cabem no
+// veículo: a checagem na transação da retirada deixa passar só o que a capacidade comporta
+func TestOrderClaimServiceConcurrentClaimsRespectCapacity(t *testing.T) {
+	db := openTestDB(t)
+
+	suffix := time.Now().Format("150405.000000")
+	customer := models.User{Name: "Cliente", Email: "load-customer-" + suffix + "@example.com", Type: models.CustomerType}
+	courier := models.User{Name: "Entregador", Email: "load-courier-" + suffix + "@example.com", Type: models.DeliveryType}
+	for _, user := range []*models.User{&customer, &courier} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	vehicle := models.Vehicle{CourierID: courier.ID, Type: models.VehicleMotorcycle, CapacityBoxes: 4, DocumentStatus: models.VehicleDocumentVerified}
+	if err := db.Create(&vehicle).Error; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	orders := make([]models.Order, 6)
+	for i := range orders {
+		orders[i] = models.Order{CustomerID: customer.ID, Status: models.StatusReady, Boxes: 2}
+		if err := db.Create(&orders[i]).Error; err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	t.Cleanup(func() {
+		db.Where("order_id IN (?)", db.Model(&models.Order{}).Select("id").Where("customer_id = ?", customer.ID)).Delete(&models.OrderStatusChange{})
+		db.Unscoped().Where("customer_id = ?", customer.ID).Delete(&models.Order{})
+		db.Delete(&vehicle)
+		db.Unscoped().Delete(&[]models.User{customer, courier})
+	})
+
+	service := NewOrderClaimService(NewDBOrderClaimStore(db))
+	service.SetLoadChecker(NewVehicleService(db, nil, true))
+
+	var claimed, full int64
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for _, order := range orders {
+		wg.Add(1)
+		go func(orderID uint) {
+			defer wg.Done()
+			<-start
+			err := service.Claim(orderID, courier.ID)
+			switch {
+			case err == nil:
+				atomic.AddInt64(&claimed, 1)
+			case errors.Is(err, ErrVehicleFull):
+				atomic.AddInt64(&full, 1)
+			default:
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}(order.ID)
+	}
+	close(start)
+	wg.Wait()
+
+	var carrying int
+	db.Model(&models.Order{}).Select("COALESCE(SUM(boxes), 0)").
+		Where("delivery_id = ? AND status = ?", courier.ID, models.StatusDelivering).Scan(&carrying)
+	if claimed != 2 || full != 4 || carrying != 4 {
+		t.Errorf("Expected 2 claims filling 4 boxes, got %d claimed, %d rejected, %d boxes", claimed, full, carrying)
+	}
+}
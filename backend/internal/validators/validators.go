@@ -1,10 +1,14 @@
 package validators
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
+	"time"
 	"unicode"
+	"unicode/utf8"
 
+	"cupcake-delivery/internal/models"
 	"cupcake-delivery/internal/utils"
 )
 
@@ -100,7 +104,7 @@ func ValidateName(name string) *utils.ValidationError {
 
 // ValidateUserType valida tipo de usuário
 func ValidateUserType(userType string) *utils.ValidationError {
-	validTypes := []string{"customer", "delivery", "admin"}
+	validTypes := []string{"customer", "delivery", "admin", "kitchen"}
 	for _, validType := range validTypes {
 		if userType == validType {
 			return nil
@@ -109,7 +113,7 @@ func ValidateUserType(userType string) *utils.ValidationError {
 
 	return &utils.ValidationError{
 		Field:   "type",
-		Message: "Tipo de usuário deve ser 'customer', 'delivery' ou 'admin'",
+		Message: "Tipo de usuário deve ser 'customer', 'delivery', 'admin' ou 'kitchen'",
 	}
 }
 
@@ -228,7 +232,7 @@ func ValidateQuantity(quantity int) *utils.ValidationError {
 
 // ValidateOrderStatus valida status do pedido
 func ValidateOrderStatus(status string) *utils.ValidationError {
-	validStatuses := []string{"pending", "preparing", "ready", "delivering", "delivered"}
+	validStatuses := []string{"pending", "preparing", "ready", "delivering", "delivered", "delivery_failed", "returned"}
 	for _, validStatus := range validStatuses {
 		if status == validStatus {
 			return nil
@@ -237,6 +241,480 @@ func ValidateOrderStatus(status string) *utils.ValidationError {
 
 	return &utils.ValidationError{
 		Field:   "status",
-		Message: "Status deve ser 'pending', 'preparing', 'ready', 'delivering' ou 'delivered'",
+		Message: "Status deve ser 'pending', 'preparing', 'ready', 'delivering', 'delivered', 'delivery_failed' ou 'returned'",
 	}
 }
+
+// Unidades federativas brasileiras
+var validUFs = map[string]bool{
+	"AC": true, "AL": true, "AP": true, "AM": true, "BA": true, "CE": true, "DF": true,
+	"ES": true, "GO": true, "MA": true, "MT": true, "MS": true, "MG": true, "PA": true,
+	"PB": true, "PR": true, "PE": true, "PI": true, "RJ": true, "RN": true, "RS": true,
+	"RO": true, "RR": true, "SC": true, "SP": true, "SE": true, "TO": true,
+}
+
+// NormalizeCEP remove pontuação e espaços do CEP, mantendo apenas os dígitos
+func NormalizeCEP(cep string) string {
+	var digits strings.Builder
+	for _, char := range cep {
+		if unicode.IsDigit(char) {
+			digits.WriteRune(char)
+		}
+	}
+	return digits.String()
+}
+
+// ValidateCEP valida CEP (aceita 00000-000 ou 00000000)
+func ValidateCEP(cep string) *utils.ValidationError {
+	cep = strings.TrimSpace(cep)
+	if cep == "" {
+		return &utils.ValidationError{
+			Field:   "cep",
+			Message: "CEP é obrigatório",
+		}
+	}
+
+	cepRegex := regexp.MustCompile(`^\d{5}-?\d{3}$`)
+	if !cepRegex.MatchString(cep) {
+		return &utils.ValidationError{
+			Field:   "cep",
+			Message: "CEP deve ter 8 dígitos no formato 00000-000",
+		}
+	}
+
+	return nil
+}
+
+// ValidateUF valida a sigla do estado
+func ValidateUF(uf string) *utils.ValidationError {
+	uf = strings.ToUpper(strings.TrimSpace(uf))
+	if uf == "" {
+		return &utils.ValidationError{
+			Field:   "state",
+			Message: "UF é obrigatória",
+		}
+	}
+
+	if !validUFs[uf] {
+		return &utils.ValidationError{
+			Field:   "state",
+			Message: "UF inválida",
+		}
+	}
+
+	return nil
+}
+
+// ValidateAddressNumber valida o número do endereço (ex.: "123", "123A" ou "S/N")
+func ValidateAddressNumber(number string) *utils.ValidationError {
+	number = strings.ToUpper(strings.TrimSpace(number))
+	if number == "" {
+		return &utils.ValidationError{
+			Field:   "number",
+			Message: "Número é obrigatório (use S/N se não houver)",
+		}
+	}
+
+	numberRegex := regexp.MustCompile(`^(\d{1,6}[A-Z]?|S/?N)$`)
+	if !numberRegex.MatchString(number) {
+		return &utils.ValidationError{
+			Field:   "number",
+			Message: "Número inválido",
+		}
+	}
+
+	return nil
+}
+
+// ValidatePostalAddress valida todos os campos de um endereço estruturado
+func ValidatePostalAddress(address models.PostalAddress) []utils.ValidationError {
+	var validationErrors []utils.ValidationError
+
+	if err := ValidateCEP(address.CEP); err != nil {
+		validationErrors = append(validationErrors, *err)
+	}
+
+	if err := validateAddressText("street", "Logradouro", address.Street, 200, "Logradouro é obrigatório"); err != nil {
+		validationErrors = append(validationErrors, *err)
+	}
+
+	if err := ValidateAddressNumber(address.Number); err != nil {
+		validationErrors = append(validationErrors, *err)
+	}
+
+	if err := validateAddressText("complement", "Complemento", address.Complement, 100, ""); err != nil {
+		validationErrors = append(validationErrors, *err)
+	}
+
+	if err := validateAddressText("neighborhood", "Bairro", address.Neighborhood, 100, "Bairro é obrigatório"); err != nil {
+		validationErrors = append(validationErrors, *err)
+	}
+
+	if err := validateAddressText("city", "Cidade", address.City, 100, "Cidade é obrigatória"); err != nil {
+		validationErrors = append(validationErrors, *err)
+	}
+
+	if err := ValidateUF(address.State); err != nil {
+		validationErrors = append(validationErrors, *err)
+	}
+
+	return validationErrors
+}
+
+// validateAddressText valida tamanho de um campo de texto; requiredMessage vazio torna o campo opcional
+func validateAddressText(field, label, value string, maxLen int, requiredMessage string) *utils.ValidationError {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		if requiredMessage == "" {
+			return nil
+		}
+		return &utils.ValidationError{
+			Field:   field,
+			Message: requiredMessage,
+		}
+	}
+
+	if utf8.RuneCountInString(value) > maxLen {
+		return &utils.ValidationError{
+			Field:   field,
+			Message: fmt.Sprintf("%s não pode ter mais de %d caracteres", label, maxLen),
+		}
+	}
+
+	return nil
+}
+
+// NormalizeDocument remove pontuação do CPF/CNPJ, mantendo apenas os dígitos de 0 a 9
+func NormalizeDocument(document string) string {
+	return asciiDigits(document)
+}
+
+// asciiDigits mantém apenas os dígitos de 0 a 9, descartando dígitos de outros sistemas de escrita
+func asciiDigits(value string) string {
+	var digits strings.Builder
+	for _, char := range value {
+		if char >= '0' && char <= '9' {
+			digits.WriteRune(char)
+		}
+	}
+	return digits.String()
+}
+
+// ValidateCPF valida CPF pelos dígitos verificadores (aceita 000.000.000-00 ou 00000000000)
+func ValidateCPF(cpf string) *utils.ValidationError {
+	cpf = strings.TrimSpace(cpf)
+	if cpf == "" {
+		return &utils.ValidationError{
+			Field:   "cpf",
+			Message: "CPF é obrigatório",
+		}
+	}
+
+	cpfRegex := regexp.MustCompile(`^(\d{3}\.\d{3}\.\d{3}-\d{2}|\d{11})$`)
+	digits := NormalizeDocument(cpf)
+	if !cpfRegex.MatchString(cpf) || repeatedDigits(digits) ||
+		!checkDigitMatches(digits[:9], digits[9], cpfWeights[1:]) ||
+		!checkDigitMatches(digits[:10], digits[10], cpfWeights) {
+		return &utils.ValidationError{
+			Field:   "cpf",
+			Message: "CPF inválido",
+		}
+	}
+
+	return nil
+}
+
+// ValidateCNPJ valida CNPJ pelos dígitos verificadores (aceita 00.000.000/0000-00 ou 00000000000000)
+func ValidateCNPJ(cnpj string) *utils.ValidationError {
+	cnpj = strings.TrimSpace(cnpj)
+	if cnpj == "" {
+		return &utils.ValidationError{
+			Field:   "cnpj",
+			Message: "CNPJ é obrigatório",
+		}
+	}
+
+	cnpjRegex := regexp.MustCompile(`^(\d{2}\.\d{3}\.\d{3}/\d{4}-\d{2}|\d{14})$`)
+	digits := NormalizeDocument(cnpj)
+	if !cnpjRegex.MatchString(cnpj) || repeatedDigits(digits) ||
+		!checkDigitMatches(digits[:12], digits[12], cnpjWeights[1:]) ||
+		!checkDigitMatches(digits[:13], digits[13], cnpjWeights) {
+		return &utils.ValidationError{
+			Field:   "cnpj",
+			Message: "CNPJ inválido",
+		}
+	}
+
+	return nil
+}
+
+// ValidateDocument valida CPF (pessoa física) ou CNPJ (cliente empresa) conforme a quantidade de dígitos
+func ValidateDocument(document string) *utils.ValidationError {
+	var err *utils.ValidationError
+	switch len(NormalizeDocument(document)) {
+	case 0:
+		err = &utils.ValidationError{Message: "CPF ou CNPJ é obrigatório"}
+	case 11:
+		err = ValidateCPF(document)
+	case 14:
+		err = ValidateCNPJ(document)
+	default:
+		err = &utils.ValidationError{Message: "Documento deve ser um CPF (11 dígitos) ou CNPJ (14 dígitos)"}
+	}
+
+	if err != nil {
+		err.Field = "document"
+	}
+	return err
+}
+
+var (
+	cpfWeights  = []int{11, 10, 9, 8, 7, 6, 5, 4, 3, 2}
+	cnpjWeights = []int{6, 5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}
+)
+
+// checkDigitMatches calcula o dígito verificador módulo 11 de base e compara com expected
+func checkDigitMatches(base string, expected byte, weights []int) bool {
+	sum := 0
+	for i := range base {
+		sum += int(base[i]-'0') * weights[i]
+	}
+
+	digit := 11 - sum%11
+	if digit >= 10 {
+		digit = 0
+	}
+	return byte('0'+digit) == expected
+}
+
+// repeatedDigits identifica sequências como 111.111.111-11, que passam no cálculo mas não são válidas
+func repeatedDigits(digits string) bool {
+	return strings.Count(digits, digits[:1]) == len(digits)
+}
+
+// NormalizePhone converte telefones brasileiros para E.164 (+55DDNNNNNNNNN).
+// Aceita pontuação, prefixo +55/55 e o zero de longa distância; celulares têm 9 dígitos
+// começando por 9 e fixos têm 8 dígitos começando de 2 a 5.
+func NormalizePhone(phone string) (string, *utils.ValidationError) {
+	phone = strings.TrimSpace(phone)
+	if phone == "" {
+		return "", &utils.ValidationError{
+			Field:   "phone",
+			Message: "Telefone é obrigatório",
+		}
+	}
+
+	invalid := &utils.ValidationError{
+		Field:   "phone",
+		Message: "Telefone inválido. Use DDD + número, ex.: (11) 98765-4321",
+	}
+
+	phoneRegex := regexp.MustCompile(`^\+?[\d\s().-]+$`)
+	if !phoneRegex.MatchString(phone) {
+		return "", invalid
+	}
+
+	digits := asciiDigits(phone)
+	switch {
+	case strings.HasPrefix(phone, "+"):
+		if !strings.HasPrefix(digits, "55") {
+			return "", invalid
+		}
+		digits = digits[2:]
+	case strings.HasPrefix(digits, "0"):
+		digits = digits[1:]
+	case strings.HasPrefix(digits, "55") && len(digits) >= 12:
+		digits = digits[2:]
+	}
+
+	mobileRegex := regexp.MustCompile(`^[1-9]{2}9\d{8}$`)
+	landlineRegex := regexp.MustCompile(`^[1-9]{2}[2-5]\d{7}$`)
+	if !mobileRegex.MatchString(digits) && !landlineRegex.MatchString(digits) {
+		return "", invalid
+	}
+
+	return "+55" + digits, nil
+}
+
+// ValidatePhone valida telefone brasileiro (celular ou fixo)
+func ValidatePhone(phone string) *utils.ValidationError {
+	_, err := NormalizePhone(phone)
+	return err
+}
+
+// ValidateGeoJSONPolygon valida um Polygon GeoJSON: anéis fechados com pelo menos 4 posições
+// e coordenadas [longitude, latitude] dentro dos limites
+func ValidateGeoJSONPolygon(polygon models.GeoJSONPolygon) *utils.ValidationError {
+	if polygon.Type != "Polygon" {
+		return &utils.ValidationError{
+			Field:   "polygon",
+			Message: "Polígono deve ser um GeoJSON do tipo 'Polygon'",
+		}
+	}
+
+	if len(polygon.Coordinates) == 0 {
+		return &utils.ValidationError{
+			Field:   "polygon",
+			Message: "Polígono deve ter pelo menos um anel de coordenadas",
+		}
+	}
+
+	for _, ring := range polygon.Coordinates {
+		if len(ring) < 4 || ring[0] != ring[len(ring)-1] {
+			return &utils.ValidationError{
+				Field:   "polygon",
+				Message: "Cada anel do polígono deve ter pelo menos 4 posições e terminar na posição inicial",
+			}
+		}
+		for _, position := range ring {
+			if position[0] < -180 || position[0] > 180 || position[1] < -90 || position[1] > 90 {
+				return &utils.ValidationError{
+					Field:   "polygon",
+					Message: "Coordenadas devem estar no formato [longitude, latitude]",
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidateShiftPeriod valida o período de um turno de entregador (máximo de 12 horas)
+func ValidateShiftPeriod(startsAt, endsAt time.Time) *utils.ValidationError {
+	if !endsAt.After(startsAt) {
+		return &utils.ValidationError{
+			Field:   "ends_at",
+			Message: "Fim do turno deve ser depois do início",
+		}
+	}
+
+	if endsAt.Sub(startsAt) > 12*time.Hour {
+		return &utils.ValidationError{
+			Field:   "ends_at",
+			Message: "Turno não pode ter mais de 12 horas",
+		}
+	}
+
+	return nil
+}
+
+// ValidateCoordinates valida uma posição GPS em graus decimais
+func ValidateCoordinates(latitude, longitude float64) *utils.ValidationError {
+	if latitude < -90 || latitude > 90 {
+		return &utils.ValidationError{
+			Field:   "latitude",
+			Message: "Latitude deve estar entre -90 e 90",
+		}
+	}
+
+	if longitude < -180 || longitude > 180 {
+		return &utils.ValidationError{
+			Field:   "longitude",
+			Message: "Longitude deve estar entre -180 e 180",
+		}
+	}
+
+	return nil
+}
+
+// ValidateRescheduleTime valida a nova data de entrega: no futuro e em até 7 dias
+func ValidateRescheduleTime(scheduledFor, now time.Time) *utils.ValidationError {
+	if !scheduledFor.After(now) {
+		return &utils.ValidationError{
+			Field:   "scheduled_for",
+			Message: "Nova entrega deve ser agendada para o futuro",
+		}
+	}
+
+	if scheduledFor.Sub(now) > 7*24*time.Hour {
+		return &utils.ValidationError{
+			Field:   "scheduled_for",
+			Message: "Nova entrega deve ser agendada para os próximos 7 dias",
+		}
+	}
+
+	return nil
+}
+
+// ValidateTip valida a gorjeta para o entregador; zero significa sem gorjeta
+func ValidateTip(tip float64) *utils.ValidationError {
+	if tip < 0 {
+		return &utils.ValidationError{
+			Field:   "tip",
+			Message: "Gorjeta não pode ser negativa",
+		}
+	}
+
+	if tip > 500 {
+		return &utils.ValidationError{
+			Field:   "tip",
+			Message: "Gorjeta não pode ser maior que R$ 500,00",
+		}
+	}
+
+	return nil
+}
+
+// NormalizePlate remove espaços e hífen e deixa a placa em maiúsculas
+func NormalizePlate(plate string) string {
+	plate = strings.ToUpper(strings.TrimSpace(plate))
+	return strings.NewReplacer("-", "", " ", "").Replace(plate)
+}
+
+// ValidateVehicle valida o veículo do entregador: tipo, placa no padrão antigo (ABC1234) ou
+// Mercosul (ABC1D23) para motos e carros, e capacidade de até 50 caixas (zero usa o padrão do tipo)
+func ValidateVehicle(vehicleType models.VehicleType, plate string, capacityBoxes int) []utils.ValidationError {
+	var validationErrors []utils.ValidationError
+
+	if !vehicleType.Valid() {
+		validationErrors = append(validationErrors, utils.ValidationError{
+			Field:   "type",
+			Message: "Veículo deve ser bike, motorcycle ou car",
+		})
+	}
+
+	plateRegex := regexp.MustCompile(`^[A-Z]{3}\d[A-Z0-9]\d{2}$`)
+	switch {
+	case vehicleType.NeedsPlate() && plate == "":
+		validationErrors = append(validationErrors, utils.ValidationError{
+			Field:   "plate",
+			Message: "Placa é obrigatória para motos e carros",
+		})
+	case plate != "" && !plateRegex.MatchString(NormalizePlate(plate)):
+		validationErrors = append(validationErrors, utils.ValidationError{
+			Field:   "plate",
+			Message: "Placa inválida",
+		})
+	}
+
+	if capacityBoxes < 0 || capacityBoxes > 50 {
+		validationErrors = append(validationErrors, utils.ValidationError{
+			Field:   "capacity_boxes",
+			Message: "Capacidade deve ser de até 50 caixas",
+		})
+	}
+
+	return validationErrors
+}
+
+// ValidateProductDelivery valida o que o produto exige do veículo: caixas por unidade (1 a 50)
+// e porte mínimo, vazio quando qualquer veículo serve
+func ValidateProductDelivery(boxes int, requiredVehicle models.VehicleType) []utils.ValidationError {
+	var validationErrors []utils.ValidationError
+
+	if boxes < 1 || boxes > 50 {
+		validationErrors = append(validationErrors, utils.ValidationError{
+			Field:   "boxes",
+			Message: "Produto deve ocupar de 1 a 50 caixas",
+		})
+	}
+
+	if requiredVehicle != "" && !requiredVehicle.Valid() {
+		validationErrors = append(validationErrors, utils.ValidationError{
+			Field:   "requiredVehicle",
+			Message: "Veículo exigido deve ser bike, motorcycle ou car",
+		})
+	}
+
+	return validationErrors
+}
@@ -2,6 +2,9 @@ package validators
 
 import (
 	"testing"
+	"time"
+
+	"cupcake-delivery/internal/models"
 )
 
 func TestValidateEmail(t *testing.T) {
@@ -263,3 +266,767 @@ func TestValidateQuantity(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateCEP(t *testing.T) {
+	testCases := []struct {
+		name        string
+		cep         string
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:        "Valid CEP with hyphen",
+			cep:         "01310-100",
+			expectError: false,
+		},
+		{
+			name:        "Valid CEP digits only",
+			cep:         "01310100",
+			expectError: false,
+		},
+		{
+			name:        "Empty CEP",
+			cep:         "",
+			expectError: true,
+			errorMsg:    "CEP é obrigatório",
+		},
+		{
+			name:        "CEP too short",
+			cep:         "1310-100",
+			expectError: true,
+			errorMsg:    "CEP deve ter 8 dígitos no formato 00000-000",
+		},
+		{
+			name:        "CEP with letters",
+			cep:         "0131A-100",
+			expectError: true,
+			errorMsg:    "CEP deve ter 8 dígitos no formato 00000-000",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateCEP(tc.cep)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				} else if err.Message != tc.errorMsg {
+					t.Errorf("Expected error message '%s', got '%s'", tc.errorMsg, err.Message)
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Expected no error but got: %s", err.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeCEP(t *testing.T) {
+	if cep := NormalizeCEP(" 01310-100 "); cep != "01310100" {
+		t.Errorf("Expected '01310100', got '%s'", cep)
+	}
+}
+
+func TestValidateUF(t *testing.T) {
+	testCases := []struct {
+		name        string
+		uf          string
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:        "Valid UF",
+			uf:          "SP",
+			expectError: false,
+		},
+		{
+			name:        "Lowercase UF",
+			uf:          "rj",
+			expectError: false,
+		},
+		{
+			name:        "Empty UF",
+			uf:          "",
+			expectError: true,
+			errorMsg:    "UF é obrigatória",
+		},
+		{
+			name:        "Unknown UF",
+			uf:          "XX",
+			expectError: true,
+			errorMsg:    "UF inválida",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateUF(tc.uf)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				} else if err.Message != tc.errorMsg {
+					t.Errorf("Expected error message '%s', got '%s'", tc.errorMsg, err.Message)
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Expected no error but got: %s", err.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateAddressNumber(t *testing.T) {
+	testCases := []struct {
+		name        string
+		number      string
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:        "Numeric",
+			number:      "1578",
+			expectError: false,
+		},
+		{
+			name:        "Number with letter",
+			number:      "12b",
+			expectError: false,
+		},
+		{
+			name:        "Without number",
+			number:      "s/n",
+			expectError: false,
+		},
+		{
+			name:        "Empty number",
+			number:      "",
+			expectError: true,
+			errorMsg:    "Número é obrigatório (use S/N se não houver)",
+		},
+		{
+			name:        "Free text",
+			number:      "casa 2",
+			expectError: true,
+			errorMsg:    "Número inválido",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateAddressNumber(tc.number)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				} else if err.Message != tc.errorMsg {
+					t.Errorf("Expected error message '%s', got '%s'", tc.errorMsg, err.Message)
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Expected no error but got: %s", err.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestValidatePostalAddress(t *testing.T) {
+	valid := models.PostalAddress{
+		CEP:          "01310-100",
+		Street:       "Avenida Paulista",
+		Number:       "1578",
+		Neighborhood: "Bela Vista",
+		City:         "São Paulo",
+		State:        "SP",
+	}
+	if errs := ValidatePostalAddress(valid); len(errs) != 0 {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+
+	errs := ValidatePostalAddress(models.PostalAddress{CEP: "01310-100", Number: "10"})
+	fields := map[string]bool{}
+	for _, err := range errs {
+		fields[err.Field] = true
+	}
+	for _, field := range []string{"street", "neighborhood", "city", "state"} {
+		if !fields[field] {
+			t.Errorf("Expected error for field %s, got %v", field, errs)
+		}
+	}
+	if fields["cep"] || fields["number"] || fields["complement"] {
+		t.Errorf("Unexpected errors: %v", errs)
+	}
+}
+
+func TestValidateCPF(t *testing.T) {
+	testCases := []struct {
+		name        string
+		cpf         string
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:        "Valid formatted CPF",
+			cpf:         "529.982.247-25",
+			expectError: false,
+		},
+		{
+			name:        "Valid CPF digits only",
+			cpf:         "11144477735",
+			expectError: false,
+		},
+		{
+			name:        "Empty CPF",
+			cpf:         "",
+			expectError: true,
+			errorMsg:    "CPF é obrigatório",
+		},
+		{
+			name:        "Wrong first check digit",
+			cpf:         "529.982.247-35",
+			expectError: true,
+			errorMsg:    "CPF inválido",
+		},
+		{
+			name:        "Wrong second check digit",
+			cpf:         "529.982.247-26",
+			expectError: true,
+			errorMsg:    "CPF inválido",
+		},
+		{
+			name:        "Repeated digits",
+			cpf:         "111.111.111-11",
+			expectError: true,
+			errorMsg:    "CPF inválido",
+		},
+		{
+			name:        "Too short",
+			cpf:         "5299822472",
+			expectError: true,
+			errorMsg:    "CPF inválido",
+		},
+		{
+			name:        "Malformed punctuation",
+			cpf:         "529-982-247.25",
+			expectError: true,
+			errorMsg:    "CPF inválido",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateCPF(tc.cpf)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				} else if err.Message != tc.errorMsg {
+					t.Errorf("Expected error message '%s', got '%s'", tc.errorMsg, err.Message)
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Expected no error but got: %s", err.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateCNPJ(t *testing.T) {
+	testCases := []struct {
+		name        string
+		cnpj        string
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:        "Valid formatted CNPJ",
+			cnpj:        "11.222.333/0001-81",
+			expectError: false,
+		},
+		{
+			name:        "Valid CNPJ digits only",
+			cnpj:        "11444777000161",
+			expectError: false,
+		},
+		{
+			name:        "Empty CNPJ",
+			cnpj:        "",
+			expectError: true,
+			errorMsg:    "CNPJ é obrigatório",
+		},
+		{
+			name:        "Wrong check digits",
+			cnpj:        "11.222.333/0001-82",
+			expectError: true,
+			errorMsg:    "CNPJ inválido",
+		},
+		{
+			name:        "Repeated digits",
+			cnpj:        "00000000000000",
+			expectError: true,
+			errorMsg:    "CNPJ inválido",
+		},
+		{
+			name:        "Too long",
+			cnpj:        "114447770001611",
+			expectError: true,
+			errorMsg:    "CNPJ inválido",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateCNPJ(tc.cnpj)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				} else if err.Message != tc.errorMsg {
+					t.Errorf("Expected error message '%s', got '%s'", tc.errorMsg, err.Message)
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Expected no error but got: %s", err.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeDocument(t *testing.T) {
+	testCases := []struct {
+		document string
+		expected string
+	}{
+		{"529.982.247-25", "52998224725"},
+		{" 11.222.333/0001-81 ", "11222333000181"},
+		{"529 982 247 25", "52998224725"},
+		{"٥٢٩.982.247-25", "98224725"}, // Dígitos de outros sistemas de escrita não entram no documento
+		{"", ""},
+	}
+
+	for _, tc := range testCases {
+		if got := NormalizeDocument(tc.document); got != tc.expected {
+			t.Errorf("Expected %q for %q, got %q", tc.expected, tc.document, got)
+		}
+	}
+}
+
+func TestValidateDocument(t *testing.T) {
+	testCases := []struct {
+		name        string
+		document    string
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:        "CPF",
+			document:    "529.982.247-25",
+			expectError: false,
+		},
+		{
+			name:        "CNPJ",
+			document:    "11.222.333/0001-81",
+			expectError: false,
+		},
+		{
+			name:        "Empty document",
+			document:    "",
+			expectError: true,
+			errorMsg:    "CPF ou CNPJ é obrigatório",
+		},
+		{
+			name:        "Invalid CPF",
+			document:    "123.456.789-00",
+			expectError: true,
+			errorMsg:    "CPF inválido",
+		},
+		{
+			name:        "Unknown length",
+			document:    "123456",
+			expectError: true,
+			errorMsg:    "Documento deve ser um CPF (11 dígitos) ou CNPJ (14 dígitos)",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateDocument(tc.document)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				} else if err.Message != tc.errorMsg || err.Field != "document" {
+					t.Errorf("Expected error '%s' on document, got '%s' on %s", tc.errorMsg, err.Message, err.Field)
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Expected no error but got: %s", err.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizePhone(t *testing.T) {
+	invalidMsg := "Telefone inválido. Use DDD + número, ex.: (11) 98765-4321"
+
+	testCases := []struct {
+		name        string
+		phone       string
+		expected    string
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:     "Formatted mobile",
+			phone:    "(11) 98765-4321",
+			expected: "+5511987654321",
+		},
+		{
+			name:     "Mobile already in E.164",
+			phone:    "+55 11 98765-4321",
+			expected: "+5511987654321",
+		},
+		{
+			name:     "Mobile with country code without plus",
+			phone:    "5511987654321",
+			expected: "+5511987654321",
+		},
+		{
+			name:     "Mobile with trunk prefix",
+			phone:    "011 98765-4321",
+			expected: "+5511987654321",
+		},
+		{
+			name:     "Landline",
+			phone:    "(21) 3456-7890",
+			expected: "+552134567890",
+		},
+		{
+			name:     "Landline with country code",
+			phone:    "552134567890",
+			expected: "+552134567890",
+		},
+		{
+			name:        "Empty phone",
+			phone:       "",
+			expectError: true,
+			errorMsg:    "Telefone é obrigatório",
+		},
+		{
+			name:        "Mobile without leading 9",
+			phone:       "(11) 88765-4321",
+			expectError: true,
+			errorMsg:    invalidMsg,
+		},
+		{
+			name:        "Without area code",
+			phone:       "98765-4321",
+			expectError: true,
+			errorMsg:    invalidMsg,
+		},
+		{
+			name:        "Invalid area code",
+			phone:       "(01) 98765-4321",
+			expectError: true,
+			errorMsg:    invalidMsg,
+		},
+		{
+			name:        "Foreign country code",
+			phone:       "+1 415 555 2671",
+			expectError: true,
+			errorMsg:    invalidMsg,
+		},
+		{
+			name:        "Letters",
+			phone:       "11 9876A-4321",
+			expectError: true,
+			errorMsg:    invalidMsg,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			phone, err := NormalizePhone(tc.phone)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				} else if err.Message != tc.errorMsg {
+					t.Errorf("Expected error message '%s', got '%s'", tc.errorMsg, err.Message)
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Expected no error but got: %s", err.Message)
+				} else if phone != tc.expected {
+					t.Errorf("Expected '%s', got '%s'", tc.expected, phone)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateGeoJSONPolygon(t *testing.T) {
+	square := [][2]float64{{-46.7, -23.6}, {-46.6, -23.6}, {-46.6, -23.5}, {-46.7, -23.5}, {-46.7, -23.6}}
+
+	testCases := []struct {
+		name        string
+		polygon     models.GeoJSONPolygon
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:        "Valid polygon",
+			polygon:     models.GeoJSONPolygon{Type: "Polygon", Coordinates: [][][2]float64{square}},
+			expectError: false,
+		},
+		{
+			name:        "Wrong type",
+			polygon:     models.GeoJSONPolygon{Type: "Point", Coordinates: [][][2]float64{square}},
+			expectError: true,
+			errorMsg:    "Polígono deve ser um GeoJSON do tipo 'Polygon'",
+		},
+		{
+			name:        "Without rings",
+			polygon:     models.GeoJSONPolygon{Type: "Polygon"},
+			expectError: true,
+			errorMsg:    "Polígono deve ter pelo menos um anel de coordenadas",
+		},
+		{
+			name:        "Open ring",
+			polygon:     models.GeoJSONPolygon{Type: "Polygon", Coordinates: [][][2]float64{square[:4]}},
+			expectError: true,
+			errorMsg:    "Cada anel do polígono deve ter pelo menos 4 posições e terminar na posição inicial",
+		},
+		{
+			name: "Latitude out of range",
+			polygon: models.GeoJSONPolygon{Type: "Polygon", Coordinates: [][][2]float64{
+				{{-23.6, -46.7}, {-23.6, -146.6}, {-23.5, -46.6}, {-23.6, -46.7}},
+			}},
+			expectError: true,
+			errorMsg:    "Coordenadas devem estar no formato [longitude, latitude]",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateGeoJSONPolygon(tc.polygon)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				} else if err.Message != tc.errorMsg {
+					t.Errorf("Expected error message '%s', got '%s'", tc.errorMsg, err.Message)
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Expected no error but got: %s", err.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateShiftPeriod(t *testing.T) {
+	start := time.Date(2024, 5, 10, 18, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name        string
+		endsAt      time.Time
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:        "Valid shift",
+			endsAt:      start.Add(6 * time.Hour),
+			expectError: false,
+		},
+		{
+			name:        "Ends before start",
+			endsAt:      start.Add(-time.Hour),
+			expectError: true,
+			errorMsg:    "Fim do turno deve ser depois do início",
+		},
+		{
+			name:        "Zero length",
+			endsAt:      start,
+			expectError: true,
+			errorMsg:    "Fim do turno deve ser depois do início",
+		},
+		{
+			name:        "Too long",
+			endsAt:      start.Add(13 * time.Hour),
+			expectError: true,
+			errorMsg:    "Turno não pode ter mais de 12 horas",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateShiftPeriod(start, tc.endsAt)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				} else if err.Message != tc.errorMsg {
+					t.Errorf("Expected error message '%s', got '%s'", tc.errorMsg, err.Message)
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Expected no error but got: %s", err.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateCoordinates(t *testing.T) {
+	testCases := []struct {
+		name          string
+		latitude      float64
+		longitude     float64
+		expectError   bool
+		expectedField string
+	}{
+		{"São Paulo", -23.5505, -46.6333, false, ""},
+		{"Limits", 90, -180, false, ""},
+		{"Latitude out of range", -91, -46.6, true, "latitude"},
+		{"Longitude out of range", -23.5, 181, true, "longitude"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateCoordinates(tc.latitude, tc.longitude)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				} else if err.Field != tc.expectedField {
+					t.Errorf("Expected error on field '%s', got '%s'", tc.expectedField, err.Field)
+				}
+			} else if err != nil {
+				t.Errorf("Expected no error but got: %s", err.Message)
+			}
+		})
+	}
+}
+
+func TestValidateRescheduleTime(t *testing.T) {
+	now := time.Date(2024, 5, 10, 18, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name         string
+		scheduledFor time.Time
+		expectError  bool
+		errorMsg     string
+	}{
+		{"Tomorrow", now.Add(24 * time.Hour), false, ""},
+		{"Exactly 7 days", now.Add(7 * 24 * time.Hour), false, ""},
+		{"Now", now, true, "Nova entrega deve ser agendada para o futuro"},
+		{"Past", now.Add(-time.Hour), true, "Nova entrega deve ser agendada para o futuro"},
+		{"Too far", now.Add(8 * 24 * time.Hour), true, "Nova entrega deve ser agendada para os próximos 7 dias"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateRescheduleTime(tc.scheduledFor, now)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				} else if err.Message != tc.errorMsg {
+					t.Errorf("Expected error message '%s', got '%s'", tc.errorMsg, err.Message)
+				}
+			} else if err != nil {
+				t.Errorf("Expected no error but got: %s", err.Message)
+			}
+		})
+	}
+}
+
+func TestValidateTip(t *testing.T) {
+	testCases := []struct {
+		name        string
+		tip         float64
+		expectError bool
+		errorMsg    string
+	}{
+		{"No tip", 0, false, ""},
+		{"Valid tip", 7.5, false, ""},
+		{"Maximum tip", 500, false, ""},
+		{"Negative tip", -1, true, "Gorjeta não pode ser negativa"},
+		{"Too high tip", 500.01, true, "Gorjeta não pode ser maior que R$ 500,00"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateTip(tc.tip)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				} else if err.Message != tc.errorMsg {
+					t.Errorf("Expected error message '%s', got '%s'", tc.errorMsg, err.Message)
+				}
+			} else if err != nil {
+				t.Errorf("Expected no error but got: %s", err.Message)
+			}
+		})
+	}
+}
+
+func TestValidateVehicle(t *testing.T) {
+	testCases := []struct {
+		name        string
+		vehicleType models.VehicleType
+		plate       string
+		capacity    int
+		errorFields []string
+	}{
+		{"Bike without plate", models.VehicleBike, "", 0, nil},
+		{"Motorcycle with old plate", models.VehicleMotorcycle, "abc-1234", 4, nil},
+		{"Car with Mercosul plate", models.VehicleCar, "BRA2E19", 12, nil},
+		{"Unknown type", models.VehicleType("truck"), "ABC1234", 0, []string{"type"}},
+		{"Car without plate", models.VehicleCar, "", 0, []string{"plate"}},
+		{"Invalid plate", models.VehicleMotorcycle, "AB12345", 0, []string{"plate"}},
+		{"Negative capacity", models.VehicleBike, "", -1, []string{"capacity_boxes"}},
+		{"Capacity too high", models.VehicleCar, "BRA2E19", 51, []string{"capacity_boxes"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := ValidateVehicle(tc.vehicleType, tc.plate, tc.capacity)
+			if len(errs) != len(tc.errorFields) {
+				t.Fatalf("Expected errors on %v, got %v", tc.errorFields, errs)
+			}
+			for i, field := range tc.errorFields {
+				if errs[i].Field != field {
+					t.Errorf("Expected error on '%s', got '%s'", field, errs[i].Field)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateProductDelivery(t *testing.T) {
+	testCases := []struct {
+		name            string
+		boxes           int
+		requiredVehicle models.VehicleType
+		errorFields     []string
+	}{
+		{"Any vehicle", 1, "", nil},
+		{"Large cake by car", 3, models.VehicleCar, nil},
+		{"No boxes", 0, "", []string{"boxes"}},
+		{"Too many boxes", 51, "", []string{"boxes"}},
+		{"Unknown vehicle", 1, models.VehicleType("truck"), []string{"requiredVehicle"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := ValidateProductDelivery(tc.boxes, tc.requiredVehicle)
+			if len(errs) != len(tc.errorFields) {
+				t.Fatalf("Expected errors on %v, got %v", tc.errorFields, errs)
+			}
+			for i, field := range tc.errorFields {
+				if errs[i].Field != field {
+					t.Errorf("Expected error on '%s', got '%s'", field, errs[i].Field)
+				}
+			}
+		})
+	}
+}
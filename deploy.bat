@@ -25,7 +25,7 @@ if not exist .env (
     echo 📝 Criando arquivo .env a partir do exemplo...
     copy .env.example .env
     echo ⚠️  IMPORTANTE: Edite o arquivo .env com suas configurações antes de continuar!
-    echo    Especialmente: DB_PASSWORD e JWT_SECRET
+    echo    Especialmente: DB_PASSWORD, JWT_KEYS_DIR e JWT_ACTIVE_KID
     pause
 )
 
@@ -24,7 +24,7 @@ if [ ! -f .env ]; then
     echo "📝 Criando arquivo .env a partir do exemplo..."
     cp .env.example .env
     echo "⚠️  IMPORTANTE: Edite o arquivo .env com suas configurações antes de continuar!"
-    echo "   Especialmente: DB_PASSWORD e JWT_SECRET"
+    echo "   Especialmente: DB_PASSWORD, JWT_KEYS_DIR e JWT_ACTIVE_KID"
     read -p "Pressione Enter após editar o arquivo .env..."
 fi
 
@@ -6,7 +6,13 @@ interface User {
   name: string;
   email: string;
   type: 'customer' | 'delivery' | 'admin';
-  vehicle?: string;
+  vehicle?: {
+    type: 'bike' | 'motorcycle' | 'car';
+    plate?: string;
+    capacity_boxes: number;
+    refrigerated: boolean;
+    document_status: 'missing' | 'pending' | 'verified' | 'rejected';
+  };
 }
 
 interface AuthContextType {
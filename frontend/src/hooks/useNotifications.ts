@@ -1,4 +1,4 @@
-import { useState, useEffect, useCallback } from 'react';
+import { useState, useEffect, useCallback, useRef } from 'react';
 import { notificationService, Notification } from '../services/notificationService';
 import { useToast } from '../contexts/ToastContext';
 import { useAuth } from '../contexts/AuthContext';
@@ -7,6 +7,8 @@ export function useNotifications() {
   const [notifications, setNotifications] = useState<Notification[]>([]);
   const [unreadCount, setUnreadCount] = useState(0);
   const [loading, setLoading] = useState(false);
+  // IDs já na lista, para o stream não contar de novo uma notificação reenviada ao reconectar
+  const notificationIds = useRef(new Set<number>());
   const { showToast } = useToast();
   const { isAuthenticated, loading: authLoading } = useAuth();
 
@@ -117,15 +119,33 @@ export function useNotifications() {
     }
   }, [isAuthenticated, authLoading]); // Removido fetchNotifications e fetchUnreadCount das dependências para evitar loops
 
-  // Poll para buscar novas notificações a cada 30 segundos (só se logado e não carregando)
+  useEffect(() => {
+    notificationIds.current = new Set(notifications.map(notif => notif.id));
+  }, [notifications]);
+
+  // Novas notificações chegam pelo stream (SSE); sem suporte a EventSource, busca a contagem a cada 30 segundos
   useEffect(() => {
     if (!isAuthenticated || authLoading) return;
-    
-    const interval = setInterval(() => {
-      fetchUnreadCount();
-    }, 30000);
 
-    return () => clearInterval(interval);
+    const stream = notificationService.openStream();
+    if (!stream) {
+      const interval = setInterval(() => {
+        fetchUnreadCount();
+      }, 30000);
+      return () => clearInterval(interval);
+    }
+
+    stream.addEventListener('notification', (event) => {
+      const notification: Notification = JSON.parse((event as MessageEvent).data);
+      if (notificationIds.current.has(notification.id)) return;
+      notificationIds.current.add(notification.id);
+      setNotifications(prev => [notification, ...prev]);
+      if (!notification.is_read) {
+        setUnreadCount(prev => prev + 1);
+      }
+    });
+
+    return () => stream.close();
   }, [fetchUnreadCount, isAuthenticated, authLoading]);
 
   return {
@@ -90,7 +90,14 @@ export default function Cart() {
         items: cartItems.map(item => ({
           product_id: item.productId,
           quantity: item.quantity
-        }))
+        })),
+        cep: address.zipCode,
+        street: address.street,
+        number: address.number,
+        complement: address.complement,
+        neighborhood: address.neighborhood,
+        city: address.city,
+        state: address.state
       };
 
       await apiService.createOrder(orderData);
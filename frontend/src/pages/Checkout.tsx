@@ -2,7 +2,7 @@ import { useState, useEffect } from 'react';
 import { useNavigate } from 'react-router-dom';
 import { useCart } from '../hooks/useCart';
 import { useDocumentTitle } from '../hooks/useDocumentTitle';
-import apiService from '../services/api';
+import apiService, { PostalAddressData, SavedAddress } from '../services/api';
 
 interface Product {
   ID: number;
@@ -14,13 +14,38 @@ interface Product {
   UpdatedAt: string;
 }
 
+const emptyAddress: PostalAddressData = {
+  cep: '',
+  street: '',
+  number: '',
+  complement: '',
+  neighborhood: '',
+  city: '',
+  state: ''
+};
+
+const inputClass = 'w-full px-3 py-2 border border-gray-300 dark:border-dark-600 rounded-md focus:outline-none focus:ring-2 focus:ring-pink-500 bg-white dark:bg-dark-700 text-gray-900 dark:text-white placeholder-gray-500 dark:placeholder-gray-400 transition-colors';
+
+// Mensagem do backend: erros de validação por campo ou o campo error
+function orderErrorMessage(error: any): string {
+  const data = error?.response?.data;
+  if (data?.validations?.length) {
+    return data.validations.map((v: { message: string }) => v.message).join(' ');
+  }
+  return data?.error && data.error !== 'validation_error' ? data.error : 'Erro ao processar pedido. Tente novamente.';
+}
+
 export default function Checkout() {
   const { cartItems, clearCart } = useCart();
   const [products, setProducts] = useState<Product[]>([]);
   const [loading, setLoading] = useState(true);
   const [submitting, setSubmitting] = useState(false);
-  const [address, setAddress] = useState('');
+  const [savedAddresses, setSavedAddresses] = useState<SavedAddress[]>([]);
+  const [addressId, setAddressId] = useState<number | 'new'>('new');
+  const [address, setAddress] = useState<PostalAddressData>(emptyAddress);
   const [phone, setPhone] = useState('');
+  const [taxDocument, setTaxDocument] = useState('');
+  const [error, setError] = useState('');
   const [paymentMethod, setPaymentMethod] = useState('cash');
   const [notes, setNotes] = useState('');
   const navigate = useNavigate();
@@ -47,8 +72,21 @@ export default function Checkout() {
 
   const loadProducts = async () => {
     try {
-      const data = await apiService.getProducts();
+      const [data, addresses, profile] = await Promise.all([
+        apiService.getProducts(),
+        apiService.getAddresses().catch(() => [] as SavedAddress[]),
+        apiService.getProfile().catch(() => null)
+      ]);
       setProducts(data);
+      setSavedAddresses(addresses);
+      const defaultAddress = addresses.find(a => a.isDefault) || addresses[0];
+      setAddressId(defaultAddress ? defaultAddress.ID : 'new');
+      if (profile?.phone) {
+        setPhone(profile.phone);
+      }
+      if (profile?.document) {
+        setTaxDocument(profile.document);
+      }
     } catch (error) {
       console.error('Erro ao carregar produtos:', error);
     } finally {
@@ -72,11 +110,38 @@ export default function Checkout() {
     }, 0);
   };
 
+  const updateAddress = (field: keyof PostalAddressData, value: string) => {
+    setAddress(prev => ({ ...prev, [field]: value }));
+  };
+
+  // Preenche logradouro, bairro, cidade e UF pelo CEP; o que o cliente já digitou é mantido
+  const handleCEPBlur = async () => {
+    const cep = address.cep.replace(/\D/g, '');
+    if (cep.length !== 8) return;
+    try {
+      const found = await apiService.lookupCEP(cep);
+      setAddress(prev => ({
+        ...prev,
+        street: prev.street || found.street,
+        neighborhood: prev.neighborhood || found.neighborhood,
+        city: prev.city || found.city,
+        state: prev.state || found.state
+      }));
+    } catch (error) {
+      console.error('Erro ao consultar CEP:', error);
+    }
+  };
+
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
-    
-    if (!address.trim() || !phone.trim()) {
-      alert('Por favor, preencha todos os campos obrigatórios.');
+    setError('');
+
+    if (!phone.trim()) {
+      setError('Informe um telefone para contato.');
+      return;
+    }
+    if (addressId === 'new' && (!address.cep.trim() || !address.number.trim())) {
+      setError('Informe o CEP e o número do endereço de entrega.');
       return;
     }
 
@@ -88,8 +153,9 @@ export default function Checkout() {
           product_id: item.productId,
           quantity: item.quantity
         })),
-        address: address.trim(),
+        ...(addressId === 'new' ? address : { address_id: addressId }),
         phone: phone.trim(),
+        ...(taxDocument.trim() ? { document: taxDocument.trim() } : {}),
         paymentMethod,
         notes: notes.trim()
       };
@@ -103,7 +169,7 @@ export default function Checkout() {
       navigate('/');
     } catch (error) {
       console.error('Erro ao criar pedido:', error);
-      alert('Erro ao processar pedido. Tente novamente.');
+      setError(orderErrorMessage(error));
     } finally {
       setSubmitting(false);
     }
@@ -154,14 +220,71 @@ export default function Checkout() {
                 <label className="block text-sm font-medium text-gray-700 dark:text-gray-300 mb-2">
                   Endereço de Entrega *
                 </label>
-                <textarea
-                  value={address}
-                  onChange={(e) => setAddress(e.target.value)}
-                  rows={3}
-                  className="w-full px-3 py-2 border border-gray-300 dark:border-dark-600 rounded-md focus:outline-none focus:ring-2 focus:ring-pink-500 bg-white dark:bg-dark-700 text-gray-900 dark:text-white placeholder-gray-500 dark:placeholder-gray-400 transition-colors"
-                  placeholder="Rua, número, bairro, cidade..."
-                  required
-                />
+                {savedAddresses.length > 0 && (
+                  <select
+                    value={addressId}
+                    onChange={(e) => setAddressId(e.target.value === 'new' ? 'new' : Number(e.target.value))}
+                    className={`${inputClass} mb-3`}
+                  >
+                    {savedAddresses.map(saved => (
+                      <option key={saved.ID} value={saved.ID}>
+                        {saved.label ? `${saved.label} - ${saved.address}` : saved.address}
+                      </option>
+                    ))}
+                    <option value="new">Outro endereço</option>
+                  </select>
+                )}
+                {addressId === 'new' && (
+                  <div className="grid grid-cols-6 gap-3">
+                    <input
+                      value={address.cep}
+                      onChange={(e) => updateAddress('cep', e.target.value)}
+                      onBlur={handleCEPBlur}
+                      className={`${inputClass} col-span-3`}
+                      placeholder="CEP"
+                      inputMode="numeric"
+                      required
+                    />
+                    <input
+                      value={address.number}
+                      onChange={(e) => updateAddress('number', e.target.value)}
+                      className={`${inputClass} col-span-3`}
+                      placeholder="Número"
+                      required
+                    />
+                    <input
+                      value={address.street}
+                      onChange={(e) => updateAddress('street', e.target.value)}
+                      className={`${inputClass} col-span-6`}
+                      placeholder="Rua"
+                    />
+                    <input
+                      value={address.complement}
+                      onChange={(e) => updateAddress('complement', e.target.value)}
+                      className={`${inputClass} col-span-6`}
+                      placeholder="Complemento (opcional)"
+                    />
+                    <input
+                      value={address.neighborhood}
+                      onChange={(e) => updateAddress('neighborhood', e.target.value)}
+                      className={`${inputClass} col-span-6`}
+                      placeholder="Bairro"
+                    />
+                    <input
+                      value={address.city}
+                      onChange={(e) => updateAddress('city', e.target.value)}
+                      className={`${inputClass} col-span-4`}
+                      placeholder="Cidade"
+                    />
+                    <input
+                      value={address.state}
+                      onChange={(e) => updateAddress('state', e.target.value.toUpperCase())}
+                      className={`${inputClass} col-span-2`}
+                      placeholder="UF"
+                      maxLength={2}
+                    />
+                  </div>
+                )}
               </div>
 
               <div>
@@ -178,6 +301,19 @@ export default function Checkout() {
                 />
               </div>
 
+              <div>
+                <label className="block text-sm font-medium text-gray-700 dark:text-gray-300 mb-2">
+                  CPF/CNPJ na Nota (opcional)
+                </label>
+                <input
+                  value={taxDocument}
+                  onChange={(e) => setTaxDocument(e.target.value)}
+                  className={inputClass}
+                  placeholder="000.000.000-00"
+                  inputMode="numeric"
+                />
+              </div>
+
               <div>
                 <label className="block text-sm font-medium text-gray-700 dark:text-gray-300 mb-2">
                   Forma de Pagamento
@@ -206,6 +342,10 @@ export default function Checkout() {
                 />
               </div>
 
+              {error && (
+                <p className="text-sm text-red-600 dark:text-red-400">{error}</p>
+              )}
+
               <button
                 type="submit"
                 disabled={submitting}
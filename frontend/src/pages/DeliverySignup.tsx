@@ -11,7 +11,8 @@ export default function DeliverySignup() {
     email: '',
     password: '',
     confirmPassword: '',
-    vehicle: ''
+    vehicle: '',
+    plate: ''
   });
   const [error, setError] = useState('');
   const [loading, setLoading] = useState(false);
@@ -42,13 +43,22 @@ export default function DeliverySignup() {
       return;
     }
 
+    if (formData.vehicle !== 'bike' && !formData.plate) {
+      setError('Por favor, informe a placa do veículo');
+      setLoading(false);
+      return;
+    }
+
     try {
       await register({
         name: formData.name,
         email: formData.email,
         password: formData.password,
         type: 'delivery',
-        vehicle: formData.vehicle
+        vehicle: {
+          type: formData.vehicle,
+          plate: formData.vehicle === 'bike' ? '' : formData.plate
+        }
       });
       
       navigate('/login', {
@@ -130,13 +140,33 @@ export default function DeliverySignup() {
                   className="appearance-none block w-full px-3 py-2 border border-gray-300 rounded-md shadow-sm placeholder-gray-400 focus:outline-none focus:ring-pink-500 focus:border-pink-500 sm:text-sm"
                 >
                   <option value="">Selecione um veículo</option>
-                  <option value="moto">Moto</option>
-                  <option value="carro">Carro</option>
-                  <option value="bicicleta">Bicicleta</option>
+                  <option value="motorcycle">Moto</option>
+                  <option value="car">Carro</option>
+                  <option value="bike">Bicicleta</option>
                 </select>
               </div>
             </div>
 
+            {formData.vehicle && formData.vehicle !== 'bike' && (
+              <div>
+                <label htmlFor="plate" className="block text-sm font-medium text-gray-700">
+                  Placa
+                </label>
+                <div className="mt-1">
+                  <input
+                    id="plate"
+                    name="plate"
+                    type="text"
+                    required
+                    placeholder="ABC1D23"
+                    value={formData.plate}
+                    onChange={(e) => setFormData({ ...formData, plate: e.target.value.toUpperCase() })}
+                    className="appearance-none block w-full px-3 py-2 border border-gray-300 rounded-md shadow-sm placeholder-gray-400 focus:outline-none focus:ring-pink-500 focus:border-pink-500 sm:text-sm"
+                  />
+                </div>
+              </div>
+            )}
+
             <div>
               <label htmlFor="password" className="block text-sm font-medium text-gray-700">
                 Senha
@@ -5,7 +5,14 @@ interface RegisterData {
   email: string;
   password: string;
   type: 'customer' | 'delivery' | 'admin';
-  vehicle?: string;
+  vehicle?: VehicleData;
+}
+
+interface VehicleData {
+  type: 'bike' | 'motorcycle' | 'car';
+  plate?: string;
+  capacity_boxes?: number;
+  refrigerated?: boolean;
 }
 
 interface LoginCredentials {
@@ -20,11 +27,32 @@ interface ProductData {
   imageUrl: string;
 }
 
-interface OrderData {
+export interface PostalAddressData {
+  cep: string;
+  street: string;
+  number: string;
+  complement?: string;
+  neighborhood: string;
+  city: string;
+  state: string;
+}
+
+export interface SavedAddress extends PostalAddressData {
+  ID: number;
+  label: string;
+  address: string;
+  isDefault: boolean;
+}
+
+// Endereço salvo (address_id) ou estruturado; sem nenhum dos dois, o backend usa o endereço padrão
+interface OrderData extends Partial<PostalAddressData> {
   items: Array<{
     product_id: number;
     quantity: number;
   }>;
+  address_id?: number;
+  phone?: string;
+  document?: string;
 }
 
 class ApiService {
@@ -168,6 +196,19 @@ class ApiService {
     });
   }
 
+  // Profile endpoints
+  async getProfile() {
+    return this.makeRequest('/me');
+  }
+
+  async getAddresses(): Promise<SavedAddress[]> {
+    return this.makeRequest('/me/addresses');
+  }
+
+  async lookupCEP(cep: string): Promise<PostalAddressData> {
+    return this.makeRequest(`/cep/${encodeURIComponent(cep)}`);
+  }
+
   // Order endpoints
   async createOrder(orderData: OrderData) {
     return this.makeRequest('/orders', {
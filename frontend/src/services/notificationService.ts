@@ -71,6 +71,15 @@ class NotificationService {
     }
   }
 
+  // Stream das notificações (SSE); o navegador reconecta sozinho e retoma pelo último ID recebido
+  openStream(): EventSource | null {
+    const token = localStorage.getItem('token');
+    if (!token || typeof EventSource === 'undefined') {
+      return null;
+    }
+    return new EventSource(`${this.baseURL}/notifications/stream?access_token=${encodeURIComponent(token)}`);
+  }
+
   async markAsRead(notificationId: number): Promise<{ message: string }> {
     const headers = this.getAuthHeader();
     if (!headers) {